@@ -0,0 +1,60 @@
+package dgo
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/dgo/util"
+)
+
+type (
+	// ChangeKind tells what kind of difference a Change describes
+	ChangeKind int
+
+	// Change describes one difference between two values
+	Change interface {
+		util.Indentable
+		fmt.Stringer
+
+		// Kind returns the kind of change
+		Kind() ChangeKind
+
+		// Path returns the JSON Pointer (RFC 6901) to the value that changed. The path of a removed value is
+		// valid in the original value. All other paths are valid in the new value.
+		Path() string
+
+		// Old returns the original value. It is nil when the value was added. For a ChangeMoved, it is the
+		// Integer position of the key in the original Map.
+		Old() Value
+
+		// New returns the new value. It is nil when the value was removed. For a ChangeMoved, it is the
+		// Integer position of the key in the new Map.
+		New() Value
+	}
+
+	// Diff is the structural difference between two values.
+	Diff interface {
+		util.Indentable
+		fmt.Stringer
+
+		// Changes returns the changes in the order they were found
+		Changes() []Change
+
+		// Empty returns true when there are no changes
+		Empty() bool
+	}
+)
+
+const (
+	// ChangeAdded is a value that is present in the new value only
+	ChangeAdded = ChangeKind(iota)
+
+	// ChangeRemoved is a value that is present in the original value only
+	ChangeRemoved
+
+	// ChangeModified is a value that has been replaced with a different value
+	ChangeModified
+
+	// ChangeMoved is a Map key that has a different position relative to the other keys that are present
+	// in both the original and the new Map
+	ChangeMoved
+)
@@ -0,0 +1,38 @@
+package dgo
+
+type (
+	// FloatFormat determines how an encoder formats Float values
+	FloatFormat int
+
+	// EncoderOptions controls how values are encoded by the streaming JSON and YAML encoders. The zero value
+	// produces compact JSON and YAML with two space indentation, retains the order of map keys, doesn't escape
+	// HTML characters, and formats floats in the same way as the String method of a Float.
+	EncoderOptions struct {
+		// Indent is the string used for one level of indentation. JSON is written without any whitespace when
+		// it is empty. YAML is indented using two spaces when it is empty and it must only contain spaces.
+		Indent string
+
+		// EscapeHTML causes the characters <, >, and & in JSON strings to be escaped as \u003c, \u003e, and
+		// \u0026 so that the JSON can be safely embedded in HTML
+		EscapeHTML bool
+
+		// SortKeys causes the entries of maps to be written in key order rather than in insertion order
+		SortKeys bool
+
+		// Floats determines how Float values are formatted
+		Floats FloatFormat
+	}
+)
+
+const (
+	// FloatDefault formats floats using the shortest representation that retains the value and uses an
+	// exponent for large and small exponents
+	FloatDefault = FloatFormat(iota)
+
+	// FloatDecimal formats floats using the shortest representation that retains the value without using an
+	// exponent
+	FloatDecimal
+
+	// FloatExponent formats floats using the shortest representation that retains the value with an exponent
+	FloatExponent
+)
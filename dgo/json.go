@@ -0,0 +1,57 @@
+package dgo
+
+type (
+	// DuplicateKeyPolicy determines how a JSON decoder treats an object where the same key occurs more than once
+	DuplicateKeyPolicy int
+
+	// NumberMode determines what values a JSON decoder produces for numbers
+	NumberMode int
+
+	// JSONDecoderOptions controls how JSON is decoded. The zero value decodes in the same way as UnmarshalJSON,
+	// i.e. the last of duplicate keys wins, numbers become Integer or Float values, there are no limits, and the
+	// result is frozen.
+	JSONDecoderOptions struct {
+		// DuplicateKeys is the policy for objects where the same key occurs more than once
+		DuplicateKeys DuplicateKeyPolicy
+
+		// Numbers determines what values numbers are decoded into
+		Numbers NumberMode
+
+		// MaxDepth is the maximum nesting depth of arrays and objects. Zero means no limit.
+		MaxDepth int
+
+		// MaxSize is the maximum size of the document in bytes. Zero means no limit.
+		MaxSize int
+
+		// Mutable causes the decoded arrays and maps to be mutable rather than frozen
+		Mutable bool
+	}
+)
+
+const (
+	// DuplicateKeyLast retains the value of the last occurrence of a key
+	DuplicateKeyLast = DuplicateKeyPolicy(iota)
+
+	// DuplicateKeyFirst retains the value of the first occurrence of a key
+	DuplicateKeyFirst
+
+	// DuplicateKeyError makes the decoder return an error when a key occurs more than once
+	DuplicateKeyError
+)
+
+const (
+	// NumberInt64 decodes integers that fit in an int64 into Integer values and all other numbers into Float
+	// values
+	NumberInt64 = NumberMode(iota)
+
+	// NumberFloat decodes all numbers into Float values
+	NumberFloat
+
+	// NumberBig decodes integers that fit in an int64 into Integer values, integers that don't fit into frozen
+	// native *big.Int values, and all other numbers into Float values. The GoValue of such a native value is a
+	// copy of the integer.
+	NumberBig
+
+	// NumberPreserve decodes all numbers into native json.Number values that retain the original text
+	NumberPreserve
+)
@@ -134,6 +134,24 @@ type (
 		WithoutAll(keys Array) Map
 	}
 
+	// SortedMap is a Map that keeps its entries ordered by the natural order of their keys at all times. The
+	// order is determined by the CompareTo method of the keys. Keys of different types that cannot be compared
+	// are ordered by their type, and keys of the same type that cannot be compared are ordered by their string form.
+	SortedMap interface {
+		Map
+
+		// Ceiling returns the entry with the least key that is greater than or equal to the given key.
+		Ceiling(key interface{}) (MapEntry, bool)
+
+		// Floor returns the entry with the greatest key that is less than or equal to the given key.
+		Floor(key interface{}) (MapEntry, bool)
+
+		// SubMap returns a new SortedMap with the entries of this map that have a key that is greater than or
+		// equal to from and less than to. A nil from or to means that the range is unbounded in that direction.
+		// The frozen status of this SortedMap is inherited by the new SortedMap.
+		SubMap(from, to interface{}) SortedMap
+	}
+
 	// MapType is implemented by types representing implementations of the Map value
 	MapType interface {
 		SizedType
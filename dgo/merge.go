@@ -0,0 +1,24 @@
+package dgo
+
+// MergeStrategy determines how two values are merged. The second operand of a merge has precedence over
+// the first, i.e. when the values cannot be merged, the second value is used. Maps are merged so that the
+// result contains the keys of the first Map in their original order followed by the keys that are unique
+// to the second Map.
+//
+// All results are frozen.
+type MergeStrategy interface {
+	// Name returns the name of the strategy, i.e. "first", "hash", "deep", or "deep-unique".
+	Name() string
+
+	// Merge merges the two given values and returns the frozen result.
+	Merge(a, b Value) Value
+
+	// MergeAll merges the given values from left to right and returns the frozen result. The last value
+	// has the highest precedence. MergeAll returns Nil when no values are given.
+	MergeAll(values ...Value) Value
+
+	// WithOverride returns a copy of this strategy that uses the given strategy when merging the values of
+	// the given key. Nested Maps are merged using the same strategy, so the override applies at any depth
+	// except below keys that are merged using a different strategy.
+	WithOverride(key interface{}, s MergeStrategy) MergeStrategy
+}
@@ -0,0 +1,46 @@
+package dgo
+
+type (
+	// PathError is the error produced when a JSON Pointer (RFC 6901) cannot be resolved against a value.
+	PathError interface {
+		Value
+		error
+
+		// Path returns the part of the pointer that was resolved when the error occurred, including the
+		// token that could not be resolved.
+		Path() string
+	}
+
+	// MissingKeyError is the PathError produced when a Map has no entry for a pointer token.
+	MissingKeyError interface {
+		PathError
+
+		// Key returns the key that could not be found
+		Key() string
+	}
+
+	// IndexOutOfRangeError is the PathError produced when a pointer token denotes an index that is outside of the
+	// range of an Array.
+	IndexOutOfRangeError interface {
+		PathError
+
+		// Index returns the index that was out of range. The index is equal to the length of the Array when the
+		// token is "-".
+		Index() int
+
+		// Len returns the length of the Array
+		Len() int
+	}
+
+	// PathTypeError is the PathError produced when a pointer token cannot be applied to a value because the value
+	// isn't a Map or an Array, or because the token isn't a valid Array index.
+	PathTypeError interface {
+		PathError
+
+		// Token returns the token that could not be applied
+		Token() string
+
+		// Value returns the value that the token could not be applied to
+		Value() Value
+	}
+)
@@ -0,0 +1,11 @@
+package dgo
+
+// Query is a compiled JSONPath expression that selects values from a Value.
+type Query interface {
+	// Select returns a frozen Array with all values in the given value that are matched by the query. The
+	// values are in the order they are found when traversing the given value depth first.
+	Select(value Value) Array
+
+	// String returns the expression that the query was compiled from
+	String() string
+}
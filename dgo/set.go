@@ -0,0 +1,72 @@
+package dgo
+
+import (
+	"encoding/json"
+
+	"github.com/lyraproj/dgo/util"
+)
+
+type (
+	// Set represents a collection of unique values. Uniqueness is determined using the HashCode and Equals
+	// methods of the values. The Set preserves the order by which the values were added.
+	//
+	// All values added to a Set are frozen since a mutation of a value would change its hash code.
+	Set interface {
+		Iterable
+		Freezable
+		util.Indentable
+		json.Marshaler
+		json.Unmarshaler
+
+		// Add adds the given value to this Set unless it is already present. The method returns true if the
+		// value was added and false if it was already present. It panics if the receiver is frozen.
+		Add(val interface{}) bool
+
+		// AddAll adds all values of the given Iterable to this Set. It panics if the receiver is frozen.
+		AddAll(values Iterable)
+
+		// Contains returns true if the given value is present in this Set.
+		Contains(val interface{}) bool
+
+		// Copy returns a copy of the Set. The copy is frozen or mutable depending on the given argument. A
+		// request to create a frozen copy of an already frozen Set is a no-op that returns the receiver.
+		Copy(frozen bool) Set
+
+		// Difference returns a new Set containing the values of this Set that are not present in the
+		// given Set. The frozen status of this Set is inherited by the new Set.
+		Difference(other Set) Set
+
+		// Intersection returns a new Set containing the values of this Set that are also present in the
+		// given Set. The frozen status of this Set is inherited by the new Set.
+		Intersection(other Set) Set
+
+		// Len returns the number of values in this Set.
+		Len() int
+
+		// Remove removes the given value from this Set. The method returns true if the value was removed
+		// and false if it wasn't present. It panics if the receiver is frozen.
+		Remove(val interface{}) bool
+
+		// SetType sets the type for this Set to the given SetType. The Set must be mutable and an instance
+		// of the given type
+		SetType(t SetType)
+
+		// SubsetOf returns true if all values of this Set are also present in the given Set.
+		SubsetOf(other Set) bool
+
+		// ToArray returns a frozen Array containing the values of this Set in insertion order.
+		ToArray() Array
+
+		// Union returns a new Set containing the values of this Set followed by the values of the given
+		// Set that were not present in this Set. The frozen status of this Set is inherited by the new Set.
+		Union(other Set) Set
+	}
+
+	// SetType is implemented by types representing implementations of the Set value
+	SetType interface {
+		SizedType
+
+		// ElementType returns the type of the elements for instances of this type
+		ElementType() Type
+	}
+)
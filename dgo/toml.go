@@ -0,0 +1,49 @@
+package dgo
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+type (
+	// LocalDate is a date without a time of day or a time zone, such as a TOML local date.
+	LocalDate struct {
+		Year  int
+		Month time.Month
+		Day   int
+	}
+
+	// LocalTime is a time of day without a date or a time zone, such as a TOML local time.
+	LocalTime struct {
+		Hour       int
+		Minute     int
+		Second     int
+		Nanosecond int
+	}
+
+	// LocalDateTime is a date and a time of day without a time zone, such as a TOML local date-time.
+	LocalDateTime struct {
+		Date LocalDate
+		Time LocalTime
+	}
+)
+
+// String returns the date in the form YYYY-MM-DD
+func (d LocalDate) String() string {
+	return fmt.Sprintf(`%04d-%02d-%02d`, d.Year, int(d.Month), d.Day)
+}
+
+// String returns the time in the form HH:MM:SS followed by the fraction of the second, if any
+func (t LocalTime) String() string {
+	s := fmt.Sprintf(`%02d:%02d:%02d`, t.Hour, t.Minute, t.Second)
+	if t.Nanosecond != 0 {
+		s += strings.TrimRight(fmt.Sprintf(`.%09d`, t.Nanosecond), `0`)
+	}
+	return s
+}
+
+// String returns the date and the time separated by a 'T'
+func (dt LocalDateTime) String() string {
+	return dt.Date.String() + `T` + dt.Time.String()
+}
@@ -101,4 +101,17 @@ const (
 	IdOneOf
 
 	IdError
+
+	IdNever
+
+	IdPredicate
+
+	IdSet
+	IdSetExact
+	IdSetElementSized
+
+	// IdExtension is the first TypeIdentifier that is allocated for a TypeKind that is registered by an
+	// extension. Identifiers for such kinds are allocated in registration order and are therefore sorted
+	// after all built in identifiers.
+	IdExtension
 )
@@ -0,0 +1,24 @@
+package dgo
+
+// TypeKind is a kind of type that has been added to the type system by an extension. Once registered,
+// the name of the kind can be used in type expressions, either alone or followed by arguments in square
+// brackets, e.g. "semver" or "semver[1,2]". All types of the kind must return the kind's Identifier from
+// their TypeIdentifier method.
+type TypeKind interface {
+	// Identifier returns the TypeIdentifier that was allocated for this kind when it was registered.
+	Identifier() TypeIdentifier
+
+	// Name returns the name that represents this kind in type expressions.
+	Name() string
+
+	// Tag returns the YAML tag that is used when serializing types of this kind.
+	Tag() string
+
+	// New creates a type of this kind from the arguments that follows the name in a type expression. The
+	// arguments are empty when the name is not followed by square brackets.
+	New(args Array) (Type, error)
+
+	// TypeString returns the string representation of the given type of this kind, i.e. a type expression
+	// that New will recreate the type from.
+	TypeString(t Type) string
+}
@@ -2,8 +2,12 @@ package require
 
 import (
 	"fmt"
+	"math/rand"
+	"os"
 	"regexp"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/lyraproj/dgo/dgo"
 	"github.com/lyraproj/dgo/internal"
@@ -150,3 +154,77 @@ func Panic(t *testing.T, f func(), v interface{}) {
 	}
 	t.Errorf(`recovered "%s" does not match "%v"`, err.Error(), v)
 }
+
+const (
+	// forAllCases is the number of values that ForAll generates and tests
+	forAllCases = 100
+
+	// forAllSeedVariable is the name of the environment variable that, when set, provides the seed that
+	// ForAll uses for its first case. The seed of each subsequent case is incremented by one.
+	forAllSeedVariable = `DGO_SEED`
+
+	// maxShrinkSteps limits the number of times that a counterexample is shrunk
+	maxShrinkSteps = 1000
+)
+
+// ForAll will fail unless the given property holds for a number of randomly generated instances of the
+// given type. A property that panics is considered to not hold.
+//
+// When a counterexample is found, it is shrunk within the constraints of the type until no smaller value
+// can be found for which the property doesn't hold. Arrays become shorter, optional Struct entries are
+// removed, and integers and floats move toward the minimum of their range. The failure reports both the
+// original and the shrunk value together with the seed that was used so that the failure can be
+// reproduced by assigning that seed to the environment variable DGO_SEED.
+func ForAll(t *testing.T, typ dgo.Type, property func(v dgo.Value) bool) {
+	t.Helper()
+	seed := time.Now().UnixNano()
+	if s, ok := os.LookupEnv(forAllSeedVariable); ok {
+		var err error
+		if seed, err = strconv.ParseInt(s, 10, 64); err != nil {
+			t.Errorf(`%s: %s`, forAllSeedVariable, err.Error())
+			return
+		}
+	}
+	for i := 0; i < forAllCases; i++ {
+		cs := seed + int64(i)
+		v, err := internal.Generate(typ, rand.NewSource(cs))
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		if holds(property, v) {
+			continue
+		}
+		sv, steps := shrinkCounterexample(typ, v, property)
+		t.Errorf("property does not hold for %s\nshrunk from %s in %d steps\nreproduce using %s=%d",
+			sv, v, steps, forAllSeedVariable, cs)
+		return
+	}
+}
+
+func holds(property func(v dgo.Value) bool, v dgo.Value) (ok bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			ok = false
+		}
+	}()
+	return property(v)
+}
+
+// shrinkCounterexample repeatedly replaces the given value with the first shrunk candidate for which the
+// property doesn't hold.
+func shrinkCounterexample(typ dgo.Type, v dgo.Value, property func(v dgo.Value) bool) (dgo.Value, int) {
+	steps := 0
+next:
+	for steps < maxShrinkSteps {
+		for _, c := range internal.Shrink(typ, v) {
+			if !holds(property, c) {
+				v = c
+				steps++
+				continue next
+			}
+		}
+		break
+	}
+	return v, steps
+}
@@ -0,0 +1,580 @@
+package internal
+
+import (
+	"math"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// typeKind is a coarse classification of types. Two types that have different known kinds cannot
+// have any instances in common.
+type typeKind int
+
+const (
+	kindUnknown = typeKind(iota)
+	kindNil
+	kindBoolean
+	kindInteger
+	kindFloat
+	kindBinary
+	kindString
+	kindRegexp
+	kindArray
+	kindSet
+	kindMap
+	kindError
+	kindMeta
+)
+
+func kindOf(t dgo.Type) typeKind {
+	switch t.TypeIdentifier() {
+	case dgo.IdNil:
+		return kindNil
+	case dgo.IdBoolean, dgo.IdFalse, dgo.IdTrue:
+		return kindBoolean
+	case dgo.IdInteger, dgo.IdIntegerExact, dgo.IdIntegerRange:
+		return kindInteger
+	case dgo.IdFloat, dgo.IdFloatExact, dgo.IdFloatRange:
+		return kindFloat
+	case dgo.IdBinary, dgo.IdBinaryExact:
+		return kindBinary
+	case dgo.IdString, dgo.IdStringExact, dgo.IdStringPattern, dgo.IdStringSized:
+		return kindString
+	case dgo.IdRegexp, dgo.IdRegexpExact:
+		return kindRegexp
+	case dgo.IdArray, dgo.IdArrayExact, dgo.IdArrayElementSized, dgo.IdTuple:
+		return kindArray
+	case dgo.IdSet, dgo.IdSetExact, dgo.IdSetElementSized:
+		return kindSet
+	case dgo.IdMap, dgo.IdMapExact, dgo.IdMapSized, dgo.IdStruct:
+		return kindMap
+	case dgo.IdError:
+		return kindError
+	case dgo.IdMeta:
+		return kindMeta
+	}
+	return kindUnknown
+}
+
+// exactValue returns the value of a type that represents exactly one value
+func exactValue(t dgo.Type) (dgo.Value, bool) {
+	switch t.TypeIdentifier() {
+	case dgo.IdIntegerExact, dgo.IdFloatExact, dgo.IdBinaryExact, dgo.IdStringExact, dgo.IdRegexpExact,
+		dgo.IdArrayExact, dgo.IdSetExact, dgo.IdMapExact:
+		return t.(dgo.ExactType).Value(), true
+	}
+	return nil, false
+}
+
+// Intersect returns a type that represents the values that are instances of all of the given types. The
+// result is computed where possible, e.g. the intersection of two integer ranges is a new range and the
+// intersection of two Struct types is a Struct where the common entries are intersected. The Never type
+// is returned when the types have no instances in common. An AllOf type is returned for intersections
+// that cannot be computed.
+func Intersect(types []dgo.Type) dgo.Type {
+	var ops []dgo.Type
+	for _, t := range types {
+		ops = addIntersection(ops, t)
+		if len(ops) == 1 && ops[0] == DefaultNeverType {
+			break
+		}
+	}
+	return AllOfType(ops)
+}
+
+// addIntersection intersects the given type with each of the given operands of an AllOf and returns the
+// resulting operands.
+func addIntersection(ops []dgo.Type, t dgo.Type) []dgo.Type {
+	if at, ok := t.(*allOfType); ok {
+		for _, o := range at.slice {
+			ops = addIntersection(ops, o.(dgo.Type))
+		}
+		return ops
+	}
+	for i, o := range ops {
+		r := intersect(o, t)
+		if _, ok := r.(*allOfType); ok {
+			continue
+		}
+		if r == DefaultNeverType {
+			return []dgo.Type{r}
+		}
+		// The result may intersect with other operands now
+		return replaceOperand(ops, i, r, addIntersection)
+	}
+	return append(ops, t)
+}
+
+// replaceOperand removes the operand at the given position, adds the given type to the remaining operands
+// using the given function, and moves the type back to the position of the removed operand unless it was
+// merged with another operand.
+func replaceOperand(ops []dgo.Type, i int, t dgo.Type, add func([]dgo.Type, dgo.Type) []dgo.Type) []dgo.Type {
+	n := len(ops) - 1
+	rest := append(append(make([]dgo.Type, 0, len(ops)), ops[:i]...), ops[i+1:]...)
+	rest = add(rest, t)
+	if len(rest) == n+1 && i < n {
+		copy(rest[i+1:], rest[i:n])
+		rest[i] = t
+	}
+	return rest
+}
+
+func intersect(a, b dgo.Type) dgo.Type {
+	// The Assignable method of a Not type is not exact enough to be trusted here
+	if nt, ok := a.(*notType); ok {
+		return intersectNot(nt, b)
+	}
+	if nt, ok := b.(*notType); ok {
+		return intersectNot(nt, a)
+	}
+	switch {
+	case a.Assignable(b):
+		return b
+	case b.Assignable(a):
+		return a
+	}
+	if v, ok := exactValue(a); ok {
+		return exactOrNever(a, b.Instance(v))
+	}
+	if v, ok := exactValue(b); ok {
+		return exactOrNever(b, a.Instance(v))
+	}
+	if at, ok := a.(*anyOfType); ok {
+		return distributeIntersection(at, b)
+	}
+	if bt, ok := b.(*anyOfType); ok {
+		return distributeIntersection(bt, a)
+	}
+	ka, kb := kindOf(a), kindOf(b)
+	if ka != kindUnknown && kb != kindUnknown && ka != kb {
+		return DefaultNeverType
+	}
+	switch ka {
+	case kindBoolean:
+		// Neither is assignable to the other so one must be true and the other false
+		return DefaultNeverType
+	case kindInteger:
+		ar, br := a.(dgo.IntegerRangeType), b.(dgo.IntegerRangeType)
+		min, max := maxInt64(ar.Min(), br.Min()), minInt64(ar.Max(), br.Max())
+		if min > max {
+			return DefaultNeverType
+		}
+		return IntegerRangeType(min, max)
+	case kindFloat:
+		ar, br := a.(dgo.FloatRangeType), b.(dgo.FloatRangeType)
+		min, max := math.Max(ar.Min(), br.Min()), math.Min(ar.Max(), br.Max())
+		if min > max {
+			return DefaultNeverType
+		}
+		return FloatRangeType(min, max)
+	case kindString:
+		if isSizedString(a) && isSizedString(b) {
+			if min, max, ok := intersectSize(a.(dgo.SizedType), b.(dgo.SizedType)); ok {
+				return SizedStringType(min, max)
+			}
+			return DefaultNeverType
+		}
+	case kindArray:
+		return intersectArrays(a, b)
+	case kindSet:
+		if isSizedSet(a) && isSizedSet(b) {
+			at, bt := a.(dgo.SetType), b.(dgo.SetType)
+			et := Intersect([]dgo.Type{at.ElementType(), bt.ElementType()})
+			return sizedOrNever(at, bt, et, func(et dgo.Type, min, max int) dgo.Type { return newSetType(et, min, max) })
+		}
+	case kindMap:
+		return intersectMaps(a, b)
+	}
+	return &allOfType{slice: []dgo.Value{a, b}, frozen: true}
+}
+
+// intersectNot intersects the negation of a type with another type
+func intersectNot(a *notType, b dgo.Type) dgo.Type {
+	if bt, ok := b.(*notType); ok {
+		return NotType(Union([]dgo.Type{a.Negated, bt.Negated}))
+	}
+	if a.Negated.Assignable(b) {
+		return DefaultNeverType
+	}
+	if Intersect([]dgo.Type{a.Negated, b}) == DefaultNeverType {
+		return b
+	}
+	if t := subtractRange(b, a.Negated); t != nil {
+		return t
+	}
+	return &allOfType{slice: []dgo.Value{b, a}, frozen: true}
+}
+
+func exactOrNever(t dgo.Type, instance bool) dgo.Type {
+	if instance {
+		return t
+	}
+	return DefaultNeverType
+}
+
+func distributeIntersection(at *anyOfType, b dgo.Type) dgo.Type {
+	ts := make([]dgo.Type, len(at.slice))
+	for i, o := range at.slice {
+		ts[i] = Intersect([]dgo.Type{o.(dgo.Type), b})
+	}
+	return Union(ts)
+}
+
+func intersectArrays(a, b dgo.Type) dgo.Type {
+	at, aTuple := a.(*tupleType)
+	bt, bTuple := b.(*tupleType)
+	switch {
+	case aTuple && bTuple:
+		if len(at.slice) != len(bt.slice) {
+			return DefaultNeverType
+		}
+		return intersectTuple(at.slice, func(i int) dgo.Type { return bt.slice[i].(dgo.Type) })
+	case aTuple && isSizedArray(b):
+		return intersectTupleWithArray(at, b.(dgo.ArrayType))
+	case bTuple && isSizedArray(a):
+		return intersectTupleWithArray(bt, a.(dgo.ArrayType))
+	case isSizedArray(a) && isSizedArray(b):
+		aa, ba := a.(dgo.ArrayType), b.(dgo.ArrayType)
+		et := Intersect([]dgo.Type{aa.ElementType(), ba.ElementType()})
+		return sizedOrNever(aa, ba, et, func(et dgo.Type, min, max int) dgo.Type { return newArrayType(et, min, max) })
+	}
+	return &allOfType{slice: []dgo.Value{a, b}, frozen: true}
+}
+
+func intersectTupleWithArray(t *tupleType, a dgo.ArrayType) dgo.Type {
+	if l := len(t.slice); l < a.Min() || l > a.Max() {
+		return DefaultNeverType
+	}
+	et := a.ElementType()
+	return intersectTuple(t.slice, func(int) dgo.Type { return et })
+}
+
+func intersectTuple(es []dgo.Value, other func(int) dgo.Type) dgo.Type {
+	ts := make([]dgo.Type, len(es))
+	for i, e := range es {
+		ts[i] = Intersect([]dgo.Type{e.(dgo.Type), other(i)})
+		if ts[i] == DefaultNeverType {
+			return DefaultNeverType
+		}
+	}
+	return TupleType(ts)
+}
+
+func intersectMaps(a, b dgo.Type) dgo.Type {
+	as, aStruct := a.(*structType)
+	bs, bStruct := b.(*structType)
+	switch {
+	case aStruct && bStruct:
+		return intersectStructs(as, bs)
+	case isSizedMap(a) && isSizedMap(b):
+		am, bm := a.(dgo.MapType), b.(dgo.MapType)
+		kt := Intersect([]dgo.Type{am.KeyType(), bm.KeyType()})
+		vt := Intersect([]dgo.Type{am.ValueType(), bm.ValueType()})
+		if vt == DefaultNeverType {
+			kt = DefaultNeverType
+		}
+		return sizedOrNever(am, bm, kt, func(kt dgo.Type, min, max int) dgo.Type {
+			return newMapType(kt, vt, min, max)
+		})
+	}
+	return &allOfType{slice: []dgo.Value{a, b}, frozen: true}
+}
+
+// intersectStructs merges the entries of two structs. Entries that are present in both are intersected
+// and required if either of them is required. Entries that are present in one struct only are retained
+// if the other struct allows additional entries.
+func intersectStructs(a, b *structType) dgo.Type {
+	m := &hashMap{table: make([]*hashNode, tableSizeFor(a.entries.len+b.entries.len))}
+	add := func(k dgo.Value, e *entryType, other *structType) bool {
+		if oe, ok := other.entries.Get(k); ok {
+			oet := oe.(*entryType)
+			vt := Intersect([]dgo.Type{e.value, oet.value})
+			required := e.required || oet.required
+			if vt == DefaultNeverType {
+				if required {
+					return false
+				}
+				// Entry cannot be present
+				return true
+			}
+			m.Put(k, &entryType{key: e.key, value: vt, required: required})
+			return true
+		}
+		if other.additional {
+			m.Put(k, e)
+			return true
+		}
+		// Entry is disallowed by the other struct
+		return !e.required
+	}
+	for n := a.entries.first; n != nil; n = n.next {
+		if !add(n.key, n.value.(*entryType), b) {
+			return DefaultNeverType
+		}
+	}
+	for n := b.entries.first; n != nil; n = n.next {
+		if _, ok := a.entries.Get(n.key); !ok {
+			if !add(n.key, n.value.(*entryType), a) {
+				return DefaultNeverType
+			}
+		}
+	}
+	m.Freeze()
+	return &structType{additional: a.additional && b.additional, entries: m}
+}
+
+// sizedOrNever calls the given constructor with the intersection of the sizes of the given types unless
+// the sizes don't overlap or the element type is Never and the minimum size is greater than zero.
+func sizedOrNever(a, b dgo.SizedType, et dgo.Type, f func(et dgo.Type, min, max int) dgo.Type) dgo.Type {
+	min, max, ok := intersectSize(a, b)
+	if !ok {
+		return DefaultNeverType
+	}
+	if et == DefaultNeverType {
+		if min > 0 {
+			return DefaultNeverType
+		}
+		max = 0
+	}
+	return f(et, min, max)
+}
+
+func intersectSize(a, b dgo.SizedType) (int, int, bool) {
+	min, max := a.Min(), a.Max()
+	if bm := b.Min(); bm > min {
+		min = bm
+	}
+	if bm := b.Max(); bm < max {
+		max = bm
+	}
+	return min, max, min <= max
+}
+
+// Union returns a type that represents the values that are instances of at least one of the given types.
+// Operands that are assignable to other operands are dropped and overlapping or adjacent ranges are
+// merged. An AnyOf type is returned for unions that cannot be computed, and the Never type is returned
+// for the union of no types.
+func Union(types []dgo.Type) dgo.Type {
+	var ops []dgo.Type
+	for _, t := range types {
+		ops = addUnion(ops, t)
+	}
+	if len(ops) == 0 {
+		return DefaultNeverType
+	}
+	return AnyOfType(ops)
+}
+
+// addUnion merges the given type into the given operands of an AnyOf and returns the resulting operands.
+func addUnion(ops []dgo.Type, t dgo.Type) []dgo.Type {
+	if at, ok := t.(*anyOfType); ok {
+		for _, o := range at.slice {
+			ops = addUnion(ops, o.(dgo.Type))
+		}
+		return ops
+	}
+	if t == DefaultNeverType {
+		return ops
+	}
+	for i, o := range ops {
+		if r := union(o, t); r != nil {
+			// The result may merge with other operands now
+			return replaceOperand(ops, i, r, addUnion)
+		}
+	}
+	return append(ops, t)
+}
+
+// union returns the union of the two types or nil when it cannot be computed
+func union(a, b dgo.Type) dgo.Type {
+	if nt, ok := a.(*notType); ok {
+		return unionNot(nt, b)
+	}
+	if nt, ok := b.(*notType); ok {
+		return unionNot(nt, a)
+	}
+	switch {
+	case a.Assignable(b):
+		return a
+	case b.Assignable(a):
+		return b
+	}
+	if kindOf(a) != kindOf(b) {
+		return nil
+	}
+	switch kindOf(a) {
+	case kindBoolean:
+		// Neither is assignable to the other so one must be true and the other false
+		return DefaultBooleanType
+	case kindInteger:
+		ar, br := a.(dgo.IntegerRangeType), b.(dgo.IntegerRangeType)
+		if ar.Min() > br.Min() {
+			ar, br = br, ar
+		}
+		// Ranges must overlap or be adjacent
+		if ar.Max() == math.MaxInt64 || br.Min() <= ar.Max()+1 {
+			return IntegerRangeType(ar.Min(), maxInt64(ar.Max(), br.Max()))
+		}
+	case kindFloat:
+		ar, br := a.(dgo.FloatRangeType), b.(dgo.FloatRangeType)
+		if ar.Min() > br.Min() {
+			ar, br = br, ar
+		}
+		if br.Min() <= ar.Max() {
+			return FloatRangeType(ar.Min(), math.Max(ar.Max(), br.Max()))
+		}
+	case kindString:
+		if isSizedString(a) && isSizedString(b) {
+			as, bs := a.(dgo.SizedType), b.(dgo.SizedType)
+			if as.Min() > bs.Min() {
+				as, bs = bs, as
+			}
+			if as.Max() == math.MaxInt64 || bs.Min() <= as.Max()+1 {
+				max := as.Max()
+				if bs.Max() > max {
+					max = bs.Max()
+				}
+				return SizedStringType(as.Min(), max)
+			}
+		}
+	}
+	return nil
+}
+
+// unionNot returns the union of the negation of a type and another type or nil when it cannot be computed
+func unionNot(a *notType, b dgo.Type) dgo.Type {
+	if bt, ok := b.(*notType); ok {
+		if it := Intersect([]dgo.Type{a.Negated, bt.Negated}); it != DefaultNeverType {
+			return NotType(it)
+		}
+		return DefaultAnyType
+	}
+	if b.Assignable(a.Negated) {
+		return DefaultAnyType
+	}
+	if Intersect([]dgo.Type{a.Negated, b}) == DefaultNeverType {
+		return a
+	}
+	return nil
+}
+
+// Subtract returns a type that represents the values that are instances of the type a but not of the
+// type b. The result is computed where possible, e.g. subtracting a range from another range yields the
+// remaining part of the first range. A type equivalent to a&!b is returned when the difference cannot
+// be computed.
+func Subtract(a, b dgo.Type) dgo.Type {
+	if bt, ok := b.(*notType); ok {
+		return Intersect([]dgo.Type{a, bt.Negated})
+	}
+	if at, ok := a.(*notType); ok {
+		return NotType(Union([]dgo.Type{at.Negated, b}))
+	}
+	if b.Assignable(a) {
+		return DefaultNeverType
+	}
+	if at, ok := a.(*anyOfType); ok {
+		ts := make([]dgo.Type, len(at.slice))
+		for i, o := range at.slice {
+			ts[i] = Subtract(o.(dgo.Type), b)
+		}
+		return Union(ts)
+	}
+	if bt, ok := b.(*anyOfType); ok {
+		for _, o := range bt.slice {
+			a = Subtract(a, o.(dgo.Type))
+		}
+		return a
+	}
+	if Intersect([]dgo.Type{a, b}) == DefaultNeverType {
+		return a
+	}
+	if kindOf(a) == kindBoolean && kindOf(b) == kindBoolean {
+		// a must be bool and b must be true or false
+		if b == TrueType {
+			return FalseType
+		}
+		return TrueType
+	}
+	if t := subtractRange(a, b); t != nil {
+		return t
+	}
+	return AllOfType([]dgo.Type{a, NotType(b)})
+}
+
+// subtractRange returns the parts of the integer or float range a that are outside of the range b, or nil
+// when a and b are not ranges of the same kind. The ranges must overlap.
+func subtractRange(a, b dgo.Type) dgo.Type {
+	k := kindOf(a)
+	if k != kindOf(b) {
+		return nil
+	}
+	var ts []dgo.Type
+	switch k {
+	case kindInteger:
+		ar, br := a.(dgo.IntegerRangeType), b.(dgo.IntegerRangeType)
+		if ar.Min() < br.Min() {
+			ts = append(ts, IntegerRangeType(ar.Min(), br.Min()-1))
+		}
+		if br.Max() < ar.Max() {
+			ts = append(ts, IntegerRangeType(br.Max()+1, ar.Max()))
+		}
+	case kindFloat:
+		// Float ranges are inclusive so the parts start and end at the adjacent float values
+		ar, br := a.(dgo.FloatRangeType), b.(dgo.FloatRangeType)
+		if ar.Min() < br.Min() {
+			ts = append(ts, FloatRangeType(ar.Min(), math.Nextafter(br.Min(), math.Inf(-1))))
+		}
+		if br.Max() < ar.Max() {
+			ts = append(ts, FloatRangeType(math.Nextafter(br.Max(), math.Inf(1)), ar.Max()))
+		}
+	default:
+		return nil
+	}
+	return Union(ts)
+}
+
+func isSizedString(t dgo.Type) bool {
+	switch t.(type) {
+	case defaultStringType, *sizedStringType:
+		return true
+	}
+	return false
+}
+
+func isSizedArray(t dgo.Type) bool {
+	switch t.(type) {
+	case defaultArrayType, *sizedArrayType:
+		return true
+	}
+	return false
+}
+
+func isSizedSet(t dgo.Type) bool {
+	switch t.(type) {
+	case defaultSetType, *sizedSetType:
+		return true
+	}
+	return false
+}
+
+func isSizedMap(t dgo.Type) bool {
+	switch t.(type) {
+	case defaultMapType, *sizedMapType:
+		return true
+	}
+	return false
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,164 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func TestNever(t *testing.T) {
+	require.Equal(t, `never`, typ.Never.String())
+	require.Equal(t, typ.Never, newtype.Parse(`never`))
+	require.NotInstance(t, typ.Never, vf.Nil)
+	require.Assignable(t, typ.Never, typ.Never)
+	require.NotAssignable(t, typ.Never, typ.Integer)
+	require.Equal(t, typ.Never, typ.Never.Type().(dgo.UnaryType).Operand())
+	require.NotEqual(t, typ.Never.HashCode(), typ.Any.HashCode())
+	for _, tp := range []dgo.Type{
+		typ.Any, typ.Integer, typ.String, typ.Boolean, typ.Nil, typ.Array, typ.Map, typ.Set, typ.Binary,
+		typ.Float, typ.Regexp, typ.Error, newtype.Parse(`{"a":int}`), newtype.Parse(`1..5|string`)} {
+		require.Assignable(t, tp, typ.Never)
+	}
+}
+
+func TestIntersect(t *testing.T) {
+	tests := []struct {
+		a      string
+		b      string
+		result string
+	}{
+		{`0..10`, `5..20`, `5..10`},
+		{`0..5`, `5..20`, `5`},
+		{`0..4`, `5..20`, `never`},
+		{`0..10`, `int`, `0..10`},
+		{`0.0..1.0`, `0.5..2.0`, `0.5..1.0`},
+		{`0.0..1.0`, `2.0..3.0`, `never`},
+		{`string[1,5]`, `string[3,8]`, `string[3,5]`},
+		{`string[1,2]`, `string[3,8]`, `never`},
+		{`int`, `string`, `never`},
+		{`true`, `false`, `never`},
+		{`true`, `bool`, `true`},
+		{`"abc"`, `string[1,5]`, `"abc"`},
+		{`"abc"`, `string[4]`, `never`},
+		{`/a/`, `string[1,5]`, `/a/&string[1,5]`},
+		{`3`, `0..10`, `3`},
+		{`int|string`, `1..5`, `1..5`},
+		{`0..3|7..9`, `2..8`, `2..3|7..8`},
+		{`[]0..10`, `[2,5]5..20`, `[2,5]5..10`},
+		{`[1,2]int`, `[3,4]int`, `never`},
+		{`[]int`, `[]string`, `[0,0]never`},
+		{`[1]int`, `[]string`, `never`},
+		{`{int,string}`, `{0..5,string[1]}`, `{0..5,string[1]}`},
+		{`{int,string}`, `{int}`, `never`},
+		{`{int,string}`, `{int,int}`, `never`},
+		{`{int,string}`, `[](0..5|string)`, `{0..5,string}`},
+		{`{int,string}`, `[]0..5|string`, `never`},
+		{`{int,string}`, `[3]any`, `never`},
+		{`map[string]int`, `map[string[1],1,3]0..5`, `map[string[1],1,3]0..5`},
+		{`map[string]int`, `map[string]string`, `map[never,0,0]never`},
+		{`set[int]`, `set[0..5,1]`, `set[0..5,1]`},
+		{`{"a":int,"b"?:string}`, `{"a":0..5,"b":string[1]}`, `{"a":0..5,"b":string[1]}`},
+		{`{"a":int,"b"?:string}`, `{"a":int,"b"?:int}`, `{"a":int}`},
+		{`{"a":int,"b":string}`, `{"a":int,"b":int}`, `never`},
+		{`{"a":int,"b"?:string}`, `{"a":int}`, `{"a":int}`},
+		{`{"a":int,"b":string}`, `{"a":int}`, `never`},
+		{`{"a":int}`, `{"a":int,"b":string}`, `never`},
+		{`{"a":int}`, `map[string]int`, `{"a":int}&map[string]int`},
+		{`1..10&!5`, `3..8`, `3..4|6..8`},
+		{`int`, `1..5&!3`, `1..2|4..5`},
+		{`!5`, `5`, `never`},
+		{`!5`, `3..4`, `3..4`},
+		{`!0..5`, `0..10`, `6..10`},
+		{`!0..5`, `int`, `..-1|6..`},
+		{`!0.0..5.0`, `0.0..10.0`, `5.000000000000001..10.0`},
+		{`string`, `!"a"`, `string&!"a"`},
+		{`!int`, `!string`, `!(int|string)`},
+		{`type[int]`, `type[string]`, `type[int]&type[string]`},
+	}
+	for _, tc := range tests {
+		r := newtype.Intersect(newtype.Parse(tc.a), newtype.Parse(tc.b))
+		require.Equal(t, tc.result, r.String())
+	}
+	require.Equal(t, typ.Any, newtype.Intersect())
+	require.Equal(t, `2..3`, newtype.Intersect(newtype.Parse(`0..5`), newtype.Parse(`2..10`), newtype.Parse(`..3`)).String())
+	require.Equal(t, typ.Never, newtype.Intersect(newtype.Parse(`0..5`), typ.String, typ.Integer))
+	require.Equal(t, `3|5`, newtype.Intersect(newtype.Parse(`0..5&!4`), newtype.Parse(`3..7`)).String())
+}
+
+func TestUnion(t *testing.T) {
+	tests := []struct {
+		a      string
+		b      string
+		result string
+	}{
+		{`0..10`, `5..20`, `0..20`},
+		{`0..4`, `5..20`, `0..20`},
+		{`0..3`, `5..20`, `0..3|5..20`},
+		{`0..`, `5..20`, `0..`},
+		{`..3`, `5..`, `..3|5..`},
+		{`1`, `2`, `1..2`},
+		{`0.0..1.0`, `0.5..2.0`, `0.0..2.0`},
+		{`0.0..1.0`, `1.5..2.0`, `0.0..1.0|1.5..2.0`},
+		{`string[1,5]`, `string[3,8]`, `string[1,8]`},
+		{`string[1,5]`, `string[6]`, `string[1]`},
+		{`string[1,2]`, `string[5,8]`, `string[1,2]|string[5,8]`},
+		{`true`, `false`, `bool`},
+		{`"a"`, `string`, `string`},
+		{`int`, `string`, `int|string`},
+		{`int|string`, `1..5`, `int|string`},
+		{`0..3|7..9`, `4..6`, `0..9`},
+		{`never`, `int`, `int`},
+		{`/a/`, `/b/`, `/a/|/b/`},
+		{`!int`, `string`, `!int`},
+		{`!0..5`, `int`, `any`},
+		{`!0..5`, `2..8`, `!0..5|2..8`},
+		{`!int`, `!string`, `any`},
+	}
+	for _, tc := range tests {
+		r := newtype.Union(newtype.Parse(tc.a), newtype.Parse(tc.b))
+		require.Equal(t, tc.result, r.String())
+	}
+	require.Equal(t, typ.Never, newtype.Union())
+	require.Equal(t, typ.Never, newtype.Union(typ.Never, typ.Never))
+	require.Equal(t, `string|int`, newtype.Union(typ.String, newtype.Parse(`1..3`), typ.Integer).String())
+}
+
+func TestSubtract(t *testing.T) {
+	tests := []struct {
+		a      string
+		b      string
+		result string
+	}{
+		{`0..10`, `3..5`, `0..2|6..10`},
+		{`0..10`, `0..5`, `6..10`},
+		{`0..10`, `5..20`, `0..4`},
+		{`0..10`, `int`, `never`},
+		{`0..10`, `20..30`, `0..10`},
+		{`int`, `0..`, `..-1`},
+		{`int|string`, `string`, `int`},
+		{`0..10`, `1..2|4..5`, `0|3|6..10`},
+		{`bool`, `true`, `false`},
+		{`bool`, `false`, `true`},
+		{`string`, `"a"`, `string&!"a"`},
+		{`float`, `0.0..1.0`, `..-5E-324|1.0000000000000002..`},
+		{`0.0..1.0`, `0.5`, `0.0..0.49999999999999994|0.5000000000000001..1.0`},
+		{`0..10`, `!3..5`, `3..5`},
+		{`!int`, `string`, `!(int|string)`},
+	}
+	for _, tc := range tests {
+		r := newtype.Subtract(newtype.Parse(tc.a), newtype.Parse(tc.b))
+		require.Equal(t, tc.result, r.String())
+	}
+
+	// A float range excludes the boundaries of the subtracted range
+	r := newtype.Subtract(typ.Float, newtype.Parse(`0.0..1.0`))
+	require.Equal(t, r, newtype.Parse(r.String()))
+	require.Instance(t, r, -0.1)
+	require.Instance(t, r, 1.1)
+	require.NotInstance(t, r, 0.0)
+	require.NotInstance(t, r, 1.0)
+}
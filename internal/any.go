@@ -15,6 +15,14 @@ func (t anyType) Equals(other interface{}) bool {
 	return t == other
 }
 
+func (t *anyType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t anyType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t anyType) HashCode() int {
 	return int(dgo.IdAny)
 }
@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -127,6 +126,14 @@ func (t defaultArrayType) Equals(other interface{}) bool {
 	return t == other
 }
 
+func (t *defaultArrayType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t defaultArrayType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t defaultArrayType) HashCode() int {
 	return int(dgo.IdArray)
 }
@@ -191,6 +198,14 @@ func (t *sizedArrayType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *sizedArrayType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *sizedArrayType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *sizedArrayType) HashCode() int {
 	h := int(dgo.IdArray)
 	if t.min > 0 {
@@ -210,9 +225,13 @@ func (t *sizedArrayType) Instance(value interface{}) bool {
 }
 
 func (t *sizedArrayType) DeepInstance(guard dgo.RecursionGuard, value dgo.Value) bool {
-	if ov, ok := value.(*array); ok {
+	switch ov := value.(type) {
+	case *array:
 		l := len(ov.slice)
 		return t.min <= l && l <= t.max && allInstance(guard, t.elementType, ov.slice)
+	case dgo.Array:
+		l := ov.Len()
+		return t.min <= l && l <= t.max && ov.All(func(e dgo.Value) bool { return Instance(guard, t.elementType, e) })
 	}
 	return false
 }
@@ -292,12 +311,20 @@ func (t *exactArrayType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactArrayType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactArrayType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactArrayType) HashCode() int {
 	return (*array)(t).HashCode()*7 + int(dgo.IdArrayExact)
 }
 
 func (t *exactArrayType) Instance(value interface{}) bool {
-	if ot, ok := value.(*array); ok {
+	if ot, ok := value.(dgo.Array); ok {
 		return (*array)(t).Equals(ot)
 	}
 	return false
@@ -332,6 +359,14 @@ func (t *exactArrayType) Unbounded() bool {
 	return false
 }
 
+func (t *exactElementsType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactElementsType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactElementsType) HashCode() int {
 	return (*array)(t).HashCode()*7 + int(dgo.IdArrayExact)
 }
@@ -448,12 +483,13 @@ func (t *tupleType) DeepAssignable(guard dgo.RecursionGuard, other dgo.Type) boo
 		if len(es) == 0 {
 			return true
 		}
-		if ot.Min() == len(es) && ot.Max() == len(es) {
-			et := ot.ElementType()
-			for i := range es {
-				if !Assignable(guard, es[i].(dgo.Type), et) {
-					return false
-				}
+		if ot.Min() != len(es) || ot.Max() != len(es) {
+			return false
+		}
+		et := ot.ElementType()
+		for i := range es {
+			if !Assignable(guard, es[i].(dgo.Type), et) {
+				return false
 			}
 		}
 		return true
@@ -482,6 +518,14 @@ func (t *tupleType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *tupleType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *tupleType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *tupleType) HashCode() int {
 	return (*array)(t).HashCode()*7 + int(dgo.IdTuple)
 }
@@ -491,12 +535,12 @@ func (t *tupleType) Instance(value interface{}) bool {
 }
 
 func (t *tupleType) DeepInstance(guard dgo.RecursionGuard, value dgo.Value) bool {
-	if ov, ok := value.(*array); ok {
+	if ov, ok := value.(dgo.Array); ok {
 		es := t.slice
 		if len(es) == 0 {
 			return true
 		}
-		s := ov.slice
+		s := sliceOf(ov)
 		if len(s) == len(es) {
 			for i := range es {
 				if !Instance(guard, es[i].(dgo.Type), s[i]) {
@@ -757,12 +801,12 @@ func (v *array) CompareTo(other interface{}) (int, bool) {
 }
 
 func (v *array) deepCompare(seen []dgo.Value, other deepCompare) (int, bool) {
-	ov, ok := other.(*array)
+	ov, ok := other.(dgo.Array)
 	if !ok {
 		return 0, false
 	}
 	a := v.slice
-	b := ov.slice
+	b := sliceOf(ov)
 	top := len(a)
 	max := len(b)
 	r := 0
@@ -827,8 +871,8 @@ func (v *array) Equals(other interface{}) bool {
 }
 
 func (v *array) deepEqual(seen []dgo.Value, other deepEqual) bool {
-	if ov, ok := other.(*array); ok {
-		return sliceEquals(seen, v.slice, ov.slice)
+	if ov, ok := other.(dgo.Array); ok && len(v.slice) == ov.Len() {
+		return sliceEquals(seen, v.slice, sliceOf(ov))
 	}
 	return false
 }
@@ -854,6 +898,14 @@ func (v *array) FrozenCopy() dgo.Value {
 	return v.Copy(true)
 }
 
+func (v *array) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v *array) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v *array) HashCode() int {
 	return v.deepHashCode(nil)
 }
@@ -997,9 +1049,8 @@ func (v *array) Reject(predicate dgo.Predicate) dgo.Array {
 }
 
 func (v *array) SameValues(other dgo.Array) bool {
-	oa := other.(*array)
 	a := v.slice
-	b := oa.slice
+	b := sliceOf(other)
 	l := len(a)
 	if l != len(b) {
 		return false
@@ -1134,9 +1185,9 @@ func (v *array) ToMapFromEntries() (dgo.Map, bool) {
 	for i := range ms {
 		nd, ok := ms[i].(*hashNode)
 		if !ok {
-			var ea *array
-			if ea, ok = ms[i].(*array); ok && len(ea.slice) == 2 {
-				nd = &hashNode{key: ea.slice[0], value: ea.slice[1]}
+			var ea dgo.Array
+			if ea, ok = ms[i].(dgo.Array); ok && ea.Len() == 2 {
+				nd = &hashNode{key: ea.Get(0), value: ea.Get(1)}
 			} else {
 				return nil, false
 			}
@@ -1203,16 +1254,7 @@ func (v *array) MarshalJSON() ([]byte, error) {
 }
 
 func (v *array) MarshalYAML() (interface{}, error) {
-	a := v.slice
-	s := make([]*yaml.Node, len(a))
-	var err error
-	for i := range a {
-		s[i], err = yamlEncodeValue(a[i])
-		if err != nil {
-			return nil, err
-		}
-	}
-	return &yaml.Node{Kind: yaml.SequenceNode, Tag: `!!seq`, Content: s}, nil
+	return yamlEncodeValue(v)
 }
 
 func (v *array) Pop() (dgo.Value, bool) {
@@ -1230,15 +1272,14 @@ func (v *array) UnmarshalJSON(b []byte) error {
 	if v.frozen {
 		panic(frozenArray(`UnmarshalJSON`))
 	}
-	dec := json.NewDecoder(bytes.NewReader(b))
-	dec.UseNumber()
-	t, err := dec.Token()
+	d := newMutableJSONDecoder(b)
+	t, err := d.dec.Token()
 	if err == nil {
 		if delim, ok := t.(json.Delim); !ok || delim != '[' {
 			return errors.New("expecting data to be an array")
 		}
 		var a *array
-		a, err = jsonDecodeArray(dec)
+		a, err = d.decodeArray()
 		if err == nil {
 			*v = *a
 		}
@@ -1253,7 +1294,7 @@ func (v *array) UnmarshalYAML(n *yaml.Node) error {
 	if n.Kind != yaml.SequenceNode {
 		return errors.New("expecting data to be an array")
 	}
-	a, err := yamlDecodeArray(n)
+	a, err := (&yamlDecoder{}).decodeArray(n)
 	if err == nil {
 		*v = *a
 	}
@@ -1329,3 +1370,12 @@ func assignableToAll(guard dgo.RecursionGuard, t dgo.Type, vs []dgo.Value) bool
 func frozenArray(f string) error {
 	return fmt.Errorf(`%s called on a frozen Array`, f)
 }
+
+// sliceOf returns the elements of the given Array as a slice. The slice is shared with the Array when
+// possible and must not be modified.
+func sliceOf(a dgo.Array) []dgo.Value {
+	if ov, ok := a.(*array); ok {
+		return ov.slice
+	}
+	return a.AppendToSlice(make([]dgo.Value, 0, a.Len()))
+}
@@ -0,0 +1,62 @@
+package internal
+
+import (
+	"math/big"
+	"reflect"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// bigInt is a frozen native value that represents an integer that doesn't fit in an int64. The decoders
+// use it so that such integers can be contained in frozen collections. The wrapped *big.Int is never
+// modified and never exposed. GoValue returns a copy.
+type bigInt struct {
+	i *big.Int
+}
+
+var bigIntType = reflect.TypeOf(&big.Int{})
+
+// makeBigInt returns a frozen native value for the given *big.Int. The caller must not retain the *big.Int.
+func makeBigInt(i *big.Int) dgo.Native {
+	return &bigInt{i}
+}
+
+func (v *bigInt) Equals(other interface{}) bool {
+	switch ov := other.(type) {
+	case *bigInt:
+		return v.i.Cmp(ov.i) == 0
+	case native:
+		oi, ok := reflect.Value(ov).Interface().(*big.Int)
+		return ok && v.i.Cmp(oi) == 0
+	case *big.Int:
+		return v.i.Cmp(ov) == 0
+	}
+	return false
+}
+
+func (v *bigInt) Freeze() {
+}
+
+func (v *bigInt) Frozen() bool {
+	return true
+}
+
+func (v *bigInt) FrozenCopy() dgo.Value {
+	return v
+}
+
+func (v *bigInt) GoValue() interface{} {
+	return new(big.Int).Set(v.i)
+}
+
+func (v *bigInt) HashCode() int {
+	return bytesHash(v.i.Bytes())*31 + v.i.Sign()
+}
+
+func (v *bigInt) String() string {
+	return v.i.String()
+}
+
+func (v *bigInt) Type() dgo.Type {
+	return &nativeType{bigIntType}
+}
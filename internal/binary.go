@@ -35,6 +35,14 @@ func (t *exactBinaryType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactBinaryType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactBinaryType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactBinaryType) HashCode() int {
 	return bytesHash(t.bytes) * 5
 }
@@ -75,7 +83,7 @@ func (t binaryType) Assignable(other dgo.Type) bool {
 	case binaryType, *exactBinaryType:
 		return true
 	}
-	return false
+	return CheckAssignableTo(nil, other, t)
 }
 
 func (t binaryType) Equals(other interface{}) bool {
@@ -83,6 +91,14 @@ func (t binaryType) Equals(other interface{}) bool {
 	return ok
 }
 
+func (t *binaryType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t binaryType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t binaryType) HashCode() int {
 	return int(dgo.IdBinary)
 }
@@ -185,6 +201,14 @@ func (v *binary) FrozenCopy() dgo.Value {
 	return v
 }
 
+func (v *binary) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v *binary) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v *binary) HashCode() int {
 	return bytesHash(v.bytes)
 }
@@ -31,6 +31,14 @@ func (t booleanType) Equals(v interface{}) bool {
 	return t == v
 }
 
+func (t *booleanType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t booleanType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t booleanType) HashCode() int {
 	return int(t.TypeIdentifier())
 }
@@ -102,6 +110,14 @@ func (v Boolean) Equals(other interface{}) bool {
 	return false
 }
 
+func (v *Boolean) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v Boolean) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v Boolean) HashCode() int {
 	if v {
 		return 1231
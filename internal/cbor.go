@@ -0,0 +1,625 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	bin "encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/big"
+	"regexp"
+	"sort"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// CBOR major types
+const (
+	cborUint = byte(iota)
+	cborNegInt
+	cborBytes
+	cborText
+	cborArray
+	cborMap
+	cborTag
+	cborSimple
+)
+
+// CBOR tags. The type and error tags are not registered with IANA and are taken from the first come first
+// served range.
+const (
+	cborDateTimeTag  = 0
+	cborEpochTag     = 1
+	cborPosBigIntTag = 2
+	cborNegBigIntTag = 3
+	cborRegexpTag    = 35
+	cborSetTag       = 258
+	cborTypeTag      = 40000
+	cborErrorTag     = 40001
+)
+
+// cborIndefinite is the additional information that denotes an indefinite length or, for the simple
+// major type, the break code
+const cborIndefinite = 31
+
+// cborFailure is used for panics that are recovered and returned as errors by the CBOR encoder and decoder
+type cborFailure struct {
+	error
+}
+
+func cborRecover(err *error) {
+	if r := recover(); r != nil {
+		f, ok := r.(cborFailure)
+		if !ok {
+			panic(r)
+		}
+		*err = f.error
+	}
+}
+
+// MarshalCBOR returns the CBOR (RFC 8949) encoding of the given value. See EncodeCBOR for details.
+func MarshalCBOR(v dgo.Value, deterministic bool) ([]byte, error) {
+	var b bytes.Buffer
+	if err := EncodeCBOR(&b, v, deterministic); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// EncodeCBOR writes the CBOR (RFC 8949) encoding of the given value to the given writer.
+//
+// Integers and lengths always use the shortest form. A Binary is written as a byte string, a Set as an
+// array with tag 258, a Regexp as a text string with tag 35, and types and errors as text strings with
+// the tags 40000 and 40001. Native time.Time values are written with tag 1 when they represent a whole
+// second and with tag 0 otherwise. Native *big.Int values that don't fit in 64 bits are written with tag
+// 2 or 3. An error is returned if the value contains a native value of any other kind.
+//
+// When deterministic is true, the encoding follows the core deterministic encoding requirements of
+// RFC 8949, i.e. floats use the shortest form that preserves their value and map keys are sorted by the
+// bytewise lexical order of their encodings. The same value will then always yield the same bytes.
+func EncodeCBOR(w io.Writer, v dgo.Value, deterministic bool) (err error) {
+	defer cborRecover(&err)
+	bw := bufio.NewWriter(w)
+	e := &cborEncoder{w: bw, deterministic: deterministic}
+	e.value(v)
+	return bw.Flush()
+}
+
+// UnmarshalCBOR decodes the given CBOR encoded bytes into a frozen dgo.Value.
+func UnmarshalCBOR(b []byte) (dgo.Value, error) {
+	r := bytes.NewReader(b)
+	v, err := DecodeCBOR(r)
+	if err == nil && r.Len() > 0 {
+		err = errors.New(`cbor: unexpected trailing bytes`)
+	}
+	return v, err
+}
+
+// DecodeCBOR reads one CBOR encoded data item from the given reader and returns it as a frozen dgo.Value.
+// No bytes beyond the end of the data item are read so consecutive items can be read from the same
+// reader.
+//
+// Integers that don't fit in an int64 are returned as frozen native *big.Int values and timestamps as native
+// time.Time values. The content of an unknown tag is returned as if it wasn't tagged.
+func DecodeCBOR(r io.Reader) (v dgo.Value, err error) {
+	defer cborRecover(&err)
+	d := &cborDecoder{r: r}
+	v = d.value()
+	if v == nil {
+		d.fail(errors.New(`cbor: unexpected break code`))
+	}
+	return v, nil
+}
+
+type cborEncoder struct {
+	w             io.Writer
+	deterministic bool
+	buf           [9]byte
+}
+
+func (e *cborEncoder) write(b []byte) {
+	if _, err := e.w.Write(b); err != nil {
+		panic(cborFailure{err})
+	}
+}
+
+// writeHead writes the initial byte of a data item of the given major type followed by the given argument
+// in its shortest form
+func (e *cborEncoder) writeHead(major byte, n uint64) {
+	b := e.buf[:]
+	m := major << 5
+	switch {
+	case n < 24:
+		b[0] = m | byte(n)
+		b = b[:1]
+	case n <= math.MaxUint8:
+		b[0] = m | 24
+		b[1] = byte(n)
+		b = b[:2]
+	case n <= math.MaxUint16:
+		b[0] = m | 25
+		bin.BigEndian.PutUint16(b[1:], uint16(n))
+		b = b[:3]
+	case n <= math.MaxUint32:
+		b[0] = m | 26
+		bin.BigEndian.PutUint32(b[1:], uint32(n))
+		b = b[:5]
+	default:
+		b[0] = m | 27
+		bin.BigEndian.PutUint64(b[1:], n)
+	}
+	e.write(b)
+}
+
+func (e *cborEncoder) writeText(s string) {
+	e.writeHead(cborText, uint64(len(s)))
+	e.write([]byte(s))
+}
+
+func (e *cborEncoder) writeInt(i int64) {
+	if i >= 0 {
+		e.writeHead(cborUint, uint64(i))
+	} else {
+		e.writeHead(cborNegInt, uint64(-1-i))
+	}
+}
+
+func (e *cborEncoder) writeFloat(f float64) {
+	if e.deterministic {
+		if h, ok := float16Bits(f); ok {
+			e.write([]byte{cborSimple<<5 | 25, byte(h >> 8), byte(h)})
+			return
+		}
+		if f32 := float32(f); float64(f32) == f {
+			b := e.buf[:5]
+			b[0] = cborSimple<<5 | 26
+			bin.BigEndian.PutUint32(b[1:], math.Float32bits(f32))
+			e.write(b)
+			return
+		}
+	}
+	b := e.buf[:]
+	b[0] = cborSimple<<5 | 27
+	bin.BigEndian.PutUint64(b[1:], math.Float64bits(f))
+	e.write(b)
+}
+
+func (e *cborEncoder) writeBigInt(i *big.Int) {
+	switch {
+	case i.IsInt64():
+		e.writeInt(i.Int64())
+	case i.IsUint64():
+		e.writeHead(cborUint, i.Uint64())
+	case i.Sign() > 0:
+		e.writeHead(cborTag, cborPosBigIntTag)
+		e.writeBytes(i.Bytes())
+	default:
+		// Tag 3 represents -1 - n
+		n := new(big.Int).Neg(i)
+		n.Sub(n, big.NewInt(1))
+		e.writeHead(cborTag, cborNegBigIntTag)
+		e.writeBytes(n.Bytes())
+	}
+}
+
+func (e *cborEncoder) writeBytes(b []byte) {
+	e.writeHead(cborBytes, uint64(len(b)))
+	e.write(b)
+}
+
+func (e *cborEncoder) writeTime(t time.Time) {
+	if t.Nanosecond() == 0 {
+		e.writeHead(cborTag, cborEpochTag)
+		e.writeInt(t.Unix())
+	} else {
+		e.writeHead(cborTag, cborDateTimeTag)
+		e.writeText(t.Format(time.RFC3339Nano))
+	}
+}
+
+func (e *cborEncoder) value(v dgo.Value) {
+	switch v := v.(type) {
+	case nil, nilValue:
+		e.write([]byte{cborSimple<<5 | 22})
+	case Boolean:
+		if v {
+			e.write([]byte{cborSimple<<5 | 21})
+		} else {
+			e.write([]byte{cborSimple<<5 | 20})
+		}
+	case Integer:
+		e.writeInt(int64(v))
+	case Float:
+		e.writeFloat(float64(v))
+	case *hstring:
+		e.writeText(v.s)
+	case *binary:
+		e.writeBytes(v.bytes)
+	case *Regexp:
+		e.writeHead(cborTag, cborRegexpTag)
+		e.writeText(v.String())
+	case dgo.Type:
+		e.writeHead(cborTag, cborTypeTag)
+		e.writeText(TypeString(v))
+	case *hashSet:
+		e.writeHead(cborTag, cborSetTag)
+		e.setValues(v.m.Keys().(*array).slice)
+	case dgo.Array:
+		e.values(sliceOf(v))
+	case dgo.Map:
+		e.mapValue(v)
+	case dgo.Native:
+		switch gv := v.GoValue().(type) {
+		case time.Time:
+			e.writeTime(gv)
+		case *big.Int:
+			e.writeBigInt(gv)
+		default:
+			panic(cborFailure{fmt.Errorf(`cbor: unable to encode a value of type %s`, TypeString(v.Type()))})
+		}
+	case error:
+		e.writeHead(cborTag, cborErrorTag)
+		e.writeText(v.Error())
+	default:
+		panic(cborFailure{fmt.Errorf(`cbor: unable to encode a value of type %s`, TypeString(v.Type()))})
+	}
+}
+
+func (e *cborEncoder) values(vs []dgo.Value) {
+	e.writeHead(cborArray, uint64(len(vs)))
+	for _, v := range vs {
+		e.value(v)
+	}
+}
+
+// setValues writes the elements of a set. The elements are sorted by the bytewise lexical order of their
+// encoding when the encoder is deterministic.
+func (e *cborEncoder) setValues(vs []dgo.Value) {
+	if !e.deterministic {
+		e.values(vs)
+		return
+	}
+	es := make([][]byte, len(vs))
+	for i, v := range vs {
+		es[i] = deterministicEncoding(v)
+	}
+	sort.Slice(es, func(i, j int) bool { return bytes.Compare(es[i], es[j]) < 0 })
+	e.writeHead(cborArray, uint64(len(es)))
+	for _, b := range es {
+		e.write(b)
+	}
+}
+
+// deterministicEncoding returns the deterministic encoding of the given value
+func deterministicEncoding(v dgo.Value) []byte {
+	var b bytes.Buffer
+	ke := &cborEncoder{w: &b, deterministic: true}
+	ke.value(v)
+	return b.Bytes()
+}
+
+func (e *cborEncoder) mapValue(m dgo.Map) {
+	e.writeHead(cborMap, uint64(m.Len()))
+	if !e.deterministic {
+		m.Each(func(me dgo.MapEntry) {
+			e.value(me.Key())
+			e.value(me.Value())
+		})
+		return
+	}
+
+	// Keys are sorted by the bytewise lexical order of their deterministic encoding
+	type encodedEntry struct {
+		key   []byte
+		value dgo.Value
+	}
+	es := make([]encodedEntry, 0, m.Len())
+	m.Each(func(me dgo.MapEntry) {
+		es = append(es, encodedEntry{key: deterministicEncoding(me.Key()), value: me.Value()})
+	})
+	sort.Slice(es, func(i, j int) bool { return bytes.Compare(es[i].key, es[j].key) < 0 })
+	for _, ee := range es {
+		e.write(ee.key)
+		e.value(ee.value)
+	}
+}
+
+// float16Bits returns the IEEE 754 half precision representation of the given float and true if that
+// representation preserves the value.
+func float16Bits(f float64) (uint16, bool) {
+	switch {
+	case math.IsNaN(f):
+		return 0x7e00, true
+	case math.IsInf(f, 1):
+		return 0x7c00, true
+	case math.IsInf(f, -1):
+		return 0xfc00, true
+	}
+	f32 := float32(f)
+	if float64(f32) != f {
+		return 0, false
+	}
+	b := math.Float32bits(f32)
+	sign := uint16(b>>16) & 0x8000
+	exp := int(b>>23&0xff) - 127
+	mant := b & 0x7fffff
+	switch {
+	case f == 0:
+		return sign, true
+	case exp >= -14 && exp <= 15:
+		// Normal half precision number, the 13 low bits of the mantissa must be zero
+		if mant&0x1fff != 0 {
+			return 0, false
+		}
+		return sign | uint16(exp+15)<<10 | uint16(mant>>13), true
+	case exp >= -24 && exp < -14:
+		// Subnormal half precision number
+		shift := uint(-exp - 14 + 13)
+		full := mant | 0x800000
+		if full&(1<<shift-1) != 0 {
+			return 0, false
+		}
+		return sign | uint16(full>>shift), true
+	}
+	return 0, false
+}
+
+// float16Value returns the float that the given IEEE 754 half precision bits represent
+func float16Value(h uint16) float64 {
+	exp := int(h>>10) & 0x1f
+	mant := float64(h & 0x3ff)
+	var f float64
+	switch exp {
+	case 0:
+		f = math.Ldexp(mant, -24)
+	case 0x1f:
+		if mant == 0 {
+			f = math.Inf(1)
+		} else {
+			f = math.NaN()
+		}
+	default:
+		f = math.Ldexp(mant+1024, exp-25)
+	}
+	if h&0x8000 != 0 {
+		f = -f
+	}
+	return f
+}
+
+type cborDecoder struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+func (d *cborDecoder) fail(err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	panic(cborFailure{err})
+}
+
+func (d *cborDecoder) read(n int) []byte {
+	b := d.buf[:n]
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+// readBytes reads exactly n bytes. The bytes are read in chunks so that a corrupt length doesn't cause a
+// huge allocation up front.
+func (d *cborDecoder) readBytes(n uint64) []byte {
+	b, err := ioutil.ReadAll(io.LimitReader(d.r, int64(n)))
+	if err == nil && uint64(len(b)) != n {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+// readHead reads the initial byte and argument of a data item. The returned info is cborIndefinite when
+// the data item has an indefinite length or is a break code.
+func (d *cborDecoder) readHead() (major, info byte, n uint64) {
+	ib := d.read(1)[0]
+	major = ib >> 5
+	info = ib & 0x1f
+	switch {
+	case info < 24:
+		n = uint64(info)
+	case info == 24:
+		n = uint64(d.read(1)[0])
+	case info == 25:
+		n = uint64(bin.BigEndian.Uint16(d.read(2)))
+	case info == 26:
+		n = uint64(bin.BigEndian.Uint32(d.read(4)))
+	case info == 27:
+		n = bin.BigEndian.Uint64(d.read(8))
+	case info == cborIndefinite && major != cborUint && major != cborNegInt && major != cborTag:
+	default:
+		d.fail(fmt.Errorf(`cbor: invalid additional information %d for major type %d`, info, major))
+	}
+	return
+}
+
+// value decodes the next data item. A nil value is returned when a break code is found.
+func (d *cborDecoder) value() dgo.Value {
+	major, info, n := d.readHead()
+	switch major {
+	case cborUint:
+		if n > math.MaxInt64 {
+			return makeBigInt(new(big.Int).SetUint64(n))
+		}
+		return Integer(int64(n))
+	case cborNegInt:
+		if n > math.MaxInt64 {
+			i := new(big.Int).SetUint64(n)
+			return makeBigInt(i.Neg(i).Sub(i, big.NewInt(1)))
+		}
+		return Integer(-1 - int64(n))
+	case cborBytes:
+		return &binary{bytes: d.readString(cborBytes, info, n), frozen: true}
+	case cborText:
+		return makeHString(string(d.readString(cborText, info, n)))
+	case cborArray:
+		return &array{slice: d.readValues(info, n), frozen: true}
+	case cborMap:
+		return d.mapValue(info, n)
+	case cborTag:
+		return d.tagged(n)
+	}
+	return d.simple(info, n)
+}
+
+// readString reads a definite length string or the concatenated chunks of an indefinite length string
+func (d *cborDecoder) readString(major, info byte, n uint64) []byte {
+	if info != cborIndefinite {
+		return d.readBytes(n)
+	}
+	var b []byte
+	for {
+		cm, ci, cn := d.readHead()
+		if cm == cborSimple && ci == cborIndefinite {
+			return b
+		}
+		if cm != major || ci == cborIndefinite {
+			d.fail(errors.New(`cbor: invalid chunk in indefinite length string`))
+		}
+		b = append(b, d.readBytes(cn)...)
+	}
+}
+
+func (d *cborDecoder) readValues(info byte, n uint64) []dgo.Value {
+	// The slice grows as elements are read so that a corrupt length doesn't cause a huge allocation
+	s := make([]dgo.Value, 0, minInt(int(n&0xffff), 1024))
+	for i := uint64(0); info == cborIndefinite || i < n; i++ {
+		v := d.value()
+		if v == nil {
+			if info != cborIndefinite {
+				d.fail(errors.New(`cbor: unexpected break code`))
+			}
+			break
+		}
+		s = append(s, v)
+	}
+	return s
+}
+
+func (d *cborDecoder) mapValue(info byte, n uint64) dgo.Value {
+	m := MutableMap(minInt(int(n&0xffff), 1024), nil)
+	for i := uint64(0); info == cborIndefinite || i < n; i++ {
+		k := d.value()
+		if k == nil {
+			if info != cborIndefinite {
+				d.fail(errors.New(`cbor: unexpected break code`))
+			}
+			break
+		}
+		v := d.value()
+		if v == nil {
+			d.fail(errors.New(`cbor: unexpected break code`))
+		}
+		m.Put(k, v)
+	}
+	m.Freeze()
+	return m
+}
+
+func (d *cborDecoder) content() dgo.Value {
+	v := d.value()
+	if v == nil {
+		d.fail(errors.New(`cbor: unexpected break code`))
+	}
+	return v
+}
+
+func (d *cborDecoder) text(tag uint64) string {
+	if s, ok := d.content().(*hstring); ok {
+		return s.s
+	}
+	d.fail(fmt.Errorf(`cbor: the content of tag %d must be a text string`, tag))
+	return ``
+}
+
+func (d *cborDecoder) tagged(tag uint64) dgo.Value {
+	switch tag {
+	case cborDateTimeTag:
+		t, err := time.Parse(time.RFC3339Nano, d.text(tag))
+		if err != nil {
+			d.fail(err)
+		}
+		return Value(t)
+	case cborEpochTag:
+		switch s := d.content().(type) {
+		case Integer:
+			return Value(time.Unix(int64(s), 0).UTC())
+		case Float:
+			sec, frac := math.Modf(float64(s))
+			return Value(time.Unix(int64(sec), int64(frac*1e9)).UTC())
+		}
+		d.fail(errors.New(`cbor: the content of tag 1 must be a number`))
+	case cborPosBigIntTag, cborNegBigIntTag:
+		b, ok := d.content().(*binary)
+		if !ok {
+			d.fail(fmt.Errorf(`cbor: the content of tag %d must be a byte string`, tag))
+		}
+		i := new(big.Int).SetBytes(b.bytes)
+		if tag == cborNegBigIntTag {
+			i.Neg(i).Sub(i, big.NewInt(1))
+		}
+		if i.IsInt64() {
+			return Integer(i.Int64())
+		}
+		return makeBigInt(i)
+	case cborRegexpTag:
+		rx, err := regexp.Compile(d.text(tag))
+		if err != nil {
+			d.fail(err)
+		}
+		return (*Regexp)(rx)
+	case cborSetTag:
+		a, ok := d.content().(*array)
+		if !ok {
+			d.fail(errors.New(`cbor: the content of tag 258 must be an array`))
+		}
+		s := setFromSlice(a.slice)
+		s.Freeze()
+		return s
+	case cborTypeTag:
+		t, err := parseType(d.text(tag))
+		if err != nil {
+			d.fail(err)
+		}
+		return t
+	case cborErrorTag:
+		return &errw{errors.New(d.text(tag))}
+	}
+	return d.content()
+}
+
+func (d *cborDecoder) simple(info byte, n uint64) dgo.Value {
+	switch info {
+	case 20:
+		return False
+	case 21:
+		return True
+	case 22, 23:
+		return Nil
+	case 25:
+		return Float(float16Value(uint16(n)))
+	case 26:
+		return Float(float64(math.Float32frombits(uint32(n))))
+	case 27:
+		return Float(math.Float64frombits(n))
+	case cborIndefinite:
+		return nil
+	}
+	d.fail(fmt.Errorf(`cbor: unsupported simple value %d`, n))
+	return nil
+}
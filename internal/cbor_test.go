@@ -0,0 +1,258 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"math/big"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func cborRoundTrip(t *testing.T, v dgo.Value, deterministic bool) string {
+	t.Helper()
+	var b []byte
+	var err error
+	if deterministic {
+		b, err = vf.MarshalDeterministicCBOR(v)
+	} else {
+		b, err = vf.MarshalCBOR(v)
+	}
+	require.Nil(t, err)
+	r, err := vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.Equal(t, v, r)
+	return hex.EncodeToString(b)
+}
+
+func TestCBOR_values(t *testing.T) {
+	// Expected encodings from RFC 8949 Appendix A
+	tests := []struct {
+		v   interface{}
+		hex string
+	}{
+		{0, `00`},
+		{23, `17`},
+		{24, `1818`},
+		{1000, `1903e8`},
+		{1000000, `1a000f4240`},
+		{1000000000000, `1b000000e8d4a51000`},
+		{-1, `20`},
+		{-1000, `3903e7`},
+		{false, `f4`},
+		{true, `f5`},
+		{nil, `f6`},
+		{``, `60`},
+		{`IETF`, `6449455446`},
+		{`ü`, `62c3bc`},
+		{[]byte{1, 2, 3, 4}, `4401020304`},
+		{vf.Values(1, vf.Values(2, 3), vf.Values(4, 5)), `8301820203820405`},
+		{1.1, `fb3ff199999999999a`},
+		{regexp.MustCompile(`a+`), `d82362612b`},
+		{typ.Integer, `d99c4063696e74`},
+		{vf.Set(1, 2), `d90102820102`},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.hex, cborRoundTrip(t, vf.Value(tc.v), false))
+	}
+	cborRoundTrip(t, vf.Value(errors.New(`oops`)), false)
+	cborRoundTrip(t, newtype.Parse(`{"a":int,"b"?:[]string}`), false)
+	cborRoundTrip(t, vf.Set(1, 2).Type(), false)
+}
+
+func TestCBOR_deterministic(t *testing.T) {
+	tests := []struct {
+		v   interface{}
+		hex string
+	}{
+		{0.0, `f90000`},
+		{1.0, `f93c00`},
+		{1.5, `f93e00`},
+		{65504.0, `f97bff`},
+		{100000.0, `fa47c35000`},
+		{5.960464477539063e-8, `f90001`},
+		{0.00006103515625, `f90400`},
+		{-4.0, `f9c400`},
+		{1.1, `fb3ff199999999999a`},
+		{math.Inf(1), `f97c00`},
+		{math.Inf(-1), `f9fc00`},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.hex, cborRoundTrip(t, vf.Value(tc.v), true))
+	}
+	b, _ := vf.MarshalDeterministicCBOR(math.NaN())
+	require.Equal(t, `f97e00`, hex.EncodeToString(b))
+
+	a := vf.MutableMap(0, nil)
+	a.Put(`b`, 1)
+	a.Put(100, 2)
+	a.Put(`a`, vf.Values(1.5))
+	a.Put(-1, 3)
+	c := vf.MutableMap(0, nil)
+	c.Put(-1, 3)
+	c.Put(`a`, vf.Values(1.5))
+	c.Put(100, 2)
+	c.Put(`b`, 1)
+	ab := cborRoundTrip(t, a, true)
+	require.Equal(t, `a41864022003616181f93e00616201`, ab)
+	require.Equal(t, ab, cborRoundTrip(t, c, true))
+	require.NotEqual(t, ab, cborRoundTrip(t, c, false))
+
+	sb := cborRoundTrip(t, vf.Set(`b`, 100, `a`, -1), true)
+	require.Equal(t, `d901028418642061616162`, sb)
+	require.Equal(t, sb, cborRoundTrip(t, vf.Set(-1, `a`, 100, `b`), true))
+	require.NotEqual(t, sb, cborRoundTrip(t, vf.Set(`b`, 100, `a`, -1), false))
+}
+
+func TestCBOR_bigint(t *testing.T) {
+	b, err := vf.MarshalCBOR(new(big.Int).SetUint64(math.MaxUint64))
+	require.Nil(t, err)
+	require.Equal(t, `1bffffffffffffffff`, hex.EncodeToString(b))
+	v, err := vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.Equal(t, 0, new(big.Int).SetUint64(math.MaxUint64).Cmp(v.(dgo.Native).GoValue().(*big.Int)))
+
+	n, _ := new(big.Int).SetString(`-18446744073709551617`, 10)
+	b, err = vf.MarshalCBOR(n)
+	require.Nil(t, err)
+	require.Equal(t, `c349010000000000000000`, hex.EncodeToString(b))
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.Equal(t, 0, n.Cmp(v.(dgo.Native).GoValue().(*big.Int)))
+
+	p, _ := new(big.Int).SetString(`18446744073709551616`, 10)
+	b, err = vf.MarshalCBOR(p)
+	require.Nil(t, err)
+	require.Equal(t, `c249010000000000000000`, hex.EncodeToString(b))
+
+	b, _ = vf.MarshalCBOR(big.NewInt(-5))
+	require.Equal(t, `24`, hex.EncodeToString(b))
+
+	b, _ = hex.DecodeString(`3bffffffffffffffff`)
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.Equal(t, `-18446744073709551616`, v.(dgo.Native).GoValue().(*big.Int).String())
+
+	b, _ = hex.DecodeString(`c24101`)
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.Equal(t, 1, v)
+
+	b, _ = hex.DecodeString(`c249010000000000000000`)
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.True(t, v.(dgo.Freezable).Frozen())
+	a := vf.Values(v)
+	require.Equal(t, p, a.Get(0))
+	v.(dgo.Native).GoValue().(*big.Int).SetInt64(0)
+	require.Equal(t, p, v)
+
+	b, err = vf.MarshalCBOR(vf.Values(v))
+	require.Nil(t, err)
+	require.Equal(t, `81c249010000000000000000`, hex.EncodeToString(b))
+}
+
+func TestCBOR_timestamp(t *testing.T) {
+	tm := time.Unix(1363896240, 0).UTC()
+	b, err := vf.MarshalCBOR(tm)
+	require.Nil(t, err)
+	require.Equal(t, `c11a514b67b0`, hex.EncodeToString(b))
+	v, err := vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.True(t, tm.Equal(v.(dgo.Native).GoValue().(time.Time)))
+
+	tm = time.Unix(1363896240, 500000000).UTC()
+	b, err = vf.MarshalCBOR(tm)
+	require.Nil(t, err)
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.True(t, tm.Equal(v.(dgo.Native).GoValue().(time.Time)))
+
+	b, _ = hex.DecodeString(`c074323031332d30332d32315432303a30343a30305a`)
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.True(t, time.Unix(1363896240, 0).Equal(v.(dgo.Native).GoValue().(time.Time)))
+
+	b, _ = hex.DecodeString(`c1fb41d452d9ec200000`)
+	v, err = vf.UnmarshalCBOR(b)
+	require.Nil(t, err)
+	require.True(t, time.Unix(1363896240, 500000000).Equal(v.(dgo.Native).GoValue().(time.Time)))
+}
+
+func TestCBOR_indefinite(t *testing.T) {
+	tests := []struct {
+		hex string
+		v   dgo.Value
+	}{
+		{`5f42010243030405ff`, vf.Value([]byte{1, 2, 3, 4, 5})},
+		{`7f657374726561646d696e67ff`, vf.String(`streaming`)},
+		{`9fff`, vf.Values()},
+		{`9f018202039f0405ffff`, vf.Values(1, vf.Values(2, 3), vf.Values(4, 5))},
+		{`bf61610161629f0203ffff`, vf.Map(map[string]interface{}{`a`: 1, `b`: vf.Values(2, 3)})},
+		{`f7`, vf.Nil},
+		{`d8ff01`, vf.Integer(1)},
+		{`f93c00`, vf.Float(1)},
+		{`fa47c35000`, vf.Float(100000)},
+	}
+	for _, tc := range tests {
+		b, _ := hex.DecodeString(tc.hex)
+		v, err := vf.UnmarshalCBOR(b)
+		require.Nil(t, err)
+		require.Equal(t, tc.v, v)
+	}
+}
+
+func TestCBOR_stream(t *testing.T) {
+	var b bytes.Buffer
+	require.Nil(t, vf.EncodeCBOR(&b, vf.Values(1, `a`), false))
+	require.Nil(t, vf.EncodeCBOR(&b, `b`, true))
+	v, err := vf.DecodeCBOR(&b)
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, `a`), v)
+	v, err = vf.DecodeCBOR(&b)
+	require.Nil(t, err)
+	require.Equal(t, `b`, v)
+	_, err = vf.DecodeCBOR(&b)
+	require.NotNil(t, err)
+}
+
+func TestCBOR_fail(t *testing.T) {
+	_, err := vf.MarshalCBOR(vf.MutableValues(nil, struct{ A int }{1}))
+	require.NotNil(t, err)
+
+	for _, s := range []string{
+		``,
+		`ff`,
+		`1c`,
+		`1f`,
+		`83`,
+		`6161ff`,
+		`8201ff`,
+		`a1ff`,
+		`a101ff`,
+		`5f4101610fff`,
+		`5f5f4101ffff`,
+		`f8ff`,
+		`c06161`,
+		`c001`,
+		`c06178`,
+		`c16161`,
+		`c201`,
+		`d8236128`,
+		`d901020100`,
+		`d99c406178`,
+		`c1ff`,
+	} {
+		b, _ := hex.DecodeString(s)
+		_, err := vf.UnmarshalCBOR(b)
+		require.NotNil(t, err)
+	}
+}
@@ -1,9 +1,25 @@
 package internal
 
-import "testing"
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+)
 
 func Test_decodeEndToken(t *testing.T) {
 	if `unexpected delimiter ']'` != endOfArray.Error() {
 		t.Failed()
 	}
 }
+
+func Test_generateAdditionalEntries(t *testing.T) {
+	st := Struct([]dgo.MapEntryType{StructEntry(`a`, DefaultIntegerType, true)}).(*structType)
+	st.additional = true
+	for seed := int64(0); seed < 50; seed++ {
+		v, err := Generate(st, rand.NewSource(seed))
+		if err != nil || !st.Instance(v) {
+			t.Fatalf(`seed %d: unable to generate an instance of %s`, seed, st)
+		}
+	}
+}
@@ -0,0 +1,408 @@
+package internal
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+)
+
+type (
+	change struct {
+		kind dgo.ChangeKind
+		path string
+		old  dgo.Value
+		new  dgo.Value
+	}
+
+	diff struct {
+		changes []dgo.Change
+	}
+)
+
+// Diff returns the structural difference between the values a and b.
+//
+// Maps are compared key by key and Arrays are compared using their longest common subsequence so that
+// inserted and removed elements don't cause the elements that follow to be reported as modified. A
+// Map key that is present in both maps but has a different position relative to the other common keys
+// is reported as moved.
+//
+// Values that are the same instance or that have the same hash code, are Equal, and have their
+// Map keys in the same order are considered identical without further comparison.
+func Diff(a, b dgo.Value) dgo.Diff {
+	d := &diff{}
+	d.diff(nil, ``, a, b)
+	return d
+}
+
+func (d *diff) Changes() []dgo.Change {
+	return d.changes
+}
+
+func (d *diff) Empty() bool {
+	return len(d.changes) == 0
+}
+
+// AppendTo appends a report in unified format where each change is denoted by a header containing
+// the path, followed by lines starting with '-' for removed values and '+' for added values.
+func (d *diff) AppendTo(w *util.Indenter) {
+	for i, c := range d.changes {
+		if i > 0 {
+			diffNewLine(w)
+		}
+		c.AppendTo(w)
+	}
+}
+
+func (d *diff) String() string {
+	return util.ToIndentedString(d)
+}
+
+func (d *diff) add(kind dgo.ChangeKind, path string, old, new dgo.Value) {
+	d.changes = append(d.changes, &change{kind: kind, path: path, old: old, new: new})
+}
+
+func (d *diff) diff(seen []dgo.Value, path string, a, b dgo.Value) {
+	if identical(nil, a, b) {
+		return
+	}
+	switch ac := a.(type) {
+	case dgo.Map:
+		if bc, ok := b.(dgo.Map); ok {
+			if !recursionHit(seen, a) {
+				d.diffMaps(append(seen, a), path, ac, bc)
+			}
+			return
+		}
+	case dgo.Array:
+		if bc, ok := b.(dgo.Array); ok {
+			if !recursionHit(seen, a) {
+				d.diffArrays(append(seen, a), path, ac, bc)
+			}
+			return
+		}
+	}
+	d.add(dgo.ChangeModified, path, a, b)
+}
+
+func (d *diff) diffMaps(seen []dgo.Value, path string, a, b dgo.Map) {
+	var ac, bc []dgo.Value
+	a.Each(func(e dgo.MapEntry) {
+		k := e.Key()
+		if bv, ok := b.Get(k); ok {
+			ac = append(ac, k)
+			d.diff(seen, path+`/`+pointerToken(k), e.Value(), bv)
+		} else {
+			d.add(dgo.ChangeRemoved, path+`/`+pointerToken(k), e.Value(), nil)
+		}
+	})
+	b.Each(func(e dgo.MapEntry) {
+		k := e.Key()
+		if _, ok := a.Get(k); ok {
+			bc = append(bc, k)
+		} else {
+			d.add(dgo.ChangeAdded, path+`/`+pointerToken(k), nil, e.Value())
+		}
+	})
+
+	// Common keys that are not part of the longest common subsequence have moved.
+	inA, _ := lcs(len(ac), len(bc), func(i, j int) bool { return ac[i].Equals(bc[j]) })
+	for i, k := range ac {
+		if !inA[i] {
+			d.add(dgo.ChangeMoved, path+`/`+pointerToken(k), Integer(keyPosition(a, k)), Integer(keyPosition(b, k)))
+		}
+	}
+}
+
+func (d *diff) diffArrays(seen []dgo.Value, path string, a, b dgo.Array) {
+	as := sliceOf(a)
+	bs := sliceOf(b)
+
+	// Skip common prefix and suffix
+	s := 0
+	for s < len(as) && s < len(bs) && identical(nil, as[s], bs[s]) {
+		s++
+	}
+	ea, eb := len(as), len(bs)
+	for ea > s && eb > s && identical(nil, as[ea-1], bs[eb-1]) {
+		ea--
+		eb--
+	}
+	as = as[s:ea]
+	bs = bs[s:eb]
+
+	ah := make([]int, len(as))
+	for i, v := range as {
+		ah[i] = v.HashCode()
+	}
+	bh := make([]int, len(bs))
+	for i, v := range bs {
+		bh[i] = v.HashCode()
+	}
+	inA, inB := lcs(len(as), len(bs), func(i, j int) bool { return ah[i] == bh[j] && as[i].Equals(bs[j]) })
+
+	elemPath := func(i int) string { return path + `/` + strconv.Itoa(s+i) }
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		if i < len(as) && j < len(bs) && inA[i] && inB[j] {
+			// Equal, but Map keys may still be in different order
+			d.diff(seen, elemPath(j), as[i], bs[j])
+			i++
+			j++
+			continue
+		}
+		ri := i
+		for i < len(as) && !inA[i] {
+			i++
+		}
+		aj := j
+		for j < len(bs) && !inB[j] {
+			j++
+		}
+
+		// Pair removed elements with added elements and report them as modified
+		for ri < i && aj < j {
+			d.diff(seen, elemPath(aj), as[ri], bs[aj])
+			ri++
+			aj++
+		}
+		for ; ri < i; ri++ {
+			// Index in the original array
+			d.add(dgo.ChangeRemoved, path+`/`+strconv.Itoa(s+ri), as[ri], nil)
+		}
+		for ; aj < j; aj++ {
+			d.add(dgo.ChangeAdded, elemPath(aj), nil, bs[aj])
+		}
+	}
+}
+
+// identical returns true if the given values are the same instance, or if they have the same hash code,
+// are equal, and contain maps with equal keys in the same order.
+func identical(seen []dgo.Value, a, b dgo.Value) bool {
+	if a == b {
+		return true
+	}
+	if seen == nil && (a.HashCode() != b.HashCode() || !a.Equals(b)) {
+		return false
+	}
+	if recursionHit(seen, a) {
+		return true
+	}
+	switch ac := a.(type) {
+	case dgo.Map:
+		bc := b.(dgo.Map)
+		seen = append(seen, a)
+		bks := bc.Keys()
+		i := 0
+		return ac.All(func(e dgo.MapEntry) bool {
+			bk := bks.Get(i)
+			i++
+			if !e.Key().Equals(bk) {
+				return false
+			}
+			bv, _ := bc.Get(bk)
+			return identical(seen, e.Value(), bv)
+		})
+	case dgo.Array:
+		bc := b.(dgo.Array)
+		seen = append(seen, a)
+		i := 0
+		return ac.All(func(e dgo.Value) bool {
+			bv := bc.Get(i)
+			i++
+			return identical(seen, e, bv)
+		})
+	}
+	return true
+}
+
+// lcs computes the longest common subsequence of two sequences with the given lengths using the given
+// function to compare elements. It returns two slices that tell which elements of each sequence that
+// are part of the subsequence.
+//
+// Hirschberg's algorithm is used so that the memory needed is linear in the length of the sequences.
+func lcs(n, m int, eq func(i, j int) bool) ([]bool, []bool) {
+	l := &lcsState{inA: make([]bool, n), inB: make([]bool, m), eq: eq}
+	l.solve(0, n, 0, m)
+	return l.inA, l.inB
+}
+
+type lcsState struct {
+	inA []bool
+	inB []bool
+	eq  func(i, j int) bool
+}
+
+func (l *lcsState) match(i, j int) {
+	l.inA[i] = true
+	l.inB[j] = true
+}
+
+// solve finds the longest common subsequence of a[i0:i1] and b[j0:j1]
+func (l *lcsState) solve(i0, i1, j0, j1 int) {
+	// Common prefix and suffix are always part of the subsequence
+	for i0 < i1 && j0 < j1 && l.eq(i0, j0) {
+		l.match(i0, j0)
+		i0++
+		j0++
+	}
+	for i0 < i1 && j0 < j1 && l.eq(i1-1, j1-1) {
+		i1--
+		j1--
+		l.match(i1, j1)
+	}
+	if i0 == i1 || j0 == j1 {
+		return
+	}
+	if i1-i0 == 1 {
+		for j := j0; j < j1; j++ {
+			if l.eq(i0, j) {
+				l.match(i0, j)
+				break
+			}
+		}
+		return
+	}
+
+	// Split a in the middle and find the split of b that gives the longest subsequence
+	mid := (i0 + i1) / 2
+	f := l.forward(i0, mid, j0, j1)
+	r := l.reverse(mid, i1, j0, j1)
+	k, best := 0, -1
+	for x := range f {
+		if c := f[x] + r[x]; c > best {
+			k, best = x, c
+		}
+	}
+	l.solve(i0, mid, j0, j0+k)
+	l.solve(mid, i1, j0+k, j1)
+}
+
+// forward returns a slice where element k is the length of the longest common subsequence of a[i0:i1]
+// and b[j0:j0+k]
+func (l *lcsState) forward(i0, i1, j0, j1 int) []int {
+	w := j1 - j0
+	prev := make([]int, w+1)
+	cur := make([]int, w+1)
+	for i := i0; i < i1; i++ {
+		for k := 1; k <= w; k++ {
+			switch {
+			case l.eq(i, j0+k-1):
+				cur[k] = prev[k-1] + 1
+			case prev[k] >= cur[k-1]:
+				cur[k] = prev[k]
+			default:
+				cur[k] = cur[k-1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// reverse returns a slice where element k is the length of the longest common subsequence of a[i0:i1]
+// and b[j0+k:j1]
+func (l *lcsState) reverse(i0, i1, j0, j1 int) []int {
+	w := j1 - j0
+	prev := make([]int, w+1)
+	cur := make([]int, w+1)
+	for i := i1 - 1; i >= i0; i-- {
+		for k := w - 1; k >= 0; k-- {
+			switch {
+			case l.eq(i, j0+k):
+				cur[k] = prev[k+1] + 1
+			case prev[k] >= cur[k+1]:
+				cur[k] = prev[k]
+			default:
+				cur[k] = cur[k+1]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// keyPosition returns the position of the given key in the given map
+func keyPosition(m dgo.Map, k dgo.Value) int {
+	p := 0
+	m.AnyKey(func(mk dgo.Value) bool {
+		if mk.Equals(k) {
+			return true
+		}
+		p++
+		return false
+	})
+	return p
+}
+
+// pointerToken returns the given key as an escaped JSON Pointer token
+func pointerToken(k dgo.Value) string {
+	var s string
+	if ks, ok := k.(dgo.String); ok {
+		s = ks.GoString()
+	} else {
+		s = k.String()
+	}
+	return strings.NewReplacer(`~`, `~0`, `/`, `~1`).Replace(s)
+}
+
+// diffNewLine starts a new line, or appends a space if the given indenter doesn't produce new lines
+func diffNewLine(w *util.Indenter) {
+	if w.Indenting() {
+		w.NewLine()
+	} else {
+		w.AppendRune(' ')
+	}
+}
+
+// appendDiffValue appends the unindented string representation of the given value
+func appendDiffValue(w *util.Indenter, v dgo.Value) {
+	if iv, ok := v.(util.Indentable); ok {
+		w.Append(util.ToString(iv))
+	} else {
+		w.Append(v.String())
+	}
+}
+
+func (c *change) AppendTo(w *util.Indenter) {
+	w.Append(`@@ `)
+	w.Append(c.path)
+	w.Append(` @@`)
+	switch c.kind {
+	case dgo.ChangeMoved:
+		diffNewLine(w)
+		w.Printf(`~ moved from position %s to %s`, c.old, c.new)
+	default:
+		if c.old != nil {
+			diffNewLine(w)
+			w.Append(`- `)
+			appendDiffValue(w, c.old)
+		}
+		if c.new != nil {
+			diffNewLine(w)
+			w.Append(`+ `)
+			appendDiffValue(w, c.new)
+		}
+	}
+}
+
+func (c *change) Kind() dgo.ChangeKind {
+	return c.kind
+}
+
+func (c *change) New() dgo.Value {
+	return c.new
+}
+
+func (c *change) Old() dgo.Value {
+	return c.old
+}
+
+func (c *change) Path() string {
+	return c.path
+}
+
+func (c *change) String() string {
+	return util.ToIndentedString(c)
+}
@@ -0,0 +1,164 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/util"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func ExampleDiff() {
+	a := vf.Values(
+		`services`, vf.Values(
+			vf.Values(`name`, `web`, `port`, 80).ToMap(),
+			vf.Values(`name`, `db`, `port`, 5432).ToMap()),
+		`debug`, true).ToMap()
+	b := vf.Values(
+		`services`, vf.Values(
+			vf.Values(`name`, `web`, `port`, 8080).ToMap(),
+			vf.Values(`name`, `db`, `port`, 5432).ToMap(),
+			vf.Values(`name`, `cache`, `port`, 6379).ToMap())).ToMap()
+	fmt.Println(vf.Diff(a, b))
+	// Output:
+	// @@ /services/0/port @@
+	// - 80
+	// + 8080
+	// @@ /services/2 @@
+	// + {"name":"cache","port":6379}
+	// @@ /debug @@
+	// - true
+}
+
+func requireChange(t *testing.T, c dgo.Change, kind dgo.ChangeKind, path string, old, new interface{}) {
+	t.Helper()
+	require.Equal(t, int(kind), int(c.Kind()))
+	require.Equal(t, path, c.Path())
+	if old == nil {
+		require.Nil(t, c.Old())
+	} else {
+		require.Equal(t, old, c.Old())
+	}
+	if new == nil {
+		require.Nil(t, c.New())
+	} else {
+		require.Equal(t, new, c.New())
+	}
+}
+
+func TestDiff_identical(t *testing.T) {
+	a := vf.Values(1, vf.Map(map[string]int{`a`: 1}))
+	d := vf.Diff(a, a)
+	require.True(t, d.Empty())
+	require.Equal(t, ``, d.String())
+	require.True(t, vf.Diff(a, a.Copy(false)).Empty())
+	require.True(t, vf.Diff(vf.Value(`x`), vf.Value(`x`)).Empty())
+}
+
+func TestDiff_scalars(t *testing.T) {
+	d := vf.Diff(vf.Value(1), vf.Value(`1`))
+	require.Equal(t, 1, len(d.Changes()))
+	requireChange(t, d.Changes()[0], dgo.ChangeModified, ``, 1, `1`)
+	require.Equal(t, "@@  @@\n- 1\n+ \"1\"", d.String())
+
+	d = vf.Diff(vf.Values(1), vf.Map(map[string]int{`a`: 1}))
+	requireChange(t, d.Changes()[0], dgo.ChangeModified, ``, vf.Values(1), vf.Map(map[string]int{`a`: 1}))
+}
+
+func TestDiff_maps(t *testing.T) {
+	a := vf.Values(`a`, 1, `b/c`, 2, `d~`, vf.Values(`x`, 1).ToMap(), 4, `four`).ToMap()
+	b := vf.Values(`a`, 1, `d~`, vf.Values(`x`, 2).ToMap(), `e`, nil, 4, `FOUR`).ToMap()
+	cs := vf.Diff(a, b).Changes()
+	require.Equal(t, 4, len(cs))
+	requireChange(t, cs[0], dgo.ChangeRemoved, `/b~1c`, 2, nil)
+	requireChange(t, cs[1], dgo.ChangeModified, `/d~0/x`, 1, 2)
+	requireChange(t, cs[2], dgo.ChangeModified, `/4`, `four`, `FOUR`)
+	requireChange(t, cs[3], dgo.ChangeAdded, `/e`, nil, vf.Nil)
+}
+
+func TestDiff_moved(t *testing.T) {
+	a := vf.Values(`a`, 1, `b`, 2, `c`, 3, `d`, 4).ToMap()
+	b := vf.Values(`b`, 2, `c`, 3, `x`, 0, `a`, 1, `d`, 4).ToMap()
+	cs := vf.Diff(a, b).Changes()
+	require.Equal(t, 2, len(cs))
+	requireChange(t, cs[0], dgo.ChangeAdded, `/x`, nil, 0)
+	requireChange(t, cs[1], dgo.ChangeMoved, `/a`, 0, 3)
+	require.Equal(t, "@@ /a @@\n~ moved from position 0 to 3", cs[1].String())
+
+	// Maps that are equal but ordered differently
+	ma := vf.Values(vf.Values(`m`, a).ToMap())
+	mb := vf.Values(vf.Values(`m`, vf.Values(`a`, 1, `b`, 2, `d`, 4, `c`, 3).ToMap()).ToMap())
+	require.Equal(t, ma, mb)
+	cs = vf.Diff(ma, mb).Changes()
+	require.Equal(t, 1, len(cs))
+	requireChange(t, cs[0], dgo.ChangeMoved, `/0/m/c`, 2, 3)
+}
+
+func TestDiff_arrays(t *testing.T) {
+	a := vf.Values(1, 2, 3, 4, 5, 6)
+	b := vf.Values(1, 3, 4, 10, 11, 5, 7)
+	cs := vf.Diff(a, b).Changes()
+	require.Equal(t, 4, len(cs))
+	requireChange(t, cs[0], dgo.ChangeRemoved, `/1`, 2, nil)
+	requireChange(t, cs[1], dgo.ChangeAdded, `/3`, nil, 10)
+	requireChange(t, cs[2], dgo.ChangeAdded, `/4`, nil, 11)
+	requireChange(t, cs[3], dgo.ChangeModified, `/6`, 6, 7)
+	require.Equal(t, "@@ /1 @@\n- 2\n@@ /3 @@\n+ 10\n@@ /4 @@\n+ 11\n@@ /6 @@\n- 6\n+ 7", vf.Diff(a, b).String())
+
+	cs = vf.Diff(vf.Values(1, 2), vf.Values()).Changes()
+	require.Equal(t, 2, len(cs))
+	requireChange(t, cs[1], dgo.ChangeRemoved, `/1`, 2, nil)
+
+	cs = vf.Diff(vf.Values(1, 2, 3), vf.Values(3, 1, 2)).Changes()
+	require.Equal(t, 2, len(cs))
+	requireChange(t, cs[0], dgo.ChangeAdded, `/0`, nil, 3)
+	requireChange(t, cs[1], dgo.ChangeRemoved, `/2`, 3, nil)
+}
+
+func TestDiff_largeArrays(t *testing.T) {
+	as := make([]dgo.Value, 0, 2000)
+	bs := make([]dgo.Value, 0, 2000)
+	for i := 0; i < 2000; i++ {
+		if i%100 != 7 {
+			as = append(as, vf.Integer(int64(i)))
+		}
+		if i%100 != 42 {
+			bs = append(bs, vf.Integer(int64(i)))
+		}
+	}
+	cs := vf.Diff(vf.Array(as), vf.Array(bs)).Changes()
+	require.Equal(t, 40, len(cs))
+	requireChange(t, cs[0], dgo.ChangeAdded, `/7`, nil, 7)
+	requireChange(t, cs[1], dgo.ChangeRemoved, `/41`, 42, nil)
+	for _, c := range cs {
+		require.True(t, c.Kind() == dgo.ChangeAdded || c.Kind() == dgo.ChangeRemoved)
+	}
+}
+
+func TestDiff_report(t *testing.T) {
+	d := vf.Diff(vf.Values(1, 2), vf.Values(1, 3))
+	w := util.NewIndenter(``)
+	d.AppendTo(w)
+	require.Equal(t, `@@ /1 @@ - 2 + 3`, w.String())
+	require.Equal(t, `@@ /1 @@
+- 2
++ 3`, d.String())
+}
+
+func TestDiff_recursive(t *testing.T) {
+	a := vf.MutableMap(0, nil)
+	a.Put(`name`, `a`)
+	a.Put(`self`, a)
+	b := vf.MutableMap(0, nil)
+	b.Put(`name`, `b`)
+	b.Put(`self`, b)
+	cs := vf.Diff(a, b).Changes()
+	require.Equal(t, 1, len(cs))
+	requireChange(t, cs[0], dgo.ChangeModified, `/name`, `a`, `b`)
+
+	c := vf.MutableValues(nil, 1)
+	c.Add(c)
+	require.True(t, vf.Diff(c, c.Copy(false)).Empty())
+}
@@ -0,0 +1,158 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+)
+
+// encoderFailure is used for panics that are recovered and returned as errors by the streaming encoders
+type encoderFailure struct {
+	error
+}
+
+// streamEncoder contains what is common to the streaming JSON and YAML encoders. It writes to a buffered
+// writer so that no representation of the complete document is ever built in memory.
+type streamEncoder struct {
+	w    *bufio.Writer
+	opts dgo.EncoderOptions
+
+	// active contains the collections that are currently being written and is used to detect collections
+	// that contain themselves
+	active map[dgo.Value]bool
+}
+
+func newStreamEncoder(w io.Writer, opts dgo.EncoderOptions) streamEncoder {
+	return streamEncoder{w: bufio.NewWriter(w), opts: opts, active: make(map[dgo.Value]bool)}
+}
+
+func encoderRecover(err *error) {
+	if r := recover(); r != nil {
+		f, ok := r.(encoderFailure)
+		if !ok {
+			panic(r)
+		}
+		*err = f.error
+	}
+}
+
+func (e *streamEncoder) fail(format string, args ...interface{}) {
+	panic(encoderFailure{fmt.Errorf(format, args...)})
+}
+
+func (e *streamEncoder) flush() {
+	if err := e.w.Flush(); err != nil {
+		panic(encoderFailure{err})
+	}
+}
+
+func (e *streamEncoder) writeString(s string) {
+	if _, err := e.w.WriteString(s); err != nil {
+		panic(encoderFailure{err})
+	}
+}
+
+func (e *streamEncoder) writeByte(b byte) {
+	if err := e.w.WriteByte(b); err != nil {
+		panic(encoderFailure{err})
+	}
+}
+
+// enter registers the given collection as being written and fails if it already is
+func (e *streamEncoder) enter(v dgo.Value) {
+	if e.active[v] {
+		e.fail(`unable to encode a collection that contains itself`)
+	}
+	e.active[v] = true
+}
+
+func (e *streamEncoder) leave(v dgo.Value) {
+	delete(e.active, v)
+}
+
+// entries returns the entries of the given map, sorted by key when the options say so. The keys are sorted
+// in the same total order as the keys of a SortedMap so keys of any type can be sorted.
+func (e *streamEncoder) entries(m dgo.Map) []dgo.MapEntry {
+	es := make([]dgo.MapEntry, 0, m.Len())
+	m.Each(func(me dgo.MapEntry) { es = append(es, me) })
+	if e.opts.SortKeys {
+		sort.SliceStable(es, func(i, j int) bool { return compareKeys(es[i].Key(), es[j].Key()) < 0 })
+	}
+	return es
+}
+
+// float returns the given finite float formatted according to the options
+func (e *streamEncoder) float(f float64) string {
+	switch e.opts.Floats {
+	case dgo.FloatDecimal:
+		s := strconv.FormatFloat(f, 'f', -1, 64)
+		if math.Trunc(f) == f {
+			s += `.0`
+		}
+		return s
+	case dgo.FloatExponent:
+		return strconv.FormatFloat(f, 'e', -1, 64)
+	default:
+		return util.Ftoa(f)
+	}
+}
+
+const hexDigits = `0123456789abcdef`
+
+// quote writes the given string as a double quoted string that is valid in both JSON and YAML. Characters
+// that aren't printable in YAML are escaped. So are <, >, and & when escapeHTML is true.
+func (e *streamEncoder) quote(s string, escapeHTML bool) {
+	e.writeByte('"')
+	start := 0
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			if c >= 0x20 && c < 0x7f && c != '"' && c != '\\' && !(escapeHTML && (c == '<' || c == '>' || c == '&')) {
+				i++
+				continue
+			}
+			e.writeString(s[start:i])
+			switch c {
+			case '"', '\\':
+				e.writeByte('\\')
+				e.writeByte(c)
+			case '\n':
+				e.writeString(`\n`)
+			case '\r':
+				e.writeString(`\r`)
+			case '\t':
+				e.writeString(`\t`)
+			default:
+				e.writeString(`\u00`)
+				e.writeByte(hexDigits[c>>4])
+				e.writeByte(hexDigits[c&0xf])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 || r <= 0x9f || r == 0x2028 || r == 0x2029 {
+			e.writeString(s[start:i])
+			if r == utf8.RuneError {
+				e.writeString(`\ufffd`)
+			} else {
+				e.writeString(`\u`)
+				e.writeString(strconv.FormatInt(int64(r)|0x10000, 16)[1:])
+			}
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	e.writeString(s[start:])
+	e.writeByte('"')
+}
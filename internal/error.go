@@ -22,6 +22,14 @@ func (t errType) Equals(other interface{}) bool {
 	return t == DefaultErrorType
 }
 
+func (t *errType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t errType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t errType) HashCode() int {
 	return int(t.TypeIdentifier())
 }
@@ -56,6 +64,14 @@ func (e *errw) Equals(other interface{}) bool {
 	return false
 }
 
+func (e *errw) GobDecode(b []byte) error {
+	return gobDecodeInto(b, e)
+}
+
+func (e *errw) GobEncode() ([]byte, error) {
+	return gobEncode(e)
+}
+
 func (e *errw) HashCode() int {
 	return stringHash(e.error.Error())
 }
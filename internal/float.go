@@ -57,6 +57,14 @@ func (t *floatRangeType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *floatRangeType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *floatRangeType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *floatRangeType) HashCode() int {
 	h := int(dgo.IdFloatRange)
 	if t.min > 0 {
@@ -108,6 +116,14 @@ func (t exactFloatType) Equals(other interface{}) bool {
 	return t == other
 }
 
+func (t *exactFloatType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t exactFloatType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t exactFloatType) HashCode() int {
 	return Float(t).HashCode() * 3
 }
@@ -151,7 +167,7 @@ func (t floatType) Assignable(other dgo.Type) bool {
 	case floatType, exactFloatType, *floatRangeType:
 		return true
 	}
-	return false
+	return CheckAssignableTo(nil, other, t)
 }
 
 func (t floatType) Equals(other interface{}) bool {
@@ -159,6 +175,14 @@ func (t floatType) Equals(other interface{}) bool {
 	return ok
 }
 
+func (t *floatType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t floatType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t floatType) HashCode() int {
 	return int(dgo.IdFloat)
 }
@@ -236,6 +260,14 @@ func (v Float) Equals(other interface{}) bool {
 	return ok && float64(v) == f
 }
 
+func (v *Float) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v Float) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v Float) HashCode() int {
 	return int(v)
 }
@@ -0,0 +1,515 @@
+package internal
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"reflect"
+	"regexp"
+	"regexp/syntax"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type generator struct {
+	rnd   *rand.Rand
+	depth int
+}
+
+const (
+	// generateAttempts is the number of candidates that rejection sampling will try before giving up
+	generateAttempts = 100
+
+	// generateExtraSize is the max number of elements, bytes, or entries that is added to the minimum
+	// size of an unbounded type
+	generateExtraSize = 8
+
+	// generateMaxDepth is the max nesting of collections generated for an unconstrained type
+	generateMaxDepth = 3
+
+	// generateMaxRepeat is the max number of repetitions generated for an unbounded regexp repeat
+	generateMaxRepeat = 5
+)
+
+// Generate returns a random instance of the given type using the given source of randomness. The same
+// type and an equally seeded source will always produce the same value. All collections in the returned
+// value are frozen.
+//
+// Size constraints and integer and float ranges are respected. Strings that must match a pattern are
+// produced from the syntax tree of the regular expression, also when the pattern is combined with sized
+// strings in an AllOf, and instances of predicate types by the generator that was registered with the
+// predicate. Values for AllOf, OneOf, and Not types, and for predicate types without a generator, are
+// produced using rejection sampling.
+//
+// An error is returned when no instance of the type could be produced, which is always the case for
+// types that have no instances, such as an AnyOf without operands.
+func Generate(t dgo.Type, src rand.Source) (dgo.Value, error) {
+	g := &generator{rnd: rand.New(src)}
+	if v, ok := g.generate(t); ok && t.Instance(v) {
+		return v, nil
+	}
+	return nil, fmt.Errorf(`unable to generate an instance of %s`, TypeString(t))
+}
+
+func (g *generator) generate(t dgo.Type) (dgo.Value, bool) {
+	switch t := t.(type) {
+	case *patternType:
+		return g.pattern(t.Regexp, 0, math.MaxInt64)
+	case *exactElementsType:
+		return g.oneOfValues(t.slice)
+	case *exactMapKeysType:
+		return g.oneOfValues(sliceOf((*hashMap)(t).Keys()))
+	case *exactMapValuesType:
+		return g.oneOfValues(sliceOf((*hashMap)(t).Values()))
+	case dgo.ExactType:
+		return t.Value(), true
+	case anyType:
+		return g.any(), true
+	case nilType:
+		return Nil, true
+	case dgo.BooleanType:
+		b := g.rnd.Intn(2) == 0
+		if !t.IsInstance(b) {
+			b = !b
+		}
+		return Boolean(b), true
+	case dgo.IntegerRangeType:
+		return Integer(g.int64(t.Min(), t.Max())), true
+	case dgo.FloatRangeType:
+		return Float(g.float64(t.Min(), t.Max())), true
+	case defaultStringType, *sizedStringType:
+		st := t.(dgo.StringType)
+		return makeHString(g.string(st.Min(), st.Max())), true
+	case binaryType:
+		bs := make([]byte, g.rnd.Intn(generateExtraSize+1))
+		g.rnd.Read(bs)
+		return Binary(bs), true
+	case regexpType:
+		return Value(regexp.MustCompile(regexp.QuoteMeta(g.string(1, generateExtraSize)))), true
+	case errType:
+		return Value(errors.New(g.string(1, generateExtraSize))), true
+	case *nativeType:
+		if t.rt == nil {
+			return Native(reflect.ValueOf(g.string(0, generateExtraSize))), true
+		}
+		return Native(reflect.New(t.rt).Elem()), true
+	case *metaType:
+		if t.tp == nil {
+			return &metaType{g.scalarType()}, true
+		}
+		return t.tp, true
+	case dgo.TupleType:
+		if ets := t.ElementTypes(); ets.Len() > 0 {
+			return g.tuple(ets)
+		}
+		return g.array(DefaultAnyType, 0, math.MaxInt64)
+	case defaultSetType, *sizedSetType:
+		st := t.(dgo.SetType)
+		return g.set(st.ElementType(), st.Min(), st.Max())
+	case dgo.ArrayType:
+		return g.array(t.ElementType(), t.Min(), t.Max())
+	case dgo.StructType:
+		return g.structMap(t)
+	case dgo.MapType:
+		return g.hashMap(t.KeyType(), t.ValueType(), t.Min(), t.Max())
+	case *notType:
+		return g.sample(t, func() (dgo.Value, bool) { return g.any(), true })
+	case *predicateType:
+		if t.generate != nil {
+			return t.generate(g.rnd), true
+		}
+		return g.sample(t, func() (dgo.Value, bool) { return g.any(), true })
+	case dgo.TernaryType:
+		return g.ternary(t)
+	}
+	return nil, false
+}
+
+// sample uses rejection sampling to find a value produced by the given function that is an instance of
+// the given type.
+func (g *generator) sample(t dgo.Type, f func() (dgo.Value, bool)) (dgo.Value, bool) {
+	for i := 0; i < generateAttempts; i++ {
+		if v, ok := f(); ok && t.Instance(v) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+func (g *generator) ternary(t dgo.TernaryType) (dgo.Value, bool) {
+	ops := sliceOf(t.Operands())
+	if len(ops) == 0 {
+		if t.Operator() == dgo.OpAnd {
+			return g.any(), true
+		}
+		return nil, false
+	}
+	if t.Operator() == dgo.OpAnd {
+		if srcs := allOfSources(ops); len(srcs) < len(ops) {
+			ops = srcs
+		} else if f := g.allOfPattern(ops); f != nil {
+			return g.sample(t, f)
+		}
+	}
+	pick := func() (dgo.Value, bool) { return g.generate(ops[g.rnd.Intn(len(ops))].(dgo.Type)) }
+	if t.Operator() == dgo.OpOr {
+		return pick()
+	}
+	// OpAnd and OpOne both require that the candidate is checked against all operands
+	return g.sample(t, pick)
+}
+
+// allOfSources returns the operands of an AllOf that candidates are produced from. Predicates that have a
+// generator are used when present since their instances are unlikely to be produced by other types.
+func allOfSources(ops []dgo.Value) []dgo.Value {
+	var srcs []dgo.Value
+	for _, op := range ops {
+		if pt, ok := op.(*predicateType); ok && pt.generate != nil {
+			srcs = append(srcs, op)
+		}
+	}
+	if len(srcs) > 0 {
+		return srcs
+	}
+	return ops
+}
+
+// allOfPattern returns a function that produces candidates for an AllOf with a pattern operand. The
+// candidates match the pattern and have a size that satisfies the sized string operands. Nil is returned
+// when there is no pattern operand.
+func (g *generator) allOfPattern(ops []dgo.Value) func() (dgo.Value, bool) {
+	var rx *regexp.Regexp
+	min, max := 0, math.MaxInt64
+	for _, op := range ops {
+		switch op := op.(type) {
+		case *patternType:
+			if rx == nil {
+				rx = op.Regexp
+			}
+		case *sizedStringType:
+			if op.min > min {
+				min = op.min
+			}
+			if op.max < max {
+				max = op.max
+			}
+		}
+	}
+	if rx == nil {
+		return nil
+	}
+	return func() (dgo.Value, bool) { return g.pattern(rx, min, max) }
+}
+
+func (g *generator) oneOfValues(vs []dgo.Value) (dgo.Value, bool) {
+	if len(vs) == 0 {
+		return g.any(), true
+	}
+	return vs[g.rnd.Intn(len(vs))], true
+}
+
+// any returns a random value. Collections are only produced until the max depth is reached.
+func (g *generator) any() dgo.Value {
+	n := 6
+	if g.depth >= generateMaxDepth {
+		n = 4
+	}
+	var v dgo.Value
+	switch g.rnd.Intn(n) {
+	case 0:
+		v = Nil
+	case 1:
+		v = Boolean(g.rnd.Intn(2) == 0)
+	case 2:
+		v = Integer(g.int64(math.MinInt64, math.MaxInt64))
+	case 3:
+		v = makeHString(g.string(0, math.MaxInt64))
+	case 4:
+		v, _ = g.array(DefaultAnyType, 0, math.MaxInt64)
+	default:
+		v, _ = g.hashMap(DefaultStringType, DefaultAnyType, 0, math.MaxInt64)
+	}
+	return v
+}
+
+var generatedScalarTypes = []dgo.Type{
+	DefaultNilType, DefaultBooleanType, DefaultIntegerType, DefaultFloatType, DefaultStringType, DefaultBinaryType}
+
+func (g *generator) scalarType() dgo.Type {
+	return generatedScalarTypes[g.rnd.Intn(len(generatedScalarTypes))]
+}
+
+// size returns a random size between min and max where max is capped so that unbounded types produce
+// reasonably small values.
+func (g *generator) size(min, max int) int {
+	if max-min > generateExtraSize {
+		max = min + generateExtraSize
+	}
+	return min + g.rnd.Intn(max-min+1)
+}
+
+// int64 returns a random integer in the inclusive range min to max. The boundaries and values close to
+// zero are favoured since they are more likely to reveal errors.
+func (g *generator) int64(min, max int64) int64 {
+	switch g.rnd.Intn(8) {
+	case 0:
+		return min
+	case 1:
+		return max
+	case 2, 3, 4:
+		if min <= 0 && max >= 0 {
+			lo, hi := int64(-100), int64(100)
+			if lo < min {
+				lo = min
+			}
+			if hi > max {
+				hi = max
+			}
+			return lo + g.rnd.Int63n(hi-lo+1)
+		}
+	}
+	w := uint64(max - min)
+	if w == math.MaxUint64 {
+		return int64(g.rnd.Uint64())
+	}
+	return min + int64(g.rnd.Uint64()%(w+1))
+}
+
+// float64 returns a random float in the inclusive range min to max. The boundaries and values close to
+// zero are favoured since they are more likely to reveal errors.
+func (g *generator) float64(min, max float64) float64 {
+	switch g.rnd.Intn(8) {
+	case 0:
+		return min
+	case 1:
+		return max
+	case 2, 3, 4:
+		if min <= 0 && max >= 0 {
+			min = math.Max(min, -100)
+			max = math.Min(max, 100)
+		}
+	}
+	f := g.rnd.Float64()
+	return min*(1-f) + max*f
+}
+
+// string returns a random string of printable ASCII characters. The size is counted in bytes.
+func (g *generator) string(min, max int) string {
+	bs := make([]byte, g.size(min, max))
+	for i := range bs {
+		bs[i] = byte(' ' + g.rnd.Intn('~'-' '+1))
+	}
+	return string(bs)
+}
+
+// pattern returns a string that matches the given regexp and has a size between min and max. A match that
+// is too short is padded with random characters before or after the match, which only retains the match
+// when the regexp isn't anchored on that side.
+func (g *generator) pattern(rx *regexp.Regexp, min, max int) (dgo.Value, bool) {
+	re, err := syntax.Parse(rx.String(), syntax.Perl)
+	if err != nil {
+		return nil, false
+	}
+	re = re.Simplify()
+	begin, end := anchors(re)
+	for i := 0; i < generateAttempts; i++ {
+		var rs []rune
+		if !g.regexp(re, &rs) {
+			continue
+		}
+		s := string(rs)
+		if len(s) < min {
+			n := min - len(s)
+			if max-min > generateExtraSize {
+				n += g.rnd.Intn(generateExtraSize + 1)
+			} else {
+				n += g.rnd.Intn(max - min + 1)
+			}
+			var b int
+			switch {
+			case begin && end:
+				continue
+			case begin:
+				b = 0
+			case end:
+				b = n
+			default:
+				b = g.rnd.Intn(n + 1)
+			}
+			s = g.string(b, b) + s + g.string(n-b, n-b)
+		}
+		if len(s) <= max && rx.MatchString(s) {
+			return makeHString(s), true
+		}
+	}
+	return nil, false
+}
+
+// anchors returns whether the given regexp syntax tree is anchored at the beginning and at the end
+func anchors(re *syntax.Regexp) (bool, bool) {
+	subs := []*syntax.Regexp{re}
+	if re.Op == syntax.OpConcat && len(re.Sub) > 0 {
+		subs = re.Sub
+	}
+	first, last := subs[0].Op, subs[len(subs)-1].Op
+	return first == syntax.OpBeginText || first == syntax.OpBeginLine,
+		last == syntax.OpEndText || last == syntax.OpEndLine
+}
+
+// regexp appends runes that matches the given regexp syntax tree. Assertions such as line boundaries
+// are ignored here since the result is validated against the compiled regexp.
+func (g *generator) regexp(re *syntax.Regexp, rs *[]rune) bool {
+	switch re.Op {
+	case syntax.OpNoMatch:
+		return false
+	case syntax.OpLiteral:
+		*rs = append(*rs, re.Rune...)
+	case syntax.OpCharClass:
+		if len(re.Rune) == 0 {
+			return false
+		}
+		p := g.rnd.Intn(len(re.Rune)/2) * 2
+		lo, hi := re.Rune[p], re.Rune[p+1]
+		*rs = append(*rs, lo+rune(g.rnd.Int63n(int64(hi-lo)+1)))
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		*rs = append(*rs, rune(' '+g.rnd.Intn('~'-' '+1)))
+	case syntax.OpCapture:
+		return g.regexp(re.Sub[0], rs)
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+		if max < 0 {
+			max = min + generateMaxRepeat
+		}
+		for n := min + g.rnd.Intn(max-min+1); n > 0; n-- {
+			if !g.regexp(re.Sub[0], rs) {
+				return false
+			}
+		}
+	case syntax.OpConcat:
+		for _, s := range re.Sub {
+			if !g.regexp(s, rs) {
+				return false
+			}
+		}
+	case syntax.OpAlternate:
+		return g.regexp(re.Sub[g.rnd.Intn(len(re.Sub))], rs)
+	}
+	return true
+}
+
+func (g *generator) tuple(ets dgo.Array) (dgo.Value, bool) {
+	g.depth++
+	defer func() { g.depth-- }()
+	s := make([]dgo.Value, ets.Len())
+	for i := range s {
+		v, ok := g.generate(ets.Get(i).(dgo.Type))
+		if !ok {
+			return nil, false
+		}
+		s[i] = v
+	}
+	return &array{slice: s, frozen: true}, true
+}
+
+func (g *generator) array(et dgo.Type, min, max int) (dgo.Value, bool) {
+	g.depth++
+	defer func() { g.depth-- }()
+	s := make([]dgo.Value, g.size(min, max))
+	for i := range s {
+		v, ok := g.generate(et)
+		if !ok {
+			return nil, false
+		}
+		s[i] = v
+	}
+	return &array{slice: s, frozen: true}, true
+}
+
+func (g *generator) set(et dgo.Type, min, max int) (dgo.Value, bool) {
+	g.depth++
+	defer func() { g.depth-- }()
+	n := g.size(min, max)
+	s := MutableSet(nil, nil)
+	for i := 0; s.Len() < n && i < n+generateAttempts; i++ {
+		v, ok := g.generate(et)
+		if !ok {
+			return nil, false
+		}
+		s.Add(v)
+	}
+	if s.Len() < min {
+		return nil, false
+	}
+	s.Freeze()
+	return s, true
+}
+
+func (g *generator) hashMap(kt, vt dgo.Type, min, max int) (dgo.Value, bool) {
+	g.depth++
+	defer func() { g.depth-- }()
+	n := g.size(min, max)
+	m := MutableMap(n, nil)
+	for i := 0; m.Len() < n && i < n+generateAttempts; i++ {
+		k, ok := g.generate(kt)
+		if !ok {
+			return nil, false
+		}
+		if _, found := m.Get(k); found {
+			continue
+		}
+		v, ok := g.generate(vt)
+		if !ok {
+			return nil, false
+		}
+		m.Put(k, v)
+	}
+	if m.Len() < min {
+		return nil, false
+	}
+	m.Freeze()
+	return m, true
+}
+
+// structMap returns a map with all required entries of the given struct, a random selection of its
+// optional entries, and, when the struct allows it, some additional entries.
+func (g *generator) structMap(t dgo.StructType) (dgo.Value, bool) {
+	g.depth++
+	defer func() { g.depth-- }()
+	es := sliceOf(t.Entries())
+	m := MutableMap(len(es), nil)
+	for _, ev := range es {
+		e := ev.(dgo.MapEntryType)
+		if !(e.Required() || g.rnd.Intn(2) == 0) {
+			continue
+		}
+		k, ok := g.generate(e.KeyType())
+		if !ok {
+			return nil, false
+		}
+		v, ok := g.generate(e.ValueType())
+		if !ok {
+			return nil, false
+		}
+		m.Put(k, v)
+	}
+	if t.Additional() {
+		for n := g.rnd.Intn(3); n > 0; n-- {
+			k := makeHString(g.string(1, generateExtraSize))
+			if t.Entries().Any(func(ev dgo.Value) bool { return ev.(dgo.MapEntryType).KeyType().Instance(k) }) {
+				continue
+			}
+			m.Put(k, g.any())
+		}
+	}
+	m.Freeze()
+	return m, true
+}
@@ -0,0 +1,172 @@
+package internal_test
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func requireGenerated(t *testing.T, tp dgo.Type) {
+	t.Helper()
+	for seed := int64(0); seed < 200; seed++ {
+		v, err := vf.Generate(tp, rand.NewSource(seed))
+		if err != nil {
+			t.Fatalf(`seed %d: %s`, seed, err.Error())
+		}
+		if !tp.Instance(v) {
+			t.Fatalf(`seed %d: %s is not an instance of %s`, seed, v, tp)
+		}
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	types := []string{
+		`any`,
+		`nil`,
+		`bool`,
+		`true`,
+		`false`,
+		`int`,
+		`3..8`,
+		`-10..-3`,
+		`1..`,
+		`..-5`,
+		`42`,
+		`float`,
+		`0.5..2.5`,
+		`-1.0..`,
+		`3.14`,
+		`string`,
+		`string[3,5]`,
+		`string[100]`,
+		`"hello"`,
+		`/^[a-z]+\d{2,4}(-[A-F0-9]{3})?$/`,
+		`/^(foo|bar)\.(baz)*\s?x$/`,
+		`/[^a]b./`,
+		`string[2,2]&/b/`,
+		`/b/&string[2,2]`,
+		`string[5,8]&/^ab/`,
+		`string[5,8]&/ab$/`,
+		`string[10]&/\d+/`,
+		`string[1,4]&string[3,9]&/x/`,
+		`binary`,
+		`[]int`,
+		`[2,4]0..9`,
+		`[1]string[1]`,
+		`{int,string,{bool}}`,
+		`{}`,
+		`map[string]int`,
+		`map[string[1],2,5]1..3`,
+		`map[bool,2]int`,
+		`{"a":int,"b"?:string[1,3],"c":{"d"?:bool}}`,
+		`int|string`,
+		`0..10&5..20`,
+		`0..10^5..20`,
+		`!int`,
+		`!(int|string|nil)`,
+		`1|2|3|"x"`,
+	}
+	for _, ts := range types {
+		requireGenerated(t, newtype.Parse(ts))
+	}
+	requireGenerated(t, newtype.Set(newtype.IntegerRange(0, 100), 3, 5))
+	requireGenerated(t, typ.Set)
+	requireGenerated(t, typ.Regexp)
+	requireGenerated(t, typ.Integer.Type())
+	requireGenerated(t, typ.Integer.Type().Type())
+	requireGenerated(t, typ.Error)
+	requireGenerated(t, typ.Native)
+	requireGenerated(t, newtype.FromReflected(reflect.TypeOf(struct{ A int }{})))
+	requireGenerated(t, vf.Values(1, 2).Type())
+	requireGenerated(t, vf.Map(map[string]int{`a`: 1}).Type())
+	requireGenerated(t, vf.Set(1, 2).Type())
+	requireGenerated(t, vf.Values(1, 1).Type().(dgo.ArrayType).ElementType())
+	requireGenerated(t, vf.Values().Type().(dgo.ArrayType).ElementType())
+	requireGenerated(t, vf.Map(map[string]int{`a`: 1}).Type().(dgo.MapType).KeyType())
+	requireGenerated(t, vf.Map(map[string]int{`a`: 1}).Type().(dgo.MapType).ValueType())
+}
+
+var tripleType = newtype.RegisterGeneratedPredicate(`triple`,
+	func(v dgo.Value) bool {
+		i, ok := v.(dgo.Integer)
+		return ok && i.GoInt()%3 == 0
+	},
+	func(rnd *rand.Rand) dgo.Value { return vf.Integer(int64(rnd.Intn(1000) * 3)) })
+
+func TestGenerate_predicates(t *testing.T) {
+	for _, ts := range []string{
+		`uuid`, `email`, `uri`, `ipv4`, `ipv6`, `hostname`, `date`, `semantic_version`,
+		`string&uuid`, `string&email`, `email&string[1,30]`, `string&uri`, `string&ipv4`, `string&ipv6`,
+		`string&hostname`, `string&date`, `string&semantic_version`,
+		`triple`, `0..3000&triple`, `0..100&even`,
+	} {
+		requireGenerated(t, newtype.Parse(ts))
+	}
+	require.Same(t, tripleType, newtype.Predicate(`triple`))
+}
+
+func TestGenerate_sizes(t *testing.T) {
+	for seed := int64(0); seed < 100; seed++ {
+		v, err := vf.Generate(newtype.Parse(`[3,5]string[2,4]`), rand.NewSource(seed))
+		require.Nil(t, err)
+		a := v.(dgo.Array)
+		require.True(t, a.Len() >= 3 && a.Len() <= 5)
+		require.True(t, a.Frozen())
+		a.Each(func(e dgo.Value) {
+			l := len(e.(dgo.String).GoString())
+			require.True(t, l >= 2 && l <= 4)
+		})
+	}
+}
+
+func TestGenerate_optionalEntries(t *testing.T) {
+	st := newtype.Parse(`{"a":int,"b"?:int}`)
+	withB, withoutB := 0, 0
+	for seed := int64(0); seed < 100; seed++ {
+		v, err := vf.Generate(st, rand.NewSource(seed))
+		require.Nil(t, err)
+		m := v.(dgo.Map)
+		_, ok := m.Get(`a`)
+		require.True(t, ok)
+		if _, ok = m.Get(`b`); !ok {
+			withoutB++
+		} else {
+			withB++
+		}
+	}
+	require.True(t, withB > 0)
+	require.True(t, withoutB > 0)
+}
+
+func TestGenerate_deterministic(t *testing.T) {
+	tp := newtype.Parse(`{"a":[]int,"b"?:/^x+y?$/,"c":map[string]float}`)
+	a, err := vf.Generate(tp, rand.NewSource(7))
+	require.Nil(t, err)
+	b, err := vf.Generate(tp, rand.NewSource(7))
+	require.Nil(t, err)
+	require.Equal(t, a, b)
+}
+
+func TestGenerate_impossible(t *testing.T) {
+	for _, tp := range []dgo.Type{
+		typ.AnyOf,
+		typ.Not,
+		newtype.Parse(`int&string`),
+		newtype.Parse(`/a\bb/`),
+		newtype.Parse(`map[bool,3]int`),
+		newtype.Parse(`{"a":int&string}`),
+		newtype.Parse(`{int&string}`),
+		newtype.Parse(`[1]int&string`),
+	} {
+		_, err := vf.Generate(tp, rand.NewSource(0))
+		require.NotNil(t, err)
+	}
+	_, err := vf.Generate(newtype.Parse(`int&string`), rand.NewSource(0))
+	require.Equal(t, `unable to generate an instance of int&string`, err.Error())
+}
@@ -0,0 +1,763 @@
+package internal
+
+import (
+	"bytes"
+	bin "encoding/binary"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// Tags used in the binary representation produced by the GobEncode methods. Each value starts with one of
+// these tags. Collections and types are represented recursively.
+const (
+	gobNil = byte(iota)
+	gobTrue
+	gobFalse
+	gobInteger
+	gobFloat
+	gobString
+	gobBinary
+	gobRegexp
+	gobError
+	gobArray
+	gobPersistentArray
+	gobMap
+	gobPersistentMap
+	gobSortedMap
+	gobSet
+	gobMapEntry
+
+	gobSimpleType
+	gobExactType
+	gobElementsType
+	gobMapKeysType
+	gobMapValuesType
+	gobIntegerRangeType
+	gobFloatRangeType
+	gobSizedStringType
+	gobPatternType
+	gobSizedArrayType
+	gobTupleType
+	gobSizedSetType
+	gobSizedMapType
+	gobStructType
+	gobEntryType
+	gobAllOfType
+	gobAnyOfType
+	gobOneOfType
+	gobNotType
+	gobMetaType
+	gobPredicateType
+	gobExtensionType
+)
+
+// Flags that precede the elements of a collection
+const (
+	gobFrozen = byte(1 << iota)
+	gobTyped
+)
+
+// gobSimpleTypes are the types that are represented by their TypeIdentifier alone
+var gobSimpleTypes = map[dgo.TypeIdentifier]dgo.Type{
+	dgo.IdAny:     DefaultAnyType,
+	dgo.IdArray:   DefaultArrayType,
+	dgo.IdBinary:  DefaultBinaryType,
+	dgo.IdBoolean: DefaultBooleanType,
+	dgo.IdError:   DefaultErrorType,
+	dgo.IdFalse:   FalseType,
+	dgo.IdFloat:   DefaultFloatType,
+	dgo.IdInteger: DefaultIntegerType,
+	dgo.IdMap:     DefaultMapType,
+	dgo.IdNative:  DefaultNativeType,
+	dgo.IdNever:   DefaultNeverType,
+	dgo.IdNil:     DefaultNilType,
+	dgo.IdRegexp:  DefaultRegexpType,
+	dgo.IdSet:     DefaultSetType,
+	dgo.IdString:  DefaultStringType,
+	dgo.IdTrue:    TrueType,
+}
+
+func init() {
+	for _, v := range []dgo.Value{
+		Nil, True, Integer(0), Float(0), &hstring{}, &binary{}, &Regexp{}, &errw{}, &array{}, &persistentArray{},
+		&hashMap{}, &persistentMap{}, &sortedMap{}, &hashSet{}, &hashNode{},
+
+		DefaultAnyType, DefaultArrayType, DefaultBinaryType, DefaultBooleanType, DefaultErrorType,
+		DefaultFloatType, DefaultIntegerType, DefaultMapType, DefaultNativeType, DefaultNeverType, DefaultNilType,
+		DefaultRegexpType, DefaultSetType, DefaultStringType,
+		exactIntegerType(0), exactFloatType(0), &exactStringType{}, &exactBinaryType{}, &exactRegexpType{},
+		&exactArrayType{}, &exactElementsType{}, &exactMapType{}, &exactMapKeysType{}, &exactMapValuesType{},
+		&exactSetType{}, &exactEntryType{}, &integerRangeType{}, &floatRangeType{}, &sizedStringType{},
+		&patternType{}, &sizedArrayType{}, &tupleType{}, &sizedSetType{}, &sizedMapType{}, &structType{},
+		&entryType{}, &allOfType{}, &anyOfType{}, &oneOfType{}, &notType{}, &metaType{}, &predicateType{},
+	} {
+		gob.Register(v)
+	}
+}
+
+// gobFailure is used for panics that are recovered and returned as errors by gobEncode and gobDecode
+type gobFailure struct {
+	error
+}
+
+// gobEncode returns the binary representation of the given value
+func gobEncode(v dgo.Value) (b []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(gobFailure)
+			if !ok {
+				panic(r)
+			}
+			err = f.error
+		}
+	}()
+	w := &gobWriter{active: make(map[dgo.Value]bool)}
+	w.value(v)
+	return w.Bytes(), nil
+}
+
+// gobDecode returns the value that is represented by the given bytes
+func gobDecode(b []byte) (v dgo.Value, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(gobFailure)
+			if !ok {
+				panic(r)
+			}
+			err = f.error
+		}
+	}()
+	r := &gobReader{b: b}
+	v = r.value()
+	if r.pos != len(b) {
+		r.fail(`unexpected trailing bytes`)
+	}
+	return
+}
+
+// gobDecodeInto decodes the given bytes and assigns the result to the given target which must be a
+// pointer to a value of the same type as the decoded value, or to the value that the decoded pointer
+// points to.
+func gobDecodeInto(b []byte, target interface{}) error {
+	v, err := gobDecode(b)
+	if err != nil {
+		return err
+	}
+	tv := reflect.ValueOf(target).Elem()
+	dv := reflect.ValueOf(v)
+	switch {
+	case dv.Type() == tv.Type():
+		tv.Set(dv)
+	case dv.Kind() == reflect.Ptr && dv.Type().Elem() == tv.Type():
+		tv.Set(dv.Elem())
+	default:
+		return fmt.Errorf(`unable to gob decode a %T into a %T`, v, target)
+	}
+	return nil
+}
+
+type gobWriter struct {
+	bytes.Buffer
+
+	// active contains the collections that are currently being written and is used to detect collections
+	// that contain themselves
+	active map[dgo.Value]bool
+}
+
+// enter registers the given collection as being written and fails if it already is
+func (w *gobWriter) enter(v dgo.Value) {
+	if w.active[v] {
+		panic(gobFailure{errors.New(`unable to gob encode a collection that contains itself`)})
+	}
+	w.active[v] = true
+}
+
+func (w *gobWriter) leave(v dgo.Value) {
+	delete(w.active, v)
+}
+
+func (w *gobWriter) writeUint(u uint64) {
+	var b [bin.MaxVarintLen64]byte
+	_, _ = w.Write(b[:bin.PutUvarint(b[:], u)])
+}
+
+func (w *gobWriter) writeInt(i int64) {
+	var b [bin.MaxVarintLen64]byte
+	_, _ = w.Write(b[:bin.PutVarint(b[:], i)])
+}
+
+func (w *gobWriter) writeString(s string) {
+	w.writeUint(uint64(len(s)))
+	_, _ = w.WriteString(s)
+}
+
+func (w *gobWriter) writeBool(b bool) {
+	if b {
+		_ = w.WriteByte(1)
+	} else {
+		_ = w.WriteByte(0)
+	}
+}
+
+// writeFlags writes the flags of a collection followed by its type if it has one
+func (w *gobWriter) writeFlags(frozen bool, typ dgo.Type) {
+	var f byte
+	if frozen {
+		f |= gobFrozen
+	}
+	if typ != nil {
+		f |= gobTyped
+	}
+	_ = w.WriteByte(f)
+	if typ != nil {
+		w.typ(typ)
+	}
+}
+
+func (w *gobWriter) writeMapEntries(m dgo.Map) {
+	w.writeUint(uint64(m.Len()))
+	m.Each(func(e dgo.MapEntry) {
+		w.value(e.Key())
+		w.value(e.Value())
+	})
+}
+
+func (w *gobWriter) writeValues(vs []dgo.Value) {
+	w.writeUint(uint64(len(vs)))
+	for _, v := range vs {
+		w.value(v)
+	}
+}
+
+func (w *gobWriter) writeTypes(a *array) {
+	w.writeUint(uint64(len(a.slice)))
+	for _, v := range a.slice {
+		w.typ(v.(dgo.Type))
+	}
+}
+
+func (w *gobWriter) value(v dgo.Value) {
+	switch v.(type) {
+	case *array, *persistentArray, *hashMap, *persistentMap, *sortedMap, *hashSet:
+		w.enter(v)
+		defer w.leave(v)
+	}
+	switch v := v.(type) {
+	case nil, nilValue:
+		_ = w.WriteByte(gobNil)
+	case Boolean:
+		if v {
+			_ = w.WriteByte(gobTrue)
+		} else {
+			_ = w.WriteByte(gobFalse)
+		}
+	case Integer:
+		_ = w.WriteByte(gobInteger)
+		w.writeInt(int64(v))
+	case Float:
+		_ = w.WriteByte(gobFloat)
+		w.writeUint(math.Float64bits(float64(v)))
+	case *hstring:
+		_ = w.WriteByte(gobString)
+		w.writeString(v.s)
+	case *binary:
+		_ = w.WriteByte(gobBinary)
+		w.writeBool(v.frozen)
+		w.writeString(string(v.bytes))
+	case *Regexp:
+		_ = w.WriteByte(gobRegexp)
+		w.writeString(v.String())
+	case *errw:
+		_ = w.WriteByte(gobError)
+		w.writeString(v.Error())
+	case *array:
+		_ = w.WriteByte(gobArray)
+		var typ dgo.Type
+		if v.typ != nil {
+			typ = v.typ
+		}
+		w.writeFlags(v.frozen, typ)
+		w.writeValues(v.slice)
+	case *persistentArray:
+		_ = w.WriteByte(gobPersistentArray)
+		w.writeValues(sliceOf(v))
+	case *hashMap:
+		_ = w.WriteByte(gobMap)
+		var typ dgo.Type
+		if v.typ != nil {
+			typ = v.typ
+		}
+		w.writeFlags(v.frozen, typ)
+		w.writeMapEntries(v)
+	case *persistentMap:
+		_ = w.WriteByte(gobPersistentMap)
+		w.writeMapEntries(v)
+	case *sortedMap:
+		_ = w.WriteByte(gobSortedMap)
+		var typ dgo.Type
+		if v.typ != nil {
+			typ = v.typ
+		}
+		w.writeFlags(v.frozen, typ)
+		w.writeMapEntries(v)
+	case *hashSet:
+		_ = w.WriteByte(gobSet)
+		var typ dgo.Type
+		if v.typ != nil {
+			typ = v.typ
+		}
+		w.writeFlags(v.m.frozen, typ)
+		w.writeUint(uint64(v.m.len))
+		for e := v.m.first; e != nil; e = e.next {
+			w.value(e.key)
+		}
+	case *hashNode:
+		_ = w.WriteByte(gobMapEntry)
+		w.value(v.key)
+		w.value(v.value)
+	case dgo.Type:
+		w.typ(v)
+	default:
+		panic(gobFailure{fmt.Errorf(`unable to gob encode a %T`, v)})
+	}
+}
+
+func (w *gobWriter) typ(t dgo.Type) {
+	switch t := t.(type) {
+	case anyType, booleanType, defaultArrayType, defaultMapType, defaultSetType, defaultStringType, errType,
+		floatType, integerType, neverType, nilType, regexpType, binaryType:
+		_ = w.WriteByte(gobSimpleType)
+		w.writeUint(uint64(t.TypeIdentifier()))
+	case exactIntegerType:
+		w.exact(Integer(t))
+	case exactFloatType:
+		w.exact(Float(t))
+	case *exactStringType:
+		w.exact((*hstring)(t))
+	case *exactBinaryType:
+		w.exact((*binary)(t))
+	case *exactRegexpType:
+		w.exact((*Regexp)(t))
+	case *exactArrayType:
+		w.exact((*array)(t))
+	case *exactMapType:
+		w.exact((*hashMap)(t))
+	case *exactSetType:
+		w.exact((*hashSet)(t))
+	case *exactEntryType:
+		w.exact((*hashNode)(t))
+	case *exactElementsType:
+		_ = w.WriteByte(gobElementsType)
+		w.value((*array)(t))
+	case *exactMapKeysType:
+		_ = w.WriteByte(gobMapKeysType)
+		w.value((*hashMap)(t))
+	case *exactMapValuesType:
+		_ = w.WriteByte(gobMapValuesType)
+		w.value((*hashMap)(t))
+	case *integerRangeType:
+		_ = w.WriteByte(gobIntegerRangeType)
+		w.writeInt(t.min)
+		w.writeInt(t.max)
+	case *floatRangeType:
+		_ = w.WriteByte(gobFloatRangeType)
+		w.writeUint(math.Float64bits(t.min))
+		w.writeUint(math.Float64bits(t.max))
+	case *sizedStringType:
+		_ = w.WriteByte(gobSizedStringType)
+		w.writeInt(int64(t.min))
+		w.writeInt(int64(t.max))
+	case *patternType:
+		_ = w.WriteByte(gobPatternType)
+		w.writeString(t.Regexp.String())
+	case *sizedArrayType:
+		_ = w.WriteByte(gobSizedArrayType)
+		w.typ(t.elementType)
+		w.writeInt(int64(t.min))
+		w.writeInt(int64(t.max))
+	case *tupleType:
+		_ = w.WriteByte(gobTupleType)
+		w.writeTypes((*array)(t))
+	case *sizedSetType:
+		_ = w.WriteByte(gobSizedSetType)
+		w.typ(t.elementType)
+		w.writeInt(int64(t.min))
+		w.writeInt(int64(t.max))
+	case *sizedMapType:
+		_ = w.WriteByte(gobSizedMapType)
+		w.typ(t.keyType)
+		w.typ(t.valueType)
+		w.writeInt(int64(t.min))
+		w.writeInt(int64(t.max))
+	case *structType:
+		_ = w.WriteByte(gobStructType)
+		w.writeBool(t.additional)
+		w.writeUint(uint64(t.entries.len))
+		for e := t.entries.first; e != nil; e = e.next {
+			w.entry(e.value.(*entryType))
+		}
+	case *entryType:
+		_ = w.WriteByte(gobEntryType)
+		w.entry(t)
+	case *allOfType:
+		_ = w.WriteByte(gobAllOfType)
+		w.writeTypes((*array)(t))
+	case *anyOfType:
+		_ = w.WriteByte(gobAnyOfType)
+		w.writeTypes((*array)(t))
+	case *oneOfType:
+		_ = w.WriteByte(gobOneOfType)
+		w.writeTypes((*array)(t))
+	case *notType:
+		_ = w.WriteByte(gobNotType)
+		w.typ(t.Negated)
+	case *metaType:
+		_ = w.WriteByte(gobMetaType)
+		w.writeBool(t.tp != nil)
+		if t.tp != nil {
+			w.typ(t.tp)
+		}
+	case *predicateType:
+		_ = w.WriteByte(gobPredicateType)
+		w.writeString(t.name)
+	case *nativeType:
+		if t.rt != nil {
+			panic(gobFailure{fmt.Errorf(`unable to gob encode the native type %s`, t.rt)})
+		}
+		_ = w.WriteByte(gobSimpleType)
+		w.writeUint(uint64(dgo.IdNative))
+	default:
+		if kindByID(t.TypeIdentifier()) == nil {
+			panic(gobFailure{fmt.Errorf(`unable to gob encode a %T`, t)})
+		}
+		_ = w.WriteByte(gobExtensionType)
+		w.writeString(TypeString(t))
+	}
+}
+
+func (w *gobWriter) exact(v dgo.Value) {
+	_ = w.WriteByte(gobExactType)
+	w.value(v)
+}
+
+func (w *gobWriter) entry(e *entryType) {
+	w.typ(e.key)
+	w.typ(e.value)
+	w.writeBool(e.required)
+}
+
+type gobReader struct {
+	b   []byte
+	pos int
+}
+
+func (r *gobReader) fail(msg string) {
+	panic(gobFailure{fmt.Errorf(`gob decode: %s at position %d`, msg, r.pos)})
+}
+
+func (r *gobReader) readByte() byte {
+	if r.pos >= len(r.b) {
+		r.fail(`unexpected end of data`)
+	}
+	b := r.b[r.pos]
+	r.pos++
+	return b
+}
+
+func (r *gobReader) readUint() uint64 {
+	u, n := bin.Uvarint(r.b[r.pos:])
+	if n <= 0 {
+		r.fail(`malformed unsigned integer`)
+	}
+	r.pos += n
+	return u
+}
+
+func (r *gobReader) readInt() int64 {
+	i, n := bin.Varint(r.b[r.pos:])
+	if n <= 0 {
+		r.fail(`malformed integer`)
+	}
+	r.pos += n
+	return i
+}
+
+func (r *gobReader) readSize() int {
+	n := r.readInt()
+	if n < 0 {
+		r.fail(`size out of range`)
+	}
+	return int(n)
+}
+
+// readCount reads the number of elements of a collection. The count is checked against the number of
+// remaining bytes since each element requires at least one byte.
+func (r *gobReader) readCount() int {
+	n := r.readUint()
+	if n > uint64(len(r.b)-r.pos) {
+		r.fail(`element count out of range`)
+	}
+	return int(n)
+}
+
+func (r *gobReader) readString() string {
+	n := r.readCount()
+	s := string(r.b[r.pos : r.pos+n])
+	r.pos += n
+	return s
+}
+
+func (r *gobReader) readBool() bool {
+	return r.readByte() != 0
+}
+
+// readFlags reads the flags of a collection and its type if it has one
+func (r *gobReader) readFlags() (bool, dgo.Type) {
+	f := r.readByte()
+	var typ dgo.Type
+	if f&gobTyped != 0 {
+		typ = r.typ()
+	}
+	return f&gobFrozen != 0, typ
+}
+
+func (r *gobReader) readValues() []dgo.Value {
+	vs := make([]dgo.Value, r.readCount())
+	for i := range vs {
+		vs[i] = r.value()
+	}
+	return vs
+}
+
+func (r *gobReader) readTypes() []dgo.Value {
+	ts := make([]dgo.Value, r.readCount())
+	for i := range ts {
+		ts[i] = r.typ()
+	}
+	return ts
+}
+
+func (r *gobReader) readMap() *hashMap {
+	n := r.readCount()
+	m := MutableMap(n, nil).(*hashMap)
+	for i := 0; i < n; i++ {
+		k := r.value()
+		m.Put(k, r.value())
+	}
+	return m
+}
+
+func (r *gobReader) value() dgo.Value {
+	switch tag := r.readByte(); tag {
+	case gobNil:
+		return Nil
+	case gobTrue:
+		return True
+	case gobFalse:
+		return False
+	case gobInteger:
+		return Integer(r.readInt())
+	case gobFloat:
+		return Float(math.Float64frombits(r.readUint()))
+	case gobString:
+		return makeHString(r.readString())
+	case gobBinary:
+		frozen := r.readBool()
+		return &binary{bytes: []byte(r.readString()), frozen: frozen}
+	case gobRegexp:
+		rx, err := regexp.Compile(r.readString())
+		if err != nil {
+			panic(gobFailure{err})
+		}
+		return (*Regexp)(rx)
+	case gobError:
+		return &errw{errors.New(r.readString())}
+	case gobArray:
+		frozen, typ := r.readFlags()
+		a := &array{slice: r.readValues(), frozen: frozen}
+		if typ != nil {
+			a.typ = r.asType(typ, func(t dgo.Type) bool { _, ok := t.(dgo.ArrayType); return ok }).(dgo.ArrayType)
+		}
+		return a
+	case gobPersistentArray:
+		return PersistentArray(r.readValues())
+	case gobMap:
+		frozen, typ := r.readFlags()
+		m := r.readMap()
+		if typ != nil {
+			m.typ = r.asType(typ, isMapType).(dgo.MapType)
+		}
+		m.frozen = frozen
+		return m
+	case gobPersistentMap:
+		return persistentMapFrom(r.readMap())
+	case gobSortedMap:
+		frozen, typ := r.readFlags()
+		m := sortedMapFrom(r.readMap(), frozen)
+		if typ != nil {
+			m.typ = r.asType(typ, isMapType).(dgo.MapType)
+		}
+		return m
+	case gobSet:
+		frozen, typ := r.readFlags()
+		n := r.readCount()
+		s := newHashSet(n)
+		for i := 0; i < n; i++ {
+			s.m.Put(r.value(), True)
+		}
+		if typ != nil {
+			s.typ = r.asType(typ, func(t dgo.Type) bool { _, ok := t.(dgo.SetType); return ok }).(dgo.SetType)
+		}
+		s.m.frozen = frozen
+		return s
+	case gobMapEntry:
+		k := r.value()
+		return &hashNode{key: k, value: r.value()}
+	default:
+		r.pos--
+		return r.typ()
+	}
+}
+
+func isMapType(t dgo.Type) bool {
+	_, ok := t.(dgo.MapType)
+	return ok
+}
+
+// asType returns the given type if the given function returns true for it and fails otherwise
+func (r *gobReader) asType(t dgo.Type, f func(dgo.Type) bool) dgo.Type {
+	if !f(t) {
+		r.fail(fmt.Sprintf(`unexpected type %s`, TypeString(t)))
+	}
+	return t
+}
+
+func (r *gobReader) typ() dgo.Type {
+	switch tag := r.readByte(); tag {
+	case gobSimpleType:
+		if t, ok := gobSimpleTypes[dgo.TypeIdentifier(r.readUint())]; ok {
+			return t
+		}
+		r.fail(`unknown type identifier`)
+	case gobExactType:
+		switch v := r.value().(type) {
+		case Integer:
+			return exactIntegerType(v)
+		case Float:
+			return exactFloatType(v)
+		case *hstring:
+			return (*exactStringType)(v)
+		case *binary:
+			return (*exactBinaryType)(v)
+		case *Regexp:
+			return (*exactRegexpType)(v)
+		case *array:
+			return (*exactArrayType)(v)
+		case *hashMap:
+			return (*exactMapType)(v)
+		case *hashSet:
+			return (*exactSetType)(v)
+		case *hashNode:
+			return (*exactEntryType)(v)
+		}
+		r.fail(`unexpected exact value`)
+	case gobElementsType:
+		if a, ok := r.value().(*array); ok {
+			return (*exactElementsType)(a)
+		}
+		r.fail(`expected an array`)
+	case gobMapKeysType:
+		if m, ok := r.value().(*hashMap); ok {
+			return (*exactMapKeysType)(m)
+		}
+		r.fail(`expected a map`)
+	case gobMapValuesType:
+		if m, ok := r.value().(*hashMap); ok {
+			return (*exactMapValuesType)(m)
+		}
+		r.fail(`expected a map`)
+	case gobIntegerRangeType:
+		min := r.readInt()
+		return &integerRangeType{min: min, max: r.readInt()}
+	case gobFloatRangeType:
+		min := math.Float64frombits(r.readUint())
+		return &floatRangeType{min: min, max: math.Float64frombits(r.readUint())}
+	case gobSizedStringType:
+		min := r.readSize()
+		return &sizedStringType{min: min, max: r.readSize()}
+	case gobPatternType:
+		rx, err := regexp.Compile(r.readString())
+		if err != nil {
+			panic(gobFailure{err})
+		}
+		return &patternType{Regexp: rx}
+	case gobSizedArrayType:
+		et := r.typ()
+		min := r.readSize()
+		return &sizedArrayType{elementType: et, min: min, max: r.readSize()}
+	case gobTupleType:
+		return &tupleType{slice: r.readTypes(), frozen: true}
+	case gobSizedSetType:
+		et := r.typ()
+		min := r.readSize()
+		return &sizedSetType{elementType: et, min: min, max: r.readSize()}
+	case gobSizedMapType:
+		kt := r.typ()
+		vt := r.typ()
+		min := r.readSize()
+		return &sizedMapType{keyType: kt, valueType: vt, min: min, max: r.readSize()}
+	case gobStructType:
+		additional := r.readBool()
+		es := make([]dgo.MapEntryType, r.readCount())
+		for i := range es {
+			es[i] = r.entry()
+		}
+		st := Struct(es).(*structType)
+		st.additional = additional
+		return st
+	case gobEntryType:
+		return r.entry()
+	case gobAllOfType:
+		return &allOfType{slice: r.readTypes(), frozen: true}
+	case gobAnyOfType:
+		return &anyOfType{slice: r.readTypes(), frozen: true}
+	case gobOneOfType:
+		return &oneOfType{slice: r.readTypes(), frozen: true}
+	case gobNotType:
+		return &notType{Negated: r.typ()}
+	case gobMetaType:
+		if r.readBool() {
+			return &metaType{r.typ()}
+		}
+		return &metaType{}
+	case gobPredicateType:
+		name := r.readString()
+		if pt := predicateByName(name); pt != nil {
+			return pt
+		}
+		r.fail(fmt.Sprintf(`unknown predicate '%s'`, name))
+	case gobExtensionType:
+		s := r.readString()
+		t, err := parseType(s)
+		if err != nil {
+			panic(gobFailure{err})
+		}
+		return t
+	default:
+		r.pos--
+		r.fail(fmt.Sprintf(`unknown tag %d`, tag))
+	}
+	return nil
+}
+
+func (r *gobReader) entry() *entryType {
+	kt := r.typ()
+	vt := r.typ()
+	return &entryType{key: kt, value: vt, required: r.readBool()}
+}
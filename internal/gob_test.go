@@ -0,0 +1,181 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"reflect"
+	"regexp"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func gobRoundTrip(t *testing.T, v dgo.Value) dgo.Value {
+	t.Helper()
+	var b bytes.Buffer
+	require.Nil(t, gob.NewEncoder(&b).Encode(&v))
+	var r dgo.Value
+	require.Nil(t, gob.NewDecoder(&b).Decode(&r))
+	require.Equal(t, reflect.TypeOf(v), reflect.TypeOf(r))
+	require.Equal(t, v, r)
+	return r
+}
+
+func TestGob_values(t *testing.T) {
+	for _, v := range []dgo.Value{
+		vf.Nil,
+		vf.True,
+		vf.False,
+		vf.Integer(-42),
+		vf.Float(3.14),
+		vf.String(`hello`),
+		vf.Value([]byte{1, 2, 3}),
+		vf.Value(regexp.MustCompile(`^a+$`)),
+		vf.Values(1, `two`, 3.0, nil, vf.Values(true)),
+		vf.Map(map[string]interface{}{`a`: 1, `b`: vf.Values(`x`)}),
+		vf.Set(1, `a`),
+		vf.PersistentValues(1, 2, 3),
+		vf.PersistentMap(map[string]int{`a`: 1}),
+		vf.SortedMap(map[string]int{`b`: 2, `a`: 1}),
+		vf.Map(map[string]int{`a`: 1}).Entries().Get(0),
+	} {
+		gobRoundTrip(t, v)
+	}
+	e := gobRoundTrip(t, vf.Value(errors.New(`oops`)))
+	require.Equal(t, `oops`, e.(error).Error())
+}
+
+func TestGob_frozen(t *testing.T) {
+	a := gobRoundTrip(t, vf.MutableValues(nil, 1, 2)).(dgo.Array)
+	require.False(t, a.Frozen())
+	a.Add(3)
+	require.Equal(t, vf.Values(1, 2, 3), a)
+
+	a = gobRoundTrip(t, vf.Values(1, 2)).(dgo.Array)
+	require.True(t, a.Frozen())
+
+	m := gobRoundTrip(t, vf.MutableMap(0, nil)).(dgo.Map)
+	require.False(t, m.Frozen())
+	require.True(t, gobRoundTrip(t, vf.Map(map[string]int{`a`: 1})).(dgo.Map).Frozen())
+
+	s := gobRoundTrip(t, vf.MutableSet(nil, 1)).(dgo.Set)
+	require.False(t, s.Frozen())
+	require.True(t, gobRoundTrip(t, vf.Set(1)).(dgo.Set).Frozen())
+}
+
+func TestGob_constraints(t *testing.T) {
+	a := vf.MutableValues(nil, 1, 2)
+	a.SetType(newtype.Parse(`[]0..5`).(dgo.ArrayType))
+	a = gobRoundTrip(t, a).(dgo.Array)
+	require.Equal(t, newtype.Parse(`[]0..5`), a.Type())
+	require.Panic(t, func() { a.Add(6) }, `cannot be assigned`)
+
+	m := vf.MutableMap(0, nil)
+	m.Put(`a`, 1)
+	m.SetType(newtype.Parse(`map[string]int`).(dgo.MapType))
+	m = gobRoundTrip(t, m).(dgo.Map)
+	require.Equal(t, newtype.Parse(`map[string]int`), m.Type())
+	require.Panic(t, func() { m.Put(`b`, `x`) }, `cannot be assigned`)
+
+	s := vf.MutableSet(nil, 1)
+	s.SetType(newtype.Parse(`set[int]`).(dgo.SetType))
+	s = gobRoundTrip(t, s).(dgo.Set)
+	require.Equal(t, newtype.Parse(`set[int]`), s.Type())
+	require.Panic(t, func() { s.Add(`x`) }, `cannot be assigned`)
+}
+
+func TestGob_types(t *testing.T) {
+	for _, s := range []string{
+		`any`, `bool`, `true`, `false`, `int`, `float`, `string`, `binary`, `nil`, `never`,
+		`3`, `3.5`, `"abc"`, `1..5`, `..5`, `0.5..2.5`, `string[1,5]`, `/a+b/`,
+		`[]int`, `[1,5]string`, `{int,string}`, `set[int]`, `set[int,1,2]`, `map[string]int`, `map[string,1,2]int`,
+		`{"a":int,"b"?:string}`, `int|string`, `int&0..5`, `int^1..5`, `!int`, `type[int]`,
+		`string&email`, `[]semver[1]`,
+	} {
+		gobRoundTrip(t, newtype.Parse(s))
+	}
+	for _, tp := range []dgo.Type{
+		typ.Any, typ.Array, typ.Map, typ.Set, typ.Regexp, typ.Error, typ.Native, typ.Boolean,
+		vf.Values(1, `a`).Type(),
+		vf.Values(1, `a`).Type().(dgo.ArrayType).ElementType(),
+		vf.Map(map[string]int{`a`: 1}).Type(),
+		vf.Map(map[string]int{`a`: 1}).Type().(dgo.MapType).KeyType(),
+		vf.Map(map[string]int{`a`: 1}).Type().(dgo.MapType).ValueType(),
+		vf.Set(1, 2).Type(),
+		vf.Value([]byte{1}).Type(),
+		vf.Value(regexp.MustCompile(`a`)).Type(),
+		vf.Map(map[string]int{`a`: 1}).Entries().Get(0).Type(),
+		newtype.Parse(`{"a":int}`).(dgo.StructType).Entries().Get(0).(dgo.Type),
+		typ.Integer.Type(),
+		typ.Integer.Type().Type(),
+	} {
+		gobRoundTrip(t, tp)
+	}
+}
+
+type gobMessage struct {
+	Name  string
+	Value dgo.Value
+	Type  dgo.Type
+	Array dgo.Array
+}
+
+func TestGob_struct(t *testing.T) {
+	msg := gobMessage{
+		Name:  `m`,
+		Value: vf.Map(map[string]interface{}{`a`: vf.Values(1, 2)}),
+		Type:  newtype.Parse(`{"a":[]int}`),
+		Array: vf.Strings(`x`, `y`)}
+	var b bytes.Buffer
+	require.Nil(t, gob.NewEncoder(&b).Encode(msg))
+	var r gobMessage
+	require.Nil(t, gob.NewDecoder(&b).Decode(&r))
+	require.Equal(t, msg.Value, r.Value)
+	require.Equal(t, msg.Type, r.Type)
+	require.Equal(t, msg.Array, r.Array)
+	require.Instance(t, r.Type, r.Value)
+}
+
+func TestGob_fail(t *testing.T) {
+	var b bytes.Buffer
+	var v dgo.Value = vf.Value(reflect.ValueOf(struct{ A int }{1}).Interface())
+	require.NotNil(t, gob.NewEncoder(&b).Encode(&v))
+
+	v = vf.Values(newtype.FromReflected(reflect.TypeOf(struct{ A int }{})))
+	require.NotNil(t, gob.NewEncoder(&b).Encode(&v))
+
+	bs, err := vf.Integer(1).(gob.GobEncoder).GobEncode()
+	require.Nil(t, err)
+	var s dgo.String = vf.String(``)
+	require.NotNil(t, s.(gob.GobDecoder).GobDecode(bs))
+	require.NotNil(t, s.(gob.GobDecoder).GobDecode(bs[:1]))
+	require.NotNil(t, s.(gob.GobDecoder).GobDecode(append(bs, 0)))
+	require.NotNil(t, s.(gob.GobDecoder).GobDecode([]byte{255}))
+}
+
+func TestGob_recursive(t *testing.T) {
+	a := vf.MutableValues(nil, 1)
+	a.Add(a)
+	_, err := a.(gob.GobEncoder).GobEncode()
+	require.NotNil(t, err)
+	require.Equal(t, `unable to gob encode a collection that contains itself`, err.Error())
+
+	m := vf.MutableMap(1, nil)
+	m.Put(`a`, vf.MutableValues(nil, m))
+	var b bytes.Buffer
+	var v dgo.Value = m
+	require.NotNil(t, gob.NewEncoder(&b).Encode(&v))
+
+	// The same collection may occur more than once as long as it doesn't contain itself
+	e := vf.Values(1)
+	bs, err := vf.Values(e, e).(gob.GobEncoder).GobEncode()
+	require.Nil(t, err)
+	r := vf.MutableValues(nil)
+	require.Nil(t, r.(gob.GobDecoder).GobDecode(bs))
+	require.Equal(t, vf.Values(e, e), r)
+}
@@ -0,0 +1,184 @@
+package internal
+
+import (
+	"math/bits"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+const (
+	hamtShift = 5
+	hamtMask  = 1<<hamtShift - 1
+)
+
+type (
+	// hamtEntry is either a leaf that maps a key to an int or a reference to a sub node
+	hamtEntry struct {
+		key  dgo.Value
+		hash uint32
+		val  int
+		node *hamtNode
+	}
+
+	// hamtNode is an immutable node of a hash array mapped trie. The bitmap tells which of the 32
+	// possible positions that are occupied and the entries slice contains one entry for each such
+	// position. A collision node is used when all bits of the hash have been consumed. Its entries
+	// all have the same hash and are searched linearly.
+	hamtNode struct {
+		bitmap    uint32
+		collision bool
+		entries   []hamtEntry
+	}
+)
+
+var emptyHamt = &hamtNode{}
+
+func hamtHash(key dgo.Value) uint32 {
+	return uint32(hash(key.HashCode()))
+}
+
+// find returns the int that is associated with the given key and true, or zero and false if no
+// such key exists.
+func (n *hamtNode) find(h uint32, key dgo.Value) (int, bool) {
+	for shift := uint(0); ; shift += hamtShift {
+		if n.collision {
+			for i := range n.entries {
+				if key.Equals(n.entries[i].key) {
+					return n.entries[i].val, true
+				}
+			}
+			return 0, false
+		}
+		bit := uint32(1) << ((h >> shift) & hamtMask)
+		if n.bitmap&bit == 0 {
+			return 0, false
+		}
+		e := &n.entries[bits.OnesCount32(n.bitmap&(bit-1))]
+		if e.node == nil {
+			if e.hash == h && key.Equals(e.key) {
+				return e.val, true
+			}
+			return 0, false
+		}
+		n = e.node
+	}
+}
+
+// assoc returns a node where the given key is associated with the given int. The returned boolean
+// is true if the key was not present in this node.
+func (n *hamtNode) assoc(shift uint, h uint32, key dgo.Value, val int) (*hamtNode, bool) {
+	if n.collision {
+		for i := range n.entries {
+			if key.Equals(n.entries[i].key) {
+				c := n.copy()
+				c.entries[i].val = val
+				return c, false
+			}
+		}
+		es := make([]hamtEntry, len(n.entries)+1)
+		copy(es, n.entries)
+		es[len(n.entries)] = hamtEntry{key: key, hash: h, val: val}
+		return &hamtNode{collision: true, entries: es}, true
+	}
+
+	bit := uint32(1) << ((h >> shift) & hamtMask)
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	if n.bitmap&bit == 0 {
+		es := make([]hamtEntry, len(n.entries)+1)
+		copy(es, n.entries[:pos])
+		es[pos] = hamtEntry{key: key, hash: h, val: val}
+		copy(es[pos+1:], n.entries[pos:])
+		return &hamtNode{bitmap: n.bitmap | bit, entries: es}, true
+	}
+
+	e := &n.entries[pos]
+	var added bool
+	c := n.copy()
+	switch {
+	case e.node != nil:
+		c.entries[pos].node, added = e.node.assoc(shift+hamtShift, h, key, val)
+	case e.hash == h && key.Equals(e.key):
+		c.entries[pos].val = val
+	default:
+		c.entries[pos] = hamtEntry{node: newHamtPair(shift+hamtShift, *e, hamtEntry{key: key, hash: h, val: val})}
+		added = true
+	}
+	return c, added
+}
+
+// dissoc returns a node where the given key has been removed. The returned node is nil if it
+// became empty. The returned boolean is true if the key was present in this node.
+func (n *hamtNode) dissoc(shift uint, h uint32, key dgo.Value) (*hamtNode, bool) {
+	if n.collision {
+		for i := range n.entries {
+			if key.Equals(n.entries[i].key) {
+				return n.without(i, 0), true
+			}
+		}
+		return n, false
+	}
+
+	bit := uint32(1) << ((h >> shift) & hamtMask)
+	if n.bitmap&bit == 0 {
+		return n, false
+	}
+	pos := bits.OnesCount32(n.bitmap & (bit - 1))
+	e := &n.entries[pos]
+	if e.node == nil {
+		if e.hash == h && key.Equals(e.key) {
+			return n.without(pos, bit), true
+		}
+		return n, false
+	}
+
+	sub, removed := e.node.dissoc(shift+hamtShift, h, key)
+	if !removed {
+		return n, false
+	}
+	if sub == nil {
+		return n.without(pos, bit), true
+	}
+	c := n.copy()
+	if len(sub.entries) == 1 && sub.entries[0].node == nil {
+		// Pull single leaf up to this level
+		c.entries[pos] = sub.entries[0]
+	} else {
+		c.entries[pos].node = sub
+	}
+	return c, true
+}
+
+func (n *hamtNode) copy() *hamtNode {
+	es := make([]hamtEntry, len(n.entries))
+	copy(es, n.entries)
+	return &hamtNode{bitmap: n.bitmap, collision: n.collision, entries: es}
+}
+
+// without returns a copy of this node where the entry at the given position and the given bit
+// in the bitmap have been removed, or nil if that copy would be empty.
+func (n *hamtNode) without(pos int, bit uint32) *hamtNode {
+	l := len(n.entries) - 1
+	if l == 0 {
+		return nil
+	}
+	es := make([]hamtEntry, l)
+	copy(es, n.entries[:pos])
+	copy(es[pos:], n.entries[pos+1:])
+	return &hamtNode{bitmap: n.bitmap &^ bit, collision: n.collision, entries: es}
+}
+
+// newHamtPair creates a node that contains the two given leaf entries
+func newHamtPair(shift uint, a, b hamtEntry) *hamtNode {
+	if shift >= 32 {
+		return &hamtNode{collision: true, entries: []hamtEntry{a, b}}
+	}
+	ia := (a.hash >> shift) & hamtMask
+	ib := (b.hash >> shift) & hamtMask
+	if ia == ib {
+		return &hamtNode{bitmap: 1 << ia, entries: []hamtEntry{{node: newHamtPair(shift+hamtShift, a, b)}}}
+	}
+	if ia > ib {
+		a, b = b, a
+	}
+	return &hamtNode{bitmap: 1<<ia | 1<<ib, entries: []hamtEntry{a, b}}
+}
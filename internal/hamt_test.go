@@ -0,0 +1,88 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// collider is a value with a configurable hash code
+type collider struct {
+	id int
+	h  int
+}
+
+func (c *collider) Equals(other interface{}) bool {
+	oc, ok := other.(*collider)
+	return ok && oc.id == c.id
+}
+
+func (c *collider) HashCode() int {
+	return c.h
+}
+
+func (c *collider) String() string {
+	return `collider`
+}
+
+func (c *collider) Type() dgo.Type {
+	return DefaultAnyType
+}
+
+func Test_hamtCollisions(t *testing.T) {
+	const n = 50
+	root := emptyHamt
+	var added bool
+	for i := 0; i < n; i++ {
+		k := &collider{id: i, h: i % 3}
+		if root, added = root.assoc(0, hamtHash(k), k, i); !added {
+			t.Fatalf(`key %d was not added`, i)
+		}
+	}
+	if root, added = root.assoc(0, hamtHash(&collider{id: 4, h: 1}), &collider{id: 4, h: 1}, 100); added {
+		t.Fatal(`existing key was added`)
+	}
+	for i := 0; i < n; i++ {
+		k := &collider{id: i, h: i % 3}
+		v, ok := root.find(hamtHash(k), k)
+		if !ok || (i == 4 && v != 100) || (i != 4 && v != i) {
+			t.Fatalf(`key %d not found`, i)
+		}
+	}
+	if _, ok := root.find(0, &collider{id: n, h: 0}); ok {
+		t.Fatal(`unknown key was found`)
+	}
+
+	var removed bool
+	for i := 0; i < n; i++ {
+		k := &collider{id: i, h: i % 3}
+		if root, removed = root.dissoc(0, hamtHash(k), k); !removed {
+			t.Fatalf(`key %d was not removed`, i)
+		}
+		if root == nil {
+			break
+		}
+		if _, removed = root.dissoc(0, hamtHash(k), k); removed {
+			t.Fatalf(`key %d was removed twice`, i)
+		}
+	}
+	if root != nil {
+		t.Fatal(`root is not nil`)
+	}
+}
+
+func Test_persistentMapCompaction(t *testing.T) {
+	m := emptyPersistentMap
+	for i := 0; i < 100; i++ {
+		m = m.with(Integer(i), Integer(i))
+	}
+	for i := 0; i < 70; i++ {
+		m = m.without(Integer(i))
+	}
+	if m.len != 30 || m.order.cnt > 60 {
+		t.Fatalf(`map was not compacted, len = %d, order = %d`, m.len, m.order.cnt)
+	}
+	if v, ok := m.Get(99); !ok || v != Integer(99) {
+		t.Fatal(`compacted map lost entries`)
+	}
+}
@@ -0,0 +1,146 @@
+package internal
+
+import (
+	"math"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// DefaultInferWidth is the maximum number of operands that Infer will allow in a union before it is
+// collapsed into the any type.
+const DefaultInferWidth = 5
+
+// Infer returns the least general type that all of the given values are instances of, with the exception
+// that exact types are widened to their defaults so that e.g. an integer value yields int and a string
+// value yields string.
+//
+// Arrays yield an array type where the element type is inferred from the elements of all arrays. Maps
+// where all keys are strings yield a Struct type with one entry per key. An entry is optional unless its
+// key is present in all maps. Other maps yield a map type where the key and value types are inferred from
+// the keys and values of all maps.
+//
+// An inferred union that has more operands than the given width is collapsed into the any type. A width
+// less than one means that no such collapse will take place. The Never type is returned when no values are
+// given.
+func Infer(width int, values []dgo.Value) dgo.Type {
+	return (&inferrer{width: width}).infer(values)
+}
+
+type inferrer struct {
+	width int
+}
+
+func (n *inferrer) infer(values []dgo.Value) dgo.Type {
+	var (
+		ts      []dgo.Type
+		arrays  []dgo.Array
+		sets    []dgo.Set
+		structs []dgo.Map
+		maps    []dgo.Map
+	)
+	for _, v := range values {
+		switch v := v.(type) {
+		case *hashSet:
+			sets = append(sets, v)
+		case dgo.Array:
+			arrays = append(arrays, v)
+		case dgo.Map:
+			if v.Len() > 0 && v.AllKeys(func(k dgo.Value) bool { _, ok := k.(dgo.String); return ok }) {
+				structs = append(structs, v)
+			} else {
+				maps = append(maps, v)
+			}
+		default:
+			ts = append(ts, widen(v.Type()))
+		}
+	}
+	if len(arrays) > 0 {
+		var es []dgo.Value
+		for _, a := range arrays {
+			es = append(es, sliceOf(a)...)
+		}
+		ts = append(ts, newArrayType(n.elementType(es), 0, math.MaxInt64))
+	}
+	if len(sets) > 0 {
+		var es []dgo.Value
+		for _, s := range sets {
+			s.Each(func(e dgo.Value) { es = append(es, e) })
+		}
+		ts = append(ts, newSetType(n.elementType(es), 0, math.MaxInt64))
+	}
+	if len(structs) > 0 {
+		ts = append(ts, n.inferStruct(structs))
+	}
+	if len(maps) > 0 {
+		var ks, vs []dgo.Value
+		for _, m := range maps {
+			m.Each(func(e dgo.MapEntry) {
+				ks = append(ks, e.Key())
+				vs = append(vs, e.Value())
+			})
+		}
+		ts = append(ts, newMapType(n.elementType(ks), n.elementType(vs), 0, math.MaxInt64))
+	}
+	t := Union(ts)
+	if at, ok := t.(*anyOfType); ok && n.width > 0 && len(at.slice) > n.width {
+		t = DefaultAnyType
+	}
+	return t
+}
+
+// elementType returns the type inferred from the given values or the any type when no values are given
+func (n *inferrer) elementType(values []dgo.Value) dgo.Type {
+	if len(values) == 0 {
+		return DefaultAnyType
+	}
+	return n.infer(values)
+}
+
+// inferStruct returns a Struct type with one entry for each key found in the given maps
+func (n *inferrer) inferStruct(maps []dgo.Map) dgo.Type {
+	// Keys are retained in the order that they are first encountered
+	seen := MutableMap(0, nil)
+	var keys []dgo.Value
+	for _, m := range maps {
+		m.EachKey(func(k dgo.Value) {
+			if _, ok := seen.Get(k); !ok {
+				seen.Put(k, True)
+				keys = append(keys, k)
+			}
+		})
+	}
+	es := make([]dgo.MapEntryType, len(keys))
+	for i, k := range keys {
+		var vs []dgo.Value
+		for _, m := range maps {
+			if v, ok := m.Get(k); ok {
+				vs = append(vs, v)
+			}
+		}
+		es[i] = StructEntry(k.String(), n.infer(vs), len(vs) == len(maps))
+	}
+	return Struct(es)
+}
+
+// widen returns the default type for the kind of the given type
+func widen(t dgo.Type) dgo.Type {
+	switch kindOf(t) {
+	case kindNil:
+		return DefaultNilType
+	case kindBoolean:
+		return DefaultBooleanType
+	case kindInteger:
+		return DefaultIntegerType
+	case kindFloat:
+		return DefaultFloatType
+	case kindBinary:
+		return DefaultBinaryType
+	case kindString:
+		return DefaultStringType
+	case kindRegexp:
+		return DefaultRegexpType
+	case kindError:
+		return DefaultErrorType
+	}
+	return t
+}
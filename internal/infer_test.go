@@ -0,0 +1,64 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func TestInfer(t *testing.T) {
+	tests := []struct {
+		values []string
+		result string
+	}{
+		{[]string{`3`}, `int`},
+		{[]string{`3`, `8`}, `int`},
+		{[]string{`3`, `"a"`}, `int|string`},
+		{[]string{`3.2`, `null`}, `float|nil`},
+		{[]string{`true`, `false`}, `bool`},
+		{[]string{`[1,2,3]`}, `[]int`},
+		{[]string{`[1,"a"]`, `[2]`}, `[](int|string)`},
+		{[]string{`[]`}, `[]any`},
+		{[]string{`[[1],[2,3]]`}, `[][]int`},
+		{[]string{`{"a":1}`}, `{"a":int}`},
+		{[]string{`{"a":1,"b":"x"}`, `{"a":2}`}, `{"a":int,"b"?:string}`},
+		{[]string{`{"a":1}`, `{"a":"x"}`}, `{"a":int|string}`},
+		{[]string{`{}`}, `map[any]any`},
+		{[]string{`{"a":1}`, `[1]`}, `[]int|{"a":int}`},
+	}
+	for _, tc := range tests {
+		vs := make([]dgo.Value, len(tc.values))
+		for i, s := range tc.values {
+			vs[i] = jsonValue(t, s)
+		}
+		require.Equal(t, tc.result, newtype.Infer(vs...).String())
+	}
+	require.Equal(t, typ.Never, newtype.Infer())
+	require.Equal(t, `map[int]string`, newtype.Infer(vf.Map(map[int]string{1: `a`, 2: `b`})).String())
+	require.Equal(t, `{"b":string}|map[int]string`, newtype.Infer(vf.Map(map[int]string{1: `a`}), vf.Map(map[string]string{`b`: `c`})).String())
+	require.Equal(t, `set[int]`, newtype.Infer(vf.Set(1, 2)).String())
+}
+
+func TestInfer_document(t *testing.T) {
+	docs := jsonValue(t, `[
+		{"id":1,"name":"first","tags":["a","b"]},
+		{"id":2,"name":"second"},
+		{"id":3,"name":"third","tags":[]}
+	]`)
+	it := newtype.Infer(docs)
+	require.Equal(t, `[]{"id":int,"name":string,"tags"?:[]string}`, it.String())
+	require.Instance(t, it, docs)
+}
+
+func TestInfer_width(t *testing.T) {
+	vs := vf.Values(1, `a`, 2.0, true, nil, []int{1}).AppendToSlice(nil)
+	require.Equal(t, typ.Any, newtype.Infer(vs...))
+	require.Equal(t, typ.Any, newtype.InferWithWidth(5, vs...))
+	require.Equal(t, `nil|bool|int|float|string|[]int`, newtype.Normalize(newtype.InferWithWidth(6, vs...)).String())
+	require.Equal(t, `nil|bool|int|float|string|[]int`, newtype.Normalize(newtype.InferWithWidth(0, vs...)).String())
+	require.Equal(t, `[]any`, newtype.InferWithWidth(1, vf.Values(1, `a`)).String())
+}
@@ -57,6 +57,14 @@ func (t *integerRangeType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *integerRangeType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *integerRangeType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *integerRangeType) HashCode() int {
 	h := int(dgo.IdIntegerRange)
 	if t.min > 0 {
@@ -110,6 +118,14 @@ func (t exactIntegerType) Equals(other interface{}) bool {
 	return t == other
 }
 
+func (t *exactIntegerType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t exactIntegerType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t exactIntegerType) HashCode() int {
 	return Integer(t).HashCode() * 5
 }
@@ -153,7 +169,7 @@ func (t integerType) Assignable(other dgo.Type) bool {
 	case integerType, exactIntegerType, *integerRangeType:
 		return true
 	}
-	return false
+	return CheckAssignableTo(nil, other, t)
 }
 
 func (t integerType) Equals(other interface{}) bool {
@@ -161,6 +177,14 @@ func (t integerType) Equals(other interface{}) bool {
 	return ok
 }
 
+func (t *integerType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t integerType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t integerType) HashCode() int {
 	return int(dgo.IdInteger)
 }
@@ -240,6 +264,14 @@ func (v Integer) Equals(other interface{}) bool {
 	return ok && int64(v) == i
 }
 
+func (v *Integer) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v Integer) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v Integer) HashCode() int {
 	return int(v ^ (v >> 32))
 }
@@ -4,14 +4,43 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"reflect"
+	"strings"
 
 	"github.com/lyraproj/dgo/dgo"
 )
 
+// jsonDecoder decodes the tokens produced by a json.Decoder into dgo values
+type jsonDecoder struct {
+	dec   *json.Decoder
+	opts  dgo.JSONDecoderOptions
+	depth int
+}
+
+// UnmarshalJSON decodes the given JSON into a frozen dgo.Value
 func UnmarshalJSON(b []byte) (dgo.Value, error) {
+	return UnmarshalJSONWithOptions(b, dgo.JSONDecoderOptions{})
+}
+
+// UnmarshalJSONWithOptions decodes the given JSON into a dgo.Value using the given options
+func UnmarshalJSONWithOptions(b []byte, opts dgo.JSONDecoderOptions) (dgo.Value, error) {
+	if opts.MaxSize > 0 && len(b) > opts.MaxSize {
+		return nil, fmt.Errorf(`json document size %d exceeds the maximum size %d`, len(b), opts.MaxSize)
+	}
+	return newJSONDecoder(b, opts).decodeValue()
+}
+
+func newJSONDecoder(b []byte, opts dgo.JSONDecoderOptions) *jsonDecoder {
 	dec := json.NewDecoder(bytes.NewReader(b))
 	dec.UseNumber()
-	return jsonDecodeValue(dec)
+	return &jsonDecoder{dec: dec, opts: opts}
+}
+
+// newMutableJSONDecoder returns a decoder that produces mutable values. It is used when decoding into an
+// existing collection.
+func newMutableJSONDecoder(b []byte) *jsonDecoder {
+	return newJSONDecoder(b, dgo.JSONDecoderOptions{Mutable: true})
 }
 
 type decodeEndToken rune
@@ -23,40 +52,67 @@ func (e decodeEndToken) Error() string {
 	return fmt.Sprintf(`unexpected delimiter '%c'`, rune(e))
 }
 
-func jsonDecodeValue(dec *json.Decoder) (e dgo.Value, err error) {
+func (d *jsonDecoder) decodeValue() (e dgo.Value, err error) {
 	var t json.Token
-	if t, err = dec.Token(); err != nil {
+	if t, err = d.dec.Token(); err != nil {
 		return
 	}
 	switch t := t.(type) {
 	case json.Delim:
 		switch t {
 		case '[':
-			return jsonDecodeArray(dec)
+			return d.decodeArray()
 		case '{':
-			return jsonDecodeMap(dec)
+			return d.decodeMap()
 		default:
 			return nil, decodeEndToken(t)
 		}
 	case json.Number:
-		if i, err := t.Int64(); err == nil {
-			return Integer(i), nil
-		} else {
-			f, _ := t.Float64()
-			return Float(f), nil
-		}
+		return d.number(t), nil
 	default:
 		return Value(t), nil
 	}
 }
 
-func jsonDecodeArray(dec *json.Decoder) (*array, error) {
+func (d *jsonDecoder) number(n json.Number) dgo.Value {
+	switch d.opts.Numbers {
+	case dgo.NumberFloat:
+	case dgo.NumberPreserve:
+		return native(reflect.ValueOf(n))
+	default:
+		if i, err := n.Int64(); err == nil {
+			return Integer(i)
+		}
+		if d.opts.Numbers == dgo.NumberBig && !strings.ContainsAny(string(n), `.eE`) {
+			if bi, ok := new(big.Int).SetString(string(n), 10); ok {
+				return makeBigInt(bi)
+			}
+		}
+	}
+	f, _ := n.Float64()
+	return Float(f)
+}
+
+// enter increases the nesting depth and returns an error if the maximum depth is exceeded
+func (d *jsonDecoder) enter() error {
+	d.depth++
+	if d.opts.MaxDepth > 0 && d.depth > d.opts.MaxDepth {
+		return fmt.Errorf(`json nesting depth exceeds the maximum depth %d`, d.opts.MaxDepth)
+	}
+	return nil
+}
+
+func (d *jsonDecoder) decodeArray() (*array, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
 	s := make([]dgo.Value, 0)
 	for {
-		e, err := jsonDecodeValue(dec)
+		e, err := d.decodeValue()
 		if err != nil {
 			if err == endOfArray {
-				return &array{slice: s, frozen: true}, nil
+				d.depth--
+				return &array{slice: s, frozen: !d.opts.Mutable}, nil
 			}
 			return nil, err
 		}
@@ -64,37 +120,36 @@ func jsonDecodeArray(dec *json.Decoder) (*array, error) {
 	}
 }
 
-func jsonDecodeMap(dec *json.Decoder) (*hashMap, error) {
-	m := &hashMap{}
+func (d *jsonDecoder) decodeMap() (*hashMap, error) {
+	if err := d.enter(); err != nil {
+		return nil, err
+	}
+	m := MutableMap(0, nil).(*hashMap)
 	for {
-		k, err := jsonDecodeValue(dec)
+		k, err := d.decodeValue()
 		if err != nil {
 			if err == endOfMap {
 				break
 			}
 			return nil, err
 		}
-		v, err := jsonDecodeValue(dec)
+		v, err := d.decodeValue()
 		if err != nil {
 			return nil, err
 		}
-		nd := &hashNode{key: k, value: v, prev: m.last}
-		if m.first == nil {
-			m.first = nd
-		} else {
-			m.last.next = nd
+		if _, ok := m.Get(k); ok {
+			switch d.opts.DuplicateKeys {
+			case dgo.DuplicateKeyFirst:
+				continue
+			case dgo.DuplicateKeyError:
+				return nil, fmt.Errorf(`duplicate key %q in json object`, k.String())
+			}
 		}
-		m.last = nd
-		m.len++
+		m.Put(k, v)
 	}
-
-	tbl := make([]*hashNode, tableSizeFor(m.len))
-	hl := len(tbl) - 1
-	for e := m.first; e != nil; e = e.next {
-		hk := hl & hash(e.key.HashCode())
-		e.hashNext = tbl[hk]
-		tbl[hk] = e
+	d.depth--
+	if !d.opts.Mutable {
+		m.frozen = true
 	}
-	m.table = tbl
 	return m, nil
 }
@@ -2,9 +2,12 @@ package internal_test
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"math/big"
 	"testing"
 
+	"github.com/lyraproj/dgo/dgo"
 	require "github.com/lyraproj/dgo/dgo_test"
 	"github.com/lyraproj/dgo/util"
 	"github.com/lyraproj/dgo/vf"
@@ -89,3 +92,92 @@ func TestMap_UnmarshalLong(t *testing.T) {
 	require.Nil(t, err)
 	require.Equal(t, 100, m.Len())
 }
+
+func TestMap_UnmarshalJSON_mutable(t *testing.T) {
+	m := vf.MutableMap(7, nil)
+	require.Nil(t, m.UnmarshalJSON([]byte(`{"a":[1]}`)))
+	m.Put(`b`, 2)
+	a, _ := m.Get(`a`)
+	a.(dgo.Array).Add(2)
+	require.Equal(t, vf.Map(map[string]interface{}{`a`: vf.Values(1, 2), `b`: 2}), m)
+}
+
+func TestUnmarshalJSON_duplicateKeys(t *testing.T) {
+	doc := []byte(`{"a":1,"b":2,"a":3}`)
+	v, err := vf.UnmarshalJSON(doc)
+	require.Nil(t, err)
+	m := v.(dgo.Map)
+	require.Equal(t, 2, m.Len())
+	require.Equal(t, vf.Strings(`a`, `b`), m.Keys())
+	require.Equal(t, vf.Values(3, 2), m.Values())
+	require.True(t, m.Frozen())
+
+	v, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{DuplicateKeys: dgo.DuplicateKeyFirst})
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, 2), v.(dgo.Map).Values())
+
+	_, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{DuplicateKeys: dgo.DuplicateKeyError})
+	require.Equal(t, `duplicate key "a" in json object`, err.Error())
+}
+
+func TestUnmarshalJSONWithOptions_numbers(t *testing.T) {
+	doc := []byte(`[1,2.5,12345678901234567890,1e3]`)
+	v, err := vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{Numbers: dgo.NumberInt64})
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, 2.5, 12345678901234567890.0, 1000.0), v)
+
+	v, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{Numbers: dgo.NumberFloat})
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1.0, 2.5, 12345678901234567890.0, 1000.0), v)
+
+	v, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{Numbers: dgo.NumberBig})
+	require.Nil(t, err)
+	bi, _ := new(big.Int).SetString(`12345678901234567890`, 10)
+	a := v.(dgo.Array)
+	require.Equal(t, 1, a.Get(0))
+	require.Equal(t, 1000.0, a.Get(3))
+	require.Equal(t, bi, a.Get(2).(dgo.Native).GoValue())
+	require.True(t, a.Frozen())
+	require.True(t, a.Get(2).(dgo.Freezable).Frozen())
+	a.Get(2).(dgo.Native).GoValue().(*big.Int).SetInt64(0)
+	require.Equal(t, bi, a.Get(2).(dgo.Native).GoValue())
+
+	v, err = vf.UnmarshalJSONWithOptions([]byte(`{"a":123456789012345678901234567890}`),
+		dgo.JSONDecoderOptions{Numbers: dgo.NumberBig})
+	require.Nil(t, err)
+	bi, _ = new(big.Int).SetString(`123456789012345678901234567890`, 10)
+	pm := vf.PersistentMap(v.(dgo.Map))
+	require.Equal(t, v, pm)
+	pv, _ := pm.Get(`a`)
+	require.Equal(t, bi, pv)
+	sm := vf.SortedMap(v.(dgo.Map))
+	require.Equal(t, v, sm)
+
+	v, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{Numbers: dgo.NumberPreserve})
+	require.Nil(t, err)
+	a = v.(dgo.Array)
+	require.True(t, json.Number(`12345678901234567890`) == a.Get(2).(dgo.Native).GoValue())
+	require.True(t, json.Number(`1e3`) == a.Get(3).(dgo.Native).GoValue())
+}
+
+func TestUnmarshalJSONWithOptions_limits(t *testing.T) {
+	doc := []byte(`{"a":[{"b":[]}]}`)
+	_, err := vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{MaxDepth: 4})
+	require.Nil(t, err)
+	_, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{MaxDepth: 3})
+	require.Equal(t, `json nesting depth exceeds the maximum depth 3`, err.Error())
+
+	_, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{MaxSize: len(doc)})
+	require.Nil(t, err)
+	_, err = vf.UnmarshalJSONWithOptions(doc, dgo.JSONDecoderOptions{MaxSize: 10})
+	require.Equal(t, `json document size 16 exceeds the maximum size 10`, err.Error())
+}
+
+func TestUnmarshalJSONWithOptions_mutable(t *testing.T) {
+	v, err := vf.UnmarshalJSONWithOptions([]byte(`{"a":[1]}`), dgo.JSONDecoderOptions{Mutable: true})
+	require.Nil(t, err)
+	m := v.(dgo.Map)
+	require.False(t, m.Frozen())
+	a, _ := m.Get(`a`)
+	require.False(t, a.(dgo.Array).Frozen())
+}
@@ -0,0 +1,136 @@
+package internal
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type jsonEncoder struct {
+	streamEncoder
+}
+
+// EncodeJSON writes the JSON representation of the given value to the given writer. Unlike MarshalJSON,
+// the JSON is written while the value is traversed so no copy of the complete document is kept in memory.
+//
+// Binaries are written as base64 encoded strings, and types, regexps, and errors as strings. Sets are
+// written as arrays. Map keys that are integers, floats, or booleans are written as strings. Native values
+// are written using encoding/json. An error is returned for other map keys, for floats that are NaN or
+// infinite, and for collections that contain themselves.
+func EncodeJSON(w io.Writer, v dgo.Value, opts dgo.EncoderOptions) (err error) {
+	defer encoderRecover(&err)
+	e := &jsonEncoder{streamEncoder: newStreamEncoder(w, opts)}
+	e.value(v, 0)
+	e.flush()
+	return nil
+}
+
+func (e *jsonEncoder) value(v dgo.Value, level int) {
+	switch v := v.(type) {
+	case nilValue:
+		e.writeString(`null`)
+	case Boolean:
+		e.writeString(strconv.FormatBool(bool(v)))
+	case Integer:
+		e.writeString(strconv.FormatInt(int64(v), 10))
+	case Float:
+		f := float64(v)
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			e.fail(`unable to encode the float %g as JSON`, f)
+		}
+		e.writeString(e.float(f))
+	case *hstring:
+		e.quote(v.s, e.opts.EscapeHTML)
+	case dgo.Type:
+		e.quote(TypeString(v), e.opts.EscapeHTML)
+	case *binary:
+		e.quote(v.String(), false)
+	case *Regexp:
+		e.quote(v.String(), e.opts.EscapeHTML)
+	case error:
+		e.quote(v.Error(), e.opts.EscapeHTML)
+	case *hashSet:
+		e.array(v, v.m.Len(), v.Each, level)
+	case dgo.Array:
+		e.array(v, v.Len(), v.Each, level)
+	case dgo.Map:
+		e.object(v, level)
+	case dgo.Native:
+		bs, err := json.Marshal(v.GoValue())
+		if err != nil {
+			panic(encoderFailure{err})
+		}
+		if _, err = e.w.Write(bs); err != nil {
+			panic(encoderFailure{err})
+		}
+	default:
+		e.fail(`unable to encode a value of type %s as JSON`, TypeString(v.Type()))
+	}
+}
+
+// newLine writes a newline followed by the indentation for the given level unless the encoder is compact
+func (e *jsonEncoder) newLine(level int) {
+	if e.opts.Indent == `` {
+		return
+	}
+	e.writeByte('\n')
+	for i := 0; i < level; i++ {
+		e.writeString(e.opts.Indent)
+	}
+}
+
+func (e *jsonEncoder) array(v dgo.Value, n int, each func(dgo.Doer), level int) {
+	if n == 0 {
+		e.writeString(`[]`)
+		return
+	}
+	e.enter(v)
+	e.writeByte('[')
+	first := true
+	each(func(ev dgo.Value) {
+		if first {
+			first = false
+		} else {
+			e.writeByte(',')
+		}
+		e.newLine(level + 1)
+		e.value(ev, level+1)
+	})
+	e.newLine(level)
+	e.writeByte(']')
+	e.leave(v)
+}
+
+func (e *jsonEncoder) object(m dgo.Map, level int) {
+	if m.Len() == 0 {
+		e.writeString(`{}`)
+		return
+	}
+	e.enter(m)
+	e.writeByte('{')
+	for i, me := range e.entries(m) {
+		if i > 0 {
+			e.writeByte(',')
+		}
+		e.newLine(level + 1)
+		switch k := me.Key().(type) {
+		case *hstring:
+			e.quote(k.s, e.opts.EscapeHTML)
+		case Integer, Float, Boolean:
+			e.quote(k.String(), false)
+		default:
+			e.fail(`unable to encode a map key of type %s as JSON`, TypeString(k.Type()))
+		}
+		e.writeByte(':')
+		if e.opts.Indent != `` {
+			e.writeByte(' ')
+		}
+		e.value(me.Value(), level+1)
+	}
+	e.newLine(level)
+	e.writeByte('}')
+	e.leave(m)
+}
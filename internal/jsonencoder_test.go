@@ -0,0 +1,150 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func encodeJSON(t *testing.T, v interface{}, opts dgo.EncoderOptions) string {
+	t.Helper()
+	b := bytes.Buffer{}
+	require.Nil(t, vf.EncodeJSON(&b, v, opts))
+	return b.String()
+}
+
+func TestEncodeJSON(t *testing.T) {
+	v := vf.Values(`hello`, true, 1, 3.14, nil, vf.Map(map[string]interface{}{`a`: 1}), vf.Values(), vf.Map(map[string]int{}))
+	require.Equal(t, `["hello",true,1,3.14,null,{"a":1},[],{}]`, encodeJSON(t, v, dgo.EncoderOptions{}))
+
+	b, err := vf.MarshalJSON(v)
+	require.Nil(t, err)
+	require.Equal(t, string(b), encodeJSON(t, v, dgo.EncoderOptions{}))
+}
+
+func TestEncodeJSON_indent(t *testing.T) {
+	v := vf.Map(map[string]interface{}{`a`: vf.Values(1, 2), `b`: vf.Map(map[string]int{`c`: 3})})
+	require.Equal(t, `{
+  "a": [
+    1,
+    2
+  ],
+  "b": {
+    "c": 3
+  }
+}`, encodeJSON(t, v, dgo.EncoderOptions{Indent: `  `, SortKeys: true}))
+}
+
+func TestEncodeJSON_sortKeys(t *testing.T) {
+	m := vf.MutableMap(3, nil)
+	m.Put(`b`, 1)
+	m.Put(`c`, 2)
+	m.Put(`a`, 3)
+	require.Equal(t, `{"b":1,"c":2,"a":3}`, encodeJSON(t, m, dgo.EncoderOptions{}))
+	require.Equal(t, `{"a":3,"b":1,"c":2}`, encodeJSON(t, m, dgo.EncoderOptions{SortKeys: true}))
+
+	m = vf.MutableMap(2, nil)
+	m.Put(vf.Map(map[string]int{`b`: 1}), 1)
+	m.Put(vf.Map(map[string]int{`a`: 1}), 2)
+	err := vf.EncodeJSON(&bytes.Buffer{}, m, dgo.EncoderOptions{SortKeys: true})
+	require.NotNil(t, err)
+	require.True(t, regexp.MustCompile(`unable to encode a map key`).MatchString(err.Error()), err.Error())
+}
+
+func TestEncodeJSON_strings(t *testing.T) {
+	s := "<a href=\"x\">&\\\n\t\x01\u2028ä\x7f\xff"
+	require.Equal(t, `"<a href=\"x\">&\\\n\t\u0001\u2028ä\u007f\ufffd"`, encodeJSON(t, s, dgo.EncoderOptions{}))
+	require.Equal(t, `"\u003ca href=\"x\"\u003e\u0026\\\n\t\u0001\u2028ä\u007f\ufffd"`,
+		encodeJSON(t, s, dgo.EncoderOptions{EscapeHTML: true}))
+
+	v, err := vf.UnmarshalJSON([]byte(encodeJSON(t, "a\u0085b\U0001F600", dgo.EncoderOptions{})))
+	require.Nil(t, err)
+	require.Equal(t, "a\u0085b\U0001F600", v)
+}
+
+func TestEncodeJSON_floats(t *testing.T) {
+	v := vf.Values(1.0, 0.5, 1e21, 1.5e-7)
+	require.Equal(t, `[1.0,0.5,1E+21,1.5E-07]`, encodeJSON(t, v, dgo.EncoderOptions{}))
+	require.Equal(t, `[1.0,0.5,1000000000000000000000.0,0.00000015]`, encodeJSON(t, v, dgo.EncoderOptions{Floats: dgo.FloatDecimal}))
+	require.Equal(t, `[1e+00,5e-01,1e+21,1.5e-07]`, encodeJSON(t, v, dgo.EncoderOptions{Floats: dgo.FloatExponent}))
+
+	err := vf.EncodeJSON(&bytes.Buffer{}, math.Inf(1), dgo.EncoderOptions{})
+	require.Equal(t, `unable to encode the float +Inf as JSON`, err.Error())
+}
+
+func TestEncodeJSON_nonJSON(t *testing.T) {
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	m := vf.MutableMap(7, nil)
+	m.Put(`binary`, []byte{1, 2, 3})
+	m.Put(`regexp`, regexp.MustCompile(`a+`))
+	m.Put(`type`, typ.Integer)
+	m.Put(`error`, errors.New(`oops`))
+	m.Put(`set`, vf.Set(`x`))
+	m.Put(`time`, tm)
+	m.Put(1, `one`)
+	require.Equal(t,
+		`{"binary":"AQID","regexp":"a+","type":"int","error":"oops","set":["x"],"time":"2020-01-02T03:04:05Z","1":"one"}`,
+		encodeJSON(t, m, dgo.EncoderOptions{}))
+}
+
+func TestEncodeJSON_errors(t *testing.T) {
+	err := vf.EncodeJSON(&bytes.Buffer{}, vf.Map(map[interface{}]int{vf.Values(1): 1}), dgo.EncoderOptions{})
+	require.Equal(t, `unable to encode a map key of type {1} as JSON`, err.Error())
+
+	err = vf.EncodeJSON(&bytes.Buffer{}, make(chan int), dgo.EncoderOptions{})
+	require.Equal(t, `json: unsupported type: chan int`, err.Error())
+
+	a := vf.MutableValues(nil, 1)
+	a.Add(a)
+	err = vf.EncodeJSON(&bytes.Buffer{}, a, dgo.EncoderOptions{})
+	require.Equal(t, `unable to encode a collection that contains itself`, err.Error())
+
+	// The same collection may be referenced more than once
+	b := vf.Values(1)
+	require.Equal(t, `[[1],[1]]`, encodeJSON(t, vf.Values(b, b), dgo.EncoderOptions{}))
+
+	err = vf.EncodeJSON(failingWriter{}, vf.Values(1), dgo.EncoderOptions{})
+	require.Equal(t, `write failed`, err.Error())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New(`write failed`)
+}
+
+func largeValue() dgo.Value {
+	a := vf.MutableValues(nil)
+	for i := 0; i < 10000; i++ {
+		a.Add(vf.Map(map[string]interface{}{`id`: i, `name`: `some name`, `tags`: vf.Strings(`a`, `b`), `score`: 0.5}))
+	}
+	return a
+}
+
+func BenchmarkEncodeJSON(b *testing.B) {
+	v := largeValue()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_ = vf.EncodeJSON(ioutil.Discard, v, dgo.EncoderOptions{})
+	}
+}
+
+func BenchmarkMarshalJSON(b *testing.B) {
+	v := largeValue()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		bs, _ := vf.MarshalJSON(v)
+		_, _ = ioutil.Discard.Write(bs)
+	}
+}
@@ -0,0 +1,441 @@
+package internal
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type (
+	// jsonStreamError is returned by the streaming JSON decoder when the input isn't valid JSON or when a
+	// value isn't an instance of the expected type
+	jsonStreamError struct {
+		msg    string
+		path   string
+		offset int64
+	}
+
+	// jsonStreamDecoder decodes JSON from a reader and validates each value against an expected type as
+	// soon as the value has been read.
+	jsonStreamDecoder struct {
+		r      *bufio.Reader
+		offset int64
+		path   []string
+	}
+)
+
+var jsonNumber = regexp.MustCompile(`\A-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?\z`)
+
+func (e *jsonStreamError) Error() string {
+	return fmt.Sprintf(`%s at json pointer %q, offset %d`, e.msg, e.path, e.offset)
+}
+
+// Path returns the JSON Pointer to the value where the error was detected
+func (e *jsonStreamError) Path() string {
+	return e.path
+}
+
+// Offset returns the offset of the byte in the input where the value that caused the error starts
+func (e *jsonStreamError) Offset() int64 {
+	return e.offset
+}
+
+// DecodeJSON reads exactly one JSON value from the given reader and returns it as a frozen dgo.Value. The
+// value, and each value that it contains, is validated against the given type as soon as it has been read
+// so that the decoding stops at the first value that doesn't conform. A nil type means any value. The
+// returned error contains the JSON Pointer to the offending value and its byte offset in the input.
+func DecodeJSON(r io.Reader, typ dgo.Type) (v dgo.Value, err error) {
+	defer jsonStreamRecover(&err)
+	d := newJSONStreamDecoder(r)
+	v = d.value(jsonStreamType(typ))
+	if _, ok := d.skipWhitespace(); ok {
+		d.fail(d.offset-1, `unexpected data after top level value`)
+	}
+	return v, nil
+}
+
+// StreamJSON reads a sequence of JSON values, optionally separated by whitespace such as in NDJSON, from
+// the given reader and calls the given consumer with each value. A top level array is never kept in
+// memory. Instead, each of its elements is passed to the consumer as soon as it has been read. Each value
+// passed to the consumer is validated against the given type before it is passed. A nil type means any
+// value. The streaming stops when the end of input is reached, when an error is detected, or when the
+// consumer returns an error. An error returned by the consumer is returned verbatim.
+func StreamJSON(r io.Reader, typ dgo.Type, consumer func(dgo.Value) error) (err error) {
+	defer jsonStreamRecover(&err)
+	typ = jsonStreamType(typ)
+	d := newJSONStreamDecoder(r)
+	for {
+		c, ok := d.skipWhitespace()
+		if !ok {
+			return nil
+		}
+		if c != '[' {
+			d.unread()
+			if err = consumer(d.value(typ)); err != nil {
+				return err
+			}
+			continue
+		}
+		for i := 0; d.more(']', i); i++ {
+			d.path = append(d.path, strconv.Itoa(i))
+			v := d.value(typ)
+			d.path = d.path[:0]
+			if err = consumer(v); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func jsonStreamRecover(err *error) {
+	if r := recover(); r != nil {
+		f, ok := r.(*jsonStreamError)
+		if !ok {
+			panic(r)
+		}
+		*err = f
+	}
+}
+
+func jsonStreamType(typ dgo.Type) dgo.Type {
+	if typ == nil {
+		return DefaultAnyType
+	}
+	return typ
+}
+
+func newJSONStreamDecoder(r io.Reader) *jsonStreamDecoder {
+	return &jsonStreamDecoder{r: bufio.NewReader(r)}
+}
+
+func (d *jsonStreamDecoder) fail(offset int64, format string, args ...interface{}) {
+	path := ``
+	if len(d.path) > 0 {
+		path = `/` + strings.Join(d.path, `/`)
+	}
+	panic(&jsonStreamError{msg: fmt.Sprintf(format, args...), path: path, offset: offset})
+}
+
+// read returns the next byte from the input and true, or zero and false when the end of input is reached
+func (d *jsonStreamDecoder) read() (byte, bool) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		if err == io.EOF {
+			return 0, false
+		}
+		panic(&jsonStreamError{msg: err.Error(), offset: d.offset})
+	}
+	d.offset++
+	return c, true
+}
+
+// mustRead returns the next byte from the input and fails if the end of input is reached
+func (d *jsonStreamDecoder) mustRead() byte {
+	c, ok := d.read()
+	if !ok {
+		d.fail(d.offset, `unexpected end of JSON input`)
+	}
+	return c
+}
+
+func (d *jsonStreamDecoder) unread() {
+	_ = d.r.UnreadByte()
+	d.offset--
+}
+
+// skipWhitespace returns the first byte that isn't whitespace and true, or zero and false when the end of
+// input is reached
+func (d *jsonStreamDecoder) skipWhitespace() (byte, bool) {
+	for {
+		c, ok := d.read()
+		if !ok {
+			return 0, false
+		}
+		switch c {
+		case ' ', '\t', '\n', '\r':
+		default:
+			return c, true
+		}
+	}
+}
+
+func (d *jsonStreamDecoder) mustSkipWhitespace() byte {
+	c, ok := d.skipWhitespace()
+	if !ok {
+		d.fail(d.offset, `unexpected end of JSON input`)
+	}
+	return c
+}
+
+// more returns true if another element follows in an array or object that has read i elements so far. It
+// returns false when the given end delimiter is found.
+func (d *jsonStreamDecoder) more(end byte, i int) bool {
+	c := d.mustSkipWhitespace()
+	if c == end {
+		return false
+	}
+	if i > 0 {
+		if c != ',' {
+			d.fail(d.offset-1, `invalid character %q after element`, c)
+		}
+	} else {
+		d.unread()
+	}
+	return true
+}
+
+// value reads the next value and validates it against the given type
+func (d *jsonStreamDecoder) value(t dgo.Type) dgo.Value {
+	c := d.mustSkipWhitespace()
+	start := d.offset - 1
+	var v dgo.Value
+	switch {
+	case c == '[':
+		v = d.array(t, start)
+	case c == '{':
+		v = d.object(t, start)
+	case c == '"':
+		v = makeHString(d.string())
+	case c == '-' || c >= '0' && c <= '9':
+		d.unread()
+		v = d.number()
+	case c == 't':
+		d.literal(`true`, start)
+		v = True
+	case c == 'f':
+		d.literal(`false`, start)
+		v = False
+	case c == 'n':
+		d.literal(`null`, start)
+		v = Nil
+	default:
+		d.fail(start, `invalid character %q looking for beginning of value`, c)
+	}
+	d.check(t, v, start)
+	return v
+}
+
+func (d *jsonStreamDecoder) check(t dgo.Type, v dgo.Value, start int64) {
+	if t != DefaultAnyType && !t.Instance(v) {
+		d.fail(start, `%s`, (&typeError{expected: t, actual: v.Type()}).Error())
+	}
+}
+
+func (d *jsonStreamDecoder) literal(s string, start int64) {
+	for i := 1; i < len(s); i++ {
+		if c, ok := d.read(); !ok || c != s[i] {
+			d.fail(start, `invalid literal, expected %s`, s)
+		}
+	}
+}
+
+func (d *jsonStreamDecoder) array(t dgo.Type, start int64) dgo.Value {
+	if !accepts(t, DefaultArrayType) {
+		d.fail(start, `an array cannot be assigned to type %s`, TypeString(t))
+	}
+	s := make([]dgo.Value, 0)
+	for i := 0; d.more(']', i); i++ {
+		d.path = append(d.path, strconv.Itoa(i))
+		s = append(s, d.value(d.elementType(t, i)))
+		d.path = d.path[:len(d.path)-1]
+	}
+	return &array{slice: s, frozen: true}
+}
+
+// accepts returns true unless the given type is known to have no instances in common with the given
+// container type. It is used to reject a container before its contents are read.
+func accepts(t, containerType dgo.Type) bool {
+	return t == DefaultAnyType || Intersect([]dgo.Type{t, containerType}) != DefaultNeverType
+}
+
+// elementType returns the type of the element at the given index in an array of the given type
+func (d *jsonStreamDecoder) elementType(t dgo.Type, i int) dgo.Type {
+	at, ok := t.(dgo.ArrayType)
+	if !ok {
+		return DefaultAnyType
+	}
+	if i >= at.Max() {
+		d.fail(d.offset, `an array with more than %d elements cannot be assigned to type %s`, at.Max(), TypeString(t))
+	}
+	if tt, ok := t.(dgo.TupleType); ok {
+		if ets := tt.ElementTypes(); ets.Len() > 0 {
+			return ets.Get(i).(dgo.Type)
+		}
+	}
+	return at.ElementType()
+}
+
+func (d *jsonStreamDecoder) object(t dgo.Type, start int64) dgo.Value {
+	if !accepts(t, DefaultMapType) {
+		d.fail(start, `an object cannot be assigned to type %s`, TypeString(t))
+	}
+	m := MutableMap(0, nil)
+	for i := 0; d.more('}', i); i++ {
+		c := d.mustSkipWhitespace()
+		ks := d.offset - 1
+		if c != '"' {
+			d.fail(ks, `invalid character %q looking for beginning of object key string`, c)
+		}
+		k := makeHString(d.string())
+		d.path = append(d.path, pointerToken(k))
+		vt := d.valueType(t, k, i, ks)
+		if c = d.mustSkipWhitespace(); c != ':' {
+			d.fail(d.offset-1, `invalid character %q after object key`, c)
+		}
+		m.Put(k, d.value(vt))
+		d.path = d.path[:len(d.path)-1]
+	}
+	m.Freeze()
+	return m
+}
+
+// valueType returns the type of the value for the given key in an object of the given type that has read
+// i entries so far. The start is the offset of the key.
+func (d *jsonStreamDecoder) valueType(t dgo.Type, k dgo.Value, i int, start int64) dgo.Type {
+	switch t := t.(type) {
+	case dgo.StructType:
+		var vt dgo.Type
+		t.Entries().Any(func(e dgo.Value) bool {
+			if et := e.(dgo.MapEntryType); et.KeyType().Instance(k) {
+				vt = et.ValueType()
+				return true
+			}
+			return false
+		})
+		if vt == nil {
+			if !t.Additional() {
+				d.fail(start, `key %q is not allowed by type %s`, k.String(), TypeString(t))
+			}
+			vt = DefaultAnyType
+		}
+		return vt
+	case dgo.MapType:
+		if i >= t.Max() {
+			d.fail(start, `an object with more than %d entries cannot be assigned to type %s`, t.Max(), TypeString(t))
+		}
+		if kt := t.KeyType(); !kt.Instance(k) {
+			d.fail(start, `%s`, (&typeError{expected: kt, actual: k.Type()}).Error())
+		}
+		return t.ValueType()
+	}
+	return DefaultAnyType
+}
+
+func (d *jsonStreamDecoder) number() dgo.Value {
+	start := d.offset
+	b := make([]byte, 0, 16)
+	for {
+		c, ok := d.read()
+		if !ok {
+			break
+		}
+		if !(c >= '0' && c <= '9' || c == '-' || c == '+' || c == '.' || c == 'e' || c == 'E') {
+			d.unread()
+			break
+		}
+		b = append(b, c)
+	}
+	s := string(b)
+	if !jsonNumber.MatchString(s) {
+		d.fail(start, `invalid number %s`, s)
+	}
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Integer(i)
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		d.fail(start, `number %s is out of range`, s)
+	}
+	return Float(f)
+}
+
+// string reads a string. The initial quote has already been read.
+func (d *jsonStreamDecoder) string() string {
+	start := d.offset - 1
+	b := make([]byte, 0, 32)
+	for {
+		c, ok := d.read()
+		if !ok {
+			d.fail(start, `unterminated string`)
+		}
+		switch {
+		case c == '"':
+			return string(b)
+		case c == '\\':
+			b = d.escape(b)
+		case c < 0x20:
+			d.fail(d.offset-1, `invalid character %q in string literal`, c)
+		default:
+			b = append(b, c)
+		}
+	}
+}
+
+func (d *jsonStreamDecoder) escape(b []byte) []byte {
+	c := d.mustRead()
+	switch c {
+	case '"', '\\', '/':
+		return append(b, c)
+	case 'b':
+		return append(b, '\b')
+	case 'f':
+		return append(b, '\f')
+	case 'n':
+		return append(b, '\n')
+	case 'r':
+		return append(b, '\r')
+	case 't':
+		return append(b, '\t')
+	case 'u':
+		r := d.hex4()
+		if utf16.IsSurrogate(r) {
+			// Only a high surrogate followed by an escaped low surrogate forms a valid pair. Any other
+			// escape that follows is left to be decoded on its own.
+			if lo, ok := d.lowSurrogate(); r < 0xdc00 && ok {
+				for i := 0; i < 6; i++ {
+					d.mustRead()
+				}
+				r = utf16.DecodeRune(r, lo)
+			} else {
+				r = utf8.RuneError
+			}
+		}
+		var buf [utf8.UTFMax]byte
+		return append(b, buf[:utf8.EncodeRune(buf[:], r)]...)
+	}
+	d.fail(d.offset-1, `invalid escape character %q in string literal`, c)
+	return nil
+}
+
+// lowSurrogate returns the low surrogate and true if the next six bytes are a \u escape of one
+func (d *jsonStreamDecoder) lowSurrogate() (rune, bool) {
+	p, err := d.r.Peek(6)
+	if err != nil || p[0] != '\\' || p[1] != 'u' {
+		return 0, false
+	}
+	r, err := strconv.ParseUint(string(p[2:]), 16, 16)
+	if err != nil || r < 0xdc00 || r > 0xdfff {
+		return 0, false
+	}
+	return rune(r), true
+}
+
+func (d *jsonStreamDecoder) hex4() rune {
+	start := d.offset
+	var h [4]byte
+	for i := range h {
+		h[i] = d.mustRead()
+	}
+	r, err := strconv.ParseUint(string(h[:]), 16, 16)
+	if err != nil {
+		d.fail(start, `invalid unicode escape \u%s in string literal`, string(h[:]))
+	}
+	return rune(r)
+}
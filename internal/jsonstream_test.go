@@ -0,0 +1,217 @@
+package internal_test
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+type jsonStreamError interface {
+	error
+	Path() string
+	Offset() int64
+}
+
+func requireStreamError(t *testing.T, err error, path string, offset int64, msg string) {
+	t.Helper()
+	se, ok := err.(jsonStreamError)
+	if !ok {
+		t.Fatalf(`expected a json stream error, got %v`, err)
+	}
+	require.Equal(t, path, se.Path())
+	require.Equal(t, offset, se.Offset())
+	require.True(t, regexp.MustCompile(msg).MatchString(se.Error()), se.Error())
+}
+
+func TestDecodeJSON(t *testing.T) {
+	v, err := vf.DecodeJSON(strings.NewReader(
+		` {"a": [1, 2.5, -3e2, 12345678901234567890], "b": "x\"\\\/\b\f\n\r\tå😀", "c": [true, false, null], "d": {}} `),
+		nil)
+	require.Nil(t, err)
+	require.Equal(t, vf.Map(map[string]interface{}{
+		`a`: vf.Values(1, 2.5, -300.0, 12345678901234567890.0),
+		`b`: "x\"\\/\b\f\n\r\tå\U0001F600",
+		`c`: vf.Values(true, false, nil),
+		`d`: vf.Map(map[string]interface{}{})}), v)
+	require.True(t, v.(dgo.Map).Frozen())
+}
+
+func TestDecodeJSON_surrogates(t *testing.T) {
+	tests := []struct {
+		json string
+		s    string
+	}{
+		{`"\ud83d\ude00"`, "\U0001F600"},
+		{`"\ud800\u0041"`, "\uFFFDA"},
+		{`"\ud800\ud800\udc00"`, "\uFFFD\U00010000"},
+		{`"\udc00\udc00"`, "\uFFFD\uFFFD"},
+		{`"\ud800\n"`, "\uFFFD\n"},
+		{`"\ud800x"`, "\uFFFDx"},
+		{`"\ud800"`, "\uFFFD"},
+	}
+	for _, tc := range tests {
+		v, err := vf.DecodeJSON(strings.NewReader(tc.json), nil)
+		require.Nil(t, err)
+		require.Equal(t, tc.s, v)
+	}
+}
+
+func TestDecodeJSON_type(t *testing.T) {
+	tp := newtype.Parse(`{"name":string,"tags":[]string,"pos"?:{"x":int,"y":int}}`)
+	v, err := vf.DecodeJSON(strings.NewReader(`{"name":"a","tags":["x","y"],"pos":{"x":1,"y":2}}`), tp)
+	require.Nil(t, err)
+	require.Instance(t, tp, v)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"name":"a","tags":["x",3,"y"]}`), tp)
+	requireStreamError(t, err, `/tags/1`, 24, `a value of type 3 cannot be assigned to type string`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"name":"a","tags":[],"pos":{"x":1,"y":2,"z":3}}`), tp)
+	requireStreamError(t, err, `/pos/z`, 41, `key "z" is not allowed`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"tags":[]}`), tp)
+	requireStreamError(t, err, ``, 0, `cannot be assigned`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"name":"a","tags":{}}`), tp)
+	requireStreamError(t, err, `/tags`, 19, `an object cannot be assigned to type \[\]string`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"name":[]}`), newtype.Parse(`map[string]map[string]int`))
+	requireStreamError(t, err, `/name`, 8, `an array cannot be assigned`)
+
+	// Containers are rejected before their contents are read
+	_, err = vf.DecodeJSON(strings.NewReader(`{"a":[1,2`), typ.String)
+	requireStreamError(t, err, ``, 0, `an object cannot be assigned to type string`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`[{"a":[1,2`), newtype.Parse(`[](int|string)`))
+	requireStreamError(t, err, `/0`, 1, `an object cannot be assigned to type int\|string`)
+
+	v, err = vf.DecodeJSON(strings.NewReader(`[1,[2]]`), newtype.Parse(`[](int|[]int)`))
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, vf.Values(2)), v)
+}
+
+func TestDecodeJSON_sizedTypes(t *testing.T) {
+	_, err := vf.DecodeJSON(strings.NewReader(`[1,2,3]`), newtype.Parse(`[0,2]int`))
+	requireStreamError(t, err, `/2`, 5, `more than 2 elements`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`["a",2,3]`), newtype.Parse(`{string,int}`))
+	requireStreamError(t, err, `/2`, 7, `more than 2 elements`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`["a","b"]`), newtype.Parse(`{string,int}`))
+	requireStreamError(t, err, `/1`, 5, `cannot be assigned to type int`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"a":1,"b":2}`), newtype.Parse(`map[string,0,1]int`))
+	requireStreamError(t, err, `/b`, 7, `more than 1 entries`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`{"a":1,"bb":2}`), newtype.Parse(`map[string[1,1]]int`))
+	requireStreamError(t, err, `/bb`, 7, `cannot be assigned to type string\[1,1\]`)
+
+	_, err = vf.DecodeJSON(strings.NewReader(`[1]`), newtype.Parse(`[2,3]int`))
+	requireStreamError(t, err, ``, 0, `cannot be assigned to type \[2,3\]int`)
+}
+
+func TestDecodeJSON_syntaxErrors(t *testing.T) {
+	tests := []struct {
+		json   string
+		path   string
+		offset int64
+		msg    string
+	}{
+		{`[1 2]`, ``, 3, `invalid character '2' after element`},
+		{`{"a" 1}`, `/a`, 5, `invalid character '1' after object key`},
+		{`{1:2}`, ``, 1, `looking for beginning of object key string`},
+		{`[tru]`, `/0`, 1, `invalid literal, expected true`},
+		{`[01]`, `/0`, 1, `invalid number 01`},
+		{`[1e400]`, `/0`, 1, `number 1e400 is out of range`},
+		{`"abc`, ``, 0, `unterminated string`},
+		{`"a\qb"`, ``, 3, `invalid escape character 'q'`},
+		{`"\u12x4"`, ``, 3, `invalid unicode escape`},
+		{"\"a\nb\"", ``, 2, `invalid character '\\n' in string literal`},
+		{`[1,`, `/1`, 3, `unexpected end of JSON input`},
+		{`]`, ``, 0, `looking for beginning of value`},
+		{`{"a/~":x}`, `/a~1~0`, 7, `looking for beginning of value`},
+		{`1 2`, ``, 2, `unexpected data after top level value`},
+	}
+	for _, tc := range tests {
+		_, err := vf.DecodeJSON(strings.NewReader(tc.json), nil)
+		requireStreamError(t, err, tc.path, tc.offset, tc.msg)
+	}
+}
+
+func TestDecodeJSON_loneSurrogate(t *testing.T) {
+	v, err := vf.DecodeJSON(strings.NewReader(`"\ud83dx"`), nil)
+	require.Nil(t, err)
+	require.Equal(t, "�x", v)
+}
+
+func TestStreamJSON_array(t *testing.T) {
+	var vs []dgo.Value
+	err := vf.StreamJSON(strings.NewReader(` [ {"a":1}, {"a":2} ] `), newtype.Parse(`{"a":int}`), func(v dgo.Value) error {
+		vs = append(vs, v)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(vf.Map(map[string]int{`a`: 1}), vf.Map(map[string]int{`a`: 2})), vf.Array(vs))
+}
+
+func TestStreamJSON_ndjson(t *testing.T) {
+	var vs []dgo.Value
+	err := vf.StreamJSON(strings.NewReader("{\"a\":1}\n{\"a\":2}\n\"x\"\n"), nil, func(v dgo.Value) error {
+		vs = append(vs, v)
+		return nil
+	})
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(vf.Map(map[string]int{`a`: 1}), vf.Map(map[string]int{`a`: 2}), `x`), vf.Array(vs))
+}
+
+func TestStreamJSON_failFast(t *testing.T) {
+	n := 0
+	err := vf.StreamJSON(strings.NewReader(`[{"a":1},{"a":"x"},{"a":`), newtype.Parse(`{"a":int}`), func(v dgo.Value) error {
+		n++
+		return nil
+	})
+	require.Equal(t, 1, n)
+	requireStreamError(t, err, `/1/a`, 14, `a value of type "x" cannot be assigned to type int`)
+
+	n = 0
+	err = vf.StreamJSON(strings.NewReader("{\"a\":1}\n{\"a\":true}\n"), newtype.Parse(`{"a":int}`), func(v dgo.Value) error {
+		n++
+		return nil
+	})
+	require.Equal(t, 1, n)
+	requireStreamError(t, err, `/a`, 13, `cannot be assigned to type int`)
+}
+
+func TestStreamJSON_consumerError(t *testing.T) {
+	stop := errors.New(`stop`)
+	n := 0
+	err := vf.StreamJSON(strings.NewReader(`[1,2,3]`), nil, func(v dgo.Value) error {
+		n++
+		if n == 2 {
+			return stop
+		}
+		return nil
+	})
+	require.Equal(t, 2, n)
+	require.True(t, err == stop)
+
+	err = vf.StreamJSON(strings.NewReader(`1`), nil, func(v dgo.Value) error { return stop })
+	require.True(t, err == stop)
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New(`read failed`)
+}
+
+func TestDecodeJSON_readError(t *testing.T) {
+	_, err := vf.DecodeJSON(failingReader{}, nil)
+	require.Equal(t, `read failed at json pointer "", offset 0`, err.Error())
+}
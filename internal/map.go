@@ -1,7 +1,6 @@
 package internal
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -113,7 +112,7 @@ func (t *structType) DeepAssignable(guard dgo.RecursionGuard, other dgo.Type) bo
 				return false
 			}
 		}
-		return t.additional || oc == om.len
+		return t.additional || oc == om.Len()
 	case *exactMapType:
 		ov := (*hashMap)(ot)
 		return Instance(guard, t, ov)
@@ -140,6 +139,14 @@ func (t *structType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *structType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *structType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *structType) HashCode() int {
 	h := t.entries.HashCode()
 	if t.additional {
@@ -153,7 +160,7 @@ func (t *structType) Instance(value interface{}) bool {
 }
 
 func (t *structType) DeepInstance(guard dgo.RecursionGuard, value dgo.Value) bool {
-	if om, ok := value.(*hashMap); ok {
+	if om, ok := value.(dgo.Map); ok {
 		mm := t.entries
 		oc := 0
 		for me := mm.first; me != nil; me = me.next {
@@ -167,7 +174,7 @@ func (t *structType) DeepInstance(guard dgo.RecursionGuard, value dgo.Value) boo
 				return false
 			}
 		}
-		return t.additional || oc == om.len
+		return t.additional || oc == om.Len()
 	}
 	return false
 }
@@ -231,6 +238,14 @@ func (t *entryType) Equals(other interface{}) bool {
 	return equals(nil, t, Value(other))
 }
 
+func (t *entryType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *entryType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *entryType) HashCode() int {
 	return deepHashCode(nil, t)
 }
@@ -288,6 +303,14 @@ func (t *exactEntryType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactEntryType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactEntryType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactEntryType) HashCode() int {
 	return (*hashNode)(t).HashCode()
 }
@@ -348,6 +371,14 @@ func (v *hashNode) deepEqual(seen []dgo.Value, other deepEqual) bool {
 	return false
 }
 
+func (v *hashNode) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v *hashNode) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v *hashNode) HashCode() int {
 	return deepHashCode(nil, v)
 }
@@ -596,7 +627,7 @@ func (g *hashMap) Equals(other interface{}) bool {
 }
 
 func (g *hashMap) deepEqual(seen []dgo.Value, other deepEqual) bool {
-	if om, ok := other.(*hashMap); ok && g.len == om.len {
+	if om, ok := other.(dgo.Map); ok && g.len == om.Len() {
 		var ov dgo.Value
 		for e := g.first; e != nil; e = e.next {
 			ov, ok = om.Get(e.key)
@@ -662,14 +693,24 @@ func (g *hashMap) Get(key interface{}) (dgo.Value, bool) {
 	return nil, false
 }
 
+func (g *hashMap) GobDecode(b []byte) error {
+	return gobDecodeInto(b, g)
+}
+
+func (g *hashMap) GobEncode() ([]byte, error) {
+	return gobEncode(g)
+}
+
 func (g *hashMap) HashCode() int {
 	return deepHashCode(nil, g)
 }
 
 func (g *hashMap) deepHashCode(seen []dgo.Value) int {
+	// The hash codes of the entries are summed so that the result is independent of the order of the
+	// entries since that order isn't considered by Equals
 	h := 1
 	for e := g.first; e != nil; e = e.next {
-		h = h*31 + deepHashCode(seen, e)
+		h += deepHashCode(seen, e)
 	}
 	return h
 }
@@ -697,15 +738,14 @@ func (g *hashMap) UnmarshalJSON(b []byte) error {
 	if g.frozen {
 		panic(frozenMap(`UnmarshalJSON`))
 	}
-	dec := json.NewDecoder(bytes.NewReader(b))
-	dec.UseNumber()
-	t, err := dec.Token()
+	d := newMutableJSONDecoder(b)
+	t, err := d.dec.Token()
 	if err == nil {
 		if delim, ok := t.(json.Delim); !ok || delim != '{' {
 			return errors.New("expecting data to be an object")
 		}
 		var m *hashMap
-		m, err = jsonDecodeMap(dec)
+		m, err = d.decodeMap()
 		if err == nil {
 			*g = *m
 		}
@@ -720,7 +760,7 @@ func (g *hashMap) UnmarshalYAML(n *yaml.Node) error {
 	if n.Kind != yaml.MappingNode {
 		return errors.New("expecting data to be an object")
 	}
-	m, err := yamlDecodeMap(n)
+	m, err := (&yamlDecoder{}).decodeMap(n)
 	if err == nil {
 		*g = *m
 	}
@@ -729,23 +769,7 @@ func (g *hashMap) UnmarshalYAML(n *yaml.Node) error {
 
 // MarshalYAML returns a *yaml.Node that represents this Map.
 func (g *hashMap) MarshalYAML() (interface{}, error) {
-	s := make([]*yaml.Node, g.len*2)
-	i := 0
-	for e := g.first; e != nil; e = e.next {
-		n, err := yamlEncodeValue(e.key)
-		if err != nil {
-			return nil, err
-		}
-		s[i] = n
-		i++
-		n, err = yamlEncodeValue(e.value)
-		if err != nil {
-			return nil, err
-		}
-		s[i] = n
-		i++
-	}
-	return &yaml.Node{Kind: yaml.MappingNode, Tag: `!!map`, Content: s}, nil
+	return yamlEncodeValue(g)
 }
 
 func (g *hashMap) Merge(associations dgo.Map) dgo.Map {
@@ -986,19 +1010,25 @@ func (g *hashMap) Values() dgo.Array {
 
 func (g *hashMap) assertType(k, v dgo.Value, addedSize int) {
 	if t := g.typ; t != nil {
-		kt := t.KeyType()
-		if !kt.Instance(k) {
-			panic(IllegalAssignment(kt, k))
-		}
-		vt := t.ValueType()
-		if !vt.Instance(v) {
-			panic(IllegalAssignment(vt, v))
-		}
-		if addedSize > 0 {
-			sz := g.len + addedSize
-			if sz > t.Max() {
-				panic(IllegalSize(t, sz))
-			}
+		assertMapType(t, g.len, k, v, addedSize)
+	}
+}
+
+// assertMapType panics unless the given key and value are instances of the key and value type of the
+// given type and the given length plus the added size is within the size constraint of that type.
+func assertMapType(t dgo.MapType, l int, k, v dgo.Value, addedSize int) {
+	kt := t.KeyType()
+	if !kt.Instance(k) {
+		panic(IllegalAssignment(kt, k))
+	}
+	vt := t.ValueType()
+	if !vt.Instance(v) {
+		panic(IllegalAssignment(vt, v))
+	}
+	if addedSize > 0 {
+		sz := l + addedSize
+		if sz > t.Max() {
+			panic(IllegalSize(t, sz))
 		}
 	}
 }
@@ -1184,6 +1214,14 @@ func (t *exactMapValuesType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactMapValuesType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactMapValuesType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactMapValuesType) HashCode() int {
 	return (*hashMap)(t).HashCode()*31 + int(dgo.IdMapValuesExact)
 }
@@ -1241,6 +1279,14 @@ func (t *exactMapKeysType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactMapKeysType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactMapKeysType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactMapKeysType) HashCode() int {
 	return (*hashMap)(t).HashCode()*31 + int(dgo.IdMapKeysExact)
 }
@@ -1279,6 +1325,14 @@ func (t *sizedMapType) deepEqual(seen []dgo.Value, other deepEqual) bool {
 	return false
 }
 
+func (t *sizedMapType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *sizedMapType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *sizedMapType) HashCode() int {
 	return deepHashCode(nil, t)
 }
@@ -1305,8 +1359,8 @@ func (t *sizedMapType) Instance(value interface{}) bool {
 }
 
 func (t *sizedMapType) DeepInstance(guard dgo.RecursionGuard, value dgo.Value) bool {
-	if ov, ok := value.(*hashMap); ok {
-		l := ov.len
+	if ov, ok := value.(dgo.Map); ok {
+		l := ov.Len()
 		if t.min <= l && l <= t.max {
 			kt := t.keyType
 			vt := t.valueType
@@ -1371,6 +1425,14 @@ func (t defaultMapType) Equals(other interface{}) bool {
 	return t == other
 }
 
+func (t *defaultMapType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t defaultMapType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t defaultMapType) HashCode() int {
 	return int(dgo.IdMap)
 }
@@ -1436,12 +1498,20 @@ func (t *exactMapType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactMapType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactMapType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactMapType) HashCode() int {
 	return (*hashMap)(t).HashCode()*31 + int(dgo.IdMapExact)
 }
 
 func (t *exactMapType) Instance(value interface{}) bool {
-	if ov, ok := value.(*hashMap); ok {
+	if ov, ok := value.(dgo.Map); ok {
 		return (*hashMap)(t).Equals(ov)
 	}
 	return false
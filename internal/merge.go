@@ -0,0 +1,155 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type (
+	mergeKind int
+
+	mergeOverride struct {
+		key      dgo.Value
+		strategy dgo.MergeStrategy
+	}
+
+	mergeStrategy struct {
+		kind      mergeKind
+		overrides []mergeOverride
+	}
+)
+
+const (
+	mergeFirst = mergeKind(iota)
+	mergeHash
+	mergeDeep
+	mergeDeepUnique
+)
+
+var mergeNames = []string{`first`, `hash`, `deep`, `deep-unique`}
+
+// FirstMerge returns the strategy that doesn't merge. The second value is always used.
+func FirstMerge() dgo.MergeStrategy {
+	return &mergeStrategy{kind: mergeFirst}
+}
+
+// HashMerge returns the strategy that merges Maps recursively. All other values, including Arrays, are
+// not merged.
+func HashMerge() dgo.MergeStrategy {
+	return &mergeStrategy{kind: mergeHash}
+}
+
+// DeepMerge returns the strategy that merges Maps recursively and also merges Arrays. Arrays are
+// concatenated unless unique is true, in which case the result contains each distinct element of the two
+// Arrays once, in order of first appearance.
+func DeepMerge(unique bool) dgo.MergeStrategy {
+	if unique {
+		return &mergeStrategy{kind: mergeDeepUnique}
+	}
+	return &mergeStrategy{kind: mergeDeep}
+}
+
+// MergeStrategyByName returns the strategy with the given name. Valid names are "first", "hash", "deep",
+// and "deep-unique".
+func MergeStrategyByName(name string) (dgo.MergeStrategy, error) {
+	for i, n := range mergeNames {
+		if n == name {
+			return &mergeStrategy{kind: mergeKind(i)}, nil
+		}
+	}
+	return nil, fmt.Errorf(`%q is not a valid merge strategy`, name)
+}
+
+func (s *mergeStrategy) Name() string {
+	return mergeNames[s.kind]
+}
+
+func (s *mergeStrategy) Merge(a, b dgo.Value) dgo.Value {
+	if s.kind == mergeFirst {
+		return frozenCopy(b)
+	}
+	switch a := a.(type) {
+	case dgo.Map:
+		if b, ok := b.(dgo.Map); ok {
+			return s.mergeMaps(a, b)
+		}
+	case dgo.Array:
+		if b, ok := b.(dgo.Array); ok && s.kind != mergeHash {
+			return s.mergeArrays(a, b)
+		}
+	}
+	return frozenCopy(b)
+}
+
+func (s *mergeStrategy) MergeAll(values ...dgo.Value) dgo.Value {
+	if len(values) == 0 {
+		return Nil
+	}
+	r := frozenCopy(values[0])
+	for _, v := range values[1:] {
+		r = s.Merge(r, v)
+	}
+	return r
+}
+
+func (s *mergeStrategy) WithOverride(key interface{}, ms dgo.MergeStrategy) dgo.MergeStrategy {
+	k := Value(key)
+	os := make([]mergeOverride, 0, len(s.overrides)+1)
+	for _, o := range s.overrides {
+		if !o.key.Equals(k) {
+			os = append(os, o)
+		}
+	}
+	return &mergeStrategy{kind: s.kind, overrides: append(os, mergeOverride{key: frozenCopy(k), strategy: ms})}
+}
+
+// strategyFor returns the strategy to use when merging values of the given key
+func (s *mergeStrategy) strategyFor(key dgo.Value) dgo.MergeStrategy {
+	for _, o := range s.overrides {
+		if o.key.Equals(key) {
+			return o.strategy
+		}
+	}
+	return s
+}
+
+func (s *mergeStrategy) mergeMaps(a, b dgo.Map) dgo.Value {
+	if b.Len() == 0 {
+		return a.FrozenCopy()
+	}
+	if a.Len() == 0 {
+		return b.FrozenCopy()
+	}
+	c := MutableMap(a.Len()+b.Len(), nil)
+	a.Each(func(e dgo.MapEntry) {
+		k := e.Key()
+		v := e.Value()
+		if bv, ok := b.Get(k); ok {
+			v = s.strategyFor(k).Merge(v, bv)
+		} else {
+			v = frozenCopy(v)
+		}
+		c.Put(frozenCopy(k), v)
+	})
+	b.Each(func(e dgo.MapEntry) {
+		if _, ok := a.Get(e.Key()); !ok {
+			c.Put(frozenCopy(e.Key()), frozenCopy(e.Value()))
+		}
+	})
+	c.Freeze()
+	return c
+}
+
+func (s *mergeStrategy) mergeArrays(a, b dgo.Array) dgo.Value {
+	if s.kind == mergeDeep {
+		vs := make([]dgo.Value, 0, a.Len()+b.Len())
+		vs = a.FrozenCopy().(dgo.Array).AppendToSlice(vs)
+		return &array{slice: b.FrozenCopy().(dgo.Array).AppendToSlice(vs), frozen: true}
+	}
+	u := MutableSet(nil, nil)
+	add := func(e dgo.Value) { u.Add(frozenCopy(e)) }
+	a.Each(add)
+	b.Each(add)
+	return u.ToArray()
+}
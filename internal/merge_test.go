@@ -0,0 +1,104 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func ExampleDeepMerge() {
+	defaults, _ := vf.UnmarshalJSON([]byte(`{"log":{"level":"info","file":"app.log"},"ports":[80]}`))
+	env, _ := vf.UnmarshalJSON([]byte(`{"log":{"level":"warn"},"ports":[443]}`))
+	node, _ := vf.UnmarshalJSON([]byte(`{"ports":[80,8080]}`))
+	fmt.Println(vf.DeepMerge(true).MergeAll(defaults, env, node))
+	// Output: {"log":{"level":"warn","file":"app.log"},"ports":[80,443,8080]}
+}
+
+func TestMergeStrategy_first(t *testing.T) {
+	s := vf.FirstMerge()
+	require.Equal(t, `first`, s.Name())
+	a := jsonValue(t, `{"a":1,"b":{"c":2}}`)
+	b := jsonValue(t, `{"b":{"d":3}}`)
+	require.Equal(t, b, s.Merge(a, b))
+	require.Equal(t, `{"b":{"d":3}}`, s.WithOverride(`b`, vf.HashMerge()).Merge(a, b).String())
+}
+
+func TestMergeStrategy_hash(t *testing.T) {
+	s := vf.HashMerge()
+	require.Equal(t, `hash`, s.Name())
+	a := jsonValue(t, `{"a":1,"b":{"c":2,"e":[1]},"x":[1]}`)
+	b := jsonValue(t, `{"y":0,"b":{"d":3,"e":[2]},"a":2,"x":[2]}`)
+	require.Equal(t, `{"a":2,"b":{"c":2,"e":[2],"d":3},"x":[2],"y":0}`, s.Merge(a, b).String())
+	require.Equal(t, `{"a":1}`, s.Merge(jsonValue(t, `{"a":1}`), jsonValue(t, `{}`)).String())
+	require.Equal(t, `{"a":1}`, s.Merge(jsonValue(t, `{}`), jsonValue(t, `{"a":1}`)).String())
+	require.Equal(t, `[2]`, s.Merge(jsonValue(t, `{"a":1}`), jsonValue(t, `[2]`)).String())
+	require.Equal(t, `{"a":1}`, s.Merge(jsonValue(t, `[2]`), jsonValue(t, `{"a":1}`)).String())
+}
+
+func TestMergeStrategy_deep(t *testing.T) {
+	s := vf.DeepMerge(false)
+	require.Equal(t, `deep`, s.Name())
+	a := jsonValue(t, `{"a":[1,2],"b":{"c":[3]}}`)
+	b := jsonValue(t, `{"a":[2,3],"b":{"c":[3]}}`)
+	require.Equal(t, `{"a":[1,2,2,3],"b":{"c":[3,3]}}`, s.Merge(a, b).String())
+
+	s = vf.DeepMerge(true)
+	require.Equal(t, `deep-unique`, s.Name())
+	require.Equal(t, `{"a":[1,2,3],"b":{"c":[3]}}`, s.Merge(a, b).String())
+	require.Equal(t, `[1]`, s.Merge(jsonValue(t, `[1,1]`), jsonValue(t, `[]`)).String())
+}
+
+func TestMergeStrategy_overrides(t *testing.T) {
+	a := jsonValue(t, `{"a":[1],"b":{"a":[2],"c":{"x":1}},"c":{"x":1}}`)
+	b := jsonValue(t, `{"a":[3],"b":{"a":[4],"c":{"y":2}},"c":{"y":2}}`)
+
+	s := vf.HashMerge().WithOverride(`a`, vf.DeepMerge(false))
+	require.Equal(t, `{"a":[1,3],"b":{"a":[2,4],"c":{"x":1,"y":2}},"c":{"x":1,"y":2}}`, s.Merge(a, b).String())
+
+	s = s.WithOverride(`c`, vf.FirstMerge())
+	require.Equal(t, `{"a":[1,3],"b":{"a":[2,4],"c":{"y":2}},"c":{"y":2}}`, s.Merge(a, b).String())
+
+	// Replacing an override
+	s = s.WithOverride(`a`, vf.FirstMerge())
+	require.Equal(t, `{"a":[3],"b":{"a":[4],"c":{"y":2}},"c":{"y":2}}`, s.Merge(a, b).String())
+	require.Equal(t, `hash`, s.Name())
+
+	// Overrides are not inherited by the strategy used for a key
+	s = vf.HashMerge().WithOverride(`a`, vf.DeepMerge(false)).WithOverride(`b`, vf.HashMerge())
+	require.Equal(t, `{"a":[1,3],"b":{"a":[4],"c":{"x":1,"y":2}},"c":{"x":1,"y":2}}`, s.Merge(a, b).String())
+}
+
+func TestMergeStrategy_mergeAll(t *testing.T) {
+	s := vf.HashMerge()
+	require.Equal(t, vf.Nil, s.MergeAll())
+	m := vf.MutableMap(0, nil)
+	m.Put(`a`, vf.MutableValues(nil, 1))
+	r := s.MergeAll(m)
+	require.True(t, r.(dgo.Freezable).Frozen())
+	require.False(t, m.Frozen())
+	r = s.MergeAll(m, vf.Map(map[string]int{`b`: 2}))
+	require.Equal(t, `{"a":[1],"b":2}`, r.String())
+	require.True(t, r.(dgo.Freezable).Frozen())
+	av, _ := r.(dgo.Map).Get(`a`)
+	require.True(t, av.(dgo.Freezable).Frozen())
+	require.False(t, m.Frozen())
+
+	r = vf.DeepMerge(false).MergeAll(m, vf.Map(map[string]interface{}{`a`: vf.MutableValues(nil, 2)}))
+	av, _ = r.(dgo.Map).Get(`a`)
+	require.Equal(t, vf.Values(1, 2), av)
+	require.True(t, av.(dgo.Freezable).Frozen())
+}
+
+func TestMergeStrategyByName(t *testing.T) {
+	for _, n := range []string{`first`, `hash`, `deep`, `deep-unique`} {
+		s, err := vf.MergeStrategyByName(n)
+		require.Nil(t, err)
+		require.Equal(t, n, s.Name())
+	}
+	_, err := vf.MergeStrategyByName(`unique`)
+	require.NotNil(t, err)
+	require.Equal(t, `"unique" is not a valid merge strategy`, err.Error())
+}
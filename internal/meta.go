@@ -9,6 +9,11 @@ type metaType struct {
 	tp dgo.Type
 }
 
+// MetaType returns the type of the given type, i.e. the type whose only instance is the given type
+func MetaType(t dgo.Type) dgo.Type {
+	return &metaType{t}
+}
+
 func (t *metaType) Type() dgo.Type {
 	if t.tp == nil {
 		return t
@@ -37,6 +42,14 @@ func (t *metaType) Equals(v interface{}) bool {
 	return false
 }
 
+func (t *metaType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *metaType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *metaType) HashCode() int {
 	return int(dgo.IdMeta)*1321 + t.tp.HashCode()
 }
@@ -0,0 +1,439 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	bin "encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"regexp"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// MessagePack extension types used for values that have no MessagePack representation. The timestamp
+// extension is predefined by the MessagePack specification.
+const (
+	msgpackTimestampExt = int8(-1)
+	msgpackTypeExt      = int8(1)
+	msgpackRegexpExt    = int8(2)
+	msgpackErrorExt     = int8(3)
+)
+
+// msgpackFailure is used for panics that are recovered and returned as errors by the MessagePack encoder
+// and decoder
+type msgpackFailure struct {
+	error
+}
+
+func msgpackRecover(err *error) {
+	if r := recover(); r != nil {
+		f, ok := r.(msgpackFailure)
+		if !ok {
+			panic(r)
+		}
+		*err = f.error
+	}
+}
+
+// MarshalMsgPack returns the MessagePack encoding of the given value.
+func MarshalMsgPack(v dgo.Value) ([]byte, error) {
+	var b bytes.Buffer
+	if err := EncodeMsgPack(&b, v); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// EncodeMsgPack writes the MessagePack encoding of the given value to the given writer.
+//
+// Integers are written using the smallest possible width, a Binary is written using the bin format family,
+// and map entries are written in the order of the map. A Set is written as an array. Types, regexps, and
+// errors are written using application specific extension types and native time.Time values are written
+// using the predefined timestamp extension type. An error is returned if the value contains a native value
+// of any other kind.
+func EncodeMsgPack(w io.Writer, v dgo.Value) (err error) {
+	defer msgpackRecover(&err)
+	bw := bufio.NewWriter(w)
+	e := &msgpackEncoder{w: bw}
+	e.value(v)
+	if err = bw.Flush(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// UnmarshalMsgPack decodes the given MessagePack encoded bytes into a frozen dgo.Value. A timestamp is
+// decoded into a native time.Time in UTC since the timestamp extension type has no location.
+func UnmarshalMsgPack(b []byte) (dgo.Value, error) {
+	r := bytes.NewReader(b)
+	v, err := DecodeMsgPack(r)
+	if err == nil && r.Len() > 0 {
+		err = errors.New(`msgpack: unexpected trailing bytes`)
+	}
+	return v, err
+}
+
+// DecodeMsgPack reads one MessagePack encoded value from the given reader and returns it as a frozen
+// dgo.Value. No bytes beyond the end of the value are read so consecutive values can be read from the
+// same reader.
+func DecodeMsgPack(r io.Reader) (v dgo.Value, err error) {
+	defer msgpackRecover(&err)
+	d := &msgpackDecoder{r: r}
+	return d.value(), nil
+}
+
+type msgpackEncoder struct {
+	w   *bufio.Writer
+	buf [9]byte
+}
+
+func (e *msgpackEncoder) write(b []byte) {
+	if _, err := e.w.Write(b); err != nil {
+		panic(msgpackFailure{err})
+	}
+}
+
+func (e *msgpackEncoder) writeString(s string) {
+	if _, err := e.w.WriteString(s); err != nil {
+		panic(msgpackFailure{err})
+	}
+}
+
+// writeHeader writes the given code followed by the given number in big endian byte order using the
+// given width
+func (e *msgpackEncoder) writeHeader(code byte, n uint64, width int) {
+	b := e.buf[:width+1]
+	b[0] = code
+	switch width {
+	case 1:
+		b[1] = byte(n)
+	case 2:
+		bin.BigEndian.PutUint16(b[1:], uint16(n))
+	case 4:
+		bin.BigEndian.PutUint32(b[1:], uint32(n))
+	case 8:
+		bin.BigEndian.PutUint64(b[1:], n)
+	}
+	e.write(b)
+}
+
+// writeLength writes the header for a string, binary, array, or map of the given length. The fix code
+// is used when the fixMax is greater than zero and the length is less than or equal to it. The codes are
+// for the 8, 16, and 32 bit length formats. A zero code means that the format doesn't exist.
+func (e *msgpackEncoder) writeLength(n int, fix byte, fixMax int, codes [3]byte) {
+	switch {
+	case n <= fixMax:
+		e.writeHeader(fix|byte(n), 0, 0)
+	case n <= math.MaxUint8 && codes[0] != 0:
+		e.writeHeader(codes[0], uint64(n), 1)
+	case n <= math.MaxUint16:
+		e.writeHeader(codes[1], uint64(n), 2)
+	case uint64(n) <= math.MaxUint32:
+		e.writeHeader(codes[2], uint64(n), 4)
+	default:
+		panic(msgpackFailure{fmt.Errorf(`msgpack: length %d exceeds the maximum length`, n)})
+	}
+}
+
+func (e *msgpackEncoder) writeInt(i int64) {
+	switch {
+	case i >= 0 && i <= math.MaxInt8:
+		e.writeHeader(byte(i), 0, 0)
+	case i >= -32 && i < 0:
+		e.writeHeader(byte(i), 0, 0)
+	case i > 0 && i <= math.MaxUint8:
+		e.writeHeader(0xcc, uint64(i), 1)
+	case i > 0 && i <= math.MaxUint16:
+		e.writeHeader(0xcd, uint64(i), 2)
+	case i > 0 && i <= math.MaxUint32:
+		e.writeHeader(0xce, uint64(i), 4)
+	case i > 0:
+		e.writeHeader(0xcf, uint64(i), 8)
+	case i >= math.MinInt8:
+		e.writeHeader(0xd0, uint64(i), 1)
+	case i >= math.MinInt16:
+		e.writeHeader(0xd1, uint64(i), 2)
+	case i >= math.MinInt32:
+		e.writeHeader(0xd2, uint64(i), 4)
+	default:
+		e.writeHeader(0xd3, uint64(i), 8)
+	}
+}
+
+func (e *msgpackEncoder) writeStr(s string) {
+	e.writeLength(len(s), 0xa0, 31, [3]byte{0xd9, 0xda, 0xdb})
+	e.writeString(s)
+}
+
+func (e *msgpackEncoder) writeExt(typ int8, data []byte) {
+	switch len(data) {
+	case 1:
+		e.writeHeader(0xd4, uint64(typ), 1)
+	case 2:
+		e.writeHeader(0xd5, uint64(typ), 1)
+	case 4:
+		e.writeHeader(0xd6, uint64(typ), 1)
+	case 8:
+		e.writeHeader(0xd7, uint64(typ), 1)
+	case 16:
+		e.writeHeader(0xd8, uint64(typ), 1)
+	default:
+		e.writeLength(len(data), 0, -1, [3]byte{0xc7, 0xc8, 0xc9})
+		e.write([]byte{byte(typ)})
+	}
+	e.write(data)
+}
+
+func (e *msgpackEncoder) writeTimestamp(t time.Time) {
+	s := t.Unix()
+	ns := uint64(t.Nanosecond())
+	var b []byte
+	switch {
+	case s >= 0 && s>>34 == 0:
+		d := ns<<34 | uint64(s)
+		if d&0xffffffff00000000 == 0 {
+			b = make([]byte, 4)
+			bin.BigEndian.PutUint32(b, uint32(d))
+		} else {
+			b = make([]byte, 8)
+			bin.BigEndian.PutUint64(b, d)
+		}
+	default:
+		b = make([]byte, 12)
+		bin.BigEndian.PutUint32(b, uint32(ns))
+		bin.BigEndian.PutUint64(b[4:], uint64(s))
+	}
+	e.writeExt(msgpackTimestampExt, b)
+}
+
+func (e *msgpackEncoder) value(v dgo.Value) {
+	switch v := v.(type) {
+	case nil, nilValue:
+		e.writeHeader(0xc0, 0, 0)
+	case Boolean:
+		if v {
+			e.writeHeader(0xc3, 0, 0)
+		} else {
+			e.writeHeader(0xc2, 0, 0)
+		}
+	case Integer:
+		e.writeInt(int64(v))
+	case Float:
+		e.writeHeader(0xcb, math.Float64bits(float64(v)), 8)
+	case *hstring:
+		e.writeStr(v.s)
+	case *binary:
+		e.writeLength(len(v.bytes), 0, -1, [3]byte{0xc4, 0xc5, 0xc6})
+		e.write(v.bytes)
+	case *Regexp:
+		e.writeExt(msgpackRegexpExt, []byte(v.String()))
+	case dgo.Type:
+		e.writeExt(msgpackTypeExt, []byte(TypeString(v)))
+	case *hashSet:
+		e.values(v.m.Keys().(*array).slice)
+	case dgo.Array:
+		e.values(sliceOf(v))
+	case dgo.Map:
+		e.writeLength(v.Len(), 0x80, 15, [3]byte{0, 0xde, 0xdf})
+		v.Each(func(me dgo.MapEntry) {
+			e.value(me.Key())
+			e.value(me.Value())
+		})
+	case dgo.Native:
+		if t, ok := v.GoValue().(time.Time); ok {
+			e.writeTimestamp(t)
+			return
+		}
+		panic(msgpackFailure{fmt.Errorf(`msgpack: unable to encode a value of type %s`, TypeString(v.Type()))})
+	case error:
+		e.writeExt(msgpackErrorExt, []byte(v.Error()))
+	default:
+		panic(msgpackFailure{fmt.Errorf(`msgpack: unable to encode a value of type %s`, TypeString(v.Type()))})
+	}
+}
+
+func (e *msgpackEncoder) values(vs []dgo.Value) {
+	e.writeLength(len(vs), 0x90, 15, [3]byte{0, 0xdc, 0xdd})
+	for _, v := range vs {
+		e.value(v)
+	}
+}
+
+type msgpackDecoder struct {
+	r   io.Reader
+	buf [8]byte
+}
+
+func (d *msgpackDecoder) fail(err error) {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	panic(msgpackFailure{err})
+}
+
+// read reads exactly n bytes into the internal buffer and returns them. The n must not exceed 8.
+func (d *msgpackDecoder) read(n int) []byte {
+	b := d.buf[:n]
+	if _, err := io.ReadFull(d.r, b); err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+// readBytes reads exactly n bytes. The bytes are read in chunks so that a corrupt length doesn't cause a
+// huge allocation up front.
+func (d *msgpackDecoder) readBytes(n uint64) []byte {
+	b, err := ioutil.ReadAll(io.LimitReader(d.r, int64(n)))
+	if err == nil && uint64(len(b)) != n {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		d.fail(err)
+	}
+	return b
+}
+
+func (d *msgpackDecoder) readUint(width int) uint64 {
+	b := d.read(width)
+	switch width {
+	case 1:
+		return uint64(b[0])
+	case 2:
+		return uint64(bin.BigEndian.Uint16(b))
+	case 4:
+		return uint64(bin.BigEndian.Uint32(b))
+	default:
+		return bin.BigEndian.Uint64(b)
+	}
+}
+
+func (d *msgpackDecoder) value() dgo.Value {
+	c := d.read(1)[0]
+	switch {
+	case c <= 0x7f:
+		return Integer(int64(c))
+	case c >= 0xe0:
+		return Integer(int64(int8(c)))
+	case c >= 0x80 && c <= 0x8f:
+		return d.mapValue(int(c & 0x0f))
+	case c >= 0x90 && c <= 0x9f:
+		return d.arrayValue(int(c & 0x0f))
+	case c >= 0xa0 && c <= 0xbf:
+		return makeHString(string(d.readBytes(uint64(c & 0x1f))))
+	}
+	switch c {
+	case 0xc0:
+		return Nil
+	case 0xc2:
+		return False
+	case 0xc3:
+		return True
+	case 0xc4, 0xc5, 0xc6:
+		return &binary{bytes: d.readBytes(d.readUint(1 << (c - 0xc4))), frozen: true}
+	case 0xc7, 0xc8, 0xc9:
+		n := d.readUint(1 << (c - 0xc7))
+		return d.ext(int8(d.read(1)[0]), n)
+	case 0xca:
+		return Float(float64(math.Float32frombits(uint32(d.readUint(4)))))
+	case 0xcb:
+		return Float(math.Float64frombits(d.readUint(8)))
+	case 0xcc, 0xcd, 0xce, 0xcf:
+		u := d.readUint(1 << (c - 0xcc))
+		if u > math.MaxInt64 {
+			d.fail(fmt.Errorf(`msgpack: unsigned integer %d overflows int64`, u))
+		}
+		return Integer(int64(u))
+	case 0xd0:
+		return Integer(int64(int8(d.readUint(1))))
+	case 0xd1:
+		return Integer(int64(int16(d.readUint(2))))
+	case 0xd2:
+		return Integer(int64(int32(d.readUint(4))))
+	case 0xd3:
+		return Integer(int64(d.readUint(8)))
+	case 0xd4, 0xd5, 0xd6, 0xd7, 0xd8:
+		return d.ext(int8(d.read(1)[0]), 1<<(c-0xd4))
+	case 0xd9, 0xda, 0xdb:
+		return makeHString(string(d.readBytes(d.readUint(1 << (c - 0xd9)))))
+	case 0xdc, 0xdd:
+		return d.arrayValue(int(d.readUint(2 << (c - 0xdc))))
+	case 0xde, 0xdf:
+		return d.mapValue(int(d.readUint(2 << (c - 0xde))))
+	}
+	d.fail(fmt.Errorf(`msgpack: invalid code 0x%x`, c))
+	return nil
+}
+
+func (d *msgpackDecoder) arrayValue(n int) dgo.Value {
+	// The slice grows as elements are read so that a corrupt length doesn't cause a huge allocation
+	s := make([]dgo.Value, 0, minInt(n, 1024))
+	for i := 0; i < n; i++ {
+		s = append(s, d.value())
+	}
+	return &array{slice: s, frozen: true}
+}
+
+func (d *msgpackDecoder) mapValue(n int) dgo.Value {
+	m := MutableMap(minInt(n, 1024), nil)
+	for i := 0; i < n; i++ {
+		k := d.value()
+		m.Put(k, d.value())
+	}
+	m.Freeze()
+	return m
+}
+
+func (d *msgpackDecoder) ext(typ int8, n uint64) dgo.Value {
+	data := d.readBytes(n)
+	switch typ {
+	case msgpackTimestampExt:
+		return d.timestamp(data)
+	case msgpackTypeExt:
+		t, err := parseType(string(data))
+		if err != nil {
+			d.fail(err)
+		}
+		return t
+	case msgpackRegexpExt:
+		rx, err := regexp.Compile(string(data))
+		if err != nil {
+			d.fail(err)
+		}
+		return (*Regexp)(rx)
+	case msgpackErrorExt:
+		return &errw{errors.New(string(data))}
+	}
+	d.fail(fmt.Errorf(`msgpack: unknown extension type %d`, typ))
+	return nil
+}
+
+func (d *msgpackDecoder) timestamp(data []byte) dgo.Value {
+	var s int64
+	var ns int64
+	switch len(data) {
+	case 4:
+		s = int64(bin.BigEndian.Uint32(data))
+	case 8:
+		u := bin.BigEndian.Uint64(data)
+		ns = int64(u >> 34)
+		s = int64(u & 0x3ffffffff)
+	case 12:
+		ns = int64(bin.BigEndian.Uint32(data))
+		s = int64(bin.BigEndian.Uint64(data[4:]))
+	default:
+		d.fail(fmt.Errorf(`msgpack: invalid timestamp length %d`, len(data)))
+	}
+	return Value(time.Unix(s, ns).UTC())
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,152 @@
+package internal_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func msgpackRoundTrip(t *testing.T, v dgo.Value) string {
+	t.Helper()
+	b, err := vf.MarshalMsgPack(v)
+	require.Nil(t, err)
+	r, err := vf.UnmarshalMsgPack(b)
+	require.Nil(t, err)
+	require.Equal(t, v, r)
+	return hex.EncodeToString(b)
+}
+
+func TestMsgPack_integers(t *testing.T) {
+	tests := []struct {
+		i   int64
+		hex string
+	}{
+		{0, `00`},
+		{127, `7f`},
+		{128, `cc80`},
+		{255, `ccff`},
+		{256, `cd0100`},
+		{65536, `ce00010000`},
+		{math.MaxUint32 + 1, `cf0000000100000000`},
+		{-1, `ff`},
+		{-32, `e0`},
+		{-33, `d0df`},
+		{-129, `d1ff7f`},
+		{-32769, `d2ffff7fff`},
+		{math.MinInt64, `d38000000000000000`},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.hex, msgpackRoundTrip(t, vf.Integer(tc.i)))
+	}
+}
+
+func TestMsgPack_values(t *testing.T) {
+	require.Equal(t, `c0`, msgpackRoundTrip(t, vf.Nil))
+	require.Equal(t, `c3`, msgpackRoundTrip(t, vf.True))
+	require.Equal(t, `c2`, msgpackRoundTrip(t, vf.False))
+	require.Equal(t, `cb3ff8000000000000`, msgpackRoundTrip(t, vf.Float(1.5)))
+	require.Equal(t, `a3616263`, msgpackRoundTrip(t, vf.String(`abc`)))
+	require.Equal(t, `d920`+strings.Repeat(`61`, 32), msgpackRoundTrip(t, vf.String(strings.Repeat(`a`, 32))))
+	require.Equal(t, `c403010203`, msgpackRoundTrip(t, vf.Value([]byte{1, 2, 3})))
+	require.Equal(t, `930102a161`, msgpackRoundTrip(t, vf.Values(1, 2, `a`)))
+	m := vf.MutableMap(0, nil)
+	m.Put(`b`, 1)
+	m.Put(`a`, 2)
+	require.Equal(t, `82a16201a16102`, msgpackRoundTrip(t, m))
+	require.Equal(t, `d40261`, msgpackRoundTrip(t, vf.Value(regexp.MustCompile(`a`))))
+	require.Equal(t, `c70301696e74`, msgpackRoundTrip(t, typ.Integer))
+	msgpackRoundTrip(t, vf.Value(errors.New(`oops`)))
+	msgpackRoundTrip(t, vf.Set(1, 2).Type())
+
+	long := make([]interface{}, 70000)
+	for i := range long {
+		long[i] = i
+	}
+	msgpackRoundTrip(t, vf.Values(long...))
+	msgpackRoundTrip(t, vf.Value(bytes.Repeat([]byte{1}, 300)))
+	msgpackRoundTrip(t, vf.String(strings.Repeat(`a`, 70000)))
+}
+
+func TestMsgPack_nested(t *testing.T) {
+	m := vf.MutableMap(0, nil)
+	m.Put(vf.Values(1, 2), `array key`)
+	m.Put(3, vf.Map(map[string]dgo.Type{`x`: newtype.Parse(`{"a":int,"b"?:[]string}`)}))
+	m.Put(`float`, 3.0)
+	m.Put(typ.String, nil)
+	m.Freeze()
+	msgpackRoundTrip(t, m)
+
+	b, err := vf.MarshalMsgPack(vf.Set(1, 2))
+	require.Nil(t, err)
+	v, err := vf.UnmarshalMsgPack(b)
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, 2), v)
+}
+
+func TestMsgPack_timestamp(t *testing.T) {
+	for _, tm := range []time.Time{
+		time.Unix(1000, 0).UTC(),
+		time.Unix(1000, 500).UTC(),
+		time.Unix(-1000, 500).UTC(),
+		time.Unix(1<<35, 0).UTC(),
+		time.Unix(0, 0),
+		time.Unix(1000, 500).In(time.FixedZone(`CET`, 3600)),
+	} {
+		b, err := vf.MarshalMsgPack(tm)
+		require.Nil(t, err)
+		v, err := vf.UnmarshalMsgPack(b)
+		require.Nil(t, err)
+		require.Equal(t, vf.Value(tm), v)
+		require.Equal(t, vf.Value(tm).HashCode(), v.HashCode())
+		require.Equal(t, time.UTC, v.(dgo.Native).GoValue().(time.Time).Location())
+	}
+	b, _ := vf.MarshalMsgPack(time.Unix(1000, 0))
+	require.Equal(t, `d6ff000003e8`, hex.EncodeToString(b))
+}
+
+func TestMsgPack_stream(t *testing.T) {
+	var b bytes.Buffer
+	require.Nil(t, vf.EncodeMsgPack(&b, vf.Values(1, `a`)))
+	require.Nil(t, vf.EncodeMsgPack(&b, `b`))
+	v, err := vf.DecodeMsgPack(&b)
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, `a`), v)
+	v, err = vf.DecodeMsgPack(&b)
+	require.Nil(t, err)
+	require.Equal(t, `b`, v)
+	_, err = vf.DecodeMsgPack(&b)
+	require.NotNil(t, err)
+}
+
+func TestMsgPack_fail(t *testing.T) {
+	_, err := vf.MarshalMsgPack(vf.MutableValues(nil, struct{ A int }{1}))
+	require.NotNil(t, err)
+
+	for _, s := range []string{
+		``,
+		`c1`,
+		`93`,
+		`a3616263ff`,
+		`cfffffffffffffffff`,
+		`d401ff`,
+		`d40161`,
+		`d40228`,
+		`d5ff0000`,
+		`c4ff00`,
+	} {
+		b, _ := hex.DecodeString(s)
+		_, err := vf.UnmarshalMsgPack(b)
+		require.NotNil(t, err)
+	}
+}
@@ -1,8 +1,10 @@
 package internal
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
+	"time"
 
 	"github.com/lyraproj/dgo/dgo"
 )
@@ -17,6 +19,16 @@ type (
 
 var DefaultNativeType = &nativeType{}
 
+// immutableNativeTypes are the Go types that are considered frozen when wrapped in a native value. They are
+// immutable values, which allows the decoders to add them to frozen collections.
+var immutableNativeTypes = map[reflect.Type]bool{
+	reflect.TypeOf(time.Time{}):         true,
+	reflect.TypeOf(json.Number(``)):     true,
+	reflect.TypeOf(dgo.LocalDate{}):     true,
+	reflect.TypeOf(dgo.LocalTime{}):     true,
+	reflect.TypeOf(dgo.LocalDateTime{}): true,
+}
+
 func Native(rv reflect.Value) dgo.Native {
 	return native(rv)
 }
@@ -41,6 +53,14 @@ func (t *nativeType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *nativeType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *nativeType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *nativeType) HashCode() int {
 	return stringHash(t.rt.Name())*31 + int(dgo.IdNative)
 }
@@ -81,7 +101,13 @@ func (v native) Equals(other interface{}) bool {
 		if k == reflect.Func {
 			return a.Pointer() == b.Pointer()
 		}
-		return reflect.DeepEqual(a.Interface(), b.Interface())
+		ai := a.Interface()
+		if at, ok := ai.(time.Time); ok {
+			// Times that represent the same instant are equal regardless of their location
+			bt, ok := b.Interface().(time.Time)
+			return ok && at.Equal(bt)
+		}
+		return reflect.DeepEqual(ai, b.Interface())
 	}
 	return false
 }
@@ -93,7 +119,8 @@ func (v native) Freeze() {
 }
 
 func (v native) Frozen() bool {
-	return reflect.Value(v).Kind() == reflect.Func
+	rv := reflect.Value(v)
+	return rv.Kind() == reflect.Func || immutableNativeTypes[rv.Type()]
 }
 
 func (v native) FrozenCopy() dgo.Value {
@@ -110,6 +137,9 @@ func (v native) HashCode() int {
 		p := rv.Pointer()
 		return int(p ^ (p >> 32))
 	case reflect.Struct:
+		if t, ok := rv.Interface().(time.Time); ok {
+			return int(t.Unix())*31 + t.Nanosecond()
+		}
 		n := rv.NumField()
 		h := 1
 		for i := 0; i < n; i++ {
@@ -139,8 +169,11 @@ func (v native) GoValue() interface{} {
 }
 
 func toReflected(value interface{}) (reflect.Value, bool) {
-	if ov, ok := value.(native); ok {
+	switch ov := value.(type) {
+	case native:
 		return reflect.Value(ov), true
+	case *bigInt:
+		return reflect.ValueOf(ov.i), true
 	}
 	if _, ok := value.(dgo.Value); ok {
 		return reflect.Value{}, false
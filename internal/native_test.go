@@ -2,8 +2,10 @@ package internal_test
 
 import (
 	"fmt"
+	"math/big"
 	"reflect"
 	"testing"
+	"time"
 
 	"github.com/lyraproj/dgo/dgo"
 	"github.com/lyraproj/dgo/internal"
@@ -46,6 +48,16 @@ func TestNative(t *testing.T) {
 	require.Same(t, f, f.FrozenCopy())
 	f.Freeze() // No panic
 
+	require.True(t, vf.Value(time.Now()).(dgo.Freezable).Frozen())
+	require.False(t, vf.Value(big.NewInt(1)).(dgo.Freezable).Frozen())
+
+	// Times are equal when they represent the same instant
+	tm := time.Unix(1000, 500)
+	tz := vf.Value(tm.In(time.FixedZone(`CET`, 3600)))
+	require.Equal(t, vf.Value(tm.UTC()), tz)
+	require.Equal(t, vf.Value(tm.UTC()).HashCode(), tz.HashCode())
+	require.NotEqual(t, vf.Value(tm.Add(1)), tz)
+
 	require.False(t, c.Frozen())
 	require.Panic(t, func() { c.Freeze() }, `cannot be frozen`)
 	require.Panic(t, func() { c.FrozenCopy() }, `cannot be frozen`)
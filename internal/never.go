@@ -0,0 +1,49 @@
+package internal
+
+import "github.com/lyraproj/dgo/dgo"
+
+// neverType represents the empty set of values. It has no instances and is assignable to all other types.
+type neverType int
+
+const DefaultNeverType = neverType(0)
+
+func (t neverType) Assignable(other dgo.Type) bool {
+	return DefaultNeverType == other || CheckAssignableTo(nil, other, t)
+}
+
+// AssignableTo returns true since a type that has no instances is assignable to all types
+func (t neverType) AssignableTo(guard dgo.RecursionGuard, other dgo.Type) bool {
+	return true
+}
+
+func (t neverType) Equals(other interface{}) bool {
+	return t == other
+}
+
+func (t *neverType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t neverType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
+func (t neverType) HashCode() int {
+	return int(dgo.IdNever)
+}
+
+func (t neverType) Instance(value interface{}) bool {
+	return false
+}
+
+func (t neverType) String() string {
+	return TypeString(t)
+}
+
+func (t neverType) Type() dgo.Type {
+	return &metaType{t}
+}
+
+func (t neverType) TypeIdentifier() dgo.TypeIdentifier {
+	return dgo.IdNever
+}
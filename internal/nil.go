@@ -28,6 +28,14 @@ func (nilValue) CompareTo(other interface{}) (int, bool) {
 	return -1, true
 }
 
+func (v *nilValue) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v nilValue) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (nilValue) HashCode() int {
 	return 131
 }
@@ -65,6 +73,14 @@ func (t nilType) Equals(v interface{}) bool {
 	return t == v
 }
 
+func (t *nilType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t nilType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t nilType) HashCode() int {
 	return int(1 + dgo.IdNil)
 }
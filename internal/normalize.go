@@ -0,0 +1,139 @@
+package internal
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// Normalize returns a simplified but equivalent version of the given type. Nested AllOf and AnyOf types
+// are flattened, operands that are assignable to sibling operands are dropped, double negations are
+// eliminated, adjacent and overlapping ranges are folded, and the operands of AllOf, AnyOf, and OneOf
+// types are sorted in a canonical order. Element, key, and value types of collections are normalized
+// recursively.
+//
+// Two normalized types that represent the same set of values will in most cases be equal.
+func Normalize(t dgo.Type) dgo.Type {
+	switch t := t.(type) {
+	case *anyOfType:
+		return sortOperands(Union(normalizeAll(t.slice)))
+	case *allOfType:
+		return sortOperands(Intersect(normalizeAll(t.slice)))
+	case *oneOfType:
+		ts := normalizeAll(t.slice)
+		ops := make([]dgo.Type, 0, len(ts))
+		for _, o := range ts {
+			// Never cannot match a value so it doesn't affect the outcome
+			if o != DefaultNeverType {
+				ops = append(ops, o)
+			}
+		}
+		sortTypes(ops)
+		return OneOfType(ops)
+	case *notType:
+		switch n := Normalize(t.Negated); n {
+		case DefaultAnyType:
+			return DefaultNeverType
+		case DefaultNeverType:
+			return DefaultAnyType
+		default:
+			return NotType(n)
+		}
+	case *sizedArrayType:
+		return newArrayType(Normalize(t.elementType), t.min, t.max)
+	case *tupleType:
+		return TupleType(normalizeAll(t.slice))
+	case *sizedSetType:
+		return newSetType(Normalize(t.elementType), t.min, t.max)
+	case *sizedMapType:
+		return newMapType(Normalize(t.keyType), Normalize(t.valueType), t.min, t.max)
+	case *structType:
+		return normalizeStruct(t)
+	}
+	return t
+}
+
+func normalizeAll(vs []dgo.Value) []dgo.Type {
+	ts := make([]dgo.Type, len(vs))
+	for i, v := range vs {
+		ts[i] = Normalize(v.(dgo.Type))
+	}
+	return ts
+}
+
+func normalizeStruct(t *structType) dgo.Type {
+	m := &hashMap{table: make([]*hashNode, tableSizeFor(t.entries.len))}
+	for n := t.entries.first; n != nil; n = n.next {
+		e := n.value.(*entryType)
+		m.Put(n.key, &entryType{key: e.key, value: Normalize(e.value), required: e.required})
+	}
+	m.Freeze()
+	return &structType{additional: t.additional, entries: m}
+}
+
+// sortOperands returns a copy of the given AllOf or AnyOf type where the operands are in canonical
+// order. Other types are returned unaltered.
+func sortOperands(t dgo.Type) dgo.Type {
+	var vs []dgo.Value
+	switch ot := t.(type) {
+	case *allOfType:
+		vs = ot.slice
+	case *anyOfType:
+		vs = ot.slice
+	default:
+		return t
+	}
+	ts := make([]dgo.Type, len(vs))
+	for i, v := range vs {
+		ts[i] = v.(dgo.Type)
+	}
+	sortTypes(ts)
+	if _, ok := t.(*allOfType); ok {
+		return AllOfType(ts)
+	}
+	return AnyOfType(ts)
+}
+
+// sortTypes sorts the given types in canonical order. Types are grouped by kind, numeric types are
+// ordered by their lower bound, and the string representation is used as the final criteria.
+func sortTypes(ts []dgo.Type) {
+	sort.SliceStable(ts, func(i, j int) bool { return compareTypes(ts[i], ts[j]) < 0 })
+}
+
+func compareTypes(a, b dgo.Type) int {
+	ka, kb := kindRank(a), kindRank(b)
+	if ka != kb {
+		return ka - kb
+	}
+	switch ka {
+	case int(kindInteger):
+		if am, bm := a.(dgo.IntegerRangeType).Min(), b.(dgo.IntegerRangeType).Min(); am != bm {
+			if am < bm {
+				return -1
+			}
+			return 1
+		}
+	case int(kindFloat):
+		if am, bm := a.(dgo.FloatRangeType).Min(), b.(dgo.FloatRangeType).Min(); am != bm {
+			if am < bm {
+				return -1
+			}
+			return 1
+		}
+	}
+	if ia, ib := a.TypeIdentifier(), b.TypeIdentifier(); ia != ib {
+		return int(ia) - int(ib)
+	}
+	return strings.Compare(TypeString(a), TypeString(b))
+}
+
+// kindRank returns the kind of the given type as an int where unknown kinds are sorted last
+func kindRank(t dgo.Type) int {
+	k := kindOf(t)
+	if k == kindUnknown {
+		return math.MaxInt32
+	}
+	return int(k)
+}
@@ -0,0 +1,79 @@
+package internal_test
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		t      string
+		result string
+	}{
+		{`int|0..5`, `int`},
+		{`"a"|"b"|string`, `string`},
+		{`string|int`, `int|string`},
+		{`int|int`, `int`},
+		{`5..10|0..3|4`, `0..10`},
+		{`10..12|5..6`, `5..6|10..12`},
+		{`1.5..2.0|0.0..1.0`, `0.0..1.0|1.5..2.0`},
+		{`string[5,8]|string[1,4]`, `string[1,8]`},
+		{`true|false`, `bool`},
+		{`nil|string|int|bool`, `nil|bool|int|string`},
+		{`int&0..5`, `0..5`},
+		{`string[1,5]&string[3,8]`, `string[3,5]`},
+		{`int&string`, `never`},
+		{`string^int`, `int^string`},
+		{`!(!string)`, `string`},
+		{`!any`, `never`},
+		{`!(int|0..5)`, `!int`},
+		{`[](int|0..5)`, `[]int`},
+		{`[1,5](string|"a")`, `[1,5]string`},
+		{`{string|"a",int|3}`, `{string,int}`},
+		{`set[int|0..5]`, `set[int]`},
+		{`map[string|"a",1,3](int|3)`, `map[string,1,3]int`},
+		{`{"a":int|3,"b"?:string|"x"}`, `{"a":int,"b"?:string}`},
+		{`int`, `int`},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.result, newtype.Normalize(newtype.Parse(tc.t)).String())
+	}
+}
+
+func TestNormalize_nested(t *testing.T) {
+	a := newtype.AnyOf(newtype.AnyOf(typ.String, newtype.IntegerRange(5, 10)), newtype.IntegerRange(0, 4))
+	require.Equal(t, `0..10|string`, newtype.Normalize(a).String())
+
+	a = newtype.AllOf(newtype.AllOf(typ.String, newtype.Pattern(regexp.MustCompile(`a`))), newtype.String(1, 10))
+	require.Equal(t, `/a/&string[1,10]`, newtype.Normalize(a).String())
+
+	require.Equal(t, typ.Never, newtype.Normalize(newtype.AnyOf()))
+	require.Equal(t, typ.Any, newtype.Normalize(newtype.AllOf()))
+	require.Equal(t, typ.Any, newtype.Normalize(newtype.Not(typ.Never)))
+	require.Equal(t, typ.String, newtype.Normalize(newtype.OneOf(typ.String, typ.Never)))
+}
+
+func TestNormalize_equals(t *testing.T) {
+	pairs := [][2]string{
+		{`string|1..3|int`, `int|string`},
+		{`"a"|int|string`, `string|int`},
+		{`0..3|4..10`, `0..10`},
+		{`!(!int)|!(!string)`, `string|"a"|int`},
+		{`[](int|0..5)`, `[]int`},
+	}
+	for _, p := range pairs {
+		a, b := newtype.Parse(p[0]), newtype.Parse(p[1])
+		require.NotEqual(t, a, b)
+		na, nb := newtype.Normalize(a), newtype.Normalize(b)
+		require.Equal(t, na, nb)
+		require.Equal(t, na.HashCode(), nb.HashCode())
+	}
+
+	var n dgo.Type = newtype.Normalize(newtype.Parse(`1..3|string`))
+	require.Equal(t, n, newtype.Normalize(n))
+}
@@ -23,6 +23,14 @@ func (t *notType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *notType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *notType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *notType) HashCode() int {
 	return 1579 + t.Negated.HashCode()
 }
@@ -123,6 +123,19 @@ func ParseFile(fileName, content string) dgo.Type {
 	return p.popLastType()
 }
 
+// parseType parses the given string into a type and returns a parse error as an error instead of
+// panicking
+func parseType(s string) (t dgo.Type, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			t = nil
+			err = fmt.Errorf(`%v`, r)
+		}
+	}()
+	t = Parse(s)
+	return
+}
+
 func (p *parser) peekToken() *token {
 	if p.pe == nil {
 		p.pe = p.nextToken()
@@ -218,6 +231,80 @@ func (p *parser) list() {
 	p.d = append(p.d[:szp], tv)
 }
 
+// exactSet parses the elements of a set{...} expression and returns the exact type of a set that
+// contains the values that the elements represent. Each element must be an exact type.
+func (p *parser) exactSet() dgo.Type {
+	szp := len(p.d)
+	for {
+		t := p.nextToken()
+		if t.i == '}' {
+			break
+		}
+		p.anyOf(t)
+		t = p.nextToken()
+		if t.i == '}' {
+			break
+		}
+		if t.i != ',' {
+			panic(badSyntax(t, exListComma))
+		}
+	}
+	s := newHashSet(len(p.d) - szp)
+	for _, v := range p.d[szp:] {
+		e, ok := literalValue(v)
+		if !ok {
+			panic(fmt.Errorf(`the elements of an exact set must be exact types, got %s`, v))
+		}
+		s.m.Put(e, True)
+	}
+	s.m.frozen = true
+	p.d = p.d[:szp]
+	return (*exactSetType)(s)
+}
+
+// literalValue returns the value that the given parsed element represents, provided that the element
+// is a literal value or a type that matches exactly one value.
+func literalValue(v dgo.Value) (dgo.Value, bool) {
+	switch et := v.(type) {
+	case dgo.ExactType:
+		return et.Value(), true
+	case *metaType:
+		return et.tp, et.tp != nil
+	case *tupleType:
+		vs := make([]dgo.Value, len(et.slice))
+		for i, t := range et.slice {
+			e, ok := literalValue(t)
+			if !ok {
+				return nil, false
+			}
+			vs[i] = e
+		}
+		return Array(vs), true
+	case *structType:
+		if et.additional {
+			return nil, false
+		}
+		m := MutableMap(et.entries.len, nil)
+		for e := et.entries.first; e != nil; e = e.next {
+			et := e.value.(*entryType)
+			k, ok := literalValue(et.key)
+			if !ok || !et.required {
+				return nil, false
+			}
+			var ev dgo.Value
+			if ev, ok = literalValue(et.value); !ok {
+				return nil, false
+			}
+			m.Put(k, ev)
+		}
+		m.Freeze()
+		return m, true
+	case dgo.Type:
+		return nil, false
+	}
+	return v, true
+}
+
 func (p *parser) params() {
 	szp := len(p.d)
 	for {
@@ -428,8 +515,29 @@ func (p *parser) typeExpression(t *token) {
 				panic(badSyntax(n, exRightBracket))
 			}
 			tp = &metaType{typ}
+		case `set`:
+			if p.peekToken().i == '{' {
+				p.nextToken()
+				tp = p.exactSet()
+			} else if p.peekToken().i == '[' {
+				// get element type and size arguments
+				p.nextToken()
+				p.params()
+				params := p.popLast().(*array)
+				if len(params.slice) > 0 {
+					v := params.slice[0]
+					if _, ok := v.(dgo.Type); !ok {
+						params.slice[0] = v.Type()
+					}
+				}
+				tp = SetType(sliceToInterfaces(params)...)
+			} else {
+				tp = DefaultSetType
+			}
 		case `any`:
 			tp = DefaultAnyType
+		case `never`:
+			tp = DefaultNeverType
 		case `bool`:
 			tp = DefaultBooleanType
 		case `int`:
@@ -455,7 +563,24 @@ func (p *parser) typeExpression(t *token) {
 		case `nil`:
 			tp = Nil
 		default:
-			panic(fmt.Errorf(`unknown identifier '%s'`, t.s))
+			if pt := predicateByName(t.s); pt != nil {
+				tp = pt
+				break
+			}
+			k := kindByName(t.s)
+			if k == nil {
+				panic(fmt.Errorf(`unknown identifier '%s'`, t.s))
+			}
+			var args dgo.Array = &array{frozen: true}
+			if p.peekToken().i == '[' {
+				p.nextToken()
+				p.params()
+				args = p.popLast().(*array)
+			}
+			var err error
+			if tp, err = k.New(args); err != nil {
+				panic(err)
+			}
 		}
 	case stringLiteral:
 		tp = String(t.s)
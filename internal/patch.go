@@ -0,0 +1,247 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// ApplyPatch applies the given JSON Patch (RFC 6902) to the given value and returns the resulting frozen
+// value. The patch must be an Array of Maps where each Map is an operation. The supported operations are
+// "add", "remove", "replace", "move", "copy", and "test". The patch is applied atomically, i.e. an error
+// is returned and no value is produced unless all operations succeed.
+func ApplyPatch(v dgo.Value, patch dgo.Value) (dgo.Value, error) {
+	ops, ok := patch.(dgo.Array)
+	if !ok {
+		return nil, fmt.Errorf(`json patch must be an array, got %s`, TypeString(patch.Type()))
+	}
+	v = frozenCopy(v)
+	for i := 0; i < ops.Len(); i++ {
+		var err error
+		if v, err = applyPatchOp(v, ops.Get(i)); err != nil {
+			return nil, fmt.Errorf(`json patch operation %d: %s`, i, err.Error())
+		}
+	}
+	return v, nil
+}
+
+func applyPatchOp(v dgo.Value, opv dgo.Value) (dgo.Value, error) {
+	op, ok := opv.(dgo.Map)
+	if !ok {
+		return nil, fmt.Errorf(`operation must be a map, got %s`, TypeString(opv.Type()))
+	}
+	name, err := patchOpString(op, `op`)
+	if err != nil {
+		return nil, err
+	}
+	path, err := patchOpString(op, `path`)
+	if err != nil {
+		return nil, err
+	}
+	p, err := parsePointer(path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case `add`, `replace`, `test`:
+		value, ok := op.Get(`value`)
+		if !ok {
+			return nil, fmt.Errorf(`%s operation has no "value"`, name)
+		}
+		switch name {
+		case `add`:
+			return p.set(v, 0, frozenCopy(value), true)
+		case `replace`:
+			if _, err = GetPath(v, path); err != nil {
+				return nil, err
+			}
+			return p.set(v, 0, frozenCopy(value), false)
+		default:
+			var ev dgo.Value
+			if ev, err = GetPath(v, path); err != nil {
+				return nil, err
+			}
+			if !ev.Equals(value) {
+				return nil, fmt.Errorf(`test failed for path %s: expected %s, got %s`, path, value, ev)
+			}
+			return v, nil
+		}
+	case `remove`:
+		return DeletePath(v, path)
+	case `move`, `copy`:
+		from, err := patchOpString(op, `from`)
+		if err != nil {
+			return nil, err
+		}
+		value, err := GetPath(v, from)
+		if err != nil {
+			return nil, err
+		}
+		if name == `move` {
+			if from == path {
+				return v, nil
+			}
+			if strings.HasPrefix(path, from+`/`) {
+				return nil, fmt.Errorf(`cannot move %s into one of its children`, from)
+			}
+			if v, err = DeletePath(v, from); err != nil {
+				return nil, err
+			}
+		}
+		return p.set(v, 0, value, true)
+	}
+	return nil, fmt.Errorf(`unknown operation %q`, name)
+}
+
+func patchOpString(op dgo.Map, key string) (string, error) {
+	v, ok := op.Get(key)
+	if !ok {
+		return ``, fmt.Errorf(`operation has no %q`, key)
+	}
+	s, ok := v.(dgo.String)
+	if !ok {
+		return ``, fmt.Errorf(`operation %q must be a string, got %s`, key, TypeString(v.Type()))
+	}
+	return s.GoString(), nil
+}
+
+// ApplyMergePatch applies the given JSON Merge Patch (RFC 7396) to the given value and returns the
+// resulting frozen value.
+func ApplyMergePatch(v dgo.Value, patch dgo.Value) dgo.Value {
+	pm, ok := patch.(dgo.Map)
+	if !ok {
+		return frozenCopy(patch)
+	}
+	m, ok := v.(dgo.Map)
+	if ok {
+		m = m.FrozenCopy().(dgo.Map)
+	} else {
+		m = emptyMap
+	}
+	pm.Each(func(e dgo.MapEntry) {
+		pv := e.Value()
+		if pv == Nil {
+			m = m.Without(e.Key())
+			return
+		}
+		mv, ok := m.Get(e.Key())
+		if !ok {
+			mv = Nil
+		}
+		m = m.With(e.Key(), ApplyMergePatch(mv, pv))
+	})
+	return m
+}
+
+// CreatePatch returns a JSON Patch (RFC 6902) that transforms the value a into the value b. The patch
+// contains "add", "remove", and "replace" operations only. Arrays are compared using their longest
+// common subsequence so that inserted and removed elements are patched individually.
+func CreatePatch(a, b dgo.Value) dgo.Array {
+	ops := make([]dgo.Value, 0)
+	createPatch(nil, &ops, ``, a, b)
+	return &array{slice: ops, frozen: true}
+}
+
+func patchOp(name, path string, value dgo.Value) dgo.Value {
+	m := MutableMap(3, nil)
+	m.Put(`op`, name)
+	m.Put(`path`, path)
+	if value != nil {
+		m.Put(`value`, value)
+	}
+	m.Freeze()
+	return m
+}
+
+func createPatch(seen []dgo.Value, ops *[]dgo.Value, path string, a, b dgo.Value) {
+	if a == b || a.Equals(b) || recursionHit(seen, a) {
+		return
+	}
+	switch ac := a.(type) {
+	case dgo.Map:
+		if bc, ok := b.(dgo.Map); ok {
+			seen = append(seen, a)
+			ac.Each(func(e dgo.MapEntry) {
+				kp := path + `/` + pointerToken(e.Key())
+				if bv, ok := bc.Get(e.Key()); ok {
+					createPatch(seen, ops, kp, e.Value(), bv)
+				} else {
+					*ops = append(*ops, patchOp(`remove`, kp, nil))
+				}
+			})
+			bc.Each(func(e dgo.MapEntry) {
+				if _, ok := ac.Get(e.Key()); !ok {
+					*ops = append(*ops, patchOp(`add`, path+`/`+pointerToken(e.Key()), frozenCopy(e.Value())))
+				}
+			})
+			return
+		}
+	case dgo.Array:
+		if bc, ok := b.(dgo.Array); ok {
+			createArrayPatch(append(seen, a), ops, path, sliceOf(ac), sliceOf(bc))
+			return
+		}
+	}
+	*ops = append(*ops, patchOp(`replace`, path, frozenCopy(b)))
+}
+
+// createArrayPatch appends operations that transforms the array as into the array bs. The index of each
+// operation is the position in the array that results from applying all operations that precedes it.
+func createArrayPatch(seen []dgo.Value, ops *[]dgo.Value, path string, as, bs []dgo.Value) {
+	inA, inB := lcs(len(as), len(bs), func(i, j int) bool { return as[i].Equals(bs[j]) })
+	x := 0
+	i, j := 0, 0
+	for i < len(as) || j < len(bs) {
+		if i < len(as) && j < len(bs) && inA[i] && inB[j] {
+			i++
+			j++
+			x++
+			continue
+		}
+		for i < len(as) && !inA[i] {
+			if j < len(bs) && !inB[j] {
+				createPatch(seen, ops, path+`/`+strconv.Itoa(x), as[i], bs[j])
+				j++
+				x++
+			} else {
+				*ops = append(*ops, patchOp(`remove`, path+`/`+strconv.Itoa(x), nil))
+			}
+			i++
+		}
+		for ; j < len(bs) && !inB[j]; j++ {
+			*ops = append(*ops, patchOp(`add`, path+`/`+strconv.Itoa(x), frozenCopy(bs[j])))
+			x++
+		}
+	}
+}
+
+// CreateMergePatch returns a JSON Merge Patch (RFC 7396) that transforms the value a into the value b.
+// Map entries with a Nil value in b cannot be represented in a merge patch and are treated as removed.
+func CreateMergePatch(a, b dgo.Value) dgo.Value {
+	am, ok := a.(dgo.Map)
+	if !ok {
+		return frozenCopy(b)
+	}
+	bm, ok := b.(dgo.Map)
+	if !ok {
+		return frozenCopy(b)
+	}
+	p := MutableMap(0, nil)
+	am.Each(func(e dgo.MapEntry) {
+		if bv, ok := bm.Get(e.Key()); !ok {
+			p.Put(e.Key(), Nil)
+		} else if !e.Value().Equals(bv) {
+			p.Put(e.Key(), CreateMergePatch(e.Value(), bv))
+		}
+	})
+	bm.Each(func(e dgo.MapEntry) {
+		if _, ok := am.Get(e.Key()); !ok {
+			p.Put(e.Key(), frozenCopy(e.Value()))
+		}
+	})
+	p.Freeze()
+	return p
+}
@@ -0,0 +1,223 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func jsonValue(t *testing.T, s string) dgo.Value {
+	t.Helper()
+	v, err := vf.UnmarshalJSON([]byte(s))
+	require.Nil(t, err)
+	return v
+}
+
+func ExampleApplyPatch() {
+	doc, _ := vf.UnmarshalJSON([]byte(`{"name":"web","ports":[80,443]}`))
+	patch, _ := vf.UnmarshalJSON([]byte(`[
+		{"op":"replace","path":"/name","value":"app"},
+		{"op":"add","path":"/ports/1","value":8080},
+		{"op":"remove","path":"/ports/0"}]`))
+	v, err := vf.ApplyPatch(doc, patch)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(v)
+	// Output: {"name":"app","ports":[8080,443]}
+}
+
+func ExampleApplyMergePatch() {
+	doc, _ := vf.UnmarshalJSON([]byte(`{"title":"Goodbye!","author":{"givenName":"John","familyName":"Doe"}}`))
+	patch, _ := vf.UnmarshalJSON([]byte(`{"title":"Hello!","author":{"familyName":null},"tags":["example"]}`))
+	fmt.Println(vf.ApplyMergePatch(doc, patch))
+	// Output: {"title":"Hello!","author":{"givenName":"John"},"tags":["example"]}
+}
+
+func TestApplyPatch(t *testing.T) {
+	doc := jsonValue(t, `{"a":{"b":[1,2,3]},"c":"x"}`)
+	tests := []struct {
+		patch  string
+		result string
+	}{
+		{`[]`, `{"a":{"b":[1,2,3]},"c":"x"}`},
+		{`[{"op":"add","path":"/d","value":true}]`, `{"a":{"b":[1,2,3]},"c":"x","d":true}`},
+		{`[{"op":"add","path":"/c","value":"y"}]`, `{"a":{"b":[1,2,3]},"c":"y"}`},
+		{`[{"op":"add","path":"/a/b/0","value":0}]`, `{"a":{"b":[0,1,2,3]},"c":"x"}`},
+		{`[{"op":"add","path":"/a/b/3","value":4}]`, `{"a":{"b":[1,2,3,4]},"c":"x"}`},
+		{`[{"op":"add","path":"/a/b/-","value":4}]`, `{"a":{"b":[1,2,3,4]},"c":"x"}`},
+		{`[{"op":"add","path":"","value":[1]}]`, `[1]`},
+		{`[{"op":"remove","path":"/a/b/1"}]`, `{"a":{"b":[1,3]},"c":"x"}`},
+		{`[{"op":"remove","path":"/c"}]`, `{"a":{"b":[1,2,3]}}`},
+		{`[{"op":"replace","path":"/a/b/1","value":{"x":null}}]`, `{"a":{"b":[1,{"x":null},3]},"c":"x"}`},
+		{`[{"op":"replace","path":"/c","value":1}]`, `{"a":{"b":[1,2,3]},"c":1}`},
+		{`[{"op":"move","from":"/c","path":"/a/c"}]`, `{"a":{"b":[1,2,3],"c":"x"}}`},
+		{`[{"op":"move","from":"/a/b/0","path":"/a/b/2"}]`, `{"a":{"b":[2,3,1]},"c":"x"}`},
+		{`[{"op":"move","from":"/c","path":"/c"}]`, `{"a":{"b":[1,2,3]},"c":"x"}`},
+		{`[{"op":"copy","from":"/a/b","path":"/c"}]`, `{"a":{"b":[1,2,3]},"c":[1,2,3]}`},
+		{`[{"op":"test","path":"/a/b","value":[1,2,3]},{"op":"remove","path":"/a"}]`, `{"c":"x"}`},
+	}
+	for _, tc := range tests {
+		v, err := vf.ApplyPatch(doc, jsonValue(t, tc.patch))
+		require.Nil(t, err)
+		require.Equal(t, tc.result, v.String())
+		require.True(t, v.(dgo.Freezable).Frozen())
+	}
+	require.Equal(t, `{"a":{"b":[1,2,3]},"c":"x"}`, doc.String())
+}
+
+func TestApplyPatch_persistent(t *testing.T) {
+	v, err := vf.ApplyPatch(vf.PersistentValues(1, 2), jsonValue(t, `[{"op":"add","path":"/1","value":3}]`))
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(1, 3, 2), v)
+}
+
+func TestApplyPatch_mutable(t *testing.T) {
+	m := vf.MutableMap(0, nil)
+	m.Put(`a`, vf.MutableValues(nil, 1))
+	v, err := vf.ApplyPatch(m, jsonValue(t, `[{"op":"test","path":"/a/0","value":1}]`))
+	require.Nil(t, err)
+	require.True(t, v.(dgo.Map).Frozen())
+	require.False(t, m.Frozen())
+}
+
+func TestApplyPatch_errors(t *testing.T) {
+	doc := jsonValue(t, `{"a":{"b":[1,2,3]},"c":"x"}`)
+	tests := []struct {
+		patch string
+		error string
+	}{
+		{`{}`, `json patch must be an array, got {}`},
+		{`[1]`, `json patch operation 0: operation must be a map, got 1`},
+		{`[{"path":"/c"}]`, `json patch operation 0: operation has no "op"`},
+		{`[{"op":1,"path":"/c"}]`, `json patch operation 0: operation "op" must be a string, got 1`},
+		{`[{"op":"add"}]`, `json patch operation 0: operation has no "path"`},
+		{`[{"op":"add","path":"c","value":1}]`, `json patch operation 0: json pointer "c" does not start with '/'`},
+		{`[{"op":"add","path":"/c"}]`, `json patch operation 0: add operation has no "value"`},
+		{`[{"op":"add","path":"/a/b/4","value":1}]`,
+			`json patch operation 0: json pointer /a/b/4: index 4 is out of range for an array of length 3`},
+		{`[{"op":"add","path":"/x/y","value":1}]`, `json patch operation 0: json pointer /x: no entry found for key "x"`},
+		{`[{"op":"replace","path":"/d","value":1}]`, `json patch operation 0: json pointer /d: no entry found for key "d"`},
+		{`[{"op":"remove","path":"/d"}]`, `json patch operation 0: json pointer /d: no entry found for key "d"`},
+		{`[{"op":"test","path":"/d","value":1}]`, `json patch operation 0: json pointer /d: no entry found for key "d"`},
+		{`[{"op":"test","path":"/c","value":1}]`, `json patch operation 0: test failed for path /c: expected 1, got x`},
+		{`[{"op":"add","path":"/c","value":1},{"op":"foo","path":"/c"}]`, `json patch operation 1: unknown operation "foo"`},
+		{`[{"op":"move","path":"/c"}]`, `json patch operation 0: operation has no "from"`},
+		{`[{"op":"move","from":"/d","path":"/c"}]`, `json patch operation 0: json pointer /d: no entry found for key "d"`},
+		{`[{"op":"move","from":"/a","path":"/a/b"}]`, `json patch operation 0: cannot move /a into one of its children`},
+		{`[{"op":"move","from":"","path":"/c"}]`, `json patch operation 0: cannot move  into one of its children`},
+	}
+	for _, tc := range tests {
+		_, err := vf.ApplyPatch(doc, jsonValue(t, tc.patch))
+		require.NotNil(t, err)
+		require.Equal(t, tc.error, err.Error())
+	}
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		target string
+		patch  string
+		result string
+	}{
+		{`{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{`{"a":"b"}`, `{"a":null}`, `{}`},
+		{`{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{`{"a":["b"]}`, `{"a":"c"}`, `{"a":"c"}`},
+		{`{"a":"c"}`, `{"a":["b"]}`, `{"a":["b"]}`},
+		{`{"a":{"b":"c"}}`, `{"a":{"b":"d","c":null}}`, `{"a":{"b":"d"}}`},
+		{`{"a":[{"b":"c"}]}`, `{"a":[1]}`, `{"a":[1]}`},
+		{`["a","b"]`, `["c","d"]`, `["c","d"]`},
+		{`{"a":"b"}`, `["c"]`, `["c"]`},
+		{`{"a":"foo"}`, `null`, `null`},
+		{`{"a":"foo"}`, `"bar"`, `bar`},
+		{`{"e":null}`, `{"a":1}`, `{"e":null,"a":1}`},
+		{`[1,2]`, `{"a":"b","c":null}`, `{"a":"b"}`},
+		{`{}`, `{"a":{"bb":{"ccc":null}}}`, `{"a":{"bb":{}}}`},
+	}
+	for _, tc := range tests {
+		v := vf.ApplyMergePatch(jsonValue(t, tc.target), jsonValue(t, tc.patch))
+		require.Equal(t, tc.result, v.String())
+	}
+}
+
+func TestCreatePatch(t *testing.T) {
+	tests := []struct {
+		a     string
+		b     string
+		patch string
+	}{
+		{`{"a":1}`, `{"a":1}`, `[]`},
+		{`{"a":1,"b":2}`, `{"a":3,"c":4}`,
+			`[{"op":"replace","path":"/a","value":3},{"op":"remove","path":"/b"},{"op":"add","path":"/c","value":4}]`},
+		{`{"a/b":{"c~":[1]}}`, `{"a/b":{"c~":[2]}}`, `[{"op":"replace","path":"/a~1b/c~0/0","value":2}]`},
+		{`[1,2,3,4,5]`, `[1,3,4,7,8,5,9]`,
+			`[{"op":"remove","path":"/1"},{"op":"add","path":"/3","value":7},{"op":"add","path":"/4","value":8},` +
+				`{"op":"add","path":"/6","value":9}]`},
+		{`[1,2,3]`, `[4,3]`, `[{"op":"replace","path":"/0","value":4},{"op":"remove","path":"/1"}]`},
+		{`[{"a":1},2]`, `[{"a":2}]`, `[{"op":"replace","path":"/0/a","value":2},{"op":"remove","path":"/1"}]`},
+		{`[1]`, `{"a":1}`, `[{"op":"replace","path":"","value":{"a":1}}]`},
+		{`"x"`, `"y"`, `[{"op":"replace","path":"","value":"y"}]`},
+	}
+	for _, tc := range tests {
+		a := jsonValue(t, tc.a)
+		b := jsonValue(t, tc.b)
+		p := vf.CreatePatch(a, b)
+		require.Equal(t, tc.patch, p.String())
+		v, err := vf.ApplyPatch(a, p)
+		require.Nil(t, err)
+		require.Equal(t, b, v)
+	}
+}
+
+func TestCreatePatch_recursive(t *testing.T) {
+	a := vf.MutableMap(0, nil)
+	a.Put(`x`, 1)
+	a.Put(`self`, a)
+	b := vf.MutableMap(0, nil)
+	b.Put(`x`, 2)
+	b.Put(`self`, b)
+	require.Equal(t, `[{"op":"replace","path":"/x","value":2}]`, vf.CreatePatch(a, b).String())
+}
+
+func TestCreateMergePatch(t *testing.T) {
+	tests := []struct {
+		a     string
+		b     string
+		patch string
+	}{
+		{`{"a":1}`, `{"a":1}`, `{}`},
+		{`{"a":1,"b":2,"c":{"d":1,"e":2}}`, `{"a":3,"c":{"d":1},"f":[1]}`, `{"a":3,"b":null,"c":{"e":null},"f":[1]}`},
+		{`[1]`, `{"a":1}`, `{"a":1}`},
+		{`{"a":1}`, `[1]`, `[1]`},
+	}
+	for _, tc := range tests {
+		a := jsonValue(t, tc.a)
+		b := jsonValue(t, tc.b)
+		p := vf.CreateMergePatch(a, b)
+		require.Equal(t, tc.patch, p.String())
+		require.Equal(t, b, vf.ApplyMergePatch(a, p))
+	}
+}
+
+func TestCreateMergePatch_keepsMutable(t *testing.T) {
+	a := vf.Map(map[string]int{`a`: 1})
+	b := vf.MutableMap(2, nil)
+	nb := vf.MutableValues(nil, 1)
+	nm := vf.MutableMap(1, nil)
+	b.Put(`b`, nb)
+	b.Put(`c`, nm)
+	p := vf.CreateMergePatch(a, b)
+	require.Equal(t, `{"a":null,"b":[1],"c":{}}`, p.String())
+	require.True(t, p.(dgo.Freezable).Frozen())
+	require.False(t, b.Frozen())
+	require.False(t, nb.Frozen())
+	require.False(t, nm.Frozen())
+	nb.Add(2)
+	nm.Put(`d`, 1)
+	require.Equal(t, `{"a":null,"b":[1],"c":{}}`, p.String())
+}
@@ -0,0 +1,166 @@
+package internal
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+const (
+	updateCount  = 1000
+	mapElemCount = 10000
+	mapUpdates   = 100
+)
+
+func benchSlice() []dgo.Value {
+	s := make([]dgo.Value, elemCount)
+	for i := 0; i < elemCount; i++ {
+		s[i] = Integer(i)
+	}
+	return s
+}
+
+// BenchmarkArrayEach `a.Each(x)` on an array
+func BenchmarkArrayEach(b *testing.B) {
+	a := Array(benchSlice())
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		a.Each(x)
+	}
+}
+
+// BenchmarkPersistentArrayEach `a.Each(x)` on a persistent array
+//
+// about 2 times slower than BenchmarkArrayEach due to the leaf lookups
+func BenchmarkPersistentArrayEach(b *testing.B) {
+	a := PersistentArray(benchSlice())
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		a.Each(x)
+	}
+}
+
+// BenchmarkArrayGet `for i := 0; i < l; i++ { x(a.Get(i)) }` on an array
+func BenchmarkArrayGet(b *testing.B) {
+	a := Array(benchSlice())
+
+	b.ResetTimer()
+	l := a.Len()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < l; i++ {
+			x(a.Get(i))
+		}
+	}
+}
+
+// BenchmarkPersistentArrayGet `for i := 0; i < l; i++ { x(a.Get(i)) }` on a persistent array
+//
+// about 4 times slower than BenchmarkArrayGet since each Get traverses the trie
+func BenchmarkPersistentArrayGet(b *testing.B) {
+	a := PersistentArray(benchSlice())
+
+	b.ResetTimer()
+	l := a.Len()
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < l; i++ {
+			x(a.Get(i))
+		}
+	}
+}
+
+// BenchmarkArrayWith appends updateCount values, one at a time, to a large frozen array
+func BenchmarkArrayWith(b *testing.B) {
+	a := Array(benchSlice())
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := a
+		for i := 0; i < updateCount; i++ {
+			c = c.With(Integer(i))
+		}
+	}
+}
+
+// BenchmarkPersistentArrayWith appends updateCount values, one at a time, to a large persistent array
+//
+// about 15 times faster than BenchmarkArrayWith and the difference grows with the array size
+func BenchmarkPersistentArrayWith(b *testing.B) {
+	a := PersistentArray(benchSlice())
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := a
+		for i := 0; i < updateCount; i++ {
+			c = c.With(Integer(i))
+		}
+	}
+}
+
+func benchMap(m dgo.Map) dgo.Map {
+	s := make(map[string]int, mapElemCount)
+	for i := 0; i < mapElemCount; i++ {
+		s[strconv.Itoa(i)] = i
+	}
+	return m.Merge(Map(s))
+}
+
+// BenchmarkMapWith sets mapUpdates keys, one at a time, in a large frozen map
+func BenchmarkMapWith(b *testing.B) {
+	m := benchMap(emptyMap)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := m
+		for i := 0; i < mapUpdates; i++ {
+			c = c.With(strconv.Itoa(i), -i)
+		}
+	}
+}
+
+// BenchmarkPersistentMapWith sets mapUpdates keys, one at a time, in a large persistent map
+//
+// more than 500 times faster than BenchmarkMapWith
+func BenchmarkPersistentMapWith(b *testing.B) {
+	m := benchMap(emptyPersistentMap)
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		c := m
+		for i := 0; i < mapUpdates; i++ {
+			c = c.With(strconv.Itoa(i), -i)
+		}
+	}
+}
+
+// BenchmarkMapGet looks up all keys of a large map
+func BenchmarkMapGet(b *testing.B) {
+	m := benchMap(emptyMap)
+	ks := m.Keys()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		ks.Each(func(k dgo.Value) {
+			v, _ := m.Get(k)
+			x(v)
+		})
+	}
+}
+
+// BenchmarkPersistentMapGet looks up all keys of a large persistent map
+//
+// about 4 times slower than BenchmarkMapGet
+func BenchmarkPersistentMapGet(b *testing.B) {
+	m := benchMap(emptyPersistentMap)
+	ks := m.Keys()
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		ks.Each(func(k dgo.Value) {
+			v, _ := m.Get(k)
+			x(v)
+		})
+	}
+}
@@ -0,0 +1,322 @@
+package internal
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+)
+
+// persistentArray is an always frozen dgo.Array that is backed by a pvector. The With, WithAll,
+// and WithValues methods share structure with the receiver instead of copying it.
+type persistentArray struct {
+	v *pvector
+}
+
+var emptyPersistentArray = &persistentArray{v: emptyPVector}
+
+// PersistentArray returns a frozen dgo.Array that shares structure with the arrays that are derived
+// from it using the With, WithAll, and WithValues methods. Mutable elements are frozen.
+func PersistentArray(slice []dgo.Value) dgo.Array {
+	if len(slice) == 0 {
+		return emptyPersistentArray
+	}
+	fs := make([]dgo.Value, len(slice))
+	for i := range slice {
+		fs[i] = frozenCopy(slice[i])
+	}
+	return &persistentArray{v: pvectorFromSlice(fs)}
+}
+
+// PersistentValues returns a frozen dgo.Array that shares structure with the arrays that are derived
+// from it using the With, WithAll, and WithValues methods. The values are converted to dgo.Value and
+// frozen.
+func PersistentValues(values []interface{}) dgo.Array {
+	if len(values) == 0 {
+		return emptyPersistentArray
+	}
+	return &persistentArray{v: pvectorFromSlice(valueSlice(values, true))}
+}
+
+// persistent returns a persistentArray that contains the elements of the given Array
+func persistent(a dgo.Array) *persistentArray {
+	if pa, ok := a.(*persistentArray); ok {
+		return pa
+	}
+	return PersistentArray(sliceOf(a)).(*persistentArray)
+}
+
+func (v *persistentArray) Add(vi interface{}) {
+	panic(frozenArray(`Add`))
+}
+
+func (v *persistentArray) AddAll(values dgo.Array) {
+	panic(frozenArray(`AddAll`))
+}
+
+func (v *persistentArray) AddValues(values ...interface{}) {
+	panic(frozenArray(`AddValues`))
+}
+
+func (v *persistentArray) All(predicate dgo.Predicate) bool {
+	return v.v.each(func(e dgo.Value, _ int) bool { return predicate(e) })
+}
+
+func (v *persistentArray) Any(predicate dgo.Predicate) bool {
+	return !v.v.each(func(e dgo.Value, _ int) bool { return !predicate(e) })
+}
+
+func (v *persistentArray) AppendTo(w *util.Indenter) {
+	w.AppendRune('[')
+	ew := w.Indent()
+	v.v.each(func(e dgo.Value, i int) bool {
+		if i > 0 {
+			w.AppendRune(',')
+		}
+		ew.NewLine()
+		ew.AppendValue(e)
+		return true
+	})
+	w.NewLine()
+	w.AppendRune(']')
+}
+
+func (v *persistentArray) AppendToSlice(slice []dgo.Value) []dgo.Value {
+	return v.v.appendTo(slice)
+}
+
+func (v *persistentArray) CompareTo(other interface{}) (int, bool) {
+	return compare(nil, v, Value(other))
+}
+
+func (v *persistentArray) deepCompare(seen []dgo.Value, other deepCompare) (int, bool) {
+	return v.toArray().deepCompare(seen, other)
+}
+
+func (v *persistentArray) Copy(frozen bool) dgo.Array {
+	if frozen {
+		return v
+	}
+	return &array{slice: v.v.appendTo(make([]dgo.Value, 0, v.v.cnt))}
+}
+
+func (v *persistentArray) Each(doer dgo.Doer) {
+	v.v.each(func(e dgo.Value, _ int) bool {
+		doer(e)
+		return true
+	})
+}
+
+func (v *persistentArray) EachWithIndex(doer dgo.DoWithIndex) {
+	v.v.each(func(e dgo.Value, i int) bool {
+		doer(e, i)
+		return true
+	})
+}
+
+func (v *persistentArray) Equals(other interface{}) bool {
+	return equals(nil, v, Value(other))
+}
+
+func (v *persistentArray) deepEqual(seen []dgo.Value, other deepEqual) bool {
+	if ov, ok := other.(dgo.Array); ok && v.v.cnt == ov.Len() {
+		if op, ok := ov.(*persistentArray); ok && op.v == v.v {
+			return true
+		}
+		return v.v.each(func(e dgo.Value, i int) bool { return equals(seen, e, ov.Get(i)) })
+	}
+	return false
+}
+
+func (v *persistentArray) Freeze() {
+}
+
+func (v *persistentArray) Frozen() bool {
+	return true
+}
+
+func (v *persistentArray) FrozenCopy() dgo.Value {
+	return v
+}
+
+func (v *persistentArray) Get(index int) dgo.Value {
+	return v.v.get(index)
+}
+
+func (v *persistentArray) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v *persistentArray) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
+func (v *persistentArray) HashCode() int {
+	return v.deepHashCode(nil)
+}
+
+func (v *persistentArray) deepHashCode(seen []dgo.Value) int {
+	h := 1
+	v.v.each(func(e dgo.Value, _ int) bool {
+		h = h*31 + deepHashCode(seen, e)
+		return true
+	})
+	return h
+}
+
+func (v *persistentArray) IndexOf(vi interface{}) int {
+	val := Value(vi)
+	found := -1
+	v.v.each(func(e dgo.Value, i int) bool {
+		if val.Equals(e) {
+			found = i
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func (v *persistentArray) Insert(pos int, val interface{}) {
+	panic(frozenArray(`Insert`))
+}
+
+func (v *persistentArray) Len() int {
+	return v.v.cnt
+}
+
+func (v *persistentArray) MapTo(t dgo.ArrayType, mapper dgo.Mapper) dgo.Array {
+	return persistent(v.toArray().MapTo(t, mapper))
+}
+
+func (v *persistentArray) Map(mapper dgo.Mapper) dgo.Array {
+	return persistent(v.toArray().Map(mapper))
+}
+
+func (v *persistentArray) MarshalJSON() ([]byte, error) {
+	return []byte(util.ToString(v)), nil
+}
+
+func (v *persistentArray) MarshalYAML() (interface{}, error) {
+	return yamlEncodeValue(v)
+}
+
+func (v *persistentArray) One(predicate dgo.Predicate) bool {
+	f := false
+	return v.v.each(func(e dgo.Value, _ int) bool {
+		if predicate(e) {
+			if f {
+				return false
+			}
+			f = true
+		}
+		return true
+	}) && f
+}
+
+func (v *persistentArray) Pop() (dgo.Value, bool) {
+	panic(frozenArray(`Pop`))
+}
+
+func (v *persistentArray) Reduce(mi interface{}, reductor func(memo dgo.Value, elem dgo.Value) interface{}) dgo.Value {
+	memo := Value(mi)
+	v.v.each(func(e dgo.Value, _ int) bool {
+		memo = Value(reductor(memo, e))
+		return true
+	})
+	return memo
+}
+
+func (v *persistentArray) Reject(predicate dgo.Predicate) dgo.Array {
+	return persistent(v.toArray().Reject(predicate))
+}
+
+func (v *persistentArray) Remove(pos int) dgo.Value {
+	panic(frozenArray(`Remove`))
+}
+
+func (v *persistentArray) RemoveValue(value interface{}) bool {
+	panic(frozenArray(`RemoveValue`))
+}
+
+func (v *persistentArray) SameValues(other dgo.Array) bool {
+	return v.toArray().SameValues(other)
+}
+
+func (v *persistentArray) Select(predicate dgo.Predicate) dgo.Array {
+	return persistent(v.toArray().Select(predicate))
+}
+
+func (v *persistentArray) Set(pos int, val interface{}) dgo.Value {
+	panic(frozenArray(`Set`))
+}
+
+func (v *persistentArray) SetType(t dgo.ArrayType) {
+	panic(frozenArray(`SetType`))
+}
+
+func (v *persistentArray) Sort() dgo.Array {
+	if v.v.cnt < 2 {
+		return v
+	}
+	return persistent(v.toArray().Sort())
+}
+
+func (v *persistentArray) String() string {
+	return util.ToString(v)
+}
+
+func (v *persistentArray) ToMap() dgo.Map {
+	return persistentMapFrom(v.toArray().ToMap())
+}
+
+func (v *persistentArray) ToMapFromEntries() (dgo.Map, bool) {
+	m, ok := v.toArray().ToMapFromEntries()
+	if ok {
+		m = persistentMapFrom(m)
+	}
+	return m, ok
+}
+
+func (v *persistentArray) Type() dgo.Type {
+	return (*exactArrayType)(v.toArray())
+}
+
+func (v *persistentArray) Unique() dgo.Array {
+	u := v.toArray().Unique()
+	if u.Len() == v.v.cnt {
+		return v
+	}
+	return persistent(u)
+}
+
+func (v *persistentArray) UnmarshalJSON(b []byte) error {
+	panic(frozenArray(`UnmarshalJSON`))
+}
+
+func (v *persistentArray) With(vi interface{}) dgo.Array {
+	return &persistentArray{v: v.v.push(frozenCopy(Value(vi)))}
+}
+
+func (v *persistentArray) WithAll(values dgo.Array) dgo.Array {
+	if values.Len() == 0 {
+		return v
+	}
+	pv := v.v
+	values.Each(func(e dgo.Value) { pv = pv.push(frozenCopy(e)) })
+	return &persistentArray{v: pv}
+}
+
+func (v *persistentArray) WithValues(values ...interface{}) dgo.Array {
+	if len(values) == 0 {
+		return v
+	}
+	pv := v.v
+	for i := range values {
+		pv = pv.push(frozenCopy(Value(values[i])))
+	}
+	return &persistentArray{v: pv}
+}
+
+// toArray returns a frozen *array with the elements of this persistentArray
+func (v *persistentArray) toArray() *array {
+	return &array{slice: v.v.appendTo(make([]dgo.Value, 0, v.v.cnt)), frozen: true}
+}
@@ -0,0 +1,191 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+	"gopkg.in/yaml.v3"
+)
+
+func ExamplePersistentValues() {
+	a := vf.PersistentValues(1, 2)
+	b := a.With(3)
+	fmt.Println(a)
+	fmt.Println(b)
+	// Output:
+	// [1,2]
+	// [1,2,3]
+}
+
+func TestPersistentArray_large(t *testing.T) {
+	const n = 40000
+	a := vf.PersistentValues()
+	vs := make([]dgo.Value, 0, n)
+	for i := 0; i < n; i++ {
+		a = a.With(i)
+		vs = append(vs, vf.Integer(int64(i)))
+	}
+	require.Equal(t, n, a.Len())
+	for i := 0; i < n; i++ {
+		if a.Get(i) != vf.Integer(int64(i)) {
+			t.Fatalf(`unexpected value at %d: %v`, i, a.Get(i))
+		}
+	}
+	b := vf.PersistentArray(vs)
+	require.Equal(t, a, b)
+	require.Equal(t, b, a)
+	require.Equal(t, a.HashCode(), vf.Array(vs).HashCode())
+	require.Equal(t, vf.Array(vs), a)
+
+	c := b.WithValues(`x`, `y`)
+	require.Equal(t, n, b.Len())
+	require.Equal(t, n+2, c.Len())
+	require.Equal(t, `y`, c.Get(n+1))
+	require.Equal(t, n+1, c.IndexOf(`y`))
+	require.Equal(t, -1, b.IndexOf(`y`))
+
+	require.Panic(t, func() { a.Get(n) }, `index out of range`)
+	require.Panic(t, func() { a.Get(-1) }, `index out of range`)
+}
+
+func TestPersistentArray_frozen(t *testing.T) {
+	m := vf.MutableValues(nil, `a`)
+	a := vf.PersistentValues(m)
+	require.True(t, a.Frozen())
+	require.True(t, a.Get(0).(dgo.Freezable).Frozen())
+	require.False(t, m.Frozen())
+	require.Same(t, a, a.FrozenCopy())
+	require.Same(t, a, a.Copy(true))
+	a.Freeze()
+
+	c := a.Copy(false)
+	require.False(t, c.Frozen())
+	c.Add(`b`)
+	require.Equal(t, 1, a.Len())
+
+	require.Panic(t, func() { a.Add(1) }, `Add .* frozen`)
+	require.Panic(t, func() { a.AddAll(vf.Values(1)) }, `AddAll .* frozen`)
+	require.Panic(t, func() { a.AddValues(1) }, `AddValues .* frozen`)
+	require.Panic(t, func() { a.Insert(0, 1) }, `Insert .* frozen`)
+	require.Panic(t, func() { a.Pop() }, `Pop .* frozen`)
+	require.Panic(t, func() { a.Remove(0) }, `Remove .* frozen`)
+	require.Panic(t, func() { a.RemoveValue(1) }, `RemoveValue .* frozen`)
+	require.Panic(t, func() { a.Set(0, 1) }, `Set .* frozen`)
+	require.Panic(t, func() { a.SetType(typ.Array) }, `SetType .* frozen`)
+	require.Panic(t, func() { _ = a.UnmarshalJSON([]byte(`[]`)) }, `UnmarshalJSON .* frozen`)
+}
+
+func TestPersistentArray_With(t *testing.T) {
+	a := vf.PersistentValues(1, 2)
+	require.Same(t, a, a.WithAll(vf.Values()))
+	require.Same(t, a, a.WithValues())
+	require.Equal(t, vf.Values(1, 2, 3, 4), a.WithAll(vf.Values(3, 4)))
+	require.Equal(t, vf.Values(1, 2, 3), a.With(3))
+	require.Equal(t, vf.Values(1, 2), a)
+}
+
+func TestPersistentArray_iteration(t *testing.T) {
+	a := vf.PersistentValues(1, 2, 3, 2)
+	require.True(t, a.All(func(e dgo.Value) bool { return e.(dgo.Integer).GoInt() > 0 }))
+	require.False(t, a.All(func(e dgo.Value) bool { return e.(dgo.Integer).GoInt() > 1 }))
+	require.True(t, a.Any(func(e dgo.Value) bool { return e.Equals(3) }))
+	require.False(t, a.Any(func(e dgo.Value) bool { return e.Equals(4) }))
+	require.True(t, a.One(func(e dgo.Value) bool { return e.Equals(3) }))
+	require.False(t, a.One(func(e dgo.Value) bool { return e.Equals(2) }))
+	require.False(t, a.One(func(e dgo.Value) bool { return e.Equals(4) }))
+
+	s := 0
+	a.Each(func(e dgo.Value) { s += int(e.(dgo.Integer).GoInt()) })
+	require.Equal(t, 8, s)
+
+	s = 0
+	a.EachWithIndex(func(e dgo.Value, i int) { s += i })
+	require.Equal(t, 6, s)
+
+	require.Equal(t, 8, a.Reduce(0, func(m, e dgo.Value) interface{} {
+		return m.(dgo.Integer).GoInt() + e.(dgo.Integer).GoInt()
+	}))
+	require.Equal(t, []dgo.Value{vf.Integer(1), vf.Integer(2), vf.Integer(3), vf.Integer(2)}, a.AppendToSlice(nil))
+}
+
+func TestPersistentArray_derived(t *testing.T) {
+	a := vf.PersistentValues(3, 1, 2, 1)
+	double := func(e dgo.Value) interface{} { return e.(dgo.Integer).GoInt() * 2 }
+	odd := func(e dgo.Value) bool { return e.(dgo.Integer).GoInt()%2 == 1 }
+
+	require.Equal(t, vf.Values(6, 2, 4, 2), a.Map(double))
+	require.Equal(t, vf.Values(6, 2, 4, 2), a.MapTo(newtype.Array(typ.Integer), double))
+	require.Equal(t, vf.Values(3, 1, 1), a.Select(odd))
+	require.Equal(t, vf.Values(2), a.Reject(odd))
+	require.Equal(t, vf.Values(1, 1, 2, 3), a.Sort())
+	require.Equal(t, vf.Values(3, 1, 2), a.Unique())
+	require.True(t, a.SameValues(vf.Values(1, 1, 2, 3)))
+
+	u := vf.PersistentValues(1, 2)
+	require.Same(t, u, u.Unique())
+	s := vf.PersistentValues(1)
+	require.Same(t, s, s.Sort())
+
+	for _, d := range []dgo.Array{a.Map(double), a.Select(odd), a.Sort(), a.Unique()} {
+		require.Panic(t, func() { d.Add(0) }, `Add .* frozen`)
+	}
+}
+
+func TestPersistentArray_compare(t *testing.T) {
+	a := vf.PersistentValues(1, 2)
+	c, ok := a.CompareTo(vf.Values(1, 3))
+	require.True(t, ok)
+	require.Equal(t, -1, c)
+	c, ok = vf.Values(1, 3).CompareTo(a)
+	require.True(t, ok)
+	require.Equal(t, 1, c)
+	c, ok = a.CompareTo(vf.PersistentValues(1, 2))
+	require.True(t, ok)
+	require.Equal(t, 0, c)
+	require.NotEqual(t, a, vf.Values(1, 3))
+	require.NotEqual(t, a, vf.Values(1))
+	require.NotEqual(t, a, `a`)
+}
+
+func TestPersistentArray_types(t *testing.T) {
+	a := vf.PersistentValues(1, `two`)
+	require.Instance(t, a.Type(), a)
+	require.Instance(t, a.Type(), vf.Values(1, `two`))
+	require.Instance(t, vf.Values(1, `two`).Type(), a)
+	require.Instance(t, typ.Array, a)
+	require.Instance(t, newtype.Array(newtype.AnyOf(typ.Integer, typ.String), 2, 2), a)
+	require.NotInstance(t, newtype.Array(typ.Integer), a)
+	require.Instance(t, newtype.Tuple(typ.Integer, typ.String), a)
+	require.NotInstance(t, newtype.Tuple(typ.String, typ.Integer), a)
+	require.Equal(t, `{1,"two"}`, a.Type().String())
+}
+
+func TestPersistentArray_ToMap(t *testing.T) {
+	m := vf.PersistentValues(`a`, 1, `b`, 2).ToMap()
+	require.Equal(t, vf.Map(map[string]int{`a`: 1, `b`: 2}), m)
+	require.True(t, m.Frozen())
+
+	m, ok := vf.PersistentValues(vf.Values(`a`, 1), vf.Values(`b`, 2)).ToMapFromEntries()
+	require.True(t, ok)
+	require.Equal(t, vf.Map(map[string]int{`a`: 1, `b`: 2}), m)
+
+	_, ok = vf.PersistentValues(1).ToMapFromEntries()
+	require.False(t, ok)
+}
+
+func TestPersistentArray_marshal(t *testing.T) {
+	a := vf.PersistentValues(1, `two`)
+	require.Equal(t, `[1,"two"]`, a.String())
+	b, err := a.MarshalJSON()
+	require.Nil(t, err)
+	require.Equal(t, `[1,"two"]`, string(b))
+
+	b, err = yaml.Marshal(a)
+	require.Nil(t, err)
+	require.Equal(t, "- 1\n- two\n", string(b))
+}
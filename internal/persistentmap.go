@@ -0,0 +1,319 @@
+package internal
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+)
+
+// persistentMap is an always frozen dgo.Map that is backed by a hash array mapped trie. The With,
+// Without, WithoutAll, and Merge methods share structure with the receiver instead of copying it.
+//
+// The trie maps each key to a position in a pvector of *hashNode entries. The pvector preserves
+// the insertion order. Removed entries are replaced with nil in the pvector and the pvector is
+// compacted when more than half of its positions are unused.
+type persistentMap struct {
+	root  *hamtNode
+	order *pvector
+	len   int
+}
+
+var emptyPersistentMap = &persistentMap{root: emptyHamt, order: emptyPVector}
+
+// PersistentMap returns a frozen dgo.Map that shares structure with the maps that are derived from it
+// using the With, Without, WithoutAll, and Merge methods. The argument must be a dgo.Map or a go map.
+// Mutable keys and values are frozen.
+func PersistentMap(m interface{}) dgo.Map {
+	if dm, ok := m.(dgo.Map); ok {
+		return persistentMapFrom(dm)
+	}
+	return persistentMapFrom(Map(m))
+}
+
+// persistentMapFrom returns a persistentMap that contains the entries of the given Map
+func persistentMapFrom(m dgo.Map) *persistentMap {
+	if pm, ok := m.(*persistentMap); ok {
+		return pm
+	}
+	if m.Len() == 0 {
+		return emptyPersistentMap
+	}
+	es := make([]dgo.Value, 0, m.Len())
+	root := emptyHamt
+	m.Each(func(e dgo.MapEntry) {
+		k := frozenCopy(e.Key())
+		root, _ = root.assoc(0, hamtHash(k), k, len(es))
+		es = append(es, &hashNode{key: k, value: frozenCopy(e.Value())})
+	})
+	return &persistentMap{root: root, order: pvectorFromSlice(es), len: len(es)}
+}
+
+func (g *persistentMap) All(predicate dgo.EntryPredicate) bool {
+	return g.each(func(e *hashNode) bool { return predicate(e) })
+}
+
+func (g *persistentMap) AllKeys(predicate dgo.Predicate) bool {
+	return g.each(func(e *hashNode) bool { return predicate(e.key) })
+}
+
+func (g *persistentMap) AllValues(predicate dgo.Predicate) bool {
+	return g.each(func(e *hashNode) bool { return predicate(e.value) })
+}
+
+func (g *persistentMap) Any(predicate dgo.EntryPredicate) bool {
+	return !g.each(func(e *hashNode) bool { return !predicate(e) })
+}
+
+func (g *persistentMap) AnyKey(predicate dgo.Predicate) bool {
+	return !g.each(func(e *hashNode) bool { return !predicate(e.key) })
+}
+
+func (g *persistentMap) AnyValue(predicate dgo.Predicate) bool {
+	return !g.each(func(e *hashNode) bool { return !predicate(e.value) })
+}
+
+func (g *persistentMap) AppendTo(w *util.Indenter) {
+	w.AppendRune('{')
+	ew := w.Indent()
+	first := true
+	g.each(func(e *hashNode) bool {
+		if first {
+			first = false
+		} else {
+			ew.AppendRune(',')
+		}
+		ew.NewLine()
+		ew.AppendValue(e)
+		return true
+	})
+	w.NewLine()
+	w.AppendRune('}')
+}
+
+func (g *persistentMap) Copy(frozen bool) dgo.Map {
+	if frozen {
+		return g
+	}
+	c := MutableMap(g.len, nil)
+	g.each(func(e *hashNode) bool {
+		c.Put(e.key, e.value)
+		return true
+	})
+	return c
+}
+
+func (g *persistentMap) Each(doer dgo.EntryDoer) {
+	g.each(func(e *hashNode) bool {
+		doer(e)
+		return true
+	})
+}
+
+func (g *persistentMap) EachKey(doer dgo.Doer) {
+	g.each(func(e *hashNode) bool {
+		doer(e.key)
+		return true
+	})
+}
+
+func (g *persistentMap) EachValue(doer dgo.Doer) {
+	g.each(func(e *hashNode) bool {
+		doer(e.value)
+		return true
+	})
+}
+
+func (g *persistentMap) Entries() dgo.Array {
+	es := make([]dgo.Value, 0, g.len)
+	g.each(func(e *hashNode) bool {
+		// Entries are copied since a map created from them will link them
+		es = append(es, &hashNode{key: e.key, value: e.value})
+		return true
+	})
+	return &array{slice: es, frozen: true}
+}
+
+func (g *persistentMap) Equals(other interface{}) bool {
+	return equals(nil, g, Value(other))
+}
+
+func (g *persistentMap) deepEqual(seen []dgo.Value, other deepEqual) bool {
+	if om, ok := other.(dgo.Map); ok && g.len == om.Len() {
+		if op, ok := om.(*persistentMap); ok && op.root == g.root && op.order == g.order {
+			return true
+		}
+		return g.each(func(e *hashNode) bool {
+			ov, ok := om.Get(e.key)
+			return ok && equals(seen, e.value, ov)
+		})
+	}
+	return false
+}
+
+func (g *persistentMap) Freeze() {
+}
+
+func (g *persistentMap) Frozen() bool {
+	return true
+}
+
+func (g *persistentMap) FrozenCopy() dgo.Value {
+	return g
+}
+
+func (g *persistentMap) Get(key interface{}) (dgo.Value, bool) {
+	k := Value(key)
+	if i, ok := g.root.find(hamtHash(k), k); ok {
+		return g.order.get(i).(*hashNode).value, true
+	}
+	return nil, false
+}
+
+func (g *persistentMap) GobDecode(b []byte) error {
+	return gobDecodeInto(b, g)
+}
+
+func (g *persistentMap) GobEncode() ([]byte, error) {
+	return gobEncode(g)
+}
+
+func (g *persistentMap) HashCode() int {
+	return deepHashCode(nil, g)
+}
+
+func (g *persistentMap) deepHashCode(seen []dgo.Value) int {
+	h := 1
+	g.each(func(e *hashNode) bool {
+		h += deepHashCode(seen, e)
+		return true
+	})
+	return h
+}
+
+func (g *persistentMap) Keys() dgo.Array {
+	ks := make([]dgo.Value, 0, g.len)
+	g.each(func(e *hashNode) bool {
+		ks = append(ks, e.key)
+		return true
+	})
+	return &array{slice: ks, frozen: true}
+}
+
+func (g *persistentMap) Len() int {
+	return g.len
+}
+
+func (g *persistentMap) MarshalJSON() ([]byte, error) {
+	return []byte(util.ToString(g)), nil
+}
+
+func (g *persistentMap) MarshalYAML() (interface{}, error) {
+	return yamlEncodeValue(g)
+}
+
+func (g *persistentMap) Merge(associations dgo.Map) dgo.Map {
+	if associations.Len() == 0 || SameInstance(g, associations) {
+		return g
+	}
+	if g.len == 0 {
+		return persistentMapFrom(associations)
+	}
+	c := g
+	associations.Each(func(e dgo.MapEntry) { c = c.with(e.Key(), e.Value()) })
+	return c
+}
+
+func (g *persistentMap) Put(key, value interface{}) dgo.Value {
+	panic(frozenMap(`Put`))
+}
+
+func (g *persistentMap) PutAll(associations dgo.Map) {
+	panic(frozenMap(`PutAll`))
+}
+
+func (g *persistentMap) Remove(key interface{}) dgo.Value {
+	panic(frozenMap(`Remove`))
+}
+
+func (g *persistentMap) RemoveAll(keys dgo.Array) {
+	panic(frozenMap(`RemoveAll`))
+}
+
+func (g *persistentMap) SetType(t dgo.MapType) {
+	panic(frozenMap(`SetType`))
+}
+
+func (g *persistentMap) String() string {
+	return util.ToString(g)
+}
+
+func (g *persistentMap) Type() dgo.Type {
+	return (*exactMapType)(g.Copy(false).(*hashMap))
+}
+
+func (g *persistentMap) UnmarshalJSON(b []byte) error {
+	panic(frozenMap(`UnmarshalJSON`))
+}
+
+func (g *persistentMap) Values() dgo.Array {
+	vs := make([]dgo.Value, 0, g.len)
+	g.each(func(e *hashNode) bool {
+		vs = append(vs, e.value)
+		return true
+	})
+	return &array{slice: vs, frozen: true}
+}
+
+func (g *persistentMap) With(key, value interface{}) dgo.Map {
+	return g.with(Value(key), Value(value))
+}
+
+func (g *persistentMap) Without(key interface{}) dgo.Map {
+	return g.without(Value(key))
+}
+
+func (g *persistentMap) WithoutAll(keys dgo.Array) dgo.Map {
+	c := g
+	keys.Each(func(k dgo.Value) { c = c.without(k) })
+	return c
+}
+
+// each calls the given function with each entry in insertion order. Iteration stops when the
+// function returns false. The method returns false if iteration was stopped.
+func (g *persistentMap) each(f func(*hashNode) bool) bool {
+	return g.order.each(func(e dgo.Value, _ int) bool {
+		return e == nil || f(e.(*hashNode))
+	})
+}
+
+func (g *persistentMap) with(key, value dgo.Value) *persistentMap {
+	h := hamtHash(key)
+	if i, ok := g.root.find(h, key); ok {
+		e := g.order.get(i).(*hashNode)
+		if e.value.Equals(value) {
+			return g
+		}
+		return &persistentMap{root: g.root, order: g.order.set(i, &hashNode{key: e.key, value: frozenCopy(value)}), len: g.len}
+	}
+	key = frozenCopy(key)
+	root, _ := g.root.assoc(0, h, key, g.order.cnt)
+	return &persistentMap{root: root, order: g.order.push(&hashNode{key: key, value: frozenCopy(value)}), len: g.len + 1}
+}
+
+func (g *persistentMap) without(key dgo.Value) *persistentMap {
+	h := hamtHash(key)
+	i, ok := g.root.find(h, key)
+	if !ok {
+		return g
+	}
+	l := g.len - 1
+	if l == 0 {
+		return emptyPersistentMap
+	}
+	root, _ := g.root.dissoc(0, h, key)
+	c := &persistentMap{root: root, order: g.order.set(i, nil), len: l}
+	if c.order.cnt > pvWidth && c.order.cnt > 2*l {
+		// More than half of the positions are unused
+		c = persistentMapFrom(c.Copy(false))
+	}
+	return c
+}
@@ -0,0 +1,195 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+	"gopkg.in/yaml.v3"
+)
+
+func ExamplePersistentMap() {
+	a := vf.PersistentMap(map[string]int{`a`: 1})
+	b := a.With(`b`, 2)
+	fmt.Println(a)
+	fmt.Println(b)
+	fmt.Println(b.Without(`a`))
+	// Output:
+	// {"a":1}
+	// {"a":1,"b":2}
+	// {"b":2}
+}
+
+func TestPersistentMap_large(t *testing.T) {
+	const n = 20000
+	m := vf.PersistentMap(vf.Map(map[string]int{}))
+	h := vf.MutableMap(n, nil)
+	for i := 0; i < n; i++ {
+		k := fmt.Sprintf(`k%d`, i)
+		m = m.With(k, i)
+		h.Put(k, i)
+	}
+	require.Equal(t, n, m.Len())
+	for i := 0; i < n; i++ {
+		v, ok := m.Get(fmt.Sprintf(`k%d`, i))
+		if !(ok && v.Equals(i)) {
+			t.Fatalf(`unexpected value for k%d: %v`, i, v)
+		}
+	}
+	require.Equal(t, h, m)
+	require.Equal(t, m, h)
+	require.Equal(t, h.HashCode(), m.HashCode())
+	require.Equal(t, m, vf.PersistentMap(h))
+
+	// Remove all odd keys
+	r := m
+	for i := 1; i < n; i += 2 {
+		r = r.Without(fmt.Sprintf(`k%d`, i))
+	}
+	require.Equal(t, n/2, r.Len())
+	require.Equal(t, n, m.Len())
+	ks := r.Keys()
+	for i := 0; i < n/2; i++ {
+		if !ks.Get(i).Equals(fmt.Sprintf(`k%d`, i*2)) {
+			t.Fatalf(`unexpected key at %d: %v`, i, ks.Get(i))
+		}
+	}
+	_, ok := r.Get(`k1`)
+	require.False(t, ok)
+	_, ok = m.Get(`k1`)
+	require.True(t, ok)
+
+	// Remove everything
+	for i := 0; i < n; i += 2 {
+		r = r.Without(fmt.Sprintf(`k%d`, i))
+	}
+	require.Equal(t, 0, r.Len())
+	require.Equal(t, `{}`, r.String())
+}
+
+func TestPersistentMap_With(t *testing.T) {
+	a := vf.PersistentMap(map[string]int{`a`: 1})
+	require.Same(t, a, a.With(`a`, 1))
+	b := a.With(`a`, 2)
+	require.Equal(t, vf.Map(map[string]int{`a`: 2}), b)
+	require.Equal(t, vf.Map(map[string]int{`a`: 1}), a)
+	require.Same(t, a, a.Without(`b`))
+
+	c := a.With(`b`, 2).With(`c`, 3).WithoutAll(vf.Values(`a`, `c`, `d`))
+	require.Equal(t, vf.Map(map[string]int{`b`: 2}), c)
+
+	mv := vf.MutableValues(nil, 1)
+	d := a.With(mv, mv)
+	v, _ := d.Get(vf.Values(1))
+	require.True(t, v.(dgo.Freezable).Frozen())
+	require.False(t, mv.Frozen())
+}
+
+func TestPersistentMap_Merge(t *testing.T) {
+	a := vf.PersistentMap(map[string]int{`a`: 1, `b`: 2})
+	require.Same(t, a, a.Merge(vf.Map(map[string]int{})))
+	require.Same(t, a, a.Merge(a))
+
+	m := a.Merge(vf.Map(map[string]int{`b`: 3, `c`: 4}))
+	require.Equal(t, vf.Map(map[string]int{`a`: 1, `b`: 3, `c`: 4}), m)
+	require.True(t, m.Frozen())
+
+	e := vf.PersistentMap(map[string]int{})
+	require.Same(t, a, e.Merge(a))
+	require.Equal(t, a, e.Merge(vf.Map(map[string]int{`a`: 1, `b`: 2})))
+
+	h := vf.MutableMap(0, nil)
+	h.Put(`x`, 1)
+	require.Equal(t, vf.Map(map[string]int{`x`: 1, `a`: 1, `b`: 2}), h.Merge(a))
+}
+
+func TestPersistentMap_order(t *testing.T) {
+	m := vf.PersistentMap(map[string]int{}).With(`c`, 1).With(`a`, 2).With(`b`, 3).With(`a`, 4)
+	require.Equal(t, vf.Strings(`c`, `a`, `b`), m.Keys())
+	require.Equal(t, vf.Values(1, 4, 3), m.Values())
+	require.Equal(t, `{"c":1,"a":4,"b":3}`, m.String())
+	require.Equal(t, `{"a":4,"b":3}`, m.Without(`c`).String())
+}
+
+func TestPersistentMap_iteration(t *testing.T) {
+	m := vf.PersistentMap(map[string]int{`a`: 1, `b`: 2})
+	require.True(t, m.All(func(e dgo.MapEntry) bool { return e.Value().(dgo.Integer).GoInt() > 0 }))
+	require.False(t, m.All(func(e dgo.MapEntry) bool { return e.Value().Equals(1) }))
+	require.True(t, m.AllKeys(func(k dgo.Value) bool { _, ok := k.(dgo.String); return ok }))
+	require.False(t, m.AllValues(func(v dgo.Value) bool { return v.Equals(1) }))
+	require.True(t, m.Any(func(e dgo.MapEntry) bool { return e.Key().Equals(`b`) }))
+	require.False(t, m.Any(func(e dgo.MapEntry) bool { return e.Key().Equals(`c`) }))
+	require.True(t, m.AnyKey(func(k dgo.Value) bool { return k.Equals(`a`) }))
+	require.False(t, m.AnyValue(func(v dgo.Value) bool { return v.Equals(3) }))
+
+	s := 0
+	m.Each(func(e dgo.MapEntry) { s += int(e.Value().(dgo.Integer).GoInt()) })
+	m.EachValue(func(v dgo.Value) { s += int(v.(dgo.Integer).GoInt()) })
+	require.Equal(t, 6, s)
+
+	ks := ``
+	m.EachKey(func(k dgo.Value) { ks += k.String() })
+	require.Equal(t, `ab`, ks)
+
+	es := m.Entries()
+	require.Equal(t, 2, es.Len())
+	c, ok := es.ToMapFromEntries()
+	require.True(t, ok)
+	require.Equal(t, m, c)
+}
+
+func TestPersistentMap_frozen(t *testing.T) {
+	m := vf.PersistentMap(map[string]int{`a`: 1})
+	require.True(t, m.Frozen())
+	require.Same(t, m, m.FrozenCopy())
+	require.Same(t, m, m.Copy(true))
+	m.Freeze()
+
+	c := m.Copy(false)
+	require.False(t, c.Frozen())
+	c.Put(`b`, 2)
+	require.Equal(t, 1, m.Len())
+
+	require.Panic(t, func() { m.Put(`b`, 2) }, `Put .* frozen`)
+	require.Panic(t, func() { m.PutAll(c) }, `PutAll .* frozen`)
+	require.Panic(t, func() { m.Remove(`a`) }, `Remove .* frozen`)
+	require.Panic(t, func() { m.RemoveAll(vf.Strings(`a`)) }, `RemoveAll .* frozen`)
+	require.Panic(t, func() { m.SetType(typ.Map) }, `SetType .* frozen`)
+	require.Panic(t, func() { _ = m.UnmarshalJSON([]byte(`{}`)) }, `UnmarshalJSON .* frozen`)
+}
+
+func TestPersistentMap_types(t *testing.T) {
+	m := vf.PersistentMap(map[string]interface{}{`a`: 1, `b`: `two`})
+	require.Instance(t, m.Type(), m)
+	require.Instance(t, m.Type(), vf.Map(map[string]interface{}{`a`: 1, `b`: `two`}))
+	require.Instance(t, vf.Map(map[string]interface{}{`a`: 1, `b`: `two`}).Type(), m)
+	require.Instance(t, typ.Map, m)
+	require.Instance(t, newtype.Map(typ.String, typ.Any, 2, 2), m)
+	require.NotInstance(t, newtype.Map(typ.String, typ.Integer), m)
+	require.Instance(t, newtype.Struct(
+		newtype.StructEntry(`a`, typ.Integer, true),
+		newtype.StructEntry(`b`, typ.String, true)), m)
+	require.NotInstance(t, newtype.Struct(newtype.StructEntry(`a`, typ.Integer, true)), m)
+	require.NotEqual(t, m, vf.Map(map[string]interface{}{`a`: 1, `b`: `three`}))
+	require.NotEqual(t, m, vf.Map(map[string]interface{}{`a`: 1}))
+	require.NotEqual(t, m, `a`)
+}
+
+func TestPersistentMap_marshal(t *testing.T) {
+	m := vf.PersistentMap(map[string]int{`a`: 1})
+	b, err := m.MarshalJSON()
+	require.Nil(t, err)
+	require.Equal(t, `{"a":1}`, string(b))
+
+	b, err = yaml.Marshal(m)
+	require.Nil(t, err)
+	require.Equal(t, "a: 1\n", string(b))
+}
+
+func TestPersistentMap_badArg(t *testing.T) {
+	require.Panic(t, func() { vf.PersistentMap(1) }, `not a map`)
+}
@@ -0,0 +1,394 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type (
+	missingKeyError struct {
+		path string
+		key  string
+	}
+
+	indexOutOfRangeError struct {
+		path  string
+		index int
+		len   int
+	}
+
+	pathTypeError struct {
+		path  string
+		token string
+		value dgo.Value
+	}
+
+	// pointer is a parsed JSON Pointer. The ends slice contains the position in the source string
+	// where each token ends.
+	pointer struct {
+		source string
+		tokens []string
+		ends   []int
+	}
+)
+
+// GetPath returns the value that the given JSON Pointer (RFC 6901) refers to in the given value.
+func GetPath(v dgo.Value, ptr string) (dgo.Value, error) {
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	for i := range p.tokens {
+		if v, err = p.step(v, i); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+// SetPath returns a frozen copy of the given value where the value that the given JSON Pointer (RFC 6901)
+// refers to has been set to the given value. All values but the last one denoted by the pointer must exist.
+// The last token may denote a new key in a Map. It may also be the token "-" which appends the value to
+// an Array.
+func SetPath(v dgo.Value, ptr string, value interface{}) (dgo.Value, error) {
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	return p.set(v, 0, frozenCopy(Value(value)), false)
+}
+
+// DeletePath returns a frozen copy of the given value where the value that the given JSON Pointer
+// (RFC 6901) refers to has been removed.
+func DeletePath(v dgo.Value, ptr string) (dgo.Value, error) {
+	p, err := parsePointer(ptr)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.tokens) == 0 {
+		return nil, fmt.Errorf(`json pointer "" denotes the whole value and cannot be deleted`)
+	}
+	return p.set(v, 0, nil, false)
+}
+
+func parsePointer(ptr string) (*pointer, error) {
+	p := &pointer{source: ptr}
+	if ptr == `` {
+		return p, nil
+	}
+	if ptr[0] != '/' {
+		return nil, fmt.Errorf(`json pointer %q does not start with '/'`, ptr)
+	}
+	start := 1
+	for {
+		end := strings.IndexByte(ptr[start:], '/')
+		if end < 0 {
+			end = len(ptr)
+		} else {
+			end += start
+		}
+		t := ptr[start:end]
+		if strings.IndexByte(t, '~') >= 0 {
+			var err error
+			if t, err = unescapePointerToken(ptr, t); err != nil {
+				return nil, err
+			}
+		}
+		p.tokens = append(p.tokens, t)
+		p.ends = append(p.ends, end)
+		if end == len(ptr) {
+			break
+		}
+		start = end + 1
+	}
+	return p, nil
+}
+
+func unescapePointerToken(ptr, t string) (string, error) {
+	b := strings.Builder{}
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		if c == '~' {
+			i++
+			if i < len(t) {
+				switch t[i] {
+				case '0':
+					c = '~'
+				case '1':
+					c = '/'
+				default:
+					i = len(t)
+				}
+			}
+			if i >= len(t) {
+				return ``, fmt.Errorf(`json pointer %q contains an invalid escape sequence`, ptr)
+			}
+		}
+		_ = b.WriteByte(c)
+	}
+	return b.String(), nil
+}
+
+// path returns the part of the pointer source that ends with the token at the given index
+func (p *pointer) path(i int) string {
+	return p.source[:p.ends[i]]
+}
+
+// step resolves the token at the given index in the given value
+func (p *pointer) step(v dgo.Value, i int) (dgo.Value, error) {
+	switch c := v.(type) {
+	case dgo.Map:
+		k, ok := mapKey(c, p.tokens[i])
+		if !ok {
+			return nil, &missingKeyError{path: p.path(i), key: p.tokens[i]}
+		}
+		v, _ = c.Get(k)
+		return v, nil
+	case dgo.Array:
+		x, err := p.index(c, i)
+		if err != nil {
+			return nil, err
+		}
+		if x >= c.Len() {
+			return nil, &indexOutOfRangeError{path: p.path(i), index: x, len: c.Len()}
+		}
+		return c.Get(x), nil
+	}
+	return nil, &pathTypeError{path: p.path(i), token: p.tokens[i], value: v}
+}
+
+// set returns a frozen copy of the given value where the value denoted by the tokens starting at the
+// given index has been replaced with the given value, or removed when the given value is nil. When
+// insert is true, a value denoted by the last token in an Array is inserted rather than replaced.
+func (p *pointer) set(v dgo.Value, i int, value dgo.Value, insert bool) (dgo.Value, error) {
+	if i == len(p.tokens) {
+		return value, nil
+	}
+	last := i+1 == len(p.tokens)
+	switch c := v.(type) {
+	case dgo.Map:
+		k, ok := mapKey(c, p.tokens[i])
+		if !ok {
+			if !last || value == nil {
+				return nil, &missingKeyError{path: p.path(i), key: p.tokens[i]}
+			}
+			k = makeHString(p.tokens[i])
+		}
+		m := c.FrozenCopy().(dgo.Map)
+		if last && value == nil {
+			return m.Without(k), nil
+		}
+		ev, _ := m.Get(k)
+		nv, err := p.set(ev, i+1, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		return m.With(k, nv), nil
+	case dgo.Array:
+		x, err := p.index(c, i)
+		if err != nil {
+			return nil, err
+		}
+		a := c.FrozenCopy().(dgo.Array)
+		l := a.Len()
+		if x == l && last && value != nil && (insert || p.tokens[i] == `-`) {
+			return a.With(value), nil
+		}
+		if x >= l {
+			return nil, &indexOutOfRangeError{path: p.path(i), index: x, len: l}
+		}
+		if last {
+			if value == nil {
+				return withoutIndex(a, x), nil
+			}
+			if insert {
+				return insertAtIndex(a, x, value), nil
+			}
+		}
+		nv, err := p.set(a.Get(x), i+1, value, insert)
+		if err != nil {
+			return nil, err
+		}
+		return withIndex(a, x, nv), nil
+	}
+	return nil, &pathTypeError{path: p.path(i), token: p.tokens[i], value: v}
+}
+
+// index returns the array index that the token at the given position represents. The token "-"
+// represents the index after the last element.
+func (p *pointer) index(a dgo.Array, i int) (int, error) {
+	t := p.tokens[i]
+	if t == `-` {
+		return a.Len(), nil
+	}
+	if t == `` || len(t) > 1 && t[0] == '0' || strings.IndexFunc(t, func(r rune) bool { return r < '0' || r > '9' }) >= 0 {
+		return 0, &pathTypeError{path: p.path(i), token: t, value: a}
+	}
+	x, err := strconv.Atoi(t)
+	if err != nil {
+		// index is too large
+		x = int(^uint(0) >> 1)
+	}
+	return x, nil
+}
+
+// mapKey returns the key in the given map that corresponds to the given token. Maps with non string keys
+// are supported to the extent that a key will match if its string representation equals the token.
+func mapKey(m dgo.Map, t string) (dgo.Value, bool) {
+	k := makeHString(t)
+	if _, ok := m.Get(k); ok {
+		return k, true
+	}
+	var found dgo.Value
+	m.AnyKey(func(mk dgo.Value) bool {
+		if _, ok := mk.(dgo.String); !ok && mk.String() == t {
+			found = mk
+			return true
+		}
+		return false
+	})
+	return found, found != nil
+}
+
+// withIndex returns a frozen copy of the given frozen array where the value at the given index has been
+// replaced with the given value
+func withIndex(a dgo.Array, x int, v dgo.Value) dgo.Array {
+	if pa, ok := a.(*persistentArray); ok {
+		return &persistentArray{v: pa.v.set(x, v)}
+	}
+	c := a.Copy(false)
+	c.Set(x, v)
+	c.Freeze()
+	return c
+}
+
+// insertAtIndex returns a frozen copy of the given frozen array where the given value has been inserted
+// at the given index
+func insertAtIndex(a dgo.Array, x int, v dgo.Value) dgo.Array {
+	c := a.Copy(false)
+	c.Insert(x, v)
+	if _, ok := a.(*persistentArray); ok {
+		return persistent(c)
+	}
+	c.Freeze()
+	return c
+}
+
+// withoutIndex returns a frozen copy of the given frozen array where the value at the given index has
+// been removed
+func withoutIndex(a dgo.Array, x int) dgo.Array {
+	c := a.Copy(false)
+	c.Remove(x)
+	if _, ok := a.(*persistentArray); ok {
+		return persistent(c)
+	}
+	c.Freeze()
+	return c
+}
+
+func (v *missingKeyError) Error() string {
+	return fmt.Sprintf(`json pointer %s: no entry found for key %q`, v.path, v.key)
+}
+
+func (v *missingKeyError) Equals(other interface{}) bool {
+	if ov, ok := other.(*missingKeyError); ok {
+		return *v == *ov
+	}
+	return false
+}
+
+func (v *missingKeyError) HashCode() int {
+	return stringHash(v.path)*31 + stringHash(v.key)
+}
+
+func (v *missingKeyError) Key() string {
+	return v.key
+}
+
+func (v *missingKeyError) Path() string {
+	return v.path
+}
+
+func (v *missingKeyError) String() string {
+	return v.Error()
+}
+
+func (v *missingKeyError) Type() dgo.Type {
+	return DefaultErrorType
+}
+
+func (v *indexOutOfRangeError) Error() string {
+	return fmt.Sprintf(`json pointer %s: index %d is out of range for an array of length %d`, v.path, v.index, v.len)
+}
+
+func (v *indexOutOfRangeError) Equals(other interface{}) bool {
+	if ov, ok := other.(*indexOutOfRangeError); ok {
+		return *v == *ov
+	}
+	return false
+}
+
+func (v *indexOutOfRangeError) HashCode() int {
+	return (stringHash(v.path)*31+v.index)*31 + v.len
+}
+
+func (v *indexOutOfRangeError) Index() int {
+	return v.index
+}
+
+func (v *indexOutOfRangeError) Len() int {
+	return v.len
+}
+
+func (v *indexOutOfRangeError) Path() string {
+	return v.path
+}
+
+func (v *indexOutOfRangeError) String() string {
+	return v.Error()
+}
+
+func (v *indexOutOfRangeError) Type() dgo.Type {
+	return DefaultErrorType
+}
+
+func (v *pathTypeError) Error() string {
+	if _, ok := v.value.(dgo.Array); ok {
+		return fmt.Sprintf(`json pointer %s: %q is not a valid array index`, v.path, v.token)
+	}
+	return fmt.Sprintf(`json pointer %s: cannot resolve %q in a value of type %s`, v.path, v.token, TypeString(v.value.Type()))
+}
+
+func (v *pathTypeError) Equals(other interface{}) bool {
+	if ov, ok := other.(*pathTypeError); ok {
+		return v.path == ov.path && v.token == ov.token && v.value.Equals(ov.value)
+	}
+	return false
+}
+
+func (v *pathTypeError) HashCode() int {
+	return stringHash(v.path)*31 + v.value.HashCode()
+}
+
+func (v *pathTypeError) Path() string {
+	return v.path
+}
+
+func (v *pathTypeError) String() string {
+	return v.Error()
+}
+
+func (v *pathTypeError) Token() string {
+	return v.token
+}
+
+func (v *pathTypeError) Type() dgo.Type {
+	return DefaultErrorType
+}
+
+func (v *pathTypeError) Value() dgo.Value {
+	return v.value
+}
@@ -0,0 +1,223 @@
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func pointerDoc() dgo.Value {
+	return vf.Map(map[string]interface{}{
+		`servers`: vf.Values(
+			vf.Map(map[string]interface{}{`host`: `a`, `port`: 80}),
+			vf.Map(map[string]interface{}{`host`: `b`, `port`: 8080})),
+		`a/b`:  1,
+		`m~n`:  2,
+		``:     3,
+		`ints`: vf.Map(map[int]string{1: `one`}),
+	})
+}
+
+func ExampleGetPath() {
+	v, err := vf.GetPath(pointerDoc(), `/servers/1/port`)
+	fmt.Println(v, err)
+	_, err = vf.GetPath(pointerDoc(), `/servers/2/port`)
+	fmt.Println(err)
+	// Output:
+	// 8080 <nil>
+	// json pointer /servers/2: index 2 is out of range for an array of length 2
+}
+
+func ExampleSetPath() {
+	v, _ := vf.SetPath(pointerDoc(), `/servers/0/port`, 8000)
+	fmt.Println(vf.GetPath(v, `/servers/0`))
+	// Output: {"host":"a","port":8000} <nil>
+}
+
+func TestGetPath(t *testing.T) {
+	d := pointerDoc()
+	v, err := vf.GetPath(d, ``)
+	require.Nil(t, err)
+	require.Same(t, d, v)
+
+	tests := map[string]interface{}{
+		`/servers/0/host`: `a`,
+		`/a~1b`:           1,
+		`/m~0n`:           2,
+		`/`:               3,
+		`/ints/1`:         `one`,
+	}
+	for p, e := range tests {
+		v, err = vf.GetPath(d, p)
+		require.Nil(t, err)
+		require.Equal(t, e, v)
+	}
+}
+
+func TestGetPath_errors(t *testing.T) {
+	d := pointerDoc()
+	_, err := vf.GetPath(d, `/servers/0/user`)
+	var mk dgo.MissingKeyError
+	require.True(t, errors.As(err, &mk))
+	require.Equal(t, `user`, mk.Key())
+	require.Equal(t, `/servers/0/user`, mk.Path())
+	require.Equal(t, `json pointer /servers/0/user: no entry found for key "user"`, err.Error())
+
+	_, err = vf.GetPath(d, `/servers/-`)
+	var ir dgo.IndexOutOfRangeError
+	require.True(t, errors.As(err, &ir))
+	require.Equal(t, 2, ir.Index())
+	require.Equal(t, 2, ir.Len())
+
+	_, err = vf.GetPath(d, `/servers/99999999999999999999999`)
+	require.True(t, errors.As(err, &ir))
+
+	var pt dgo.PathTypeError
+	for _, p := range []string{`/servers/01`, `/servers/x`, `/servers/`, `/servers/-1`} {
+		_, err = vf.GetPath(d, p)
+		require.True(t, errors.As(err, &pt))
+		require.Equal(t, p, pt.Path())
+	}
+	require.Equal(t, `json pointer /servers/-1: "-1" is not a valid array index`, err.Error())
+
+	_, err = vf.GetPath(d, `/servers/0/port/x`)
+	require.True(t, errors.As(err, &pt))
+	require.Equal(t, `x`, pt.Token())
+	require.Equal(t, 80, pt.Value())
+	require.Equal(t, `json pointer /servers/0/port/x: cannot resolve "x" in a value of type 80`, err.Error())
+
+	_, err = vf.GetPath(d, `servers`)
+	require.Equal(t, `json pointer "servers" does not start with '/'`, err.Error())
+	_, err = vf.GetPath(d, `/a~2b`)
+	require.Equal(t, `json pointer "/a~2b" contains an invalid escape sequence`, err.Error())
+	_, err = vf.GetPath(d, `/a~`)
+	require.NotNil(t, err)
+}
+
+func TestPathErrors_equality(t *testing.T) {
+	d := pointerDoc()
+	for _, p := range []string{`/x`, `/servers/3`, `/a~1b/x`} {
+		_, e1 := vf.GetPath(d, p)
+		_, e2 := vf.GetPath(d, p)
+		require.Equal(t, e1, e2)
+		require.Equal(t, e1.(dgo.Value).HashCode(), e2.(dgo.Value).HashCode())
+		require.Equal(t, e1.Error(), e1.(dgo.Value).String())
+		require.Instance(t, typ.Error, e1)
+		require.NotEqual(t, e1, `x`)
+	}
+}
+
+func TestSetPath(t *testing.T) {
+	m := vf.MutableMap(0, nil)
+	m.Put(`servers`, vf.MutableValues(nil, vf.Map(map[string]int{`port`: 80})))
+
+	v, err := vf.SetPath(m, `/servers/0/port`, 8000)
+	require.Nil(t, err)
+	require.True(t, v.(dgo.Freezable).Frozen())
+	require.Equal(t, `{"servers":[{"port":8000}]}`, v.String())
+	require.Equal(t, `{"servers":[{"port":80}]}`, m.String())
+	require.False(t, m.Frozen())
+
+	v, err = vf.SetPath(v, `/servers/-`, vf.Map(map[string]int{`port`: 90}))
+	require.Nil(t, err)
+	require.Equal(t, `{"servers":[{"port":8000},{"port":90}]}`, v.String())
+
+	v, err = vf.SetPath(v, `/servers/1/host`, `b`)
+	require.Nil(t, err)
+	require.Equal(t, `{"servers":[{"port":8000},{"port":90,"host":"b"}]}`, v.String())
+
+	v, err = vf.SetPath(v, ``, 1)
+	require.Nil(t, err)
+	require.Equal(t, 1, v)
+
+	v, err = vf.SetPath(pointerDoc(), `/ints/1`, `uno`)
+	require.Nil(t, err)
+	v, _ = vf.GetPath(v, `/ints`)
+	require.Equal(t, vf.Map(map[int]string{1: `uno`}), v)
+}
+
+func TestSetPath_errors(t *testing.T) {
+	d := pointerDoc()
+	_, err := vf.SetPath(d, `/x/y`, 1)
+	var mk dgo.MissingKeyError
+	require.True(t, errors.As(err, &mk))
+	require.Equal(t, `/x`, mk.Path())
+
+	_, err = vf.SetPath(d, `/servers/2`, 1)
+	var ir dgo.IndexOutOfRangeError
+	require.True(t, errors.As(err, &ir))
+
+	_, err = vf.SetPath(d, `/servers/-/x`, 1)
+	require.True(t, errors.As(err, &ir))
+
+	var pt dgo.PathTypeError
+	_, err = vf.SetPath(d, `/a~1b/x`, 1)
+	require.True(t, errors.As(err, &pt))
+	_, err = vf.SetPath(d, `/servers/x`, 1)
+	require.True(t, errors.As(err, &pt))
+	_, err = vf.SetPath(d, `/servers/0/port/x`, 1)
+	require.True(t, errors.As(err, &pt))
+	_, err = vf.SetPath(d, `x`, 1)
+	require.NotNil(t, err)
+}
+
+func TestSetPath_typed(t *testing.T) {
+	a := vf.MutableValues(newtype.Array(typ.Integer), 1, 2)
+	v, err := vf.SetPath(a, `/0`, 3)
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(3, 2), v)
+	require.Panic(t, func() { _, _ = vf.SetPath(a, `/0`, `x`) }, `cannot be assigned`)
+}
+
+func TestSetPath_persistent(t *testing.T) {
+	p := vf.PersistentMap(map[string]interface{}{`a`: vf.PersistentValues(1, 2, 3)})
+	v, err := vf.SetPath(p, `/a/1`, 5)
+	require.Nil(t, err)
+	require.Equal(t, `{"a":[1,5,3]}`, v.String())
+	require.Equal(t, `{"a":[1,2,3]}`, p.String())
+	a, _ := vf.GetPath(v, `/a`)
+	require.Same(t, a, a.(dgo.Array).Copy(true))
+
+	v, err = vf.DeletePath(p, `/a/0`)
+	require.Nil(t, err)
+	require.Equal(t, `{"a":[2,3]}`, v.String())
+	a, _ = vf.GetPath(v, `/a`)
+	require.Equal(t, `[2,3,4]`, a.(dgo.Array).With(4).String())
+}
+
+func TestDeletePath(t *testing.T) {
+	d := pointerDoc()
+	v, err := vf.DeletePath(d, `/servers/0/port`)
+	require.Nil(t, err)
+	s, _ := vf.GetPath(v, `/servers`)
+	require.Equal(t, `[{"host":"a"},{"host":"b","port":8080}]`, s.String())
+
+	v, err = vf.DeletePath(d, `/servers/0`)
+	require.Nil(t, err)
+	require.True(t, v.(dgo.Freezable).Frozen())
+	s, _ = vf.GetPath(v, `/servers`)
+	require.Equal(t, `[{"host":"b","port":8080}]`, s.String())
+
+	_, err = vf.DeletePath(d, `/servers/2`)
+	var ir dgo.IndexOutOfRangeError
+	require.True(t, errors.As(err, &ir))
+
+	_, err = vf.DeletePath(d, `/servers/-`)
+	require.True(t, errors.As(err, &ir))
+
+	_, err = vf.DeletePath(d, `/x`)
+	var mk dgo.MissingKeyError
+	require.True(t, errors.As(err, &mk))
+
+	_, err = vf.DeletePath(d, ``)
+	require.NotNil(t, err)
+
+	_, err = vf.DeletePath(d, `~`)
+	require.NotNil(t, err)
+}
@@ -0,0 +1,242 @@
+package internal
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// predicateType is a named type whose instances are the values for which a Go function returns true
+type predicateType struct {
+	name      string
+	predicate func(dgo.Value) bool
+
+	// generate, when not nil, returns a random instance of the type
+	generate func(*rand.Rand) dgo.Value
+}
+
+// predicates are guarded by the kindLock since predicates and type kinds share the same namespace
+var predicates = map[string]*predicateType{}
+
+var (
+	uuidExpr     = regexp.MustCompile(`\A[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\z`)
+	hostnameExpr = regexp.MustCompile(`\A[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?\z`)
+	semverExpr   = regexp.MustCompile(`\A(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(-((0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(\.(0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(\+([0-9a-zA-Z-]+(\.[0-9a-zA-Z-]+)*))?\z`)
+)
+
+func init() {
+	RegisterGeneratedPredicate(`uuid`, stringPredicate(uuidExpr.MatchString), stringGenerator(generateUUID))
+	RegisterGeneratedPredicate(`email`, stringPredicate(isEmail), stringGenerator(generateEmail))
+	RegisterGeneratedPredicate(`uri`, stringPredicate(isURI), stringGenerator(generateURI))
+	RegisterGeneratedPredicate(`ipv4`, stringPredicate(isIPv4), stringGenerator(generateIPv4))
+	RegisterGeneratedPredicate(`ipv6`, stringPredicate(isIPv6), stringGenerator(generateIPv6))
+	RegisterGeneratedPredicate(`hostname`, stringPredicate(isHostname), stringGenerator(generateHostname))
+	RegisterGeneratedPredicate(`date`, stringPredicate(isDate), stringGenerator(generateDate))
+	RegisterGeneratedPredicate(`semantic_version`, stringPredicate(semverExpr.MatchString),
+		stringGenerator(generateSemver))
+}
+
+// RegisterPredicate registers a type with the given name whose instances are the values for which the
+// given function returns true. The name can be used as an identifier in type expressions, e.g.
+// "string&email". The new type is returned.
+//
+// RegisterPredicate panics if the name is not a valid identifier or if it is already in use.
+func RegisterPredicate(name string, predicate func(dgo.Value) bool) dgo.Type {
+	return RegisterGeneratedPredicate(name, predicate, nil)
+}
+
+// RegisterGeneratedPredicate is like RegisterPredicate but also registers a function that Generate uses to
+// produce random instances of the type. Every value that the function returns must satisfy the predicate.
+// Instances of a predicate without a generator are found using rejection sampling.
+func RegisterGeneratedPredicate(
+	name string, predicate func(dgo.Value) bool, generate func(*rand.Rand) dgo.Value) dgo.Type {
+	if !kindNameExpr.MatchString(name) {
+		panic(fmt.Errorf(`'%s' is not a valid predicate name`, name))
+	}
+	kindLock.Lock()
+	defer kindLock.Unlock()
+	if nameInUse(name) {
+		panic(fmt.Errorf(`predicate '%s' is already registered`, name))
+	}
+	t := &predicateType{name: name, predicate: predicate, generate: generate}
+	predicates[name] = t
+	return t
+}
+
+// PredicateType returns the predicate type that was registered under the given name or nil if no such
+// type exists.
+func PredicateType(name string) dgo.Type {
+	if t := predicateByName(name); t != nil {
+		return t
+	}
+	return nil
+}
+
+func predicateByName(name string) *predicateType {
+	kindLock.RLock()
+	defer kindLock.RUnlock()
+	return predicates[name]
+}
+
+// stringPredicate returns a predicate that is true for strings that satisfy the given function
+func stringPredicate(f func(string) bool) func(dgo.Value) bool {
+	return func(v dgo.Value) bool {
+		s, ok := v.(dgo.String)
+		return ok && f(s.GoString())
+	}
+}
+
+func isEmail(s string) bool {
+	a, err := mail.ParseAddress(s)
+	return err == nil && a.Address == s
+}
+
+func isURI(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != ``
+}
+
+func isIPv4(s string) bool {
+	ip := net.ParseIP(s)
+	return ip != nil && ip.To4() != nil && !strings.Contains(s, `:`)
+}
+
+func isIPv6(s string) bool {
+	return net.ParseIP(s) != nil && strings.Contains(s, `:`)
+}
+
+func isHostname(s string) bool {
+	if len(s) == 0 || len(s) > 253 {
+		return false
+	}
+	for _, l := range strings.Split(s, `.`) {
+		if !hostnameExpr.MatchString(l) {
+			return false
+		}
+	}
+	return true
+}
+
+func isDate(s string) bool {
+	_, err := time.Parse(`2006-01-02`, s)
+	return err == nil
+}
+
+// stringGenerator returns a generator that produces strings using the given function
+func stringGenerator(f func(*rand.Rand) string) func(*rand.Rand) dgo.Value {
+	return func(rnd *rand.Rand) dgo.Value {
+		return makeHString(f(rnd))
+	}
+}
+
+const (
+	generatedLetters   = `abcdefghijklmnopqrstuvwxyz`
+	generatedHexDigits = `0123456789abcdef`
+)
+
+// generateChars returns a string with between min and max characters picked from the given characters
+func generateChars(rnd *rand.Rand, chars string, min, max int) string {
+	bs := make([]byte, min+rnd.Intn(max-min+1))
+	for i := range bs {
+		bs[i] = chars[rnd.Intn(len(chars))]
+	}
+	return string(bs)
+}
+
+func generateUUID(rnd *rand.Rand) string {
+	return generateChars(rnd, generatedHexDigits, 8, 8) + `-` + generateChars(rnd, generatedHexDigits, 4, 4) + `-` +
+		generateChars(rnd, generatedHexDigits, 4, 4) + `-` + generateChars(rnd, generatedHexDigits, 4, 4) + `-` +
+		generateChars(rnd, generatedHexDigits, 12, 12)
+}
+
+func generateHostname(rnd *rand.Rand) string {
+	labels := make([]string, 1+rnd.Intn(3))
+	for i := range labels {
+		labels[i] = generateChars(rnd, generatedLetters, 1, 8)
+	}
+	return strings.Join(labels, `.`)
+}
+
+func generateEmail(rnd *rand.Rand) string {
+	return generateChars(rnd, generatedLetters, 1, 8) + `@` + generateHostname(rnd)
+}
+
+func generateURI(rnd *rand.Rand) string {
+	schemes := []string{`http`, `https`, `ftp`, `file`}
+	return schemes[rnd.Intn(len(schemes))] + `://` + generateHostname(rnd) + `/` +
+		generateChars(rnd, generatedLetters, 0, 8)
+}
+
+func generateIPv4(rnd *rand.Rand) string {
+	return fmt.Sprintf(`%d.%d.%d.%d`, rnd.Intn(256), rnd.Intn(256), rnd.Intn(256), rnd.Intn(256))
+}
+
+func generateIPv6(rnd *rand.Rand) string {
+	gs := make([]string, 8)
+	for i := range gs {
+		gs[i] = generateChars(rnd, generatedHexDigits, 1, 4)
+	}
+	return strings.Join(gs, `:`)
+}
+
+func generateDate(rnd *rand.Rand) string {
+	return time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, rnd.Intn(200*365)).Format(`2006-01-02`)
+}
+
+func generateSemver(rnd *rand.Rand) string {
+	return fmt.Sprintf(`%d.%d.%d`, rnd.Intn(10), rnd.Intn(20), rnd.Intn(100))
+}
+
+// Assignable returns true only when the other type is the same predicate type since nothing is known
+// about the set of values that the predicate accepts.
+func (t *predicateType) Assignable(other dgo.Type) bool {
+	return t.Equals(other) || CheckAssignableTo(nil, other, t)
+}
+
+// Equals compares names since a predicate type can only be registered once under the same name
+func (t *predicateType) Equals(other interface{}) bool {
+	ot, ok := other.(*predicateType)
+	return ok && t.name == ot.name
+}
+
+func (t *predicateType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *predicateType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
+func (t *predicateType) HashCode() int {
+	return stringHash(t.name)*31 + int(dgo.IdPredicate)
+}
+
+func (t *predicateType) Instance(value interface{}) bool {
+	return t.predicate(Value(value))
+}
+
+// Name returns the name that the predicate was registered with
+func (t *predicateType) Name() string {
+	return t.name
+}
+
+func (t *predicateType) String() string {
+	return TypeString(t)
+}
+
+func (t *predicateType) Type() dgo.Type {
+	return &metaType{t}
+}
+
+func (t *predicateType) TypeIdentifier() dgo.TypeIdentifier {
+	return dgo.IdPredicate
+}
@@ -0,0 +1,94 @@
+package internal_test
+
+import (
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+var evenType = newtype.RegisterPredicate(`even`, func(v dgo.Value) bool {
+	i, ok := v.(dgo.Integer)
+	return ok && i.GoInt()%2 == 0
+})
+
+func TestPredicate(t *testing.T) {
+	require.Equal(t, `even`, evenType.String())
+	require.Same(t, evenType, newtype.Parse(`even`))
+	require.Same(t, evenType, newtype.Predicate(`even`))
+	require.Nil(t, newtype.Predicate(`odd`))
+	require.Instance(t, evenType, 4)
+	require.NotInstance(t, evenType, 3)
+	require.NotInstance(t, evenType, `4`)
+
+	tp := newtype.Parse(`0..10&even`)
+	require.Equal(t, `0..10&even`, tp.String())
+	require.Instance(t, tp, 8)
+	require.NotInstance(t, tp, 12)
+
+	require.Assignable(t, evenType, evenType)
+	require.Assignable(t, evenType, typ.Never)
+	require.NotAssignable(t, evenType, typ.Integer)
+	require.NotAssignable(t, evenType, vf.Integer(2).Type())
+	require.NotAssignable(t, typ.Integer, evenType)
+	require.NotAssignable(t, evenType, newtype.Parse(`uuid`))
+	require.Equal(t, evenType, evenType)
+	require.NotEqual(t, evenType, newtype.Parse(`uuid`))
+	require.NotEqual(t, evenType.HashCode(), newtype.Parse(`uuid`).HashCode())
+	require.Equal(t, evenType, evenType.Type().(dgo.UnaryType).Operand())
+}
+
+func TestPredicate_illegal(t *testing.T) {
+	f := func(dgo.Value) bool { return true }
+	require.Panic(t, func() { newtype.RegisterPredicate(`even`, f) }, `predicate 'even' is already registered`)
+	require.Panic(t, func() { newtype.RegisterPredicate(`int`, f) }, `predicate 'int' is already registered`)
+	require.Panic(t, func() { newtype.RegisterPredicate(`semver`, f) }, `predicate 'semver' is already registered`)
+	require.Panic(t, func() { newtype.RegisterPredicate(`not valid`, f) }, `'not valid' is not a valid predicate name`)
+	require.Panic(t, func() { newtype.RegisterKind(`even`, ``, nil, nil) }, `type kind 'even' is already registered`)
+}
+
+func TestPredicate_builtin(t *testing.T) {
+	tests := []struct {
+		name string
+		ok   []string
+		fail []string
+	}{
+		{`uuid`,
+			[]string{`123e4567-e89b-12d3-a456-426614174000`, `123E4567-E89B-12D3-A456-426614174000`},
+			[]string{`123e4567-e89b-12d3-a456-42661417400`, `123e4567e89b12d3a456426614174000`, `x23e4567-e89b-12d3-a456-426614174000`}},
+		{`email`,
+			[]string{`bob@example.com`, `bob.smith+tag@mail.example.com`},
+			[]string{`bob`, `Bob <bob@example.com>`, `bob@`, ``}},
+		{`uri`,
+			[]string{`https://example.com/a?b=c`, `mailto:bob@example.com`, `urn:isbn:0451450523`},
+			[]string{`example.com/a`, `/a/b`, `://x`}},
+		{`ipv4`,
+			[]string{`192.168.0.1`, `0.0.0.0`},
+			[]string{`256.1.1.1`, `1.2.3`, `::1`, `::ffff:192.168.0.1`}},
+		{`ipv6`,
+			[]string{`::1`, `2001:db8::8a2e:370:7334`, `::ffff:192.168.0.1`},
+			[]string{`192.168.0.1`, `2001:db8::g`, `:::`}},
+		{`hostname`,
+			[]string{`example.com`, `a`, `my-host.example.com`, `1.example.com`},
+			[]string{``, `-a.com`, `a-.com`, `a..com`, `a_b.com`, `example.com.`}},
+		{`date`,
+			[]string{`2019-12-31`, `2020-02-29`},
+			[]string{`2019-02-29`, `2019-13-01`, `19-12-31`, `2019-12-31T00:00:00Z`}},
+		{`semantic_version`,
+			[]string{`1.0.0`, `0.1.2-alpha.1`, `1.2.3+build.5`, `1.0.0-rc.1+exp.sha.5114f85`},
+			[]string{`1.0`, `01.0.0`, `1.0.0-`, `v1.0.0`}},
+	}
+	for _, tc := range tests {
+		tp := newtype.Parse(`string&` + tc.name)
+		for _, s := range tc.ok {
+			require.Instance(t, tp, s)
+		}
+		for _, s := range tc.fail {
+			require.NotInstance(t, tp, s)
+		}
+		require.NotInstance(t, newtype.Predicate(tc.name), 1)
+	}
+}
@@ -0,0 +1,188 @@
+package internal
+
+import (
+	"fmt"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+const (
+	pvShift = 5
+	pvWidth = 1 << pvShift
+	pvMask  = pvWidth - 1
+)
+
+type (
+	// pvNode is a node in the trie of a pvector. Branch nodes use the nodes slice and leaf nodes
+	// use the values slice. Both slices always have the length pvWidth.
+	pvNode struct {
+		nodes  []*pvNode
+		values []dgo.Value
+	}
+
+	// pvector is an immutable vector that uses a 32-way trie with a separate tail. Updates copy
+	// the path from the root to the affected leaf and share all other nodes with the original.
+	pvector struct {
+		cnt   int
+		shift uint
+		root  *pvNode
+		tail  []dgo.Value
+	}
+)
+
+var emptyPVector = &pvector{shift: pvShift, root: &pvNode{nodes: make([]*pvNode, pvWidth)}, tail: []dgo.Value{}}
+
+// pvectorFromSlice creates a pvector that contains the values of the given slice. The slice is
+// copied.
+func pvectorFromSlice(vs []dgo.Value) *pvector {
+	n := len(vs)
+	if n == 0 {
+		return emptyPVector
+	}
+	v := &pvector{shift: pvShift, root: emptyPVector.root}
+	i := 0
+	for ; n-i > pvWidth; i += pvWidth {
+		v.cnt = i + pvWidth
+		v.tail = make([]dgo.Value, pvWidth)
+		copy(v.tail, vs[i:v.cnt])
+		v.root, v.shift = v.pushTail()
+	}
+	v.tail = make([]dgo.Value, n-i)
+	copy(v.tail, vs[i:])
+	v.cnt = n
+	return v
+}
+
+// tailOffset returns the index of the first value in the tail
+func (v *pvector) tailOffset() int {
+	if v.cnt < pvWidth {
+		return 0
+	}
+	return ((v.cnt - 1) >> pvShift) << pvShift
+}
+
+// leafFor returns the slice that holds the value at the given index
+func (v *pvector) leafFor(i int) []dgo.Value {
+	if i < 0 || i >= v.cnt {
+		panic(fmt.Errorf(`index out of range [%d] with length %d`, i, v.cnt))
+	}
+	if i >= v.tailOffset() {
+		return v.tail
+	}
+	n := v.root
+	for level := v.shift; level > 0; level -= pvShift {
+		n = n.nodes[(i>>level)&pvMask]
+	}
+	return n.values
+}
+
+func (v *pvector) get(i int) dgo.Value {
+	return v.leafFor(i)[i&pvMask]
+}
+
+// each calls the given function with each value and its index. Iteration stops when the function
+// returns false. The method returns false if iteration was stopped.
+func (v *pvector) each(f func(dgo.Value, int) bool) bool {
+	to := v.tailOffset()
+	for i := 0; i < to; i += pvWidth {
+		leaf := v.leafFor(i)
+		for j := range leaf {
+			if !f(leaf[j], i+j) {
+				return false
+			}
+		}
+	}
+	for j := range v.tail {
+		if !f(v.tail[j], to+j) {
+			return false
+		}
+	}
+	return true
+}
+
+// appendTo appends all values of this vector to the given slice and returns the result
+func (v *pvector) appendTo(s []dgo.Value) []dgo.Value {
+	v.each(func(e dgo.Value, _ int) bool {
+		s = append(s, e)
+		return true
+	})
+	return s
+}
+
+// push returns a new vector with the given value appended to the values of this vector
+func (v *pvector) push(e dgo.Value) *pvector {
+	tl := len(v.tail)
+	if tl < pvWidth {
+		nt := make([]dgo.Value, tl+1)
+		copy(nt, v.tail)
+		nt[tl] = e
+		return &pvector{cnt: v.cnt + 1, shift: v.shift, root: v.root, tail: nt}
+	}
+	root, shift := v.pushTail()
+	return &pvector{cnt: v.cnt + 1, shift: shift, root: root, tail: []dgo.Value{e}}
+}
+
+// pushTail returns the root and shift that results from moving the full tail of this vector into
+// the trie.
+func (v *pvector) pushTail() (*pvNode, uint) {
+	leaf := &pvNode{values: v.tail}
+	if (v.cnt >> pvShift) > (1 << v.shift) {
+		// Root overflow, add a level
+		nodes := make([]*pvNode, pvWidth)
+		nodes[0] = v.root
+		nodes[1] = newPVPath(v.shift, leaf)
+		return &pvNode{nodes: nodes}, v.shift + pvShift
+	}
+	return v.pushLeaf(v.shift, v.root, leaf), v.shift
+}
+
+func (v *pvector) pushLeaf(level uint, parent, leaf *pvNode) *pvNode {
+	si := ((v.cnt - 1) >> level) & pvMask
+	nodes := make([]*pvNode, pvWidth)
+	copy(nodes, parent.nodes)
+	if level == pvShift {
+		nodes[si] = leaf
+	} else if child := parent.nodes[si]; child != nil {
+		nodes[si] = v.pushLeaf(level-pvShift, child, leaf)
+	} else {
+		nodes[si] = newPVPath(level-pvShift, leaf)
+	}
+	return &pvNode{nodes: nodes}
+}
+
+func newPVPath(level uint, leaf *pvNode) *pvNode {
+	if level == 0 {
+		return leaf
+	}
+	nodes := make([]*pvNode, pvWidth)
+	nodes[0] = newPVPath(level-pvShift, leaf)
+	return &pvNode{nodes: nodes}
+}
+
+// set returns a new vector where the value at the given index is replaced with the given value
+func (v *pvector) set(i int, e dgo.Value) *pvector {
+	if i < 0 || i >= v.cnt {
+		panic(fmt.Errorf(`index out of range [%d] with length %d`, i, v.cnt))
+	}
+	if i >= v.tailOffset() {
+		nt := make([]dgo.Value, len(v.tail))
+		copy(nt, v.tail)
+		nt[i&pvMask] = e
+		return &pvector{cnt: v.cnt, shift: v.shift, root: v.root, tail: nt}
+	}
+	return &pvector{cnt: v.cnt, shift: v.shift, root: setInPVNode(v.shift, v.root, i, e), tail: v.tail}
+}
+
+func setInPVNode(level uint, n *pvNode, i int, e dgo.Value) *pvNode {
+	if level == 0 {
+		vs := make([]dgo.Value, pvWidth)
+		copy(vs, n.values)
+		vs[i&pvMask] = e
+		return &pvNode{values: vs}
+	}
+	nodes := make([]*pvNode, pvWidth)
+	copy(nodes, n.nodes)
+	si := (i >> level) & pvMask
+	nodes[si] = setInPVNode(level-pvShift, n.nodes[si], i, e)
+	return &pvNode{nodes: nodes}
+}
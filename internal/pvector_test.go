@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+func Test_pvectorSet(t *testing.T) {
+	vs := make([]dgo.Value, 2000)
+	for i := range vs {
+		vs[i] = Integer(i)
+	}
+	a := pvectorFromSlice(vs)
+	b := a.set(0, String(`first`)).set(1999, String(`last`))
+	if a.get(0) != Integer(0) || a.get(1999) != Integer(1999) {
+		t.Fatal(`set modified the original vector`)
+	}
+	if !b.get(0).Equals(`first`) || !b.get(1999).Equals(`last`) || b.get(1000) != Integer(1000) {
+		t.Fatal(`set did not produce the expected vector`)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal(`set out of range did not panic`)
+		}
+	}()
+	a.set(2000, Nil)
+}
@@ -0,0 +1,752 @@
+package internal
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type (
+	// query is a compiled JSONPath expression
+	query struct {
+		source   string
+		segments []segment
+	}
+
+	// segment is a step in a query. A descendant segment applies its selectors to the value that it
+	// is applied to and to all values nested within that value.
+	segment struct {
+		descendant bool
+		selectors  []selector
+	}
+
+	selector interface {
+		// appendMatches appends the values in v that are matched by the selector to the given slice
+		appendMatches(root, v dgo.Value, matches []dgo.Value) []dgo.Value
+	}
+
+	nameSelector string
+
+	indexSelector int
+
+	sliceSelector struct {
+		start    int
+		end      int
+		step     int
+		hasStart bool
+		hasEnd   bool
+	}
+
+	wildcardSelector struct{}
+
+	filterSelector struct {
+		expr filterExpr
+	}
+
+	filterExpr interface {
+		// eval evaluates the expression using the given root and current value. The returned boolean
+		// is false when the expression doesn't produce a value.
+		eval(root, current dgo.Value) (dgo.Value, bool)
+	}
+
+	literalExpr struct {
+		value dgo.Value
+	}
+
+	// pathExpr is a query that starts at the current value (@) or at the root value ($)
+	pathExpr struct {
+		relative bool
+		segments []segment
+	}
+
+	notExpr struct {
+		expr filterExpr
+	}
+
+	andExpr struct {
+		left  filterExpr
+		right filterExpr
+	}
+
+	orExpr struct {
+		left  filterExpr
+		right filterExpr
+	}
+
+	compareExpr struct {
+		op    string
+		left  filterExpr
+		right filterExpr
+	}
+
+	isExpr struct {
+		expr filterExpr
+		typ  dgo.Type
+	}
+
+	queryParser struct {
+		source string
+		pos    int
+	}
+
+	// querySyntaxError is used when panicking from within the query parser
+	querySyntaxError string
+)
+
+// CompileQuery compiles the given JSONPath expression into a dgo.Query.
+//
+// The expression starts with $, denoting the root value, followed by any number of segments:
+//
+//	.name or ['name']      the value of the given key in a Map
+//	[index]                the element at the given index in an Array. Negative indexes count from the end
+//	[start:end:step]       a slice of an Array. All parts are optional
+//	.* or [*]              all values of a Map or all elements of an Array
+//	[?expr] or [?(expr)]   all values of a Map or elements of an Array that match the filter expression
+//	..segment              the segment is applied to the value and to all values nested within it
+//
+// Brackets may contain several comma separated selectors, e.g. ['a','b'] or [0,2:4].
+//
+// A filter expression compares values using the operators ==, !=, <, <=, >, and >=, where equality is
+// determined by Equals and order by CompareTo. The operands are literals (strings in single or double
+// quotes, numbers, true, false, and null) or queries starting with @ (the value being filtered) or $
+// (the root value). A query used as an operand has no value unless it produces exactly one value. Two
+// operands without value are equal and an ordering comparison is false unless both operands have a
+// value. The expression
+// "operand is <type>" is true when the operand is an instance of the given dgo type. A query that is used
+// on its own is true when it produces at least one value. Expressions are combined using !, &&, ||, and
+// parentheses.
+func CompileQuery(expr string) (q dgo.Query, err error) {
+	p := &queryParser{source: expr}
+	defer func() {
+		if r := recover(); r != nil {
+			se, ok := r.(querySyntaxError)
+			if !ok {
+				panic(r)
+			}
+			q = nil
+			err = fmt.Errorf(`query %q: %s at position %d`, expr, string(se), p.pos)
+		}
+	}()
+	q = p.parse()
+	return
+}
+
+func (q *query) Select(value dgo.Value) dgo.Array {
+	ms := evalSegments(q.segments, value, value)
+	for i := range ms {
+		ms[i] = frozenCopy(ms[i])
+	}
+	return &array{slice: ms, frozen: true}
+}
+
+func (q *query) String() string {
+	return q.source
+}
+
+// evalSegments applies the given segments, one at a time, starting with the given value and returns
+// the values produced by the last segment
+func evalSegments(segments []segment, root, v dgo.Value) []dgo.Value {
+	ms := []dgo.Value{v}
+	for i := range segments {
+		s := &segments[i]
+		var next []dgo.Value
+		for _, m := range ms {
+			next = s.appendMatches(root, m, next)
+		}
+		if len(next) == 0 {
+			return nil
+		}
+		ms = next
+	}
+	return ms
+}
+
+func (s *segment) appendMatches(root, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	if s.descendant {
+		eachDescendant(nil, v, func(d dgo.Value) {
+			matches = s.applySelectors(root, d, matches)
+		})
+		return matches
+	}
+	return s.applySelectors(root, v, matches)
+}
+
+func (s *segment) applySelectors(root, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	for _, sel := range s.selectors {
+		matches = sel.appendMatches(root, v, matches)
+	}
+	return matches
+}
+
+// eachDescendant calls the given function with the given value and then, depth first, with all values
+// that are nested in it. Containers that are already being visited are skipped so that recursive
+// values don't cause endless recursion.
+func eachDescendant(seen []dgo.Value, v dgo.Value, f func(dgo.Value)) {
+	if recursionHit(seen, v) {
+		return
+	}
+	f(v)
+	switch c := v.(type) {
+	case dgo.Map:
+		seen = append(seen, v)
+		c.EachValue(func(e dgo.Value) { eachDescendant(seen, e, f) })
+	case dgo.Array:
+		seen = append(seen, v)
+		c.Each(func(e dgo.Value) { eachDescendant(seen, e, f) })
+	}
+}
+
+func (s nameSelector) appendMatches(_, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	if m, ok := v.(dgo.Map); ok {
+		if k, ok := mapKey(m, string(s)); ok {
+			e, _ := m.Get(k)
+			matches = append(matches, e)
+		}
+	}
+	return matches
+}
+
+func (s indexSelector) appendMatches(_, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	if a, ok := v.(dgo.Array); ok {
+		i := int(s)
+		if i < 0 {
+			i += a.Len()
+		}
+		if i >= 0 && i < a.Len() {
+			matches = append(matches, a.Get(i))
+		}
+	}
+	return matches
+}
+
+func (s *sliceSelector) appendMatches(_, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	a, ok := v.(dgo.Array)
+	if !ok || s.step == 0 {
+		return matches
+	}
+	n := a.Len()
+	norm := func(i int) int {
+		if i < 0 {
+			i += n
+		}
+		return i
+	}
+	if s.step > 0 {
+		lower, upper := 0, n
+		if s.hasStart {
+			lower = clampIndex(norm(s.start), 0, n)
+		}
+		if s.hasEnd {
+			upper = clampIndex(norm(s.end), 0, n)
+		}
+		for i := lower; i < upper; i += s.step {
+			matches = append(matches, a.Get(i))
+		}
+	} else {
+		upper, lower := n-1, -1
+		if s.hasStart {
+			upper = clampIndex(norm(s.start), -1, n-1)
+		}
+		if s.hasEnd {
+			lower = clampIndex(norm(s.end), -1, n-1)
+		}
+		for i := upper; i > lower; i += s.step {
+			matches = append(matches, a.Get(i))
+		}
+	}
+	return matches
+}
+
+func clampIndex(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+func (s wildcardSelector) appendMatches(_, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	switch c := v.(type) {
+	case dgo.Map:
+		c.EachValue(func(e dgo.Value) { matches = append(matches, e) })
+	case dgo.Array:
+		c.Each(func(e dgo.Value) { matches = append(matches, e) })
+	}
+	return matches
+}
+
+func (s *filterSelector) appendMatches(root, v dgo.Value, matches []dgo.Value) []dgo.Value {
+	add := func(e dgo.Value) {
+		if test(s.expr, root, e) {
+			matches = append(matches, e)
+		}
+	}
+	switch c := v.(type) {
+	case dgo.Map:
+		c.EachValue(add)
+	case dgo.Array:
+		c.Each(add)
+	}
+	return matches
+}
+
+// test evaluates the given expression as a boolean. A query is true when it produces at least one
+// value. Other expressions are true when they produce the value true.
+func test(e filterExpr, root, current dgo.Value) bool {
+	if pe, ok := e.(*pathExpr); ok {
+		return len(pe.evaluate(root, current)) > 0
+	}
+	v, ok := e.eval(root, current)
+	return ok && v == True
+}
+
+func (e *literalExpr) eval(_, _ dgo.Value) (dgo.Value, bool) {
+	return e.value, true
+}
+
+func (e *pathExpr) evaluate(root, current dgo.Value) []dgo.Value {
+	if e.relative {
+		return evalSegments(e.segments, root, current)
+	}
+	return evalSegments(e.segments, root, root)
+}
+
+func (e *pathExpr) eval(root, current dgo.Value) (dgo.Value, bool) {
+	if ms := e.evaluate(root, current); len(ms) == 1 {
+		return ms[0], true
+	}
+	return nil, false
+}
+
+func (e *notExpr) eval(root, current dgo.Value) (dgo.Value, bool) {
+	return Boolean(!test(e.expr, root, current)), true
+}
+
+func (e *andExpr) eval(root, current dgo.Value) (dgo.Value, bool) {
+	return Boolean(test(e.left, root, current) && test(e.right, root, current)), true
+}
+
+func (e *orExpr) eval(root, current dgo.Value) (dgo.Value, bool) {
+	return Boolean(test(e.left, root, current) || test(e.right, root, current)), true
+}
+
+func (e *compareExpr) eval(root, current dgo.Value) (dgo.Value, bool) {
+	l, lok := e.left.eval(root, current)
+	r, rok := e.right.eval(root, current)
+	if !(lok && rok) {
+		// Two operands without value are considered equal
+		switch e.op {
+		case `==`:
+			return Boolean(lok == rok), true
+		case `!=`:
+			return Boolean(lok != rok), true
+		}
+		return False, true
+	}
+	switch e.op {
+	case `==`:
+		return Boolean(l.Equals(r)), true
+	case `!=`:
+		return Boolean(!l.Equals(r)), true
+	}
+	lc, ok := l.(dgo.Comparable)
+	if !ok {
+		return False, true
+	}
+	c, ok := lc.CompareTo(r)
+	if !ok {
+		return False, true
+	}
+	var result bool
+	switch e.op {
+	case `<`:
+		result = c < 0
+	case `<=`:
+		result = c <= 0
+	case `>`:
+		result = c > 0
+	default: // `>=`
+		result = c >= 0
+	}
+	return Boolean(result), true
+}
+
+func (e *isExpr) eval(root, current dgo.Value) (dgo.Value, bool) {
+	v, ok := e.expr.eval(root, current)
+	return Boolean(ok && e.typ.Instance(v)), true
+}
+
+func (p *queryParser) parse() *query {
+	p.skipWhitespace()
+	p.expect('$')
+	segments := p.parseSegments()
+	p.skipWhitespace()
+	if p.pos < len(p.source) {
+		p.fail(fmt.Sprintf(`unexpected character %q`, p.source[p.pos]))
+	}
+	return &query{source: p.source, segments: segments}
+}
+
+func (p *queryParser) fail(msg string) {
+	panic(querySyntaxError(msg))
+}
+
+func (p *queryParser) peek() byte {
+	if p.pos < len(p.source) {
+		return p.source[p.pos]
+	}
+	return 0
+}
+
+func (p *queryParser) expect(c byte) {
+	if p.peek() != c {
+		p.fail(fmt.Sprintf(`expected %q`, c))
+	}
+	p.pos++
+}
+
+func (p *queryParser) skipWhitespace() {
+	for p.pos < len(p.source) {
+		switch p.source[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// consume consumes the given string and returns true if the source continues with that string
+func (p *queryParser) consume(s string) bool {
+	if strings.HasPrefix(p.source[p.pos:], s) {
+		p.pos += len(s)
+		return true
+	}
+	return false
+}
+
+func (p *queryParser) parseSegments() []segment {
+	var segments []segment
+	for {
+		p.skipWhitespace()
+		switch {
+		case p.consume(`..`):
+			var ss []selector
+			if p.peek() == '[' {
+				ss = p.parseBracket()
+			} else {
+				ss = []selector{p.parseDotSelector()}
+			}
+			segments = append(segments, segment{descendant: true, selectors: ss})
+		case p.consume(`.`):
+			segments = append(segments, segment{selectors: []selector{p.parseDotSelector()}})
+		case p.peek() == '[':
+			segments = append(segments, segment{selectors: p.parseBracket()})
+		default:
+			return segments
+		}
+	}
+}
+
+func (p *queryParser) parseDotSelector() selector {
+	if p.consume(`*`) {
+		return wildcardSelector{}
+	}
+	n := p.parseName()
+	if n == `` {
+		p.fail(`expected a name or '*'`)
+	}
+	return nameSelector(n)
+}
+
+func (p *queryParser) parseName() string {
+	start := p.pos
+	for _, r := range p.source[start:] {
+		if !(r == '_' || r == '-' || unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			break
+		}
+		p.pos += len(string(r))
+	}
+	return p.source[start:p.pos]
+}
+
+func (p *queryParser) parseBracket() []selector {
+	p.expect('[')
+	var ss []selector
+	for {
+		p.skipWhitespace()
+		ss = append(ss, p.parseBracketSelector())
+		p.skipWhitespace()
+		if p.consume(`]`) {
+			return ss
+		}
+		if !p.consume(`,`) {
+			p.fail(`expected ',' or ']'`)
+		}
+	}
+}
+
+func (p *queryParser) parseBracketSelector() selector {
+	switch c := p.peek(); {
+	case c == '*':
+		p.pos++
+		return wildcardSelector{}
+	case c == '?':
+		p.pos++
+		return &filterSelector{expr: p.parseOr()}
+	case c == '\'' || c == '"':
+		return nameSelector(p.parseString())
+	case c == '-' || c == ':' || c >= '0' && c <= '9':
+		return p.parseIndexOrSlice()
+	}
+	p.fail(`expected a selector`)
+	return nil
+}
+
+func (p *queryParser) parseIndexOrSlice() selector {
+	s := &sliceSelector{step: 1}
+	if p.peek() != ':' {
+		s.start = p.parseInt()
+		s.hasStart = true
+		p.skipWhitespace()
+		if !p.consume(`:`) {
+			return indexSelector(s.start)
+		}
+	} else {
+		p.pos++
+	}
+	p.skipWhitespace()
+	if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+		s.end = p.parseInt()
+		s.hasEnd = true
+		p.skipWhitespace()
+	}
+	if p.consume(`:`) {
+		p.skipWhitespace()
+		if c := p.peek(); c == '-' || c >= '0' && c <= '9' {
+			s.step = p.parseInt()
+		}
+	}
+	return s
+}
+
+func (p *queryParser) parseInt() int {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for c := p.peek(); c >= '0' && c <= '9'; c = p.peek() {
+		p.pos++
+	}
+	i, err := strconv.Atoi(p.source[start:p.pos])
+	if err != nil {
+		p.pos = start
+		p.fail(`expected an integer`)
+	}
+	return i
+}
+
+func (p *queryParser) parseString() string {
+	q := p.source[p.pos]
+	p.pos++
+	b := strings.Builder{}
+	for {
+		if p.pos >= len(p.source) {
+			p.fail(`unterminated string`)
+		}
+		c := p.source[p.pos]
+		p.pos++
+		switch c {
+		case q:
+			return b.String()
+		case '\\':
+			switch p.peek() {
+			case '\\', '\'', '"', '/':
+				c = p.peek()
+			case 'n':
+				c = '\n'
+			case 't':
+				c = '\t'
+			case 'r':
+				c = '\r'
+			default:
+				p.fail(`invalid escape sequence`)
+			}
+			p.pos++
+		}
+		_ = b.WriteByte(c)
+	}
+}
+
+func (p *queryParser) parseOr() filterExpr {
+	e := p.parseAnd()
+	for {
+		p.skipWhitespace()
+		if !p.consume(`||`) {
+			return e
+		}
+		e = &orExpr{left: e, right: p.parseAnd()}
+	}
+}
+
+func (p *queryParser) parseAnd() filterExpr {
+	e := p.parseUnary()
+	for {
+		p.skipWhitespace()
+		if !p.consume(`&&`) {
+			return e
+		}
+		e = &andExpr{left: e, right: p.parseUnary()}
+	}
+}
+
+func (p *queryParser) parseUnary() filterExpr {
+	p.skipWhitespace()
+	if p.peek() == '!' && !strings.HasPrefix(p.source[p.pos:], `!=`) {
+		p.pos++
+		return &notExpr{expr: p.parseUnary()}
+	}
+	return p.parseComparison()
+}
+
+func (p *queryParser) parseComparison() filterExpr {
+	e := p.parsePrimary()
+	p.skipWhitespace()
+	for _, op := range []string{`==`, `!=`, `<=`, `>=`, `<`, `>`} {
+		if p.consume(op) {
+			return &compareExpr{op: op, left: e, right: p.parsePrimary()}
+		}
+	}
+	if p.consumeKeyword(`is`) {
+		return &isExpr{expr: e, typ: p.parseType()}
+	}
+	return e
+}
+
+func (p *queryParser) parsePrimary() filterExpr {
+	p.skipWhitespace()
+	switch c := p.peek(); {
+	case c == '(':
+		p.pos++
+		e := p.parseOr()
+		p.skipWhitespace()
+		p.expect(')')
+		return e
+	case c == '@' || c == '$':
+		p.pos++
+		return &pathExpr{relative: c == '@', segments: p.parseSegments()}
+	case c == '\'' || c == '"':
+		return &literalExpr{value: makeHString(p.parseString())}
+	case c == '-' || c >= '0' && c <= '9':
+		return &literalExpr{value: p.parseNumber()}
+	}
+	switch {
+	case p.consumeKeyword(`true`):
+		return &literalExpr{value: True}
+	case p.consumeKeyword(`false`):
+		return &literalExpr{value: False}
+	case p.consumeKeyword(`null`):
+		return &literalExpr{value: Nil}
+	}
+	p.fail(`expected a literal, '@', '$', or '('`)
+	return nil
+}
+
+// consumeKeyword consumes the given keyword if the source continues with that keyword followed by
+// something that isn't part of a name
+func (p *queryParser) consumeKeyword(kw string) bool {
+	if !strings.HasPrefix(p.source[p.pos:], kw) {
+		return false
+	}
+	e := p.pos + len(kw)
+	if e < len(p.source) {
+		if c := p.source[e]; c == '_' || c == '-' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			return false
+		}
+	}
+	p.pos = e
+	return true
+}
+
+func (p *queryParser) parseNumber() dgo.Value {
+	start := p.pos
+	isFloat := false
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for ; p.pos < len(p.source); p.pos++ {
+		c := p.source[p.pos]
+		if c == '.' || c == 'e' || c == 'E' {
+			isFloat = true
+		} else if !(c >= '0' && c <= '9' || isFloat && (c == '+' || c == '-')) {
+			break
+		}
+	}
+	s := p.source[start:p.pos]
+	if isFloat {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return Float(f)
+		}
+	} else if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return Integer(i)
+	}
+	p.pos = start
+	p.fail(`expected a number`)
+	return nil
+}
+
+// parseType parses the dgo type that follows the keyword "is". The type extends to the first
+// unbalanced right parenthesis or bracket, or to the first "&&", "||", or "," that isn't nested
+// in parentheses, brackets, or braces.
+func (p *queryParser) parseType() dgo.Type {
+	p.skipWhitespace()
+	start := p.pos
+	depth := 0
+	s := p.source
+scan:
+	for ; p.pos < len(s); p.pos++ {
+		switch c := s[p.pos]; c {
+		case '(', '[', '{':
+			depth++
+		case ')', ']', '}':
+			if depth == 0 {
+				break scan
+			}
+			depth--
+		case '"', '`', '/':
+			// skip string or regexp literal
+			for p.pos++; p.pos < len(s) && s[p.pos] != c; p.pos++ {
+				if s[p.pos] == '\\' && c != '`' {
+					p.pos++
+				}
+			}
+		case ',':
+			if depth == 0 {
+				break scan
+			}
+		case '&', '|':
+			if depth == 0 && p.pos+1 < len(s) && s[p.pos+1] == c {
+				break scan
+			}
+		}
+	}
+	ts := strings.TrimSpace(s[start:p.pos])
+	if ts == `` {
+		p.fail(`expected a type`)
+	}
+	return p.parseTypeString(ts)
+}
+
+func (p *queryParser) parseTypeString(ts string) (t dgo.Type) {
+	defer func() {
+		if r := recover(); r != nil {
+			p.fail(fmt.Sprintf(`invalid type %q: %v`, ts, r))
+		}
+	}()
+	return Parse(ts)
+}
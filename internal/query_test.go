@@ -0,0 +1,200 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func ExampleCompileQuery() {
+	q, err := vf.CompileQuery(`$.services[?(@.port > 1024)].name`)
+	if err != nil {
+		panic(err)
+	}
+	cfg := vf.Map(map[string]interface{}{
+		`services`: []map[string]interface{}{
+			{`name`: `web`, `port`: 80},
+			{`name`: `app`, `port`: 8080},
+			{`name`: `db`, `port`: 5432},
+		}})
+	fmt.Println(q.Select(cfg))
+	// Output: ["app","db"]
+}
+
+// qmap returns a frozen Map with the given keys and values in the given order
+func qmap(kvs ...interface{}) dgo.Map {
+	return vf.Values(kvs...).ToMap()
+}
+
+func queryData() dgo.Value {
+	return qmap(`store`, qmap(
+		`book`, vf.Values(
+			qmap(`category`, `reference`, `author`, `Nigel Rees`, `title`, `Sayings of the Century`, `price`, 8.95),
+			qmap(`category`, `fiction`, `author`, `Evelyn Waugh`, `title`, `Sword of Honour`, `price`, 12.99),
+			qmap(`category`, `fiction`, `author`, `Herman Melville`, `title`, `Moby Dick`, `isbn`, `0-553-21311-3`,
+				`price`, 8.99),
+			qmap(`category`, `fiction`, `author`, `J. R. R. Tolkien`, `title`, `The Lord of the Rings`,
+				`isbn`, `0-395-19395-8`, `price`, 22.99)),
+		`bicycle`, qmap(`color`, `red`, `price`, 19.95)),
+		`limit`, 10.0)
+}
+
+func selectQuery(t *testing.T, expr string, v dgo.Value) dgo.Array {
+	t.Helper()
+	q, err := vf.CompileQuery(expr)
+	require.Nil(t, err)
+	require.Equal(t, expr, q.String())
+	return q.Select(v)
+}
+
+func TestCompileQuery_names(t *testing.T) {
+	d := queryData()
+	require.Equal(t, vf.Values(d), selectQuery(t, `$`, d))
+	require.Equal(t, vf.Values(`red`), selectQuery(t, `$.store.bicycle.color`, d))
+	require.Equal(t, vf.Values(`red`), selectQuery(t, `$['store']["bicycle"]['color']`, d))
+	require.Equal(t, vf.Values(`red`, 19.95), selectQuery(t, `$.store.bicycle['color','price']`, d))
+	require.Equal(t, vf.Values(), selectQuery(t, `$.store.car`, d))
+	require.Equal(t, vf.Values(), selectQuery(t, `$.store.book.title`, d))
+	require.Equal(t, vf.Values(`Nigel Rees`), selectQuery(t, ` $.store.book [ 0 ] .author `, d))
+	require.Equal(t, vf.Values(1), selectQuery(t, `$['it\'s']`, qmap(`it's`, 1)))
+	require.Equal(t, vf.Values(2), selectQuery(t, `$.a-b`, qmap(`a-b`, 2)))
+	require.Equal(t, vf.Values(`x`), selectQuery(t, `$.3`, qmap(3, `x`)))
+}
+
+func TestCompileQuery_indexes(t *testing.T) {
+	a := vf.Values(0, 1, 2, 3, 4, 5)
+	require.Equal(t, vf.Values(2), selectQuery(t, `$[2]`, a))
+	require.Equal(t, vf.Values(5), selectQuery(t, `$[-1]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[6]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[-7]`, a))
+	require.Equal(t, vf.Values(0, 5), selectQuery(t, `$[0,-1]`, a))
+	require.Equal(t, vf.Values(1, 2), selectQuery(t, `$[1:3]`, a))
+	require.Equal(t, vf.Values(0, 1), selectQuery(t, `$[:2]`, a))
+	require.Equal(t, vf.Values(4, 5), selectQuery(t, `$[-2:]`, a))
+	require.Equal(t, a, selectQuery(t, `$[:]`, a))
+	require.Equal(t, vf.Values(0, 2, 4), selectQuery(t, `$[::2]`, a))
+	require.Equal(t, vf.Values(5, 4, 3, 2, 1, 0), selectQuery(t, `$[::-1]`, a))
+	require.Equal(t, vf.Values(4, 2), selectQuery(t, `$[4:1:-2]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[::0]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[0:10]`, qmap(`a`, 1)))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[0]`, qmap(`a`, 1)))
+}
+
+func TestCompileQuery_wildcards(t *testing.T) {
+	d := queryData()
+	require.Equal(t, vf.Values(`red`, 19.95), selectQuery(t, `$.store.bicycle.*`, d))
+	require.Equal(t, 4, selectQuery(t, `$.store.book[*]`, d).Len())
+	require.Equal(t, vf.Values(), selectQuery(t, `$.limit.*`, d))
+	require.Equal(t,
+		vf.Values(`Nigel Rees`, `Evelyn Waugh`, `Herman Melville`, `J. R. R. Tolkien`),
+		selectQuery(t, `$.store.book[*].author`, d))
+	require.Equal(t,
+		vf.Values(`Nigel Rees`, `Evelyn Waugh`, `Herman Melville`, `J. R. R. Tolkien`),
+		selectQuery(t, `$..author`, d))
+	require.Equal(t, vf.Values(8.95, 12.99, 8.99, 22.99, 19.95), selectQuery(t, `$.store..price`, d))
+	require.Equal(t, vf.Values(`Moby Dick`), selectQuery(t, `$..book[2].title`, d))
+	require.Equal(t, vf.Values(`J. R. R. Tolkien`), selectQuery(t, `$..book[-1:].author`, d))
+	require.Equal(t, vf.Values(1, vf.Values(2, 3), 2, 3), selectQuery(t, `$..*`, vf.Values(1, vf.Values(2, 3))))
+}
+
+func TestCompileQuery_filters(t *testing.T) {
+	d := queryData()
+	require.Equal(t, vf.Values(`Moby Dick`, `The Lord of the Rings`), selectQuery(t, `$..book[?(@.isbn)].title`, d))
+	require.Equal(t, vf.Values(`Sayings of the Century`, `Sword of Honour`),
+		selectQuery(t, `$..book[?(!@.isbn)].title`, d))
+	require.Equal(t, vf.Values(`Sayings of the Century`, `Moby Dick`),
+		selectQuery(t, `$..book[?@.price < $.limit].title`, d))
+	require.Equal(t, vf.Values(`Sword of Honour`, `The Lord of the Rings`),
+		selectQuery(t, `$..book[?(@.price >= $['limit'])].title`, d))
+	require.Equal(t, vf.Values(`Moby Dick`),
+		selectQuery(t, `$..book[?(@.category == 'fiction' && @.price <= 10)].title`, d))
+	require.Equal(t, vf.Values(`Sayings of the Century`, `The Lord of the Rings`),
+		selectQuery(t, `$..book[?(@.category != "fiction" || @.price > 20.0)].title`, d))
+	require.Equal(t, vf.Values(`Sword of Honour`),
+		selectQuery(t, `$..book[?(@.author == 'Evelyn Waugh')].title`, d))
+	require.Equal(t, vf.Values(8.95, 12.99), selectQuery(t, `$..book[0,1,?(@.isbn == '-')].price`, d))
+
+	a := vf.Values(1, 2.5, `three`, true, nil, vf.Values(4), qmap(`a`, 5))
+	require.Equal(t, vf.Values(true), selectQuery(t, `$[?(@ == true)]`, a))
+	require.Equal(t, vf.Values(nil), selectQuery(t, `$[?(@ == null)]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[?(@ == false)]`, a))
+	require.Equal(t, vf.Values(2.5), selectQuery(t, `$[?(@ == 25e-1)]`, a))
+	require.Equal(t, vf.Values(1, 2.5, nil), selectQuery(t, `$[?(@ < 3)]`, a))
+	require.Equal(t, vf.Values(`three`), selectQuery(t, `$[?(@ > 'a')]`, a))
+	require.Equal(t, vf.Values(1), selectQuery(t, `$[?(@ <= -1 || @ == 1)]`, vf.Values(1, 0)))
+	require.Equal(t, vf.Values(qmap(`a`, 5)), selectQuery(t, `$[?(@.a)]`, a))
+	require.Equal(t, vf.Values(qmap(`a`, 5)), selectQuery(t, `$[?(@.a != @.b)]`, a))
+	require.Equal(t, vf.Values(1, 2.5, `three`, true, nil, vf.Values(4)), selectQuery(t, `$[?(@.a == @.b)]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[?(@.a < @.b)]`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[?(@.* > 1)]`, vf.Values(vf.Values(2, 3))))
+	require.Equal(t, vf.Values(vf.Values(4)), selectQuery(t, `$[?(@[0] == 4)]`, a))
+	require.Equal(t, vf.Values(5), selectQuery(t, `$[?(true)].a`, a))
+	require.Equal(t, vf.Values(), selectQuery(t, `$[?(1)]`, a))
+	require.Equal(t, vf.Values(2), selectQuery(t, `$.x[?($.y == @)]`, qmap(`x`, vf.Values(1, 2), `y`, 2)))
+	require.Equal(t, vf.Values(`a`), selectQuery(t, `$[?((@ == 'a'))]`, vf.Strings(`a`, `b`)))
+}
+
+func TestCompileQuery_isType(t *testing.T) {
+	a := vf.Values(0, 1, 50, 100, 101, `50`, 2.5, vf.Strings(`x`), vf.Values(1, `x`))
+	require.Equal(t, vf.Values(1, 50, 100), selectQuery(t, `$[?(@ is 1..100)]`, a))
+	require.Equal(t, vf.Values(1, 50, 100), selectQuery(t, `$[?@ is 1..100]`, a))
+	require.Equal(t, vf.Values(`50`, 2.5), selectQuery(t, `$[?(@ is string|float)]`, a))
+	require.Equal(t, vf.Values(vf.Strings(`x`)), selectQuery(t, `$[?(@ is []string)]`, a))
+	require.Equal(t, vf.Values(vf.Values(1, `x`)), selectQuery(t, `$[?(@ is {int, string})]`, a))
+	require.Equal(t, vf.Values(`50`), selectQuery(t, `$[?(@ is /^\d+$/)]`, a))
+	require.Equal(t, vf.Values(`50`), selectQuery(t, `$[?(@ is "50")]`, a))
+	require.Equal(t, vf.Values(0, 101), selectQuery(t, `$[?(@ is int && !(@ is 1..100))]`, a))
+	require.Equal(t, vf.Values(0, 2.5), selectQuery(t, `$[?(@ is 0||@ is float)]`, a))
+	require.Equal(t, vf.Values(50, 50), selectQuery(t, `$[?@ is 50, ?(@ is 50..50)]`, a))
+	require.Equal(t, vf.Values(qmap(`port`, 22)), selectQuery(t, `$[?(@.port is 1..1023)]`,
+		vf.Values(qmap(`port`, 22), qmap(`port`, 8080), qmap(`name`, `x`))))
+}
+
+func TestCompileQuery_frozenResult(t *testing.T) {
+	m := vf.MutableMap(0, nil)
+	v := vf.MutableValues(nil, 1)
+	m.Put(`a`, v)
+	r := selectQuery(t, `$.a`, m)
+	require.True(t, r.Frozen())
+	require.True(t, r.Get(0).(dgo.Array).Frozen())
+	require.False(t, v.Frozen())
+}
+
+func TestCompileQuery_recursive(t *testing.T) {
+	m := vf.MutableMap(0, nil)
+	m.Put(`name`, `root`)
+	m.Put(`self`, m)
+	require.Equal(t, vf.Values(`root`), selectQuery(t, `$..name`, m))
+}
+
+func TestCompileQuery_errors(t *testing.T) {
+	for expr, msg := range map[string]string{
+		``:                   `query "": expected '\$' at position 0`,
+		`a`:                  `expected '\$' at position 0`,
+		`$a`:                 `unexpected character 'a' at position 1`,
+		`$.`:                 `expected a name or '\*' at position 2`,
+		`$[`:                 `expected a selector at position 2`,
+		`$[1`:                `expected ',' or ']' at position 3`,
+		`$[-]`:               `expected an integer at position 2`,
+		`$['a`:               `unterminated string at position 4`,
+		`$['\x']`:            `invalid escape sequence at position 4`,
+		`$[?(@.a == )]`:      `expected a literal, '@', '\$', or '\(' at position 11`,
+		`$[?(@.a == 1]`:      `expected '\)' at position 12`,
+		`$[?(@.a == 1.e)]`:   `expected a number at position 11`,
+		`$[?(@ is )]`:        `expected a type at position 9`,
+		`$[?(@ is int[)]`:    `invalid type "int\[\)"`,
+		`$[?(@ == nothing)]`: `expected a literal`,
+	} {
+		_, err := vf.CompileQuery(expr)
+		require.NotNil(t, err)
+		require.Panic(t, func() { panic(err) }, msg)
+	}
+}
+
+func TestCompileQuery_escapes(t *testing.T) {
+	m := qmap("a\n", 1, "b\t", 2, "c\r", 3, `d\`, 4, `e"`, 5, `f/`, 6)
+	require.Equal(t, vf.Values(1, 2, 3, 4, 5, 6), selectQuery(t, `$["a\n","b\t","c\r","d\\","e\"","f\/"]`, m))
+}
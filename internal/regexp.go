@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/lyraproj/dgo/dgo"
+	"gopkg.in/yaml.v3"
 )
 
 type (
@@ -31,6 +32,14 @@ func (t regexpType) Equals(v interface{}) bool {
 	return t == v
 }
 
+func (t *regexpType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t regexpType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t regexpType) HashCode() int {
 	return int(dgo.IdRegexp)
 }
@@ -66,6 +75,14 @@ func (t *exactRegexpType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *exactRegexpType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactRegexpType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactRegexpType) HashCode() int {
 	return (*Regexp)(t).HashCode()*31 + int(dgo.IdRegexpExact)
 }
@@ -115,10 +132,22 @@ func (v *Regexp) Equals(other interface{}) bool {
 	return false
 }
 
+func (v *Regexp) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v *Regexp) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v *Regexp) HashCode() int {
 	return stringHash((*regexp.Regexp)(v).String())
 }
 
+func (v *Regexp) MarshalYAML() (interface{}, error) {
+	return &yaml.Node{Kind: yaml.ScalarNode, Tag: RegexpTag, Value: v.String()}, nil
+}
+
 func (v *Regexp) String() string {
 	return (*regexp.Regexp)(v).String()
 }
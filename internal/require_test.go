@@ -2,9 +2,12 @@ package internal_test
 
 import (
 	"errors"
+	"os"
 	"testing"
 
+	"github.com/lyraproj/dgo/dgo"
 	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
 	"github.com/lyraproj/dgo/typ"
 )
 
@@ -64,4 +67,30 @@ func TestTheTester(t *testing.T) {
 	ensureFailed(t, func(ft *testing.T) {
 		require.Panic(ft, func() { panic(errors.New(`this`)) }, `that`)
 	})
+	ensureFailed(t, func(ft *testing.T) {
+		require.ForAll(ft, typ.Integer, func(v dgo.Value) bool { return v.(dgo.Integer).GoInt() < 10 })
+	})
+	ensureFailed(t, func(ft *testing.T) {
+		require.ForAll(ft, typ.String, func(v dgo.Value) bool { panic(`fail`) })
+	})
+	ensureFailed(t, func(ft *testing.T) {
+		require.ForAll(ft, newtype.Parse(`int&string`), func(v dgo.Value) bool { return true })
+	})
+	ensureFailed(t, func(ft *testing.T) {
+		_ = os.Setenv(`DGO_SEED`, `x`)
+		defer func() { _ = os.Unsetenv(`DGO_SEED`) }()
+		require.ForAll(ft, typ.Integer, func(v dgo.Value) bool { return true })
+	})
+}
+
+func TestForAll(t *testing.T) {
+	require.ForAll(t, newtype.Parse(`[0,5]0..100`), func(v dgo.Value) bool {
+		return v.(dgo.Array).Len() <= 5
+	})
+	_ = os.Setenv(`DGO_SEED`, `42`)
+	defer func() { _ = os.Unsetenv(`DGO_SEED`) }()
+	require.ForAll(t, newtype.Parse(`{"a":string[1,4],"b"?:float}`), func(v dgo.Value) bool {
+		_, ok := v.(dgo.Map).Get(`a`)
+		return ok
+	})
 }
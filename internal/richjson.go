@@ -0,0 +1,260 @@
+package internal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"regexp"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+)
+
+// The keys of the JSON object that represents a value that has no natural JSON representation
+const (
+	richTypeKey  = `__type`
+	richValueKey = `__value`
+)
+
+// MarshalRichJSON returns a JSON encoding of the given value that can be decoded back into an equal value
+// using UnmarshalRichJSON. Values that have no natural JSON representation are written as an object with
+// the two keys "__type" and "__value". The following values are tagged this way:
+//
+// Binary: {"__type":"binary","__value":"<base64 encoded bytes>"}
+//
+// Regexp: {"__type":"regexp","__value":"<regexp source>"}
+//
+// Error: {"__type":"error","__value":"<error message>"}
+//
+// Type: {"__type":"type","__value":"<type expression>"}
+//
+// Set: {"__type":"set","__value":[<elements>]}
+//
+// A Map where one or more keys are not strings, or where a key is "__type", is written as
+// {"__type":"map","__value":[[<key>,<value>],...]}.
+//
+// An error is returned if the value contains a Native value.
+func MarshalRichJSON(v dgo.Value) ([]byte, error) {
+	rv, err := toRichData(v)
+	if err != nil {
+		return nil, err
+	}
+	// Scalars are written the same way as when they are elements of a collection
+	w := util.NewIndenter(``)
+	w.AppendValue(rv)
+	return []byte(w.String()), nil
+}
+
+// UnmarshalRichJSON decodes JSON produced by MarshalRichJSON into a frozen dgo.Value.
+func UnmarshalRichJSON(b []byte) (dgo.Value, error) {
+	v, err := UnmarshalJSON(b)
+	if err != nil {
+		return nil, err
+	}
+	return fromRichData(v)
+}
+
+// toRichData converts the given value into a value that consists only of values that have a natural
+// JSON representation.
+func toRichData(v dgo.Value) (dgo.Value, error) {
+	switch v := v.(type) {
+	case nilValue, Boolean, Integer, Float, *hstring:
+		return v, nil
+	case dgo.Type:
+		return richTagged(`type`, makeHString(TypeString(v))), nil
+	case *binary:
+		return richTagged(`binary`, makeHString(v.String())), nil
+	case *Regexp:
+		return richTagged(`regexp`, makeHString(v.String())), nil
+	case error:
+		return richTagged(`error`, makeHString(v.Error())), nil
+	case *hashSet:
+		es, err := toRichSlice(v.m.Keys().(*array).slice)
+		if err != nil {
+			return nil, err
+		}
+		return richTagged(`set`, &array{slice: es, frozen: true}), nil
+	case dgo.Array:
+		es, err := toRichSlice(sliceOf(v))
+		if err != nil {
+			return nil, err
+		}
+		return &array{slice: es, frozen: true}, nil
+	case dgo.Map:
+		return toRichMap(v)
+	}
+	return nil, fmt.Errorf(`unable to encode a value of type %s as rich JSON`, TypeString(v.Type()))
+}
+
+func toRichSlice(vs []dgo.Value) ([]dgo.Value, error) {
+	rs := make([]dgo.Value, len(vs))
+	for i, e := range vs {
+		r, err := toRichData(e)
+		if err != nil {
+			return nil, err
+		}
+		rs[i] = r
+	}
+	return rs, nil
+}
+
+func toRichMap(m dgo.Map) (dgo.Value, error) {
+	plain := m.AllKeys(func(k dgo.Value) bool {
+		s, ok := k.(*hstring)
+		return ok && s.s != richTypeKey
+	})
+	rm := MutableMap(m.Len(), nil)
+	es := make([]dgo.Value, 0, m.Len())
+	var err error
+	m.Each(func(e dgo.MapEntry) {
+		if err != nil {
+			return
+		}
+		var k, v dgo.Value
+		if v, err = toRichData(e.Value()); err != nil {
+			return
+		}
+		if plain {
+			rm.Put(e.Key(), v)
+			return
+		}
+		if k, err = toRichData(e.Key()); err == nil {
+			es = append(es, &array{slice: []dgo.Value{k, v}, frozen: true})
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if plain {
+		rm.Freeze()
+		return rm, nil
+	}
+	return richTagged(`map`, &array{slice: es, frozen: true}), nil
+}
+
+func richTagged(tag string, v dgo.Value) dgo.Value {
+	m := MutableMap(2, nil)
+	m.Put(richTypeKey, tag)
+	m.Put(richValueKey, v)
+	m.Freeze()
+	return m
+}
+
+// fromRichData converts the given value, decoded from JSON, back into the value that was used when
+// producing the JSON.
+func fromRichData(v dgo.Value) (dgo.Value, error) {
+	switch v := v.(type) {
+	case *array:
+		es, err := fromRichSlice(v.slice)
+		if err != nil {
+			return nil, err
+		}
+		return &array{slice: es, frozen: true}, nil
+	case *hashMap:
+		if tag, ok := v.Get(richTypeKey); ok {
+			return fromRichTagged(tag, v)
+		}
+		m := MutableMap(v.Len(), nil)
+		var err error
+		v.Each(func(e dgo.MapEntry) {
+			if err == nil {
+				var ev dgo.Value
+				if ev, err = fromRichData(e.Value()); err == nil {
+					m.Put(e.Key(), ev)
+				}
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+		m.Freeze()
+		return m, nil
+	}
+	return v, nil
+}
+
+func fromRichSlice(vs []dgo.Value) ([]dgo.Value, error) {
+	rs := make([]dgo.Value, len(vs))
+	for i, e := range vs {
+		r, err := fromRichData(e)
+		if err != nil {
+			return nil, err
+		}
+		rs[i] = r
+	}
+	return rs, nil
+}
+
+func fromRichTagged(tag dgo.Value, m dgo.Map) (dgo.Value, error) {
+	v, ok := m.Get(richValueKey)
+	if !ok || m.Len() != 2 {
+		return nil, fmt.Errorf(`a rich JSON object must have exactly the keys "%s" and "%s"`, richTypeKey, richValueKey)
+	}
+	switch tag.String() {
+	case `binary`:
+		if s, ok := v.(*hstring); ok {
+			bs, err := base64.StdEncoding.Strict().DecodeString(s.s)
+			if err != nil {
+				return nil, err
+			}
+			return &binary{bytes: bs, frozen: true}, nil
+		}
+	case `regexp`:
+		if s, ok := v.(*hstring); ok {
+			rx, err := regexp.Compile(s.s)
+			if err != nil {
+				return nil, err
+			}
+			return (*Regexp)(rx), nil
+		}
+	case `error`:
+		if s, ok := v.(*hstring); ok {
+			return &errw{errors.New(s.s)}, nil
+		}
+	case `type`:
+		if s, ok := v.(*hstring); ok {
+			return parseType(s.s)
+		}
+	case `set`:
+		if a, ok := v.(*array); ok {
+			es, err := fromRichSlice(a.slice)
+			if err != nil {
+				return nil, err
+			}
+			s := newHashSet(len(es))
+			for _, e := range es {
+				s.Add(e)
+			}
+			s.Freeze()
+			return s, nil
+		}
+	case `map`:
+		if a, ok := v.(*array); ok {
+			return fromRichEntries(a.slice)
+		}
+	default:
+		return nil, fmt.Errorf(`unknown rich JSON type '%s'`, tag)
+	}
+	return nil, fmt.Errorf(`illegal value for rich JSON type '%s'`, tag)
+}
+
+func fromRichEntries(es []dgo.Value) (dgo.Value, error) {
+	m := MutableMap(len(es), nil)
+	for _, e := range es {
+		a, ok := e.(*array)
+		if !ok || len(a.slice) != 2 {
+			return nil, errors.New(`a rich JSON map entry must be an array with a key and a value`)
+		}
+		k, err := fromRichData(a.slice[0])
+		if err != nil {
+			return nil, err
+		}
+		v, err := fromRichData(a.slice[1])
+		if err != nil {
+			return nil, err
+		}
+		m.Put(k, v)
+	}
+	m.Freeze()
+	return m, nil
+}
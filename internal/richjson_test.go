@@ -0,0 +1,97 @@
+package internal_test
+
+import (
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func richRoundTrip(t *testing.T, v dgo.Value) string {
+	t.Helper()
+	b, err := vf.MarshalRichJSON(v)
+	require.Nil(t, err)
+	r, err := vf.UnmarshalRichJSON(b)
+	require.Nil(t, err)
+	require.Equal(t, v, r)
+	return string(b)
+}
+
+func TestMarshalRichJSON(t *testing.T) {
+	require.Equal(t, `[1,2.0,"a",true,null]`, richRoundTrip(t, vf.Values(1, 2.0, `a`, true, nil)))
+	require.Equal(t, `1`, richRoundTrip(t, vf.Value(1)))
+	require.Equal(t, `2.5`, richRoundTrip(t, vf.Value(2.5)))
+	require.Equal(t, `2.0`, richRoundTrip(t, vf.Value(2.0)))
+	require.Equal(t, `true`, richRoundTrip(t, vf.Value(true)))
+	require.Equal(t, `null`, richRoundTrip(t, vf.Nil))
+	require.Equal(t, `"a\"b"`, richRoundTrip(t, vf.Value(`a"b`)))
+	require.Equal(t, `{"a":1,"b":[1]}`, richRoundTrip(t, vf.Map(map[string]interface{}{`a`: 1, `b`: vf.Values(1)})))
+	require.Equal(t, `{"__type":"binary","__value":"AQID"}`, richRoundTrip(t, vf.Value([]byte{1, 2, 3})))
+	require.Equal(t, `{"__type":"regexp","__value":"^a+\\/$"}`, richRoundTrip(t, vf.Value(regexp.MustCompile(`^a+\/$`))))
+	require.Equal(t, `{"__type":"error","__value":"oops"}`, richRoundTrip(t, vf.Value(errors.New(`oops`))))
+	require.Equal(t, `{"__type":"type","__value":"map[string]1..3"}`, richRoundTrip(t, newtype.Parse(`map[string]1..3`)))
+	require.Equal(t, `{"__type":"set","__value":[1,"a"]}`, richRoundTrip(t, vf.Set(1, `a`)))
+	require.Equal(t, `{"__type":"type","__value":"set{1,\"a\"}"}`, richRoundTrip(t, vf.Set(1, `a`).Type()))
+	m := vf.MutableMap(2, nil)
+	m.Put(1, `a`)
+	m.Put(vf.Values(1, 2), typ.Integer)
+	require.Equal(t, `{"__type":"map","__value":[[1,"a"],[[1,2],{"__type":"type","__value":"int"}]]}`,
+		richRoundTrip(t, m))
+	require.Equal(t, `{"__type":"map","__value":[["__type","x"]]}`,
+		richRoundTrip(t, vf.Map(map[string]string{`__type`: `x`})))
+}
+
+func TestMarshalRichJSON_nested(t *testing.T) {
+	v := vf.Map(map[string]interface{}{
+		`bin`:   []byte(`hello`),
+		`types`: vf.Values(typ.String, newtype.Parse(`{"a":int,"b"?:string}`)),
+		`sets`:  vf.Values(vf.Set(vf.Value(regexp.MustCompile(`x`)))),
+		`map`:   vf.Map(map[int]interface{}{1: vf.Map(map[bool]string{true: `t`})}),
+	})
+	richRoundTrip(t, v)
+	richRoundTrip(t, vf.SortedMap(map[string]int{`b`: 2, `a`: 1}))
+	richRoundTrip(t, vf.PersistentValues(1, vf.Set(2)))
+}
+
+func TestMarshalRichJSON_fail(t *testing.T) {
+	n := vf.Value(struct{ A int }{1})
+	_, err := vf.MarshalRichJSON(vf.MutableValues(nil, n))
+	require.NotNil(t, err)
+
+	m := vf.MutableMap(0, nil)
+	m.Put(1, n)
+	_, err = vf.MarshalRichJSON(m)
+	require.NotNil(t, err)
+
+	m = vf.MutableMap(0, nil)
+	m.Put(`a`, n)
+	_, err = vf.MarshalRichJSON(vf.MutableValues(nil, m))
+	require.NotNil(t, err)
+}
+
+func TestUnmarshalRichJSON_fail(t *testing.T) {
+	for _, s := range []string{
+		`[1,`,
+		`{"__type":"binary"}`,
+		`{"__type":"binary","__value":"x","y":1}`,
+		`{"__type":"binary","__value":"***"}`,
+		`{"__type":"binary","__value":1}`,
+		`{"__type":"regexp","__value":"("}`,
+		`{"__type":"type","__value":"map["}`,
+		`{"__type":"set","__value":"x"}`,
+		`{"__type":"set","__value":[{"__type":"unknown","__value":1}]}`,
+		`{"__type":"map","__value":[1]}`,
+		`{"__type":"map","__value":[[{"__type":"x","__value":1},1]]}`,
+		`{"__type":"map","__value":[[1,{"__type":"x","__value":1}]]}`,
+		`{"__type":"unknown","__value":1}`,
+		`[{"a":{"__type":"unknown","__value":1}}]`,
+	} {
+		_, err := vf.UnmarshalRichJSON([]byte(s))
+		require.NotNil(t, err)
+	}
+}
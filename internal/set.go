@@ -0,0 +1,614 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// hashSet is an insertion ordered Set that uses the hash table of a hashMap. The elements of
+	// the set are the keys of the map. All values of the map are True.
+	hashSet struct {
+		m   *hashMap
+		typ dgo.SetType
+	}
+
+	// defaultSetType is the unconstrained set type
+	defaultSetType int
+
+	// sizedSetType represents a set with element type constraint and a size constraint
+	sizedSetType struct {
+		elementType dgo.Type
+		min         int
+		max         int
+	}
+
+	// exactSetType only matches the set that it represents
+	exactSetType hashSet
+)
+
+const DefaultSetType = defaultSetType(0)
+
+// SetType returns a type that represents a Set value
+func SetType(args ...interface{}) dgo.SetType {
+	switch len(args) {
+	case 0:
+		return DefaultSetType
+	case 1:
+		switch a0 := Value(args[0]).(type) {
+		case dgo.Type:
+			return newSetType(a0, 0, math.MaxInt64)
+		case dgo.Integer:
+			return newSetType(nil, int(a0.GoInt()), math.MaxInt64)
+		default:
+			panic(illegalArgument(`Set`, `Type or Integer`, args, 0))
+		}
+	case 2:
+		a1, ok := Value(args[1]).(dgo.Integer)
+		if !ok {
+			panic(illegalArgument(`Set`, `Integer`, args, 1))
+		}
+		switch a0 := Value(args[0]).(type) {
+		case dgo.Type:
+			return newSetType(a0, int(a1.GoInt()), math.MaxInt64)
+		case dgo.Integer:
+			return newSetType(nil, int(a0.GoInt()), int(a1.GoInt()))
+		default:
+			panic(illegalArgument(`Set`, `Type or Integer`, args, 0))
+		}
+	case 3:
+		a0, ok := Value(args[0]).(dgo.Type)
+		if !ok {
+			panic(illegalArgument(`Set`, `Type`, args, 0))
+		}
+		a1, ok := Value(args[1]).(dgo.Integer)
+		if !ok {
+			panic(illegalArgument(`Set`, `Integer`, args, 1))
+		}
+		a2, ok := Value(args[2]).(dgo.Integer)
+		if !ok {
+			panic(illegalArgument(`Set`, `Integer`, args, 2))
+		}
+		return newSetType(a0, int(a1.GoInt()), int(a2.GoInt()))
+	default:
+		panic(fmt.Errorf(`illegal number of arguments for Set. Expected 0 - 3, got %d`, len(args)))
+	}
+}
+
+func newSetType(elementType dgo.Type, min, max int) dgo.SetType {
+	if min < 0 {
+		min = 0
+	}
+	if max < 0 {
+		max = 0
+	}
+	if max < min {
+		t := max
+		max = min
+		min = t
+	}
+	if elementType == nil {
+		elementType = DefaultAnyType
+	}
+	if min == 0 && max == math.MaxInt64 && elementType == DefaultAnyType {
+		// Unbounded
+		return DefaultSetType
+	}
+	return &sizedSetType{elementType: elementType, min: min, max: max}
+}
+
+func (t defaultSetType) Assignable(other dgo.Type) bool {
+	switch other.(type) {
+	case defaultSetType, *exactSetType, *sizedSetType:
+		return true
+	}
+	return CheckAssignableTo(nil, other, t)
+}
+
+func (t defaultSetType) ElementType() dgo.Type {
+	return DefaultAnyType
+}
+
+func (t defaultSetType) Equals(other interface{}) bool {
+	return t == other
+}
+
+func (t *defaultSetType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t defaultSetType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
+func (t defaultSetType) HashCode() int {
+	return int(dgo.IdSet)
+}
+
+func (t defaultSetType) Instance(value interface{}) bool {
+	_, ok := value.(dgo.Set)
+	return ok
+}
+
+func (t defaultSetType) Max() int {
+	return math.MaxInt64
+}
+
+func (t defaultSetType) Min() int {
+	return 0
+}
+
+func (t defaultSetType) String() string {
+	return TypeString(t)
+}
+
+func (t defaultSetType) Type() dgo.Type {
+	return &metaType{t}
+}
+
+func (t defaultSetType) TypeIdentifier() dgo.TypeIdentifier {
+	return dgo.IdSet
+}
+
+func (t defaultSetType) Unbounded() bool {
+	return true
+}
+
+func (t *sizedSetType) Assignable(other dgo.Type) bool {
+	return Assignable(nil, t, other)
+}
+
+func (t *sizedSetType) DeepAssignable(guard dgo.RecursionGuard, other dgo.Type) bool {
+	switch ot := other.(type) {
+	case defaultSetType:
+		return false // lacks size
+	case *sizedSetType:
+		return t.min <= ot.min && ot.max <= t.max && Assignable(guard, t.elementType, ot.elementType)
+	case *exactSetType:
+		l := ot.m.len
+		return t.min <= l && l <= t.max && ot.m.AllKeys(func(k dgo.Value) bool { return Instance(guard, t.elementType, k) })
+	}
+	return CheckAssignableTo(guard, other, t)
+}
+
+func (t *sizedSetType) ElementType() dgo.Type {
+	return t.elementType
+}
+
+func (t *sizedSetType) Equals(other interface{}) bool {
+	if ot, ok := other.(*sizedSetType); ok {
+		return t.min == ot.min && t.max == ot.max && t.elementType.Equals(ot.elementType)
+	}
+	return false
+}
+
+func (t *sizedSetType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *sizedSetType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
+func (t *sizedSetType) HashCode() int {
+	h := int(dgo.IdSet)
+	if t.min > 0 {
+		h = h*31 + t.min
+	}
+	if t.max < math.MaxInt64 {
+		h = h*31 + t.max
+	}
+	if DefaultAnyType != t.elementType {
+		h = h*31 + t.elementType.HashCode()
+	}
+	return h
+}
+
+func (t *sizedSetType) Instance(value interface{}) bool {
+	return Instance(nil, t, Value(value))
+}
+
+func (t *sizedSetType) DeepInstance(guard dgo.RecursionGuard, value dgo.Value) bool {
+	if ov, ok := value.(*hashSet); ok {
+		l := ov.m.len
+		return t.min <= l && l <= t.max && ov.m.AllKeys(func(k dgo.Value) bool { return Instance(guard, t.elementType, k) })
+	}
+	return false
+}
+
+func (t *sizedSetType) Max() int {
+	return t.max
+}
+
+func (t *sizedSetType) Min() int {
+	return t.min
+}
+
+func (t *sizedSetType) String() string {
+	return TypeString(t)
+}
+
+func (t *sizedSetType) Type() dgo.Type {
+	return &metaType{t}
+}
+
+func (t *sizedSetType) TypeIdentifier() dgo.TypeIdentifier {
+	return dgo.IdSetElementSized
+}
+
+func (t *sizedSetType) Unbounded() bool {
+	return t.min == 0 && t.max == math.MaxInt64
+}
+
+func (t *exactSetType) Assignable(other dgo.Type) bool {
+	if ot, ok := other.(*exactSetType); ok {
+		return (*hashSet)(t).Equals((*hashSet)(ot))
+	}
+	return CheckAssignableTo(nil, other, t)
+}
+
+// ElementType returns a type that represents any of the elements of the set
+func (t *exactSetType) ElementType() dgo.Type {
+	ts := make([]dgo.Type, 0, t.m.len)
+	for e := t.m.first; e != nil; e = e.next {
+		ts = append(ts, e.key.Type())
+	}
+	return AnyOfType(ts)
+}
+
+func (t *exactSetType) Equals(other interface{}) bool {
+	if ot, ok := other.(*exactSetType); ok {
+		return (*hashSet)(t).Equals((*hashSet)(ot))
+	}
+	return false
+}
+
+func (t *exactSetType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactSetType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
+func (t *exactSetType) HashCode() int {
+	return (*hashSet)(t).HashCode()*7 + int(dgo.IdSetExact)
+}
+
+func (t *exactSetType) Instance(value interface{}) bool {
+	if ov, ok := value.(*hashSet); ok {
+		return (*hashSet)(t).Equals(ov)
+	}
+	return false
+}
+
+func (t *exactSetType) Max() int {
+	return t.m.len
+}
+
+func (t *exactSetType) Min() int {
+	return t.m.len
+}
+
+func (t *exactSetType) String() string {
+	return TypeString(t)
+}
+
+func (t *exactSetType) Type() dgo.Type {
+	return &metaType{t}
+}
+
+func (t *exactSetType) TypeIdentifier() dgo.TypeIdentifier {
+	return dgo.IdSetExact
+}
+
+func (t *exactSetType) Unbounded() bool {
+	return false
+}
+
+func (t *exactSetType) Value() dgo.Value {
+	s := (*hashSet)(t)
+	return s
+}
+
+// Set returns a frozen dgo.Set that contains the given values
+func Set(values []interface{}) dgo.Set {
+	s := newHashSet(len(values))
+	for i := range values {
+		s.m.Put(values[i], True)
+	}
+	s.m.frozen = true
+	return s
+}
+
+// MutableSet returns a mutable dgo.Set that contains the given values. The set will be constrained
+// by the given type unless it is nil.
+func MutableSet(t dgo.SetType, values []interface{}) dgo.Set {
+	s := newHashSet(len(values))
+	for i := range values {
+		s.m.Put(values[i], True)
+	}
+	if t != nil {
+		l := s.m.len
+		if l < t.Min() || l > t.Max() {
+			panic(IllegalSize(t, l))
+		}
+		et := t.ElementType()
+		if DefaultAnyType != et {
+			for e := s.m.first; e != nil; e = e.next {
+				if !et.Instance(e.key) {
+					panic(IllegalAssignment(et, e.key))
+				}
+			}
+		}
+		s.typ = t
+	}
+	return s
+}
+
+func newHashSet(capacity int) *hashSet {
+	return &hashSet{m: &hashMap{table: make([]*hashNode, tableSizeFor(int(float64(capacity)/loadFactor)))}}
+}
+
+func (s *hashSet) assertType(v dgo.Value, addedSize int) {
+	if t := s.typ; t != nil {
+		et := t.ElementType()
+		if !et.Instance(v) {
+			panic(IllegalAssignment(et, v))
+		}
+		if addedSize > 0 {
+			sz := s.m.len + addedSize
+			if sz > t.Max() {
+				panic(IllegalSize(t, sz))
+			}
+		}
+	}
+}
+
+func (s *hashSet) Add(vi interface{}) bool {
+	if s.m.frozen {
+		panic(frozenSet(`Add`))
+	}
+	v := Value(vi)
+	if _, ok := s.m.Get(v); ok {
+		return false
+	}
+	s.assertType(v, 1)
+	s.m.Put(v, True)
+	return true
+}
+
+func (s *hashSet) AddAll(values dgo.Iterable) {
+	if s.m.frozen {
+		panic(frozenSet(`AddAll`))
+	}
+	values.Each(func(v dgo.Value) { s.Add(v) })
+}
+
+func (s *hashSet) AppendTo(w *util.Indenter) {
+	w.AppendRune('[')
+	ew := w.Indent()
+	for e := s.m.first; e != nil; e = e.next {
+		if e != s.m.first {
+			w.AppendRune(',')
+		}
+		ew.NewLine()
+		ew.AppendValue(e.key)
+	}
+	w.NewLine()
+	w.AppendRune(']')
+}
+
+func (s *hashSet) Contains(vi interface{}) bool {
+	_, ok := s.m.Get(vi)
+	return ok
+}
+
+func (s *hashSet) Copy(frozen bool) dgo.Set {
+	if frozen && s.m.frozen {
+		return s
+	}
+	return &hashSet{m: s.m.Copy(frozen).(*hashMap), typ: s.typ}
+}
+
+func (s *hashSet) Difference(other dgo.Set) dgo.Set {
+	return s.filter(func(v dgo.Value) bool { return !other.Contains(v) })
+}
+
+func (s *hashSet) Each(doer dgo.Doer) {
+	s.m.EachKey(doer)
+}
+
+func (s *hashSet) Equals(other interface{}) bool {
+	if os, ok := other.(*hashSet); ok {
+		return s.m.len == os.m.len && s.SubsetOf(os)
+	}
+	return false
+}
+
+// filter returns a new set with the same type and frozen state as this set that contains the elements
+// of this set for which the given function returns true.
+func (s *hashSet) filter(f func(dgo.Value) bool) dgo.Set {
+	c := newHashSet(s.m.len)
+	c.typ = s.typ
+	for e := s.m.first; e != nil; e = e.next {
+		if f(e.key) {
+			c.m.Put(e.key, True)
+		}
+	}
+	if c.typ != nil && c.m.len < c.typ.Min() {
+		panic(IllegalSize(c.typ, c.m.len))
+	}
+	c.m.frozen = s.m.frozen
+	return c
+}
+
+func (s *hashSet) Freeze() {
+	s.m.Freeze()
+}
+
+func (s *hashSet) Frozen() bool {
+	return s.m.frozen
+}
+
+func (s *hashSet) FrozenCopy() dgo.Value {
+	return s.Copy(true)
+}
+
+func (s *hashSet) GobDecode(b []byte) error {
+	return gobDecodeInto(b, s)
+}
+
+func (s *hashSet) GobEncode() ([]byte, error) {
+	return gobEncode(s)
+}
+
+// HashCode returns the sum of the hash codes of all elements. The order of the elements is
+// insignificant.
+func (s *hashSet) HashCode() int {
+	h := int(dgo.IdSet)
+	for e := s.m.first; e != nil; e = e.next {
+		h += e.key.HashCode()
+	}
+	return h
+}
+
+func (s *hashSet) Intersection(other dgo.Set) dgo.Set {
+	return s.filter(func(v dgo.Value) bool { return other.Contains(v) })
+}
+
+func (s *hashSet) Len() int {
+	return s.m.len
+}
+
+func (s *hashSet) MarshalJSON() ([]byte, error) {
+	return []byte(util.ToString(s)), nil
+}
+
+// MarshalYAML returns a *yaml.Node that represents this Set as a sequence.
+func (s *hashSet) MarshalYAML() (interface{}, error) {
+	return yamlEncodeValue(s)
+}
+
+func (s *hashSet) Remove(vi interface{}) bool {
+	if s.m.frozen {
+		panic(frozenSet(`Remove`))
+	}
+	v := Value(vi)
+	if _, ok := s.m.Get(v); !ok {
+		return false
+	}
+	if s.typ != nil {
+		if sz := s.m.len - 1; sz < s.typ.Min() {
+			panic(IllegalSize(s.typ, sz))
+		}
+	}
+	s.m.Remove(v)
+	return true
+}
+
+func (s *hashSet) SetType(t dgo.SetType) {
+	if s.m.frozen {
+		panic(frozenSet(`SetType`))
+	}
+	if t.Instance(s) {
+		s.typ = t
+		return
+	}
+	panic(IllegalAssignment(t, s))
+}
+
+func (s *hashSet) String() string {
+	return util.ToString(s)
+}
+
+func (s *hashSet) SubsetOf(other dgo.Set) bool {
+	if s.m.len > other.Len() {
+		return false
+	}
+	for e := s.m.first; e != nil; e = e.next {
+		if !other.Contains(e.key) {
+			return false
+		}
+	}
+	return true
+}
+
+func (s *hashSet) ToArray() dgo.Array {
+	return s.m.Keys()
+}
+
+func (s *hashSet) Type() dgo.Type {
+	if s.typ == nil {
+		return (*exactSetType)(s)
+	}
+	return s.typ
+}
+
+func (s *hashSet) Union(other dgo.Set) dgo.Set {
+	if s.m.frozen && (other.Len() == 0 || SameInstance(s, other)) {
+		return s
+	}
+	c := &hashSet{m: &hashMap{len: s.m.len}, typ: s.typ}
+	s.m.resize(c.m, other.Len())
+	other.Each(func(v dgo.Value) {
+		if _, ok := c.m.Get(v); !ok {
+			c.assertType(v, 1)
+			c.m.Put(v, True)
+		}
+	})
+	c.m.frozen = s.m.frozen
+	return c
+}
+
+func (s *hashSet) UnmarshalJSON(b []byte) error {
+	if s.m.frozen {
+		panic(frozenSet(`UnmarshalJSON`))
+	}
+	d := newMutableJSONDecoder(b)
+	t, err := d.dec.Token()
+	if err == nil {
+		if delim, ok := t.(json.Delim); !ok || delim != '[' {
+			return errors.New("expecting data to be an array")
+		}
+		var a *array
+		a, err = d.decodeArray()
+		if err == nil {
+			*s = *setFromSlice(a.slice)
+		}
+	}
+	return err
+}
+
+func (s *hashSet) UnmarshalYAML(n *yaml.Node) error {
+	if s.m.frozen {
+		panic(frozenSet(`UnmarshalYAML`))
+	}
+	if n.Kind != yaml.SequenceNode {
+		return errors.New("expecting data to be an array")
+	}
+	a, err := (&yamlDecoder{}).decodeArray(n)
+	if err == nil {
+		*s = *setFromSlice(a.slice)
+	}
+	return err
+}
+
+// setFromSlice creates a mutable set from the given slice
+func setFromSlice(vs []dgo.Value) *hashSet {
+	s := newHashSet(len(vs))
+	for i := range vs {
+		s.m.Put(vs[i], True)
+	}
+	return s
+}
+
+func frozenSet(f string) error {
+	return fmt.Errorf(`%s called on a frozen Set`, f)
+}
@@ -0,0 +1,283 @@
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/internal"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+	"gopkg.in/yaml.v3"
+)
+
+func ExampleSet() {
+	s := vf.Set(`a`, `b`, `a`, `c`)
+	fmt.Println(s)
+	fmt.Println(s.Contains(`b`))
+	// Output:
+	// ["a","b","c"]
+	// true
+}
+
+func ExampleSet_algebra() {
+	a := vf.Set(1, 2, 3, 4)
+	b := vf.Set(3, 4, 5)
+	fmt.Println(a.Union(b))
+	fmt.Println(a.Intersection(b))
+	fmt.Println(a.Difference(b))
+	fmt.Println(vf.Set(3, 4).SubsetOf(a))
+	// Output:
+	// [1,2,3,4,5]
+	// [3,4]
+	// [1,2]
+	// true
+}
+
+func TestSetType(t *testing.T) {
+	tp := newtype.Set()
+	require.Same(t, typ.Set, tp)
+	require.Same(t, typ.Set, newtype.Set(typ.Any))
+	require.Same(t, typ.Set, newtype.Set(typ.Any, 0, math.MaxInt64))
+	require.Instance(t, tp, vf.Set(`a`))
+	require.NotInstance(t, tp, vf.Values(`a`))
+	require.Assignable(t, tp, newtype.Set(typ.String))
+	require.Assignable(t, tp, vf.Set(`a`).Type())
+	require.NotAssignable(t, tp, typ.Array)
+	require.Equal(t, typ.Any, tp.ElementType())
+	require.Equal(t, 0, tp.Min())
+	require.Equal(t, math.MaxInt64, tp.Max())
+	require.True(t, tp.Unbounded())
+	require.Equal(t, tp.HashCode(), tp.HashCode())
+	require.Instance(t, tp.Type(), tp)
+	require.Equal(t, `set[any]`, tp.String())
+}
+
+func TestSetType_sized(t *testing.T) {
+	tp := newtype.Set(typ.String, 1, 2)
+	require.Instance(t, tp, vf.Set(`a`, `b`))
+	require.NotInstance(t, tp, vf.Set(`a`, `b`, `c`))
+	require.NotInstance(t, tp, vf.Set())
+	require.NotInstance(t, tp, vf.Set(1))
+	require.NotInstance(t, tp, vf.Values(`a`))
+	require.Assignable(t, tp, newtype.Set(typ.String, 1, 1))
+	require.Assignable(t, tp, newtype.Set(newtype.String(1), 1, 2))
+	require.NotAssignable(t, tp, newtype.Set(typ.String, 0, 1))
+	require.NotAssignable(t, tp, newtype.Set(typ.Integer, 1, 2))
+	require.NotAssignable(t, tp, typ.Set)
+	require.Assignable(t, tp, vf.Set(`a`).Type())
+	require.NotAssignable(t, tp, vf.Set(1).Type())
+	require.Assignable(t, typ.Any, tp)
+	require.Equal(t, tp, newtype.Set(typ.String, 1, 2))
+	require.NotEqual(t, tp, newtype.Set(typ.String, 1, 3))
+	require.Equal(t, tp.HashCode(), newtype.Set(typ.String, 1, 2).HashCode())
+	require.Equal(t, `set[string,1,2]`, tp.String())
+	require.Equal(t, `set[string,1]`, newtype.Set(typ.String, 1).String())
+	require.Equal(t, `set[any,1,2]`, newtype.Set(2, 1).String())
+	require.Equal(t, `set[string]`, newtype.Set(typ.String).String())
+	require.Equal(t, 1, tp.Min())
+	require.Equal(t, 2, tp.Max())
+	require.False(t, tp.Unbounded())
+	require.Instance(t, tp.Type(), tp)
+}
+
+func TestSetType_badArgs(t *testing.T) {
+	require.Panic(t, func() { newtype.Set(`a`) }, `illegal argument`)
+	require.Panic(t, func() { newtype.Set(typ.String, `a`) }, `illegal argument`)
+	require.Panic(t, func() { newtype.Set(`a`, 1) }, `illegal argument`)
+	require.Panic(t, func() { newtype.Set(1, typ.String, 1) }, `illegal argument`)
+	require.Panic(t, func() { newtype.Set(typ.String, `a`, 1) }, `illegal argument`)
+	require.Panic(t, func() { newtype.Set(typ.String, 1, `a`) }, `illegal argument`)
+	require.Panic(t, func() { newtype.Set(typ.String, 1, 2, 3) }, `illegal number of arguments`)
+}
+
+func TestSetType_exact(t *testing.T) {
+	s := vf.Set(1, 2)
+	tp := s.Type().(dgo.SetType)
+	require.Instance(t, tp, vf.Set(2, 1))
+	require.NotInstance(t, tp, vf.Set(1))
+	require.Assignable(t, tp, vf.Set(2, 1).Type())
+	require.NotAssignable(t, tp, newtype.Set(typ.Integer))
+	require.Equal(t, tp, vf.Set(2, 1).Type())
+	require.NotEqual(t, tp, vf.Set(2).Type())
+	require.Equal(t, tp.HashCode(), vf.Set(2, 1).Type().HashCode())
+	require.Equal(t, 2, tp.Min())
+	require.Equal(t, 2, tp.Max())
+	require.False(t, tp.Unbounded())
+	require.Same(t, s, tp.(dgo.ExactType).Value())
+	require.Equal(t, `set{1,2}`, tp.String())
+	require.Instance(t, tp.ElementType(), 1)
+	require.NotInstance(t, tp.ElementType(), 3)
+	require.Instance(t, tp.Type(), tp)
+}
+
+func TestSetType_parse(t *testing.T) {
+	require.Same(t, typ.Set, newtype.Parse(`set`))
+	require.Same(t, typ.Set, newtype.Parse(`set[any]`))
+	require.Equal(t, newtype.Set(typ.String, 1, 2), newtype.Parse(`set[string,1,2]`))
+	require.Equal(t, newtype.Set(newtype.Enum(`a`, `b`)), newtype.Parse(`set["a"|"b"]`))
+	tp := newtype.Parse(vf.Set(1, 2).Type().String())
+	require.Equal(t, vf.Set(1, 2).Type(), tp)
+	require.Instance(t, tp, vf.Set(1, 2))
+	require.NotInstance(t, tp, vf.Set(1))
+	require.NotInstance(t, tp, vf.Set(1, 3))
+
+	s := vf.Set(`a`, nil, 3.5, vf.Values(1, `x`), vf.Map(map[string]int{`a`: 1}), typ.String, vf.Set(1))
+	require.Equal(t, s.Type(), newtype.Parse(s.Type().String()))
+	require.Equal(t, vf.Set().Type(), newtype.Parse(`set{}`))
+	require.Panic(t, func() { newtype.Parse(`set{1,string}`) }, `must be exact types, got string`)
+	require.Panic(t, func() { newtype.Parse(`set{1 2}`) }, `expected one of ',' or '}'`)
+}
+
+func TestSet_basics(t *testing.T) {
+	s := vf.MutableSet(nil, `a`, `b`)
+	require.True(t, s.Add(`c`))
+	require.False(t, s.Add(`a`))
+	require.Equal(t, 3, s.Len())
+	require.True(t, s.Contains(`c`))
+	require.False(t, s.Contains(`d`))
+	require.True(t, s.Remove(`b`))
+	require.False(t, s.Remove(`b`))
+	require.Equal(t, vf.Strings(`a`, `c`), s.ToArray())
+	s.AddAll(vf.Values(`d`, `a`, `e`))
+	require.Equal(t, vf.Strings(`a`, `c`, `d`, `e`), s.ToArray())
+	vs := vf.MutableValues(nil)
+	s.Each(func(v dgo.Value) { vs.Add(v) })
+	require.Equal(t, vs, s.ToArray())
+	require.Equal(t, `["a","c","d","e"]`, s.String())
+}
+
+func TestSet_Equals(t *testing.T) {
+	a := vf.Set(1, 2, 3)
+	require.Equal(t, a, vf.Set(3, 2, 1))
+	require.NotEqual(t, a, vf.Set(1, 2))
+	require.NotEqual(t, a, vf.Set(1, 2, 4))
+	require.NotEqual(t, a, vf.Values(1, 2, 3))
+	require.Equal(t, a.HashCode(), vf.Set(3, 2, 1).HashCode())
+}
+
+func TestSet_elementsAreFrozen(t *testing.T) {
+	e := vf.MutableValues(nil, 1)
+	s := vf.MutableSet(nil)
+	s.Add(e)
+	e.Add(2)
+	require.True(t, s.Contains(vf.Values(1)))
+	require.False(t, s.Contains(vf.Values(1, 2)))
+}
+
+func TestSet_Freeze(t *testing.T) {
+	s := vf.MutableSet(nil, 1)
+	require.False(t, s.Frozen())
+	c := s.FrozenCopy().(dgo.Set)
+	require.True(t, c.Frozen())
+	require.False(t, s.Frozen())
+	s.Freeze()
+	require.True(t, s.Frozen())
+	require.Same(t, s, s.Copy(true))
+	require.Panic(t, func() { s.Add(2) }, `Add called on a frozen Set`)
+	require.Panic(t, func() { s.AddAll(vf.Values(2)) }, `AddAll called on a frozen Set`)
+	require.Panic(t, func() { s.Remove(1) }, `Remove called on a frozen Set`)
+	require.Panic(t, func() { s.SetType(typ.Set) }, `SetType called on a frozen Set`)
+	m := s.Copy(false)
+	require.False(t, m.Frozen())
+	m.Add(2)
+	require.Equal(t, 1, s.Len())
+	require.Equal(t, 2, m.Len())
+}
+
+func TestSet_algebra(t *testing.T) {
+	a := vf.Set(1, 2, 3)
+	b := vf.MutableSet(nil, 2, 3, 4)
+
+	u := a.Union(b)
+	require.Equal(t, vf.Integers(1, 2, 3, 4), u.ToArray())
+	require.True(t, u.Frozen())
+	require.Same(t, a, a.Union(vf.Set()))
+	require.Same(t, a, a.Union(a))
+	require.False(t, b.Union(a).Frozen())
+
+	// A mutable set always yields a new set
+	bu := b.Union(vf.Set())
+	require.NotSame(t, b, bu)
+	bu.Add(5)
+	require.False(t, b.Contains(5))
+	require.NotSame(t, b, b.Union(b))
+
+	// The set type is retained by the new set
+	ts := vf.MutableSet(newtype.Set(typ.Integer), 1)
+	tu := ts.Union(vf.Set(2))
+	require.Equal(t, ts.Type(), tu.Type())
+	require.Panic(t, func() { tu.Add(`x`) }, `cannot be assigned`)
+	require.Panic(t, func() { ts.Union(vf.Set(`x`)) }, `cannot be assigned`)
+	ti := ts.Intersection(vf.Set(1, 2))
+	require.Equal(t, ts.Type(), ti.Type())
+	require.Panic(t, func() { ti.Add(`x`) }, `cannot be assigned`)
+	td := ts.Difference(vf.Set(2))
+	require.Equal(t, ts.Type(), td.Type())
+	require.Panic(t, func() { td.Add(`x`) }, `cannot be assigned`)
+
+	i := a.Intersection(b)
+	require.Equal(t, vf.Integers(2, 3), i.ToArray())
+	require.True(t, i.Frozen())
+	require.False(t, b.Intersection(a).Frozen())
+
+	d := a.Difference(b)
+	require.Equal(t, vf.Integers(1), d.ToArray())
+	require.Equal(t, vf.Integers(4), b.Difference(a).ToArray())
+
+	require.True(t, i.SubsetOf(a))
+	require.True(t, i.SubsetOf(b))
+	require.False(t, a.SubsetOf(b))
+	require.False(t, a.SubsetOf(vf.Set(1)))
+	require.True(t, vf.Set().SubsetOf(a))
+}
+
+func TestSet_typed(t *testing.T) {
+	tp := newtype.Set(typ.String, 1, 2)
+	s := vf.MutableSet(tp, `a`)
+	require.Same(t, tp, s.Type())
+	require.True(t, s.Add(`b`))
+	require.False(t, s.Add(`b`))
+	require.Panic(t, func() { s.Add(`c`) }, internal.IllegalSize(tp, 3))
+	require.Panic(t, func() { s.Add(3) }, internal.IllegalAssignment(typ.String, vf.Value(3)))
+	s.Remove(`b`)
+	require.Panic(t, func() { s.Remove(`a`) }, internal.IllegalSize(tp, 0))
+	require.Panic(t, func() { vf.MutableSet(tp) }, internal.IllegalSize(tp, 0))
+	require.Panic(t, func() { s.Difference(vf.Set(`a`)) }, internal.IllegalSize(tp, 0))
+	require.Panic(t, func() { s.Intersection(vf.Set(`b`)) }, internal.IllegalSize(tp, 0))
+	require.Panic(t, func() { vf.MutableSet(tp, 1) }, internal.IllegalAssignment(typ.String, vf.Value(1)))
+
+	s = vf.MutableSet(nil, 1)
+	s.SetType(newtype.Set(typ.Integer))
+	require.Equal(t, newtype.Set(typ.Integer), s.Type())
+	require.Panic(t, func() { s.SetType(newtype.Set(typ.String)) }, `cannot be assigned`)
+}
+
+func TestSet_JSON(t *testing.T) {
+	s := vf.Set(`a`, 1, nil)
+	b, err := s.MarshalJSON()
+	require.Nil(t, err)
+	require.Equal(t, `["a",1,null]`, string(b))
+
+	m := vf.MutableSet(nil)
+	require.Nil(t, m.UnmarshalJSON([]byte(`["a",1,"a"]`)))
+	require.Equal(t, vf.Set(`a`, 1), m)
+	require.Equal(t, errors.New(`expecting data to be an array`), m.UnmarshalJSON([]byte(`{"a":1}`)))
+	require.Panic(t, func() { _ = s.UnmarshalJSON([]byte(`[]`)) }, `frozen`)
+}
+
+func TestSet_YAML(t *testing.T) {
+	b, err := yaml.Marshal(vf.Set(`a`, 1))
+	require.Nil(t, err)
+	require.Equal(t, "- a\n- 1\n", string(b))
+
+	m := vf.MutableSet(nil)
+	require.Nil(t, yaml.Unmarshal([]byte("- a\n- 1\n- a\n"), m))
+	require.Equal(t, vf.Set(`a`, 1), m)
+	require.NotNil(t, yaml.Unmarshal([]byte(`a: 1`), m))
+	require.Panic(t, func() { _ = yaml.Unmarshal([]byte(`[]`), vf.Set()) }, `frozen`)
+}
@@ -0,0 +1,183 @@
+package internal
+
+import (
+	"math"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// Shrink returns values that are smaller than the given value and instances of the given type. The
+// candidates are ordered so that the ones that shrinks the value the most comes first.
+//
+// Arrays and strings shrink by removing elements, Maps by removing entries (which for a Struct means its
+// optional entries), and Sets by removing elements. Integers and floats shrink toward the minimum of their
+// range. A range without a lower bound shrinks toward zero, or toward its maximum when that is negative.
+// Elements and values of collections are shrunk recursively using the type declared for them.
+func Shrink(t dgo.Type, v dgo.Value) []dgo.Value {
+	var cs []dgo.Value
+	for _, c := range shrink(t, v) {
+		if t.Instance(c) {
+			cs = append(cs, c)
+		}
+	}
+	return cs
+}
+
+func shrink(t dgo.Type, v dgo.Value) []dgo.Value {
+	switch v := v.(type) {
+	case Integer:
+		min, max := int64(math.MinInt64), int64(math.MaxInt64)
+		if rt, ok := t.(dgo.IntegerRangeType); ok {
+			min, max = rt.Min(), rt.Max()
+		}
+		return shrinkInt(int64(v), shrinkTarget(min, max))
+	case Float:
+		min, max := -math.MaxFloat64, math.MaxFloat64
+		if rt, ok := t.(dgo.FloatRangeType); ok {
+			min, max = rt.Min(), rt.Max()
+		}
+		return shrinkFloat(float64(v), shrinkFloatTarget(min, max))
+	case *hstring:
+		return shrinkString(v.s)
+	case dgo.Array:
+		return shrinkArray(t, sliceOf(v))
+	case dgo.Map:
+		return shrinkMap(t, v)
+	case *hashSet:
+		return shrinkSet(v)
+	}
+	return nil
+}
+
+// shrinkTarget returns the minimum of the given range unless it is unbounded, in which case zero is returned
+// if it is within the range, or else the maximum.
+func shrinkTarget(min, max int64) int64 {
+	switch {
+	case min != math.MinInt64:
+		return min
+	case max < 0:
+		return max
+	}
+	return 0
+}
+
+// shrinkFloatTarget returns the minimum of the given range unless it is unbounded, in which case zero is
+// returned if it is within the range, or else the maximum.
+func shrinkFloatTarget(min, max float64) float64 {
+	switch {
+	case min != -math.MaxFloat64:
+		return min
+	case max < 0:
+		return max
+	}
+	return 0
+}
+
+func shrinkInt(x, target int64) []dgo.Value {
+	var cs []dgo.Value
+	for d := x - target; d != 0; d /= 2 {
+		cs = append(cs, Integer(x-d))
+	}
+	return cs
+}
+
+func shrinkFloat(x, target float64) []dgo.Value {
+	if x == target {
+		return nil
+	}
+	cs := []dgo.Value{Float(target)}
+	if t := math.Trunc(x); t != x && t != target {
+		cs = append(cs, Float(t))
+	}
+	for d := (x - target) / 2; math.Abs(d) >= 1; d /= 2 {
+		cs = append(cs, Float(x-d))
+	}
+	return cs
+}
+
+// removals calls the given function with the start and end of each chunk that can be removed from a
+// sequence of length n. Large chunks are produced first.
+func removals(n int, f func(s, e int)) {
+	for k := n; k > 0; k /= 2 {
+		for s := 0; s+k <= n; s += k {
+			f(s, s+k)
+		}
+	}
+}
+
+func shrinkString(str string) []dgo.Value {
+	rs := []rune(str)
+	var cs []dgo.Value
+	removals(len(rs), func(s, e int) {
+		cs = append(cs, makeHString(string(rs[:s])+string(rs[e:])))
+	})
+	return cs
+}
+
+func shrinkArray(t dgo.Type, es []dgo.Value) []dgo.Value {
+	var cs []dgo.Value
+	removals(len(es), func(s, e int) {
+		c := make([]dgo.Value, 0, len(es)-(e-s))
+		c = append(append(c, es[:s]...), es[e:]...)
+		cs = append(cs, &array{slice: c, frozen: true})
+	})
+	for i, e := range es {
+		var et dgo.Type = DefaultAnyType
+		switch t := t.(type) {
+		case dgo.TupleType:
+			if ets := t.ElementTypes(); i < ets.Len() {
+				et = ets.Get(i).(dgo.Type)
+			}
+		case dgo.ArrayType:
+			et = t.ElementType()
+		}
+		for _, se := range Shrink(et, e) {
+			c := make([]dgo.Value, len(es))
+			copy(c, es)
+			c[i] = se
+			cs = append(cs, &array{slice: c, frozen: true})
+		}
+	}
+	return cs
+}
+
+func shrinkMap(t dgo.Type, m dgo.Map) []dgo.Value {
+	var cs []dgo.Value
+	if m.Len() > 1 {
+		cs = append(cs, emptyMap)
+	}
+	m.EachKey(func(k dgo.Value) {
+		cs = append(cs, m.Without(k))
+	})
+	m.Each(func(e dgo.MapEntry) {
+		var vt dgo.Type = DefaultAnyType
+		switch t := t.(type) {
+		case dgo.StructType:
+			t.Entries().Any(func(ev dgo.Value) bool {
+				et := ev.(dgo.MapEntryType)
+				if et.KeyType().Instance(e.Key()) {
+					vt = et.ValueType()
+					return true
+				}
+				return false
+			})
+		case dgo.MapType:
+			vt = t.ValueType()
+		}
+		for _, sv := range Shrink(vt, e.Value()) {
+			cs = append(cs, m.With(e.Key(), sv))
+		}
+	})
+	return cs
+}
+
+func shrinkSet(s *hashSet) []dgo.Value {
+	var cs []dgo.Value
+	s.Each(func(e dgo.Value) {
+		c := s.Copy(false)
+		c.Remove(e)
+		c.Freeze()
+		cs = append(cs, c)
+	})
+	return cs
+}
@@ -0,0 +1,106 @@
+package internal_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/internal"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+// minimize repeatedly picks the first shrunk candidate for which the given function returns false.
+func minimize(tp dgo.Type, v dgo.Value, ok func(dgo.Value) bool) dgo.Value {
+	for {
+		found := false
+		for _, c := range internal.Shrink(tp, v) {
+			if !ok(c) {
+				v = c
+				found = true
+				break
+			}
+		}
+		if !found {
+			return v
+		}
+	}
+}
+
+func TestShrink_integer(t *testing.T) {
+	require.Equal(t, vf.Values(0, 50, 75, 88, 94, 97, 99), vf.Array(internal.Shrink(typ.Integer, vf.Integer(100))))
+	require.Equal(t, vf.Values(0, -50, -75, -88, -94, -97, -99),
+		vf.Array(internal.Shrink(typ.Integer, vf.Integer(-100))))
+	require.Equal(t, 0, len(internal.Shrink(typ.Integer, vf.Integer(0))))
+
+	// Shrinks toward the range minimum
+	tp := newtype.IntegerRange(10, 100)
+	require.Equal(t, vf.Values(10, 15, 18, 19), vf.Array(internal.Shrink(tp, vf.Integer(20))))
+	require.Equal(t, 10, minimize(tp, vf.Integer(97), func(v dgo.Value) bool { return false }))
+	require.Equal(t, 42, minimize(tp, vf.Integer(97), func(v dgo.Value) bool { return v.(dgo.Integer).GoInt() < 42 }))
+	require.Equal(t, -100, minimize(newtype.IntegerRange(-100, -10), vf.Integer(-97), func(v dgo.Value) bool { return false }))
+	require.Equal(t, -5, minimize(newtype.IntegerRange(-5, 5), vf.Integer(3), func(v dgo.Value) bool { return false }))
+	require.Equal(t, -10, minimize(newtype.Parse(`..-10`), vf.Integer(-97), func(v dgo.Value) bool { return false }))
+}
+
+func TestShrink_float(t *testing.T) {
+	require.Equal(t, vf.Values(0.0, 5.0, 2.75, 4.125), vf.Array(internal.Shrink(typ.Float, vf.Float(5.5))))
+	require.Equal(t, 0, len(internal.Shrink(typ.Float, vf.Float(0))))
+	require.Equal(t, 1.5, minimize(newtype.FloatRange(1.5, 10), vf.Float(7.25), func(v dgo.Value) bool { return false }))
+	require.Equal(t, -10.0, minimize(newtype.FloatRange(-10, -1.5), vf.Float(-7.25), func(v dgo.Value) bool { return false }))
+}
+
+func TestShrink_string(t *testing.T) {
+	require.Equal(t, vf.Values(``, `cd`, `ab`, `bcd`, `acd`, `abd`, `abc`),
+		vf.Array(internal.Shrink(typ.String, vf.String(`abcd`))))
+	require.Equal(t, `ef`, minimize(newtype.String(2, 10), vf.String(`abcdef`), func(v dgo.Value) bool { return false }))
+	require.Equal(t, `é`, minimize(typ.String, vf.String(`abéc`), func(v dgo.Value) bool {
+		return !strings.Contains(v.(dgo.String).GoString(), `é`)
+	}))
+}
+
+func TestShrink_array(t *testing.T) {
+	tp := newtype.Parse(`[1,5]0..100`)
+	v := minimize(tp, vf.Values(30, 60, 90), func(v dgo.Value) bool { return false })
+	require.Equal(t, vf.Values(0), v)
+	v = minimize(tp, vf.Values(30, 60, 90), func(v dgo.Value) bool {
+		return !v.(dgo.Array).Any(func(e dgo.Value) bool { return e.(dgo.Integer).GoInt() > 50 })
+	})
+	require.Equal(t, vf.Values(51), v)
+	require.True(t, v.(dgo.Array).Frozen())
+
+	tp = newtype.Parse(`{10..20,string}`)
+	require.Equal(t, vf.Values(10, ``), minimize(tp, vf.Values(15, `abc`), func(v dgo.Value) bool { return false }))
+}
+
+func TestShrink_map(t *testing.T) {
+	tp := newtype.Parse(`{"a":1..10,"b"?:string,"c"?:int}`)
+	v := minimize(tp, vf.Values(`a`, 5, `b`, `xyz`, `c`, 3).ToMap(), func(v dgo.Value) bool { return false })
+	require.Equal(t, vf.Map(map[string]int{`a`: 1}), v)
+	v = minimize(tp, vf.Values(`a`, 5, `b`, `xyz`, `c`, 3).ToMap(), func(v dgo.Value) bool {
+		_, ok := v.(dgo.Map).Get(`c`)
+		return !ok
+	})
+	require.Equal(t, vf.Map(map[string]int{`a`: 1, `c`: 0}), v)
+
+	tp = newtype.Parse(`map[string]1..5`)
+	v = minimize(tp, vf.Map(map[string]int{`a`: 4, `b`: 3}), func(v dgo.Value) bool { return v.(dgo.Map).Len() < 2 })
+	require.Equal(t, vf.Map(map[string]int{`a`: 1, `b`: 1}), v)
+	require.Equal(t, vf.Map(map[string]int{}), minimize(tp, vf.Map(map[string]int{`a`: 4, `b`: 3}),
+		func(v dgo.Value) bool { return false }))
+}
+
+func TestShrink_set(t *testing.T) {
+	tp := newtype.Set(1, 3)
+	v := minimize(tp, vf.Set(1, 2, 3), func(v dgo.Value) bool { return false })
+	require.Equal(t, 1, v.(dgo.Set).Len())
+	require.True(t, v.(dgo.Set).Frozen())
+}
+
+func TestShrink_other(t *testing.T) {
+	require.Equal(t, 0, len(internal.Shrink(typ.Boolean, vf.True)))
+	require.Equal(t, vf.Values(0, 5, 8, 9), vf.Array(internal.Shrink(newtype.Parse(`int|string`), vf.Integer(10))))
+	require.Equal(t, vf.Values(5, 8, 9), vf.Array(internal.Shrink(newtype.Parse(`5..10|string`), vf.Integer(10))))
+}
@@ -0,0 +1,532 @@
+package internal
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/util"
+	"gopkg.in/yaml.v3"
+)
+
+// sortedMap is a dgo.SortedMap that keeps its entries in a slice ordered by key. Lookups use binary search.
+type sortedMap struct {
+	entries []*hashNode
+	typ     dgo.MapType
+	frozen  bool
+}
+
+// SortedMap returns a frozen dgo.SortedMap that contains the entries of the given argument which must be
+// a dgo.Map or a go map.
+func SortedMap(m interface{}) dgo.SortedMap {
+	dm, ok := m.(dgo.Map)
+	if !ok {
+		dm = Map(m)
+	}
+	return sortedMapFrom(dm, true)
+}
+
+// MutableSortedMap creates an empty dgo.SortedMap. The map can be optionally constrained by the given type
+// which can be nil, the zero value of a go map, or a dgo.MapType
+func MutableSortedMap(typ interface{}) dgo.SortedMap {
+	var mt dgo.MapType
+	if typ != nil {
+		switch typ := typ.(type) {
+		case dgo.MapType:
+			mt = typ
+		default:
+			mt = TypeFromReflected(reflect.TypeOf(typ)).(dgo.MapType)
+		}
+	}
+	return &sortedMap{typ: mt}
+}
+
+// sortedMapFrom returns a sortedMap that contains the entries of the given Map.
+func sortedMapFrom(m dgo.Map, frozen bool) *sortedMap {
+	if sm, ok := m.(*sortedMap); ok && frozen && sm.frozen {
+		return sm
+	}
+	es := make([]*hashNode, 0, m.Len())
+	m.Each(func(e dgo.MapEntry) {
+		nd := &hashNode{key: frozenCopy(e.Key()), value: e.Value()}
+		if frozen {
+			nd.copyFreeze()
+		}
+		es = append(es, nd)
+	})
+	sort.SliceStable(es, func(i, j int) bool { return compareKeys(es[i].key, es[j].key) < 0 })
+	return &sortedMap{entries: es, frozen: frozen}
+}
+
+// compareKeys compares two keys using their natural order. Keys that cannot be compared are ordered
+// by the identifier of their type, and keys of the same type that cannot be compared are ordered by
+// their string form.
+func compareKeys(a, b dgo.Value) int {
+	if ac, ok := a.(dgo.Comparable); ok {
+		if c, ok := ac.CompareTo(b); ok {
+			return c
+		}
+	}
+	if bc, ok := b.(dgo.Comparable); ok {
+		if c, ok := bc.CompareTo(a); ok {
+			return -c
+		}
+	}
+	ai := a.Type().TypeIdentifier()
+	bi := b.Type().TypeIdentifier()
+	switch {
+	case ai < bi:
+		return -1
+	case ai > bi:
+		return 1
+	}
+	return strings.Compare(a.String(), b.String())
+}
+
+func (g *sortedMap) All(predicate dgo.EntryPredicate) bool {
+	for _, e := range g.entries {
+		if !predicate(e) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *sortedMap) AllKeys(predicate dgo.Predicate) bool {
+	for _, e := range g.entries {
+		if !predicate(e.key) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *sortedMap) AllValues(predicate dgo.Predicate) bool {
+	for _, e := range g.entries {
+		if !predicate(e.value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (g *sortedMap) Any(predicate dgo.EntryPredicate) bool {
+	for _, e := range g.entries {
+		if predicate(e) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *sortedMap) AnyKey(predicate dgo.Predicate) bool {
+	for _, e := range g.entries {
+		if predicate(e.key) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *sortedMap) AnyValue(predicate dgo.Predicate) bool {
+	for _, e := range g.entries {
+		if predicate(e.value) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *sortedMap) AppendTo(w *util.Indenter) {
+	w.AppendRune('{')
+	ew := w.Indent()
+	for i, e := range g.entries {
+		if i > 0 {
+			ew.AppendRune(',')
+		}
+		ew.NewLine()
+		ew.AppendValue(e)
+	}
+	w.NewLine()
+	w.AppendRune('}')
+}
+
+func (g *sortedMap) Ceiling(key interface{}) (dgo.MapEntry, bool) {
+	k := Value(key)
+	es := g.entries
+	i := sort.Search(len(es), func(i int) bool { return compareKeys(es[i].key, k) >= 0 })
+	if i < len(es) {
+		return es[i], true
+	}
+	return nil, false
+}
+
+func (g *sortedMap) Copy(frozen bool) dgo.Map {
+	if frozen && g.frozen {
+		return g
+	}
+	c := &sortedMap{entries: g.copyEntries(0), typ: g.typ, frozen: frozen}
+	if frozen {
+		for _, e := range c.entries {
+			e.copyFreeze()
+		}
+	}
+	return c
+}
+
+func (g *sortedMap) Each(doer dgo.EntryDoer) {
+	for _, e := range g.entries {
+		doer(e)
+	}
+}
+
+func (g *sortedMap) EachKey(doer dgo.Doer) {
+	for _, e := range g.entries {
+		doer(e.key)
+	}
+}
+
+func (g *sortedMap) EachValue(doer dgo.Doer) {
+	for _, e := range g.entries {
+		doer(e.value)
+	}
+}
+
+func (g *sortedMap) Entries() dgo.Array {
+	es := make([]dgo.Value, len(g.entries))
+	for i, e := range g.entries {
+		c := &hashNode{key: e.key, value: e.value}
+		c.copyFreeze()
+		es[i] = c
+	}
+	return &array{slice: es, frozen: true}
+}
+
+func (g *sortedMap) Equals(other interface{}) bool {
+	return equals(nil, g, Value(other))
+}
+
+func (g *sortedMap) deepEqual(seen []dgo.Value, other deepEqual) bool {
+	if om, ok := other.(dgo.Map); ok && len(g.entries) == om.Len() {
+		for _, e := range g.entries {
+			ov, ok := om.Get(e.key)
+			if !(ok && equals(seen, e.value, ov)) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func (g *sortedMap) Floor(key interface{}) (dgo.MapEntry, bool) {
+	k := Value(key)
+	es := g.entries
+	i := sort.Search(len(es), func(i int) bool { return compareKeys(es[i].key, k) > 0 })
+	if i > 0 {
+		return es[i-1], true
+	}
+	return nil, false
+}
+
+func (g *sortedMap) Freeze() {
+	g.frozen = true
+	for _, e := range g.entries {
+		e.Freeze()
+	}
+}
+
+func (g *sortedMap) Frozen() bool {
+	return g.frozen
+}
+
+func (g *sortedMap) FrozenCopy() dgo.Value {
+	return g.Copy(true)
+}
+
+func (g *sortedMap) Get(key interface{}) (dgo.Value, bool) {
+	if i, ok := g.find(Value(key)); ok {
+		return g.entries[i].value, true
+	}
+	return nil, false
+}
+
+func (g *sortedMap) GobDecode(b []byte) error {
+	return gobDecodeInto(b, g)
+}
+
+func (g *sortedMap) GobEncode() ([]byte, error) {
+	return gobEncode(g)
+}
+
+func (g *sortedMap) HashCode() int {
+	return deepHashCode(nil, g)
+}
+
+func (g *sortedMap) deepHashCode(seen []dgo.Value) int {
+	h := 1
+	for _, e := range g.entries {
+		h += deepHashCode(seen, e)
+	}
+	return h
+}
+
+func (g *sortedMap) Keys() dgo.Array {
+	ks := make([]dgo.Value, len(g.entries))
+	for i, e := range g.entries {
+		ks[i] = e.key
+	}
+	return &array{slice: ks, frozen: true}
+}
+
+func (g *sortedMap) Len() int {
+	return len(g.entries)
+}
+
+func (g *sortedMap) MarshalJSON() ([]byte, error) {
+	return []byte(util.ToString(g)), nil
+}
+
+// MarshalYAML returns a *yaml.Node that represents this Map.
+func (g *sortedMap) MarshalYAML() (interface{}, error) {
+	return yamlEncodeValue(g)
+}
+
+func (g *sortedMap) Merge(associations dgo.Map) dgo.Map {
+	if associations.Len() == 0 || SameInstance(g, associations) {
+		return g
+	}
+	c := &sortedMap{entries: g.copyEntries(associations.Len()), typ: g.typ}
+	associations.Each(func(e dgo.MapEntry) {
+		v := e.Value()
+		if g.frozen {
+			v = frozenCopy(v)
+		}
+		c.put(e.Key(), v)
+	})
+	c.frozen = g.frozen
+	return c
+}
+
+func (g *sortedMap) Put(key, value interface{}) dgo.Value {
+	if g.frozen {
+		panic(frozenMap(`Put`))
+	}
+	return g.put(Value(key), Value(value))
+}
+
+func (g *sortedMap) PutAll(associations dgo.Map) {
+	if associations.Len() == 0 {
+		return
+	}
+	if g.frozen {
+		panic(frozenMap(`PutAll`))
+	}
+	associations.Each(func(e dgo.MapEntry) { g.put(e.Key(), e.Value()) })
+}
+
+func (g *sortedMap) Remove(key interface{}) dgo.Value {
+	if g.frozen {
+		panic(frozenMap(`Remove`))
+	}
+	return g.remove(Value(key))
+}
+
+func (g *sortedMap) RemoveAll(keys dgo.Array) {
+	if g.frozen {
+		panic(frozenMap(`RemoveAll`))
+	}
+	keys.Each(func(k dgo.Value) { g.remove(k) })
+}
+
+func (g *sortedMap) SetType(t dgo.MapType) {
+	if g.frozen {
+		panic(frozenMap(`SetType`))
+	}
+	if t.Instance(g) {
+		g.typ = t
+		return
+	}
+	panic(IllegalAssignment(t, g))
+}
+
+func (g *sortedMap) String() string {
+	return util.ToString(g)
+}
+
+func (g *sortedMap) SubMap(from, to interface{}) dgo.SortedMap {
+	es := g.entries
+	lo := 0
+	if from != nil {
+		fk := Value(from)
+		lo = sort.Search(len(es), func(i int) bool { return compareKeys(es[i].key, fk) >= 0 })
+	}
+	hi := len(es)
+	if to != nil {
+		tk := Value(to)
+		hi = sort.Search(len(es), func(i int) bool { return compareKeys(es[i].key, tk) >= 0 })
+	}
+	if hi < lo {
+		hi = lo
+	}
+	c := &sortedMap{typ: g.typ, frozen: g.frozen}
+	if g.frozen {
+		// Entries of a frozen map never change so they can be shared
+		c.entries = append(make([]*hashNode, 0, hi-lo), es[lo:hi]...)
+	} else {
+		c.entries = make([]*hashNode, hi-lo)
+		for i, e := range es[lo:hi] {
+			c.entries[i] = &hashNode{key: e.key, value: e.value}
+		}
+	}
+	return c
+}
+
+func (g *sortedMap) Type() dgo.Type {
+	if g.typ == nil {
+		hm := MutableMap(len(g.entries), nil).(*hashMap)
+		for _, e := range g.entries {
+			hm.Put(e.key, e.value)
+		}
+		hm.frozen = true
+		return (*exactMapType)(hm)
+	}
+	return g.typ
+}
+
+func (g *sortedMap) UnmarshalJSON(b []byte) error {
+	if g.frozen {
+		panic(frozenMap(`UnmarshalJSON`))
+	}
+	d := newMutableJSONDecoder(b)
+	t, err := d.dec.Token()
+	if err == nil {
+		if delim, ok := t.(json.Delim); !ok || delim != '{' {
+			return errors.New("expecting data to be an object")
+		}
+		var m *hashMap
+		m, err = d.decodeMap()
+		if err == nil {
+			*g = *sortedMapFrom(m, false)
+		}
+	}
+	return err
+}
+
+func (g *sortedMap) UnmarshalYAML(n *yaml.Node) error {
+	if g.frozen {
+		panic(frozenMap(`UnmarshalYAML`))
+	}
+	if n.Kind != yaml.MappingNode {
+		return errors.New("expecting data to be an object")
+	}
+	m, err := (&yamlDecoder{}).decodeMap(n)
+	if err == nil {
+		*g = *sortedMapFrom(m, false)
+	}
+	return err
+}
+
+func (g *sortedMap) Values() dgo.Array {
+	vs := make([]dgo.Value, len(g.entries))
+	for i, e := range g.entries {
+		vs[i] = e.value
+	}
+	return &array{slice: vs, frozen: true}
+}
+
+func (g *sortedMap) With(key, value interface{}) dgo.Map {
+	k := Value(key)
+	v := Value(value)
+	if ov, ok := g.Get(k); ok && ov.Equals(v) {
+		return g
+	}
+	c := &sortedMap{entries: g.copyEntries(1), typ: g.typ}
+	if g.frozen {
+		v = frozenCopy(v)
+	}
+	c.put(k, v)
+	c.frozen = g.frozen
+	return c
+}
+
+func (g *sortedMap) Without(key interface{}) dgo.Map {
+	k := Value(key)
+	if _, ok := g.find(k); !ok {
+		return g
+	}
+	c := &sortedMap{entries: g.copyEntries(0), typ: g.typ, frozen: g.frozen}
+	c.remove(k)
+	return c
+}
+
+func (g *sortedMap) WithoutAll(keys dgo.Array) dgo.Map {
+	if len(g.entries) == 0 || keys.Len() == 0 {
+		return g
+	}
+	c := &sortedMap{entries: g.copyEntries(0), typ: g.typ, frozen: g.frozen}
+	keys.Each(func(k dgo.Value) { c.remove(k) })
+	if len(c.entries) == len(g.entries) {
+		return g
+	}
+	return c
+}
+
+// copyEntries returns a copy of the entries of this map with room for the given number of additional entries
+func (g *sortedMap) copyEntries(capInc int) []*hashNode {
+	es := make([]*hashNode, len(g.entries), len(g.entries)+capInc)
+	for i, e := range g.entries {
+		es[i] = &hashNode{key: e.key, value: e.value}
+	}
+	return es
+}
+
+// find returns the position of the entry with the given key and true, or the position where such an entry
+// would be inserted and false. Keys that compare as equal but aren't equal, such as 1 and 1.0, are kept
+// adjacent to each other in insertion order.
+func (g *sortedMap) find(k dgo.Value) (int, bool) {
+	es := g.entries
+	i := sort.Search(len(es), func(i int) bool { return compareKeys(es[i].key, k) >= 0 })
+	for ; i < len(es) && compareKeys(es[i].key, k) == 0; i++ {
+		if k.Equals(es[i].key) {
+			return i, true
+		}
+	}
+	return i, false
+}
+
+func (g *sortedMap) put(k, v dgo.Value) dgo.Value {
+	i, found := g.find(k)
+	if found {
+		if g.typ != nil {
+			assertMapType(g.typ, len(g.entries), k, v, 0)
+		}
+		e := g.entries[i]
+		old := e.value
+		e.value = v
+		return old
+	}
+	if g.typ != nil {
+		assertMapType(g.typ, len(g.entries), k, v, 1)
+	}
+	g.entries = append(g.entries, nil)
+	copy(g.entries[i+1:], g.entries[i:])
+	g.entries[i] = &hashNode{key: frozenCopy(k), value: v}
+	return nil
+}
+
+func (g *sortedMap) remove(k dgo.Value) dgo.Value {
+	i, found := g.find(k)
+	if !found {
+		return nil
+	}
+	es := g.entries
+	old := es[i].value
+	copy(es[i:], es[i+1:])
+	es[len(es)-1] = nil
+	g.entries = es[:len(es)-1]
+	return old
+}
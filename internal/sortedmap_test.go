@@ -0,0 +1,250 @@
+package internal_test
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+	"gopkg.in/yaml.v3"
+)
+
+func ExampleSortedMap() {
+	m := vf.MutableSortedMap(nil)
+	m.Put(`c`, 3)
+	m.Put(`a`, 1)
+	m.Put(`b`, 2)
+	fmt.Println(m)
+	m.Remove(`b`)
+	fmt.Println(m)
+	// Output:
+	// {"a":1,"b":2,"c":3}
+	// {"a":1,"c":3}
+}
+
+func ExampleSortedMap_range() {
+	m := vf.SortedMap(map[int]string{10: `ten`, 20: `twenty`, 30: `thirty`, 40: `forty`})
+	f, _ := m.Floor(25)
+	c, _ := m.Ceiling(25)
+	fmt.Println(f)
+	fmt.Println(c)
+	fmt.Println(m.SubMap(20, 40))
+	fmt.Println(m.SubMap(nil, 30))
+	fmt.Println(m.SubMap(25, nil))
+	// Output:
+	// 20:"twenty"
+	// 30:"thirty"
+	// {20:"twenty",30:"thirty"}
+	// {10:"ten",20:"twenty"}
+	// {30:"thirty",40:"forty"}
+}
+
+func TestSortedMap_order(t *testing.T) {
+	hm := vf.MutableMap(0, nil)
+	hm.Put(`b`, 2)
+	hm.Put(`c`, 3)
+	hm.Put(`a`, 1)
+	m := vf.SortedMap(hm)
+	require.Equal(t, vf.Strings(`a`, `b`, `c`), m.Keys())
+	require.Equal(t, vf.Values(1, 2, 3), m.Values())
+	require.Equal(t, hm, m)
+	require.Equal(t, m, hm)
+	require.Equal(t, hm.HashCode(), m.HashCode())
+	require.Equal(t, hm.HashCode(), vf.PersistentMap(hm).HashCode())
+	km := vf.MutableMap(1, nil)
+	km.Put(hm, `x`)
+	kv, ok := km.Get(m)
+	require.True(t, ok)
+	require.Equal(t, `x`, kv)
+	require.True(t, m.Frozen())
+
+	w := m.With(`aa`, 4)
+	require.Equal(t, vf.Strings(`a`, `aa`, `b`, `c`), w.Keys())
+	require.Equal(t, 3, m.Len())
+	require.Same(t, m, m.With(`a`, 1))
+	require.Equal(t, `{"a":1,"c":3}`, m.Without(`b`).String())
+	require.Same(t, m, m.Without(`x`))
+	require.Equal(t, `{"b":2}`, m.WithoutAll(vf.Strings(`a`, `c`)).String())
+	require.Same(t, m, m.WithoutAll(vf.Strings(`x`)))
+	require.Same(t, m, m.WithoutAll(vf.Strings()))
+	require.Equal(t, `{"a":0,"b":2,"c":3,"d":4}`, m.Merge(vf.Map(map[string]int{`d`: 4, `a`: 0})).String())
+	require.Same(t, m, m.Merge(vf.Map(map[string]int{})))
+	require.True(t, m.Merge(vf.Map(map[string]int{`d`: 4})).Frozen())
+}
+
+func TestSortedMap_mixedKeys(t *testing.T) {
+	m := vf.MutableSortedMap(nil)
+	m.Put(`a`, 1)
+	m.Put(2, 2)
+	m.Put(1.5, 3)
+	m.Put(nil, 4)
+	m.Put(1, 5)
+	m.Put(1.0, 6)
+	require.Equal(t, `{null:4,1:5,1.0:6,1.5:3,2:2,"a":1}`, m.String())
+	v, ok := m.Get(1.0)
+	require.True(t, ok)
+	require.Equal(t, 6, v)
+	require.Equal(t, 5, m.Remove(1))
+	require.Equal(t, `{null:4,1.0:6,1.5:3,2:2,"a":1}`, m.String())
+	require.Nil(t, m.Remove(1))
+
+	m.Put(vf.Values(1), 7)
+	m.Put(vf.Map(map[string]int{`a`: 1}), 8)
+	m.Put(vf.Map(map[string]int{`b`: 1}), 9)
+	v, ok = m.Get(vf.Map(map[string]int{`b`: 1}))
+	require.True(t, ok)
+	require.Equal(t, 9, v)
+	v, ok = m.Get(vf.Map(map[string]int{`a`: 1}))
+	require.True(t, ok)
+	require.Equal(t, 8, v)
+	require.Equal(t, 8, m.Len())
+}
+
+func TestSortedMap_mutable(t *testing.T) {
+	m := vf.MutableSortedMap(newtype.Map(typ.String, typ.Integer, 0, 2))
+	require.Nil(t, m.Put(`b`, 1))
+	require.Equal(t, 1, m.Put(`b`, 2))
+	m.PutAll(vf.Map(map[string]int{`a`: 1}))
+	m.PutAll(vf.Map(map[string]int{}))
+	require.Panic(t, func() { m.Put(`c`, 3) }, `size`)
+	require.Panic(t, func() { m.Put(`a`, `x`) }, `cannot be assigned`)
+	require.Panic(t, func() { m.Put(1, 1) }, `cannot be assigned`)
+	m.RemoveAll(vf.Strings(`a`))
+	require.Equal(t, `{"b":2}`, m.String())
+	require.Equal(t, newtype.Map(typ.String, typ.Integer, 0, 2), m.Type())
+	require.True(t, m.Keys().Frozen())
+	require.True(t, m.Values().Frozen())
+
+	c := m.Copy(false)
+	c.Put(`b`, 3)
+	v, _ := m.Get(`b`)
+	require.Equal(t, 2, v)
+
+	s := m.SubMap(nil, nil)
+	s.Put(`b`, 4)
+	v, _ = m.Get(`b`)
+	require.Equal(t, 2, v)
+
+	w := m.With(`a`, 0)
+	w.Put(`b`, 5)
+	v, _ = m.Get(`b`)
+	require.Equal(t, 2, v)
+	require.False(t, w.Frozen())
+
+	require.Panic(t, func() { m.SetType(newtype.Map(typ.String, typ.String)) }, `cannot be assigned`)
+	m.SetType(typ.Map)
+	m.Put(1, `x`)
+
+	mv := vf.MutableValues(nil, 1)
+	m.Put(`m`, mv)
+	f := m.Copy(true)
+	require.True(t, f.Frozen())
+	fv, _ := f.Get(`m`)
+	require.True(t, fv.(dgo.Freezable).Frozen())
+	require.False(t, mv.Frozen())
+	require.Same(t, f, f.Copy(true))
+	require.Same(t, f, f.FrozenCopy())
+
+	m.Freeze()
+	require.True(t, mv.Frozen())
+}
+
+func TestSortedMap_reflectedType(t *testing.T) {
+	m := vf.MutableSortedMap(map[string]int{})
+	m.Put(`a`, 1)
+	require.Panic(t, func() { m.Put(`b`, `two`) }, `cannot be assigned`)
+}
+
+func TestSortedMap_frozen(t *testing.T) {
+	m := vf.SortedMap(map[string]int{`a`: 1})
+	require.Panic(t, func() { m.Put(`b`, 2) }, `Put .* frozen`)
+	require.Panic(t, func() { m.PutAll(vf.Map(map[string]int{`a`: 1})) }, `PutAll .* frozen`)
+	require.Panic(t, func() { m.Remove(`a`) }, `Remove .* frozen`)
+	require.Panic(t, func() { m.RemoveAll(vf.Strings(`a`)) }, `RemoveAll .* frozen`)
+	require.Panic(t, func() { m.SetType(typ.Map) }, `SetType .* frozen`)
+	require.Panic(t, func() { _ = m.UnmarshalJSON([]byte(`{}`)) }, `UnmarshalJSON .* frozen`)
+	require.Panic(t, func() { _ = yaml.Unmarshal([]byte(`a: 1`), m) }, `UnmarshalYAML .* frozen`)
+	require.Panic(t, func() { m.SubMap(`a`, nil).Put(`b`, 2) }, `Put .* frozen`)
+}
+
+func TestSortedMap_range(t *testing.T) {
+	m := vf.SortedMap(map[int]string{10: `ten`, 20: `twenty`})
+	_, ok := m.Floor(5)
+	require.False(t, ok)
+	_, ok = m.Ceiling(25)
+	require.False(t, ok)
+	e, ok := m.Floor(20)
+	require.True(t, ok)
+	require.Equal(t, 20, e.Key())
+	e, ok = m.Ceiling(10)
+	require.True(t, ok)
+	require.Equal(t, `ten`, e.Value())
+	require.Equal(t, 0, m.SubMap(30, 10).Len())
+	require.Equal(t, 0, m.SubMap(11, 20).Len())
+}
+
+func TestSortedMap_iteration(t *testing.T) {
+	m := vf.SortedMap(map[string]int{`b`: 2, `a`: 1})
+	require.True(t, m.All(func(e dgo.MapEntry) bool { return e.Value().(dgo.Integer).GoInt() > 0 }))
+	require.False(t, m.All(func(e dgo.MapEntry) bool { return e.Value().Equals(1) }))
+	require.True(t, m.AllKeys(func(k dgo.Value) bool { _, ok := k.(dgo.String); return ok }))
+	require.False(t, m.AllKeys(func(k dgo.Value) bool { return k.Equals(`a`) }))
+	require.True(t, m.AllValues(func(v dgo.Value) bool { return v.(dgo.Integer).GoInt() > 0 }))
+	require.False(t, m.AllValues(func(v dgo.Value) bool { return v.Equals(1) }))
+	require.True(t, m.Any(func(e dgo.MapEntry) bool { return e.Key().Equals(`b`) }))
+	require.False(t, m.Any(func(e dgo.MapEntry) bool { return e.Key().Equals(`c`) }))
+	require.True(t, m.AnyKey(func(k dgo.Value) bool { return k.Equals(`a`) }))
+	require.False(t, m.AnyKey(func(k dgo.Value) bool { return k.Equals(`c`) }))
+	require.True(t, m.AnyValue(func(v dgo.Value) bool { return v.Equals(2) }))
+	require.False(t, m.AnyValue(func(v dgo.Value) bool { return v.Equals(3) }))
+
+	ks := ``
+	m.EachKey(func(k dgo.Value) { ks += k.String() })
+	m.Each(func(e dgo.MapEntry) { ks += e.Key().String() })
+	m.EachValue(func(v dgo.Value) { ks += v.String() })
+	require.Equal(t, `abab12`, ks)
+
+	c, ok := m.Entries().ToMapFromEntries()
+	require.True(t, ok)
+	require.Equal(t, m, c)
+}
+
+func TestSortedMap_types(t *testing.T) {
+	m := vf.SortedMap(map[string]interface{}{`a`: 1, `b`: `two`})
+	require.Instance(t, m.Type(), m)
+	require.Instance(t, m.Type(), vf.Map(map[string]interface{}{`b`: `two`, `a`: 1}))
+	require.Instance(t, typ.Map, m)
+	require.Instance(t, newtype.Map(typ.String, typ.Any, 2, 2), m)
+	require.Instance(t, newtype.Struct(
+		newtype.StructEntry(`a`, typ.Integer, true),
+		newtype.StructEntry(`b`, typ.String, true)), m)
+	require.NotEqual(t, m, vf.Map(map[string]interface{}{`a`: 1}))
+	require.NotEqual(t, m, vf.Map(map[string]interface{}{`a`: 1, `b`: `three`}))
+}
+
+func TestSortedMap_marshal(t *testing.T) {
+	m := vf.SortedMap(map[string]int{`b`: 2, `a`: 1})
+	b, err := m.MarshalJSON()
+	require.Nil(t, err)
+	require.Equal(t, `{"a":1,"b":2}`, string(b))
+	b, err = yaml.Marshal(m)
+	require.Nil(t, err)
+	require.Equal(t, "a: 1\nb: 2\n", string(b))
+
+	u := vf.MutableSortedMap(nil)
+	require.Nil(t, u.UnmarshalJSON([]byte(`{"x":1,"c":2}`)))
+	require.Equal(t, `{"c":2,"x":1}`, u.String())
+	require.NotNil(t, u.UnmarshalJSON([]byte(`[1]`)))
+
+	u = vf.MutableSortedMap(nil)
+	require.Nil(t, yaml.Unmarshal([]byte("x: 1\nc: 2\n"), u))
+	require.Equal(t, `{"c":2,"x":1}`, u.String())
+	require.NotNil(t, yaml.Unmarshal([]byte("- 1\n"), u))
+
+	u.Put(`y`, &failingMarshaler{})
+	_, err = yaml.Marshal(u)
+	require.NotNil(t, err)
+}
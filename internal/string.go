@@ -75,6 +75,14 @@ func (t defaultStringType) Equals(other interface{}) bool {
 	return t == other
 }
 
+func (t *defaultStringType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t defaultStringType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t defaultStringType) HashCode() int {
 	return int(dgo.IdString)
 }
@@ -127,6 +135,14 @@ func (t *exactStringType) Equals(v interface{}) bool {
 	return false
 }
 
+func (t *exactStringType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *exactStringType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *exactStringType) HashCode() int {
 	return (*hstring)(t).HashCode() * 5
 }
@@ -222,6 +238,14 @@ func (t *patternType) Equals(v interface{}) bool {
 	return false
 }
 
+func (t *patternType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *patternType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *patternType) HashCode() int {
 	return stringHash(t.String())
 }
@@ -290,6 +314,14 @@ func (t *sizedStringType) Equals(v interface{}) bool {
 	return false
 }
 
+func (t *sizedStringType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *sizedStringType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *sizedStringType) HashCode() int {
 	h := int(dgo.IdStringSized)
 	if t.min > 0 {
@@ -401,6 +433,14 @@ func (v *hstring) GoString() string {
 	return v.s
 }
 
+func (v *hstring) GobDecode(b []byte) error {
+	return gobDecodeInto(b, v)
+}
+
+func (v *hstring) GobEncode() ([]byte, error) {
+	return gobEncode(v)
+}
+
 func (v *hstring) HashCode() int {
 	if v.h == 0 {
 		v.h = stringHash(v.s)
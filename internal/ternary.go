@@ -67,6 +67,14 @@ func (t *allOfType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *allOfType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *allOfType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *allOfType) HashCode() int {
 	return (*array)(t).HashCode()*7 + int(dgo.IdAllOf)
 }
@@ -159,6 +167,14 @@ func (t *anyOfType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *anyOfType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *anyOfType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *anyOfType) HashCode() int {
 	return (*array)(t).HashCode()*7 + int(dgo.IdAnyOf)
 }
@@ -258,6 +274,14 @@ func (t *oneOfType) Equals(other interface{}) bool {
 	return false
 }
 
+func (t *oneOfType) GobDecode(b []byte) error {
+	return gobDecodeInto(b, t)
+}
+
+func (t *oneOfType) GobEncode() ([]byte, error) {
+	return gobEncode(t)
+}
+
 func (t *oneOfType) HashCode() int {
 	return (*array)(t).HashCode()
 }
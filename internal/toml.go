@@ -0,0 +1,879 @@
+package internal
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+// The ways that a TOML table can come into existence. The way determines if the table can be extended
+// later in the document.
+const (
+	tomlImplicit = byte(iota) // created as a parent of a table header
+	tomlHeader                // defined by a [table] header or an [[array]] header
+	tomlDotted                // created by a dotted key
+	tomlInline                // defined by an inline table
+)
+
+var (
+	tomlBareKey   = regexp.MustCompile(`\A[A-Za-z0-9_-]+\z`)
+	tomlDecInt    = regexp.MustCompile(`\A[+-]?(0|[1-9](_?[0-9])*)\z`)
+	tomlHexInt    = regexp.MustCompile(`\A0x[0-9A-Fa-f](_?[0-9A-Fa-f])*\z`)
+	tomlOctInt    = regexp.MustCompile(`\A0o[0-7](_?[0-7])*\z`)
+	tomlBinInt    = regexp.MustCompile(`\A0b[01](_?[01])*\z`)
+	tomlFloat     = regexp.MustCompile(`\A[+-]?(0|[1-9](_?[0-9])*)(\.[0-9](_?[0-9])*)?([eE][+-]?[0-9](_?[0-9])*)?\z`)
+	tomlLocalDate = regexp.MustCompile(`\A\d{4}-\d{2}-\d{2}\z`)
+	tomlLocalTime = regexp.MustCompile(`\A\d{2}:\d{2}:\d{2}(\.\d+)?\z`)
+	tomlDateTime  = regexp.MustCompile(`\A\d{4}-\d{2}-\d{2}[Tt ]\d{2}:\d{2}:\d{2}(\.\d+)?([Zz]|[+-]\d{2}:\d{2})?\z`)
+)
+
+// UnmarshalTOML decodes the given TOML document into a frozen dgo.Map. The entries of all tables are
+// kept in the order that they appear in the document. Offset date-times are returned as native time.Time
+// values, and local date-times, dates, and times as native dgo.LocalDateTime, dgo.LocalDate, and
+// dgo.LocalTime values.
+func UnmarshalTOML(b []byte) (dgo.Map, error) {
+	p := &tomlParser{s: string(b), line: 1, kinds: make(map[*hashMap]byte), tableArrays: make(map[*array]bool)}
+	return p.parse()
+}
+
+type tomlParser struct {
+	s    string
+	pos  int
+	line int
+
+	// kinds tracks how each table was created
+	kinds map[*hashMap]byte
+
+	// tableArrays are the arrays that were created using [[array]] headers
+	tableArrays map[*array]bool
+}
+
+// tomlError is used for panics that are recovered and returned as errors by the parser
+type tomlError struct {
+	error
+}
+
+func (p *tomlParser) fail(format string, args ...interface{}) {
+	panic(tomlError{fmt.Errorf(`toml: line %d: %s`, p.line, fmt.Sprintf(format, args...))})
+}
+
+func (p *tomlParser) parse() (m dgo.Map, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			f, ok := r.(tomlError)
+			if !ok {
+				panic(r)
+			}
+			err = f.error
+		}
+	}()
+	if !utf8.ValidString(p.s) {
+		p.fail(`document is not valid UTF-8`)
+	}
+	root := p.newTable(tomlHeader)
+	current := root
+	for {
+		p.skipBlank()
+		if p.atEnd() {
+			break
+		}
+		if p.peek() == '[' {
+			current = p.header(root)
+		} else {
+			p.keyValue(current, tomlDotted)
+		}
+		p.endOfLine()
+	}
+	root.Freeze()
+	return root, nil
+}
+
+func (p *tomlParser) newTable(kind byte) *hashMap {
+	t := MutableMap(0, nil).(*hashMap)
+	p.kinds[t] = kind
+	return t
+}
+
+func (p *tomlParser) atEnd() bool {
+	return p.pos >= len(p.s)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.atEnd() {
+		return 0
+	}
+	return p.s[p.pos]
+}
+
+func (p *tomlParser) expect(c byte) {
+	if p.peek() != c {
+		p.fail(`expected '%c'`, c)
+	}
+	p.pos++
+}
+
+// skipSpace skips spaces and tabs
+func (p *tomlParser) skipSpace() {
+	for c := p.peek(); c == ' ' || c == '\t'; c = p.peek() {
+		p.pos++
+	}
+}
+
+// skipComment skips a comment that extends to the end of the line
+func (p *tomlParser) skipComment() {
+	if p.peek() != '#' {
+		return
+	}
+	for !p.atEnd() && p.peek() != '\n' {
+		c := p.s[p.pos]
+		if c < 0x20 && c != '\t' && !(c == '\r' && p.pos+1 < len(p.s) && p.s[p.pos+1] == '\n') || c == 0x7f {
+			p.fail(`control character in comment`)
+		}
+		p.pos++
+	}
+}
+
+// newline consumes a newline and returns true, or returns false if there is no newline at the position
+func (p *tomlParser) newline() bool {
+	switch {
+	case strings.HasPrefix(p.s[p.pos:], "\n"):
+		p.pos++
+	case strings.HasPrefix(p.s[p.pos:], "\r\n"):
+		p.pos += 2
+	default:
+		return false
+	}
+	p.line++
+	return true
+}
+
+// skipBlank skips whitespace, comments, and newlines
+func (p *tomlParser) skipBlank() {
+	for {
+		p.skipSpace()
+		p.skipComment()
+		if !p.newline() {
+			return
+		}
+	}
+}
+
+func (p *tomlParser) endOfLine() {
+	p.skipSpace()
+	p.skipComment()
+	if !p.atEnd() && !p.newline() {
+		p.fail(`expected end of line`)
+	}
+}
+
+// header parses a [table] or [[array]] header and returns the table that subsequent key/value pairs are
+// added to
+func (p *tomlParser) header(root *hashMap) *hashMap {
+	p.pos++
+	isArray := p.peek() == '['
+	if isArray {
+		p.pos++
+	}
+	p.skipSpace()
+	keys := p.key()
+	p.skipSpace()
+	p.expect(']')
+	if isArray {
+		p.expect(']')
+	}
+
+	t := root
+	last := len(keys) - 1
+	for _, k := range keys[:last] {
+		t = p.subTable(t, k)
+	}
+	k := makeHString(keys[last])
+	v, ok := t.Get(k)
+	if isArray {
+		var a *array
+		if ok {
+			if a, ok = v.(*array); !ok || !p.tableArrays[a] {
+				p.fail(`key '%s' is already defined`, strings.Join(keys, `.`))
+			}
+		} else {
+			a = &array{}
+			p.tableArrays[a] = true
+			t.Put(k, a)
+		}
+		nt := p.newTable(tomlHeader)
+		a.slice = append(a.slice, nt)
+		return nt
+	}
+	if !ok {
+		nt := p.newTable(tomlHeader)
+		t.Put(k, nt)
+		return nt
+	}
+	if nt, ok := v.(*hashMap); ok && p.kinds[nt] == tomlImplicit {
+		p.kinds[nt] = tomlHeader
+		return nt
+	}
+	p.fail(`table '%s' is already defined`, strings.Join(keys, `.`))
+	return nil
+}
+
+// subTable returns the table stored under the given key in the given table when navigating a table
+// header. A missing table is created. The last table of an array of tables is returned when the key
+// denotes such an array.
+func (p *tomlParser) subTable(t *hashMap, key string) *hashMap {
+	k := makeHString(key)
+	v, ok := t.Get(k)
+	if !ok {
+		nt := p.newTable(tomlImplicit)
+		t.Put(k, nt)
+		return nt
+	}
+	switch v := v.(type) {
+	case *hashMap:
+		if p.kinds[v] != tomlInline {
+			return v
+		}
+	case *array:
+		if p.tableArrays[v] {
+			return v.slice[len(v.slice)-1].(*hashMap)
+		}
+	}
+	p.fail(`key '%s' is not a table`, key)
+	return nil
+}
+
+// keyValue parses a key/value pair and adds it to the given table. Tables created by dotted keys get the
+// given kind.
+func (p *tomlParser) keyValue(t *hashMap, kind byte) {
+	keys := p.key()
+	p.skipSpace()
+	p.expect('=')
+	p.skipSpace()
+	last := len(keys) - 1
+	for _, key := range keys[:last] {
+		k := makeHString(key)
+		v, ok := t.Get(k)
+		if !ok {
+			nt := p.newTable(kind)
+			t.Put(k, nt)
+			t = nt
+			continue
+		}
+		nt, ok := v.(*hashMap)
+		if !ok || p.kinds[nt] != kind {
+			p.fail(`key '%s' cannot be extended using a dotted key`, strings.Join(keys, `.`))
+		}
+		t = nt
+	}
+	k := makeHString(keys[last])
+	if _, ok := t.Get(k); ok {
+		p.fail(`key '%s' is already defined`, strings.Join(keys, `.`))
+	}
+	t.Put(k, p.value())
+}
+
+// key parses a simple or dotted key
+func (p *tomlParser) key() []string {
+	var keys []string
+	for {
+		var k string
+		switch p.peek() {
+		case '"':
+			k = p.basicString()
+		case '\'':
+			k = p.literalString()
+		default:
+			start := p.pos
+			for c := p.peek(); c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'; c = p.peek() {
+				p.pos++
+			}
+			if start == p.pos {
+				p.fail(`expected a key`)
+			}
+			k = p.s[start:p.pos]
+		}
+		keys = append(keys, k)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys
+		}
+		p.pos++
+		p.skipSpace()
+	}
+}
+
+func (p *tomlParser) value() dgo.Value {
+	switch c := p.peek(); {
+	case c == '"':
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			return makeHString(p.multiLineBasicString())
+		}
+		return makeHString(p.basicString())
+	case c == '\'':
+		if strings.HasPrefix(p.s[p.pos:], `'''`) {
+			return makeHString(p.multiLineLiteralString())
+		}
+		return makeHString(p.literalString())
+	case c == '[':
+		return p.array()
+	case c == '{':
+		return p.inlineTable()
+	case strings.HasPrefix(p.s[p.pos:], `true`):
+		p.pos += 4
+		return True
+	case strings.HasPrefix(p.s[p.pos:], `false`):
+		p.pos += 5
+		return False
+	}
+	return p.scalar()
+}
+
+func (p *tomlParser) array() dgo.Value {
+	p.pos++
+	es := make([]dgo.Value, 0)
+	for {
+		p.skipBlank()
+		if p.peek() == ']' {
+			break
+		}
+		es = append(es, p.value())
+		p.skipBlank()
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	p.expect(']')
+	return &array{slice: es}
+}
+
+func (p *tomlParser) inlineTable() dgo.Value {
+	p.pos++
+	t := p.newTable(tomlInline)
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return t
+	}
+	for {
+		p.skipSpace()
+		p.keyValue(t, tomlInline)
+		p.skipSpace()
+		if p.peek() != ',' {
+			break
+		}
+		p.pos++
+	}
+	p.expect('}')
+	return t
+}
+
+// token advances the position past all characters that can be part of a number, a date, or a time
+func (p *tomlParser) token() {
+	for c := p.peek(); c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '-' || c == '+' || c == '.' || c == ':'; c = p.peek() {
+		p.pos++
+	}
+}
+
+// scalar parses a number, a date, or a time
+func (p *tomlParser) scalar() dgo.Value {
+	start := p.pos
+	p.token()
+	s := p.s[start:p.pos]
+
+	// A date may be followed by a space and a time
+	if tomlLocalDate.MatchString(s) && p.peek() == ' ' && p.pos+1 < len(p.s) && p.s[p.pos+1] >= '0' && p.s[p.pos+1] <= '9' {
+		p.pos++
+		p.token()
+		s = p.s[start:p.pos]
+	}
+
+	switch {
+	case s == ``:
+		p.fail(`expected a value`)
+	case tomlDecInt.MatchString(s):
+		return p.integer(s, 10)
+	case tomlHexInt.MatchString(s):
+		return p.integer(s[2:], 16)
+	case tomlOctInt.MatchString(s):
+		return p.integer(s[2:], 8)
+	case tomlBinInt.MatchString(s):
+		return p.integer(s[2:], 2)
+	case tomlFloat.MatchString(s):
+		f, err := strconv.ParseFloat(strings.Replace(s, `_`, ``, -1), 64)
+		if err != nil {
+			p.fail(`invalid float '%s'`, s)
+		}
+		return Float(f)
+	case s == `inf` || s == `+inf`:
+		return Float(math.Inf(1))
+	case s == `-inf`:
+		return Float(math.Inf(-1))
+	case s == `nan` || s == `+nan` || s == `-nan`:
+		return Float(math.NaN())
+	case tomlDateTime.MatchString(s):
+		return p.dateTime(s)
+	case tomlLocalDate.MatchString(s):
+		return Value(localDate(p.time(`2006-01-02`, s)))
+	case tomlLocalTime.MatchString(s):
+		return Value(localTime(p.time(`15:04:05.999999999`, s)))
+	}
+	p.fail(`invalid value '%s'`, s)
+	return nil
+}
+
+func (p *tomlParser) integer(s string, base int) dgo.Value {
+	i, err := strconv.ParseInt(strings.Replace(s, `_`, ``, -1), base, 64)
+	if err != nil {
+		p.fail(`invalid integer '%s'`, s)
+	}
+	return Integer(i)
+}
+
+func (p *tomlParser) dateTime(s string) dgo.Value {
+	// Normalize the separator and the zone designator so that only two layouts are needed
+	b := []byte(s)
+	b[10] = 'T'
+	if c := b[len(b)-1]; c == 'z' {
+		b[len(b)-1] = 'Z'
+	}
+	s = string(b)
+	if strings.HasSuffix(s, `Z`) || strings.LastIndexAny(s, `+-`) > 10 {
+		return Value(p.time(time.RFC3339Nano, s))
+	}
+	t := p.time(`2006-01-02T15:04:05.999999999`, s)
+	return Value(dgo.LocalDateTime{Date: localDate(t), Time: localTime(t)})
+}
+
+func (p *tomlParser) time(layout, s string) time.Time {
+	t, err := time.Parse(layout, s)
+	if err != nil {
+		p.fail(`invalid date or time '%s'`, s)
+	}
+	return t
+}
+
+func localDate(t time.Time) dgo.LocalDate {
+	return dgo.LocalDate{Year: t.Year(), Month: t.Month(), Day: t.Day()}
+}
+
+func localTime(t time.Time) dgo.LocalTime {
+	return dgo.LocalTime{Hour: t.Hour(), Minute: t.Minute(), Second: t.Second(), Nanosecond: t.Nanosecond()}
+}
+
+func (p *tomlParser) checkControl(c byte, multiLine bool) {
+	if c < 0x20 && c != '\t' && !(multiLine && (c == '\n' || c == '\r')) || c == 0x7f {
+		p.fail(`control character in string`)
+	}
+}
+
+func (p *tomlParser) basicString() string {
+	p.pos++
+	var b strings.Builder
+	for {
+		if p.atEnd() {
+			p.fail(`unterminated string`)
+		}
+		c := p.s[p.pos]
+		switch c {
+		case '"':
+			p.pos++
+			return b.String()
+		case '\\':
+			p.escape(&b)
+		default:
+			p.checkControl(c, false)
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+func (p *tomlParser) multiLineBasicString() string {
+	p.pos += 3
+	p.newline()
+	var b strings.Builder
+	for {
+		if p.atEnd() {
+			p.fail(`unterminated string`)
+		}
+		if strings.HasPrefix(p.s[p.pos:], `"""`) {
+			// Up to two additional quotes are allowed right before the closing delimiter
+			p.pos += 3
+			for i := 0; i < 2 && p.peek() == '"'; i++ {
+				b.WriteByte('"')
+				p.pos++
+			}
+			return b.String()
+		}
+		c := p.s[p.pos]
+		switch {
+		case c == '\\' && p.lineEndingBackslash():
+		case c == '\\':
+			p.escape(&b)
+		case c == '\n' || c == '\r':
+			if !p.newline() {
+				p.fail(`control character in string`)
+			}
+			b.WriteByte('\n')
+		default:
+			p.checkControl(c, true)
+			b.WriteByte(c)
+			p.pos++
+		}
+	}
+}
+
+// lineEndingBackslash consumes a backslash that is followed by optional whitespace and a newline, along
+// with all whitespace and newlines that follow, and returns true. It returns false if the backslash at
+// the current position isn't such a backslash.
+func (p *tomlParser) lineEndingBackslash() bool {
+	i := p.pos + 1
+	for i < len(p.s) && (p.s[i] == ' ' || p.s[i] == '\t') {
+		i++
+	}
+	if !strings.HasPrefix(p.s[i:], "\n") && !strings.HasPrefix(p.s[i:], "\r\n") {
+		return false
+	}
+	p.pos = i
+	for {
+		p.skipSpace()
+		if !p.newline() {
+			return true
+		}
+	}
+}
+
+func (p *tomlParser) escape(b *strings.Builder) {
+	p.pos++
+	if p.atEnd() {
+		p.fail(`unterminated string`)
+	}
+	c := p.s[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case '"':
+		b.WriteByte('"')
+	case '\\':
+		b.WriteByte('\\')
+	case 'u', 'U':
+		n := 4
+		if c == 'U' {
+			n = 8
+		}
+		if p.pos+n > len(p.s) {
+			p.fail(`invalid unicode escape`)
+		}
+		u, err := strconv.ParseUint(p.s[p.pos:p.pos+n], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(u)) {
+			p.fail(`invalid unicode escape`)
+		}
+		p.pos += n
+		b.WriteRune(rune(u))
+	default:
+		p.fail(`invalid escape sequence '\%c'`, c)
+	}
+}
+
+func (p *tomlParser) literalString() string {
+	p.pos++
+	start := p.pos
+	for {
+		if p.atEnd() {
+			p.fail(`unterminated string`)
+		}
+		c := p.s[p.pos]
+		if c == '\'' {
+			s := p.s[start:p.pos]
+			p.pos++
+			return s
+		}
+		p.checkControl(c, false)
+		p.pos++
+	}
+}
+
+func (p *tomlParser) multiLineLiteralString() string {
+	p.pos += 3
+	p.newline()
+	var b strings.Builder
+	for {
+		if p.atEnd() {
+			p.fail(`unterminated string`)
+		}
+		if strings.HasPrefix(p.s[p.pos:], `'''`) {
+			p.pos += 3
+			for i := 0; i < 2 && p.peek() == '\''; i++ {
+				b.WriteByte('\'')
+				p.pos++
+			}
+			return b.String()
+		}
+		c := p.s[p.pos]
+		if c == '\n' || c == '\r' {
+			if !p.newline() {
+				p.fail(`control character in string`)
+			}
+			b.WriteByte('\n')
+			continue
+		}
+		p.checkControl(c, true)
+		b.WriteByte(c)
+		p.pos++
+	}
+}
+
+// MarshalTOML returns the TOML representation of the given map. Entries are written in the order of the
+// map, except that the tables of a table are written after its other entries. An array where all
+// elements are maps is written as an array of tables.
+//
+// An error is returned if the map or any value that it contains has no TOML representation, such as a map
+// with keys that aren't strings, a nil, a binary, or a type. Native time.Time values are written as offset
+// date-times, and native dgo.LocalDateTime, dgo.LocalDate, and dgo.LocalTime values in their local form.
+func MarshalTOML(m dgo.Map) ([]byte, error) {
+	w := &tomlWriter{}
+	if err := w.table(nil, m); err != nil {
+		return nil, err
+	}
+	return []byte(w.String()), nil
+}
+
+type tomlWriter struct {
+	strings.Builder
+}
+
+// isTableArray returns true if the given value is a non empty array where all elements are maps
+func isTableArray(v dgo.Value) bool {
+	a, ok := v.(dgo.Array)
+	return ok && a.Len() > 0 && a.All(func(e dgo.Value) bool { _, ok := e.(dgo.Map); return ok })
+}
+
+// table writes the entries of the given map. The path is the keys of the map in its parent tables.
+func (w *tomlWriter) table(path []string, m dgo.Map) error {
+	type tableEntry struct {
+		key   string
+		value dgo.Value
+	}
+	var tables []tableEntry
+	var err error
+	m.Each(func(e dgo.MapEntry) {
+		if err != nil {
+			return
+		}
+		k, ok := e.Key().(dgo.String)
+		if !ok {
+			err = fmt.Errorf(`toml: map key %s is not a string`, e.Key())
+			return
+		}
+		v := e.Value()
+		switch v.(type) {
+		case dgo.Map:
+			tables = append(tables, tableEntry{k.GoString(), v})
+			return
+		case dgo.Array:
+			if isTableArray(v) {
+				tables = append(tables, tableEntry{k.GoString(), v})
+				return
+			}
+		}
+		w.WriteString(tomlKey(k.GoString()))
+		w.WriteString(` = `)
+		if err = w.value(v); err == nil {
+			w.WriteByte('\n')
+		}
+	})
+	if err != nil {
+		return err
+	}
+	for _, te := range tables {
+		tp := append(append(make([]string, 0, len(path)+1), path...), te.key)
+		if tm, ok := te.value.(dgo.Map); ok {
+			w.separate()
+			w.WriteByte('[')
+			w.path(tp)
+			w.WriteString("]\n")
+			if err = w.table(tp, tm); err != nil {
+				return err
+			}
+			continue
+		}
+		a := te.value.(dgo.Array)
+		for i := 0; i < a.Len(); i++ {
+			w.separate()
+			w.WriteString(`[[`)
+			w.path(tp)
+			w.WriteString("]]\n")
+			if err = w.table(tp, a.Get(i).(dgo.Map)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// separate writes an empty line unless nothing has been written yet
+func (w *tomlWriter) separate() {
+	if w.Len() > 0 {
+		w.WriteByte('\n')
+	}
+}
+
+func (w *tomlWriter) path(keys []string) {
+	for i, k := range keys {
+		if i > 0 {
+			w.WriteByte('.')
+		}
+		w.WriteString(tomlKey(k))
+	}
+}
+
+// value writes a value that is not a table or an array of tables
+func (w *tomlWriter) value(v dgo.Value) error {
+	switch v := v.(type) {
+	case Boolean:
+		w.WriteString(v.String())
+	case Integer:
+		w.WriteString(strconv.FormatInt(int64(v), 10))
+	case Float:
+		w.WriteString(tomlFloatString(float64(v)))
+	case dgo.String:
+		w.WriteString(tomlQuote(v.GoString()))
+	case *hashSet:
+		return w.array(v.m.Keys().(*array).slice)
+	case dgo.Array:
+		return w.array(sliceOf(v))
+	case dgo.Map:
+		return w.inlineTable(v)
+	case dgo.Native:
+		switch t := v.GoValue().(type) {
+		case time.Time:
+			w.WriteString(t.Format(time.RFC3339Nano))
+			return nil
+		case dgo.LocalDateTime, dgo.LocalDate, dgo.LocalTime:
+			w.WriteString(v.String())
+			return nil
+		}
+		return fmt.Errorf(`toml: unable to represent a value of type %s`, TypeString(v.Type()))
+	default:
+		return fmt.Errorf(`toml: unable to represent a value of type %s`, TypeString(v.Type()))
+	}
+	return nil
+}
+
+func (w *tomlWriter) array(vs []dgo.Value) error {
+	w.WriteByte('[')
+	for i, e := range vs {
+		if i > 0 {
+			w.WriteString(`, `)
+		}
+		if err := w.value(e); err != nil {
+			return err
+		}
+	}
+	w.WriteByte(']')
+	return nil
+}
+
+func (w *tomlWriter) inlineTable(m dgo.Map) error {
+	w.WriteByte('{')
+	var err error
+	first := true
+	m.Each(func(e dgo.MapEntry) {
+		if err != nil {
+			return
+		}
+		k, ok := e.Key().(dgo.String)
+		if !ok {
+			err = fmt.Errorf(`toml: map key %s is not a string`, e.Key())
+			return
+		}
+		if first {
+			first = false
+			w.WriteByte(' ')
+		} else {
+			w.WriteString(`, `)
+		}
+		w.WriteString(tomlKey(k.GoString()))
+		w.WriteString(` = `)
+		err = w.value(e.Value())
+	})
+	if err != nil {
+		return err
+	}
+	if !first {
+		w.WriteByte(' ')
+	}
+	w.WriteByte('}')
+	return nil
+}
+
+func tomlKey(k string) string {
+	if tomlBareKey.MatchString(k) {
+		return k
+	}
+	return tomlQuote(k)
+}
+
+func tomlQuote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\b':
+			b.WriteString(`\b`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\f':
+			b.WriteString(`\f`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+func tomlFloatString(f float64) string {
+	switch {
+	case math.IsInf(f, 1):
+		return `inf`
+	case math.IsInf(f, -1):
+		return `-inf`
+	case math.IsNaN(f):
+		return `nan`
+	}
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, `.e`) {
+		s += `.0`
+	}
+	return s
+}
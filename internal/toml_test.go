@@ -0,0 +1,274 @@
+package internal_test
+
+import (
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func TestUnmarshalTOML(t *testing.T) {
+	m, err := vf.UnmarshalTOML([]byte(`# This is a TOML document
+title = "TOML Example"
+
+[owner]
+name = "Tom Preston-Werner"
+dob = 1979-05-27T07:32:00-08:00
+
+[database]
+enabled = true
+ports = [ 8000, 8001, 8002 ]
+data = [ ["delta", "phi"], [3.14] ]
+temp_targets = { cpu = 79.5, case = 72.0 }
+
+[servers]
+
+[servers.alpha]
+ip = "10.0.0.1"
+role = "frontend"
+
+[servers.beta]
+ip = "10.0.0.2"
+role = "backend"
+`))
+	require.Nil(t, err)
+	require.True(t, m.Frozen())
+	require.Equal(t, vf.Strings(`title`, `owner`, `database`, `servers`), m.Keys())
+	require.Equal(t, `{"title":"TOML Example","owner":{"name":"Tom Preston-Werner","dob":1979-05-27 07:32:00 -0800 -0800},`+
+		`"database":{"enabled":true,"ports":[8000,8001,8002],"data":[["delta","phi"],[3.14]],`+
+		`"temp_targets":{"cpu":79.5,"case":72.0}},"servers":{"alpha":{"ip":"10.0.0.1","role":"frontend"},`+
+		`"beta":{"ip":"10.0.0.2","role":"backend"}}}`, m.String())
+	owner, _ := m.Get(`owner`)
+	dob, _ := owner.(dgo.Map).Get(`dob`)
+	require.True(t, time.Date(1979, 5, 27, 15, 32, 0, 0, time.UTC).Equal(dob.(dgo.Native).GoValue().(time.Time)))
+}
+
+func tomlValue(t *testing.T, s string) dgo.Value {
+	t.Helper()
+	m, err := vf.UnmarshalTOML([]byte(`v = ` + s))
+	require.Nil(t, err)
+	v, _ := m.Get(`v`)
+	return v
+}
+
+func TestUnmarshalTOML_values(t *testing.T) {
+	tests := []struct {
+		toml string
+		v    interface{}
+	}{
+		{`"a\tb\"\\\u00e9\U0001F600"`, "a\tb\"\\é\U0001F600"},
+		{`'C:\Users\nodejs'`, `C:\Users\nodejs`},
+		{"\"\"\"\nRoses are red\nViolets are blue\"\"\"", "Roses are red\nViolets are blue"},
+		{"\"\"\"\r\nRoses\r\n\"\"\"", "Roses\n"},
+		{"\"\"\"\nThe quick \\\n\n   brown fox\"\"\"", "The quick brown fox"},
+		{`"""Here are two quotes: "". Simple."""`, `Here are two quotes: "". Simple.`},
+		{`""""This," she said, "is just a pointless statement.""""`, `"This," she said, "is just a pointless statement."`},
+		{"'''\nThe first newline is\ntrimmed in raw strings.'''", "The first newline is\ntrimmed in raw strings."},
+		{`''''That,' she said, 'is still pointless.''''`, `'That,' she said, 'is still pointless.'`},
+		{`+99`, 99},
+		{`-17`, -17},
+		{`1_000`, 1000},
+		{`0xDEAD_beef`, 0xdeadbeef},
+		{`0o755`, 493},
+		{`0b1101`, 13},
+		{`+1.0`, 1.0},
+		{`-0.01`, -0.01},
+		{`5e+22`, 5e+22},
+		{`6.626e-34`, 6.626e-34},
+		{`224_617.445_991_228`, 224617.445991228},
+		{`inf`, math.Inf(1)},
+		{`-inf`, math.Inf(-1)},
+		{`false`, false},
+		{`[ 1, 2.0, "three", ]`, vf.Values(1, 2.0, `three`)},
+		{"[\n  1, # one\n  2\n]", vf.Values(1, 2)},
+		{`[]`, vf.Values()},
+		{`{}`, vf.Map(map[string]int{})},
+		{`{ a.b = 1, a.c = 2 }`, vf.Map(map[string]interface{}{`a`: vf.Map(map[string]int{`b`: 1, `c`: 2})})},
+	}
+	for _, tc := range tests {
+		require.Equal(t, tc.v, tomlValue(t, tc.toml))
+	}
+	require.True(t, math.IsNaN(float64(tomlValue(t, `nan`).(dgo.Float).GoFloat())))
+}
+
+func TestUnmarshalTOML_dates(t *testing.T) {
+	tests := []struct {
+		toml string
+		t    time.Time
+	}{
+		{`1979-05-27T07:32:00Z`, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)},
+		{`1979-05-27 07:32:00z`, time.Date(1979, 5, 27, 7, 32, 0, 0, time.UTC)},
+		{`1979-05-27T00:32:00.999999-07:00`, time.Date(1979, 5, 27, 7, 32, 0, 999999000, time.UTC)},
+	}
+	for _, tc := range tests {
+		v := tomlValue(t, tc.toml)
+		require.True(t, tc.t.Equal(v.(dgo.Native).GoValue().(time.Time)))
+	}
+
+	locals := []struct {
+		toml string
+		v    interface{}
+	}{
+		{`1979-05-27T07:32:00`, dgo.LocalDateTime{
+			Date: dgo.LocalDate{Year: 1979, Month: time.May, Day: 27},
+			Time: dgo.LocalTime{Hour: 7, Minute: 32}}},
+		{`1979-05-27`, dgo.LocalDate{Year: 1979, Month: time.May, Day: 27}},
+		{`07:32:00.5`, dgo.LocalTime{Hour: 7, Minute: 32, Nanosecond: 500000000}},
+	}
+	for _, tc := range locals {
+		v := tomlValue(t, tc.toml)
+		require.Equal(t, tc.v, v.(dgo.Native).GoValue())
+		require.True(t, v.(dgo.Freezable).Frozen())
+	}
+
+	_, err := vf.UnmarshalTOML([]byte(`t = 25:00:00`))
+	require.NotNil(t, err)
+}
+
+func TestUnmarshalTOML_tables(t *testing.T) {
+	m, err := vf.UnmarshalTOML([]byte(`
+name.first = "Tom"
+name . last = "Preston"
+"quoted key".'x' = 1
+
+[a.b.c]
+d = 1
+
+[a]
+e = 2
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+
+[[products]]
+name = "Nail"
+[products.size]
+width = 2
+
+[[fruits]]
+name = "apple"
+[[fruits.varieties]]
+name = "red delicious"
+[[fruits.varieties]]
+name = "granny smith"
+`))
+	require.Nil(t, err)
+	require.Equal(t, `{"name":{"first":"Tom","last":"Preston"},"quoted key":{"x":1},"a":{"b":{"c":{"d":1}},"e":2},`+
+		`"products":[{"name":"Hammer"},{},{"name":"Nail","size":{"width":2}}],`+
+		`"fruits":[{"name":"apple","varieties":[{"name":"red delicious"},{"name":"granny smith"}]}]}`, m.String())
+	products, _ := m.Get(`products`)
+	require.True(t, products.(dgo.Array).Frozen())
+}
+
+func TestUnmarshalTOML_fail(t *testing.T) {
+	for _, s := range []string{
+		"a = 1\na = 2",
+		"a = 1\n[a]",
+		"[a]\n[a]",
+		"a.b = 1\n[a]",
+		"[a.b]\n[a]\nb.c = 1",
+		"a = {}\n[a.b]",
+		"a = [1]\n[[a]]",
+		"[[a]]\n[a]",
+		"a = { b = 1 }\na.c = 2",
+		"a = 1 b = 2",
+		"a = { b = 1, }",
+		"a = { b = 1\n}",
+		"= 1",
+		"a = ",
+		"a = 01",
+		"a = 1__0",
+		"a = 0x",
+		"a = 9223372036854775808",
+		"a = 1979-13-27",
+		"a = 1e",
+		"a = \"unterminated",
+		"a = \"\\x\"",
+		"a = \"\\u12\"",
+		"a = \"\\uD800\"",
+		"a = \"\x01\"",
+		"a = '''unterminated",
+		"a = \"\"\"unterminated",
+		"a = 'unterminated",
+		"a = \"\\",
+		"a = [1 2]",
+		"[a",
+		"[[a]",
+		"a = 1 # \x01",
+		"a = \"\xff\"",
+		"a = \"\"\"\r\"\"\"",
+		"a = '''\r'''",
+	} {
+		_, err := vf.UnmarshalTOML([]byte(s))
+		require.NotNil(t, err)
+	}
+	_, err := vf.UnmarshalTOML([]byte("a = 1\n\nb = 2\nb = 3"))
+	require.Equal(t, `toml: line 4: key 'b' is already defined`, err.Error())
+}
+
+func TestMarshalTOML(t *testing.T) {
+	doc := `title = "TOML \"Example\""
+"a key" = [1, 2.0, "three", [true], { x = 1, "y z" = [] }, {}]
+dob = 1979-05-27T07:32:00-08:00
+ldt = 1979-05-27T07:32:00.25
+ld = 1979-05-27
+lt = 07:32:00
+nums = [inf, -inf, nan, 1e+100, 0.5]
+
+[owner]
+name = "Tom\tPreston\u0001"
+
+[owner.address]
+city = "X"
+
+[[products]]
+name = "Hammer"
+
+[[products]]
+name = "Nail"
+
+[products.size]
+width = 2
+`
+	m, err := vf.UnmarshalTOML([]byte(doc))
+	require.Nil(t, err)
+	b, err := vf.MarshalTOML(m)
+	require.Nil(t, err)
+	require.Equal(t, doc, string(b))
+
+	b, err = vf.MarshalTOML(vf.Map(map[string]interface{}{`s`: vf.Set(1)}))
+	require.Nil(t, err)
+	require.Equal(t, "s = [1]\n", string(b))
+
+	b, err = vf.MarshalTOML(vf.Map(map[string]interface{}{`t`: vf.Map(map[string]int{`a`: 1})}))
+	require.Nil(t, err)
+	require.Equal(t, "[t]\na = 1\n", string(b))
+}
+
+func TestMarshalTOML_fail(t *testing.T) {
+	for _, v := range []interface{}{
+		map[int]int{1: 1},
+		map[string]interface{}{`a`: nil},
+		map[string]interface{}{`a`: vf.Values(1, nil)},
+		map[string]interface{}{`a`: map[int]int{1: 1}},
+		map[string]interface{}{`a`: vf.Values(map[int]int{1: 1})},
+		map[string]interface{}{`a`: vf.Values(vf.Values(map[string]interface{}{`x`: nil}))},
+		map[string]interface{}{`a`: vf.Values(map[string]interface{}{`x`: nil})},
+		map[string]interface{}{`a`: []byte{1}},
+		map[string]interface{}{`a`: typ.String},
+		map[string]interface{}{`a`: regexp.MustCompile(`a`)},
+		map[string]interface{}{`a`: vf.Values(vf.Values(map[int]int{1: 1}))},
+	} {
+		_, err := vf.MarshalTOML(vf.Map(v))
+		require.NotNil(t, err)
+	}
+	_, err := vf.MarshalTOML(vf.Map(map[string]interface{}{`a`: vf.MutableValues(nil, struct{ A int }{1})}))
+	require.NotNil(t, err)
+}
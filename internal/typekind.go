@@ -0,0 +1,153 @@
+package internal
+
+import (
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/lyraproj/dgo/dgo"
+)
+
+type extensionKind struct {
+	id         dgo.TypeIdentifier
+	name       string
+	tag        string
+	newType    func(args dgo.Array) (dgo.Type, error)
+	typeString func(t dgo.Type) string
+}
+
+// YAML tags used when serializing values that have no natural YAML representation
+const (
+	// DefaultTypeTag is the tag used for types that don't have a tag of their own
+	DefaultTypeTag = `!puppet.com,2019:dgo/type`
+
+	// RegexpTag is the tag used for regular expressions
+	RegexpTag = `!puppet.com,2019:dgo/regexp`
+
+	// NativeTag is the tag used for a placeholder that represents a native Go value
+	NativeTag = `!puppet.com,2019:dgo/native`
+)
+
+var (
+	kindLock     sync.RWMutex
+	kindsByName  = map[string]*extensionKind{}
+	kindsByTag   = map[string]*extensionKind{}
+	kindsByID    = map[dgo.TypeIdentifier]*extensionKind{}
+	nextKindID   = dgo.IdExtension
+	kindNameExpr = regexp.MustCompile(`\A[a-z][a-zA-Z0-9_]*\z`)
+
+	// builtinTypeNames are the identifiers that the parser recognizes without a registry lookup
+	builtinTypeNames = map[string]bool{
+		`any`: true, `binary`: true, `bool`: true, `false`: true, `float`: true, `int`: true, `map`: true,
+		`never`: true, `nil`: true, `set`: true, `string`: true, `true`: true, `type`: true}
+)
+
+// RegisterTypeKind registers a new kind of type with the given name and allocates a new TypeIdentifier
+// for it. The newType function is called by the parser when the name is found in a type expression and
+// typeString is called when a type of the new kind is converted to a string. The tag is the YAML tag used
+// when serializing types of the kind. DefaultTypeTag is used when the tag is empty.
+//
+// RegisterTypeKind panics if the name is not a valid identifier or if the name or tag is already in use.
+func RegisterTypeKind(
+	name, tag string,
+	newType func(args dgo.Array) (dgo.Type, error),
+	typeString func(t dgo.Type) string) dgo.TypeKind {
+	if !kindNameExpr.MatchString(name) {
+		panic(fmt.Errorf(`'%s' is not a valid type kind name`, name))
+	}
+	kindLock.Lock()
+	defer kindLock.Unlock()
+	if nameInUse(name) {
+		panic(fmt.Errorf(`type kind '%s' is already registered`, name))
+	}
+	if tag != `` {
+		if _, ok := kindsByTag[tag]; ok || tag == DefaultTypeTag || tag == RegexpTag || tag == NativeTag {
+			panic(fmt.Errorf(`type tag '%s' is already registered`, tag))
+		}
+	}
+	k := &extensionKind{id: nextKindID, name: name, tag: tag, newType: newType, typeString: typeString}
+	nextKindID++
+	kindsByName[name] = k
+	kindsByID[k.id] = k
+	if tag != `` {
+		kindsByTag[tag] = k
+	}
+	return k
+}
+
+// nameInUse returns true if the given name is a built in type name or the name of a registered type kind
+// or predicate. The caller must hold the kindLock.
+func nameInUse(name string) bool {
+	if builtinTypeNames[name] {
+		return true
+	}
+	if _, ok := kindsByName[name]; ok {
+		return true
+	}
+	_, ok := predicates[name]
+	return ok
+}
+
+// TypeKindByName returns the registered TypeKind with the given name or nil if no such kind exists
+func TypeKindByName(name string) dgo.TypeKind {
+	if k := kindByName(name); k != nil {
+		return k
+	}
+	return nil
+}
+
+// TypeKindByIdentifier returns the registered TypeKind with the given identifier or nil if no such
+// kind exists
+func TypeKindByIdentifier(id dgo.TypeIdentifier) dgo.TypeKind {
+	if k := kindByID(id); k != nil {
+		return k
+	}
+	return nil
+}
+
+func kindByName(name string) *extensionKind {
+	kindLock.RLock()
+	defer kindLock.RUnlock()
+	return kindsByName[name]
+}
+
+func kindByID(id dgo.TypeIdentifier) *extensionKind {
+	if id < dgo.IdExtension {
+		return nil
+	}
+	kindLock.RLock()
+	defer kindLock.RUnlock()
+	return kindsByID[id]
+}
+
+func kindByTag(tag string) *extensionKind {
+	kindLock.RLock()
+	defer kindLock.RUnlock()
+	return kindsByTag[tag]
+}
+
+func (k *extensionKind) Identifier() dgo.TypeIdentifier {
+	return k.id
+}
+
+func (k *extensionKind) Name() string {
+	return k.name
+}
+
+func (k *extensionKind) New(args dgo.Array) (dgo.Type, error) {
+	return k.newType(args)
+}
+
+func (k *extensionKind) Tag() string {
+	if k.tag == `` {
+		return DefaultTypeTag
+	}
+	return k.tag
+}
+
+func (k *extensionKind) TypeString(t dgo.Type) string {
+	if k.typeString == nil {
+		return k.name
+	}
+	return k.typeString(t)
+}
@@ -0,0 +1,145 @@
+package internal_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/newtype"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+	"gopkg.in/yaml.v3"
+)
+
+// semverType is an extension type that matches version strings, optionally restricted to one major version
+type semverType struct {
+	major int64
+}
+
+var semverPattern = regexp.MustCompile(`\A(\d+)\.\d+\.\d+\z`)
+
+var semverKind = newtype.RegisterKind(`semver`, `!example.com,2019:semver`,
+	func(args dgo.Array) (dgo.Type, error) {
+		switch args.Len() {
+		case 0:
+			return &semverType{major: -1}, nil
+		case 1:
+			if major, ok := args.Get(0).(dgo.Integer); ok {
+				return &semverType{major: major.GoInt()}, nil
+			}
+		}
+		return nil, errors.New(`semver takes one optional major version argument`)
+	},
+	func(t dgo.Type) string {
+		if st := t.(*semverType); st.major >= 0 {
+			return fmt.Sprintf(`semver[%d]`, st.major)
+		}
+		return `semver`
+	})
+
+func (t *semverType) Assignable(other dgo.Type) bool {
+	if ot, ok := other.(*semverType); ok {
+		return t.major < 0 || t.major == ot.major
+	}
+	return false
+}
+
+func (t *semverType) Equals(other interface{}) bool {
+	ot, ok := other.(*semverType)
+	return ok && t.major == ot.major
+}
+
+func (t *semverType) HashCode() int {
+	return int(t.major)*31 + int(semverKind.Identifier())
+}
+
+func (t *semverType) Instance(value interface{}) bool {
+	s, ok := vf.Value(value).(dgo.String)
+	if !ok {
+		return false
+	}
+	m := semverPattern.FindStringSubmatch(s.GoString())
+	if m == nil {
+		return false
+	}
+	major, _ := strconv.ParseInt(m[1], 10, 64)
+	return t.major < 0 || t.major == major
+}
+
+func (t *semverType) String() string {
+	return newtype.KindByIdentifier(t.TypeIdentifier()).TypeString(t)
+}
+
+func (t *semverType) Type() dgo.Type {
+	return newtype.Meta(t)
+}
+
+func (t *semverType) TypeIdentifier() dgo.TypeIdentifier {
+	return semverKind.Identifier()
+}
+
+func TestRegisterKind(t *testing.T) {
+	require.True(t, semverKind.Identifier() >= dgo.IdExtension)
+	require.Equal(t, `semver`, semverKind.Name())
+	require.Equal(t, `!example.com,2019:semver`, semverKind.Tag())
+	require.Same(t, semverKind, newtype.KindByName(`semver`))
+	require.Same(t, semverKind, newtype.KindByIdentifier(semverKind.Identifier()))
+	require.Nil(t, newtype.KindByName(`nosuchkind`))
+	require.Nil(t, newtype.KindByIdentifier(dgo.IdString))
+
+	st := newtype.Parse(`semver`)
+	require.Equal(t, `semver`, st.String())
+	require.Instance(t, st, `1.2.3`)
+	require.NotInstance(t, st, `1.2`)
+
+	st1 := newtype.Parse(`semver[1]`)
+	require.Equal(t, `semver[1]`, st1.String())
+	require.Instance(t, st1, `1.2.3`)
+	require.NotInstance(t, st1, `2.0.0`)
+	require.Assignable(t, st, st1)
+	require.NotAssignable(t, st1, st)
+
+	at := newtype.Parse(`[]semver[1]|string[1,3]`)
+	require.Equal(t, `[]semver[1]|string[1,3]`, at.String())
+	require.Instance(t, at, vf.Strings(`1.0.0`, `1.4.2`))
+
+	require.Panic(t, func() { newtype.Parse(`semver["x"]`) }, `semver takes one optional major version argument`)
+}
+
+func TestRegisterKind_illegal(t *testing.T) {
+	f := func(dgo.Array) (dgo.Type, error) { return typ.String, nil }
+	require.Panic(t, func() { newtype.RegisterKind(`semver`, ``, f, nil) }, `type kind 'semver' is already registered`)
+	require.Panic(t, func() { newtype.RegisterKind(`string`, ``, f, nil) }, `type kind 'string' is already registered`)
+	require.Panic(t, func() { newtype.RegisterKind(`Semver`, ``, f, nil) }, `'Semver' is not a valid type kind name`)
+	require.Panic(t, func() { newtype.RegisterKind(`semver2`, `!example.com,2019:semver`, f, nil) },
+		`type tag '!example.com,2019:semver' is already registered`)
+}
+
+func TestRegisterKind_defaults(t *testing.T) {
+	k := newtype.RegisterKind(`plainkind`, ``, func(dgo.Array) (dgo.Type, error) { return typ.String, nil }, nil)
+	require.Equal(t, `plainkind`, k.TypeString(typ.String))
+	require.Equal(t, `!puppet.com,2019:dgo/type`, k.Tag())
+	require.True(t, k.Identifier() > semverKind.Identifier())
+}
+
+func TestRegisterKind_yaml(t *testing.T) {
+	b, err := yaml.Marshal(vf.Values(newtype.Parse(`semver[2]`), typ.String))
+	require.Nil(t, err)
+	require.Equal(t, "- !example.com,2019:semver semver[2]\n- !puppet.com,2019:dgo/type string\n", string(b))
+
+	a := vf.MutableValues(nil)
+	require.Nil(t, yaml.Unmarshal(b, a))
+	require.Equal(t, newtype.Parse(`semver[2]`), a.Get(0))
+	require.Equal(t, typ.String, a.Get(1))
+
+	err = yaml.Unmarshal([]byte(`[!example.com,2019:semver "semver[x]"]`), vf.MutableValues(nil))
+	require.NotNil(t, err)
+
+	require.Panic(t, func() {
+		newtype.RegisterKind(`other`, `!puppet.com,2019:dgo/regexp`, nil, nil)
+	}, `type tag '!puppet.com,2019:dgo/regexp' is already registered`)
+}
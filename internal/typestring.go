@@ -130,6 +130,21 @@ func buildTypeString(typ dgo.Type, prio int, sb *strings.Builder) {
 			sb.WriteByte(']')
 		}
 		buildTypeString(at.ElementType(), typePrio, sb)
+	case dgo.IdSet:
+		sb.WriteString(`set[any]`)
+	case dgo.IdSetElementSized:
+		st := typ.(dgo.SetType)
+		sb.WriteString(`set[`)
+		buildTypeString(st.ElementType(), commaPrio, sb)
+		if !st.Unbounded() {
+			sb.WriteByte(',')
+			writeSizeBoundaries(int64(st.Min()), int64(st.Max()), sb)
+		}
+		sb.WriteByte(']')
+	case dgo.IdSetExact:
+		sb.WriteString(`set{`)
+		JoinValueTypes(typ.(dgo.ExactType).Value().(dgo.Iterable), `,`, commaPrio, sb)
+		sb.WriteByte('}')
 	case dgo.IdMap:
 		sb.WriteString(`map[any]any`)
 	case dgo.IdMapExact:
@@ -173,6 +188,8 @@ func buildTypeString(typ dgo.Type, prio int, sb *strings.Builder) {
 		sb.WriteString(`nil`)
 	case dgo.IdError:
 		sb.WriteString(`error`)
+	case dgo.IdNever:
+		sb.WriteString(`never`)
 	case dgo.IdFloat:
 		sb.WriteString(`float`)
 	case dgo.IdFloatExact:
@@ -211,6 +228,8 @@ func buildTypeString(typ dgo.Type, prio int, sb *strings.Builder) {
 		nt := typ.(dgo.UnaryType)
 		sb.WriteByte('!')
 		buildTypeString(nt.Operand(), typePrio, sb)
+	case dgo.IdPredicate:
+		sb.WriteString(typ.(*predicateType).name)
 	case dgo.IdNative:
 		sb.WriteString(typ.(dgo.NativeType).GoType().String())
 	case dgo.IdMeta:
@@ -225,5 +244,9 @@ func buildTypeString(typ dgo.Type, prio int, sb *strings.Builder) {
 				sb.WriteByte(']')
 			}
 		}
+	default:
+		if k := kindByID(typ.TypeIdentifier()); k != nil {
+			sb.WriteString(k.TypeString(typ))
+		}
 	}
 }
@@ -1,20 +1,155 @@
 package internal
 
 import (
+	"fmt"
+	"reflect"
+	"regexp"
+
 	"github.com/lyraproj/dgo/dgo"
 	"gopkg.in/yaml.v3"
 )
 
+// yamlDecoder decodes yaml.Nodes into dgo.Values. A strict decoder returns an error when it encounters a
+// tag that it doesn't recognize whereas a lenient decoder lets the yaml package decode the tagged node.
+type yamlDecoder struct {
+	strict bool
+
+	// anchors are the values decoded from nodes that have an anchor
+	anchors map[*yaml.Node]dgo.Value
+
+	// incomplete are the anchored collections that are still being decoded
+	incomplete map[*yaml.Node]bool
+
+	// keys is greater than zero while a map key is decoded
+	keys int
+}
+
+// UnmarshalYAML decodes the YAML representation of the given bytes into a dgo.Value. Nodes with
+// unrecognized tags are decoded as if they were untagged.
 func UnmarshalYAML(b []byte) (dgo.Value, error) {
+	return (&yamlDecoder{}).unmarshal(b)
+}
+
+// UnmarshalYAMLStrict is like UnmarshalYAML but returns an error when a node has a tag that isn't a core
+// YAML tag, a dgo tag, or the tag of a registered type kind.
+func UnmarshalYAMLStrict(b []byte) (dgo.Value, error) {
+	return (&yamlDecoder{strict: true}).unmarshal(b)
+}
+
+func (d *yamlDecoder) unmarshal(b []byte) (dgo.Value, error) {
 	var n yaml.Node
 	err := yaml.Unmarshal(b, &n)
 	if err != nil {
 		return nil, err
 	}
-	return yamlDecodeValue(&n)
+	return d.decodeValue(&n)
+}
+
+// yamlEncoder encodes dgo.Values into yaml.Nodes. Collections that are referenced more than once are
+// given an anchor when they are first encountered and are then represented by an alias. This makes it
+// possible to encode collections that contain themselves.
+type yamlEncoder struct {
+	// refs is the number of times that each collection is referenced
+	refs map[dgo.Value]int
+
+	// nodes are the encoded collections that have an anchor
+	nodes map[dgo.Value]*yaml.Node
 }
 
 func yamlEncodeValue(v dgo.Value) (*yaml.Node, error) {
+	e := &yamlEncoder{refs: make(map[dgo.Value]int), nodes: make(map[dgo.Value]*yaml.Node)}
+	e.count(v)
+	return e.encode(v)
+}
+
+// shareable returns true if the given value is a collection that can be referenced from several places
+func shareable(v dgo.Value) bool {
+	switch v.(type) {
+	case dgo.Type:
+		return false
+	case dgo.Array, dgo.Map, *hashSet:
+		return reflect.ValueOf(v).Kind() == reflect.Ptr
+	}
+	return false
+}
+
+// count increments the reference count of the given value and, unless it has been seen before, counts
+// the references of the values that it contains.
+func (e *yamlEncoder) count(v dgo.Value) {
+	if !shareable(v) {
+		return
+	}
+	e.refs[v]++
+	if e.refs[v] > 1 {
+		return
+	}
+	switch v := v.(type) {
+	case *hashSet:
+		v.Each(e.count)
+	case dgo.Array:
+		v.Each(e.count)
+	case dgo.Map:
+		v.Each(func(me dgo.MapEntry) {
+			e.count(me.Key())
+			e.count(me.Value())
+		})
+	}
+}
+
+func (e *yamlEncoder) encode(v dgo.Value) (*yaml.Node, error) {
+	if !shareable(v) {
+		return e.encodeScalar(v)
+	}
+	if n, ok := e.nodes[v]; ok {
+		return &yaml.Node{Kind: yaml.AliasNode, Alias: n, Value: n.Anchor}, nil
+	}
+	n := &yaml.Node{}
+	if e.refs[v] > 1 {
+		// Register before the contained values are encoded so that back references find the node
+		n.Anchor = fmt.Sprintf(`a%d`, len(e.nodes)+1)
+		e.nodes[v] = n
+	}
+	var err error
+	switch v := v.(type) {
+	case *hashSet:
+		n.Kind, n.Tag = yaml.SequenceNode, `!!seq`
+		n.Content, err = e.encodeSlice(v.m.Keys().(*array).slice)
+	case dgo.Array:
+		n.Kind, n.Tag = yaml.SequenceNode, `!!seq`
+		n.Content, err = e.encodeSlice(sliceOf(v))
+	case dgo.Map:
+		n.Kind, n.Tag = yaml.MappingNode, `!!map`
+		n.Content = make([]*yaml.Node, 0, v.Len()*2)
+		v.Each(func(me dgo.MapEntry) {
+			if err == nil {
+				var kn, vn *yaml.Node
+				if kn, err = e.encode(me.Key()); err == nil {
+					if vn, err = e.encode(me.Value()); err == nil {
+						n.Content = append(n.Content, kn, vn)
+					}
+				}
+			}
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (e *yamlEncoder) encodeSlice(vs []dgo.Value) ([]*yaml.Node, error) {
+	ns := make([]*yaml.Node, len(vs))
+	for i, v := range vs {
+		n, err := e.encode(v)
+		if err != nil {
+			return nil, err
+		}
+		ns[i] = n
+	}
+	return ns, nil
+}
+
+func (e *yamlEncoder) encodeScalar(v dgo.Value) (*yaml.Node, error) {
 	switch v := v.(type) {
 	case yaml.Marshaler:
 		yv, err := v.MarshalYAML()
@@ -37,13 +172,15 @@ func yamlEncodeValue(v dgo.Value) (*yaml.Node, error) {
 			}
 			return yamlEncodeValue(value(yv))
 		}
+		// The Go value cannot be restored so a tagged placeholder is used
+		return &yaml.Node{Tag: NativeTag, Kind: yaml.ScalarNode, Value: v.String()}, nil
 	}
 	n := &yaml.Node{}
 	n.SetString(v.String())
 	return n, nil
 }
 
-func yamlDecodeScalar(n *yaml.Node) (dgo.Value, error) {
+func (d *yamlDecoder) decodeScalar(n *yaml.Node) (dgo.Value, error) {
 	var v dgo.Value
 	switch n.Tag {
 	case `!!null`:
@@ -78,7 +215,21 @@ func yamlDecodeScalar(n *yaml.Node) (dgo.Value, error) {
 	*/
 	case `!!binary`:
 		v = BinaryFromString(n.Value)
+	case DefaultTypeTag:
+		return yamlParseType(n)
+	case RegexpTag:
+		rx, err := regexp.Compile(n.Value)
+		if err != nil {
+			return nil, fmt.Errorf(`line %d: %s`, n.Line, err.Error())
+		}
+		v = (*Regexp)(rx)
+	case NativeTag:
+		// A native placeholder only retains the string representation of the value
+		v = makeHString(n.Value)
 	default:
+		if k := kindByTag(n.Tag); k != nil {
+			return yamlParseType(n)
+		}
 		var x interface{}
 		if err := n.Decode(&x); err != nil {
 			return nil, err
@@ -88,64 +239,190 @@ func yamlDecodeScalar(n *yaml.Node) (dgo.Value, error) {
 	return v, nil
 }
 
-func yamlDecodeValue(n *yaml.Node) (v dgo.Value, err error) {
+func (d *yamlDecoder) decodeValue(n *yaml.Node) (v dgo.Value, err error) {
+	if d.strict && !knownYAMLTag(n) {
+		return nil, fmt.Errorf(`line %d: unknown tag '%s'`, n.Line, n.Tag)
+	}
 	switch n.Kind {
 	case yaml.DocumentNode:
-		v, err = yamlDecodeValue(n.Content[0])
+		v, err = d.decodeValue(n.Content[0])
 	case yaml.SequenceNode:
-		v, err = yamlDecodeArray(n)
+		v, err = d.decodeArray(n)
 	case yaml.MappingNode:
-		v, err = yamlDecodeMap(n)
+		v, err = d.decodeMap(n)
+	case yaml.AliasNode:
+		if d.keys > 0 && d.incomplete[n.Alias] {
+			// The hash code of the collection would change when its decoding completes
+			return nil, fmt.Errorf(`line %d: an alias of a collection cannot be used in a key within that collection`, n.Line)
+		}
+		var ok bool
+		if v, ok = d.anchors[n.Alias]; !ok {
+			// The anchored node is outside of the node that is being decoded
+			v, err = d.decodeValue(n.Alias)
+		}
 	default:
-		v, err = yamlDecodeScalar(n)
+		if v, err = d.decodeScalar(n); err == nil {
+			d.anchor(n, v)
+		}
 	}
 	return
 }
 
-func yamlDecodeArray(n *yaml.Node) (a *array, err error) {
+// knownYAMLTag returns true if the tag of the given node is a core YAML tag for the kind of the node, a
+// dgo tag, or the tag of a registered type kind
+func knownYAMLTag(n *yaml.Node) bool {
+	switch n.Kind {
+	case yaml.SequenceNode:
+		return n.Tag == `!!seq`
+	case yaml.MappingNode:
+		return n.Tag == `!!map`
+	case yaml.ScalarNode:
+		switch n.Tag {
+		case `!!null`, `!!bool`, `!!int`, `!!float`, `!!str`, `!!binary`, `!!timestamp`,
+			DefaultTypeTag, RegexpTag, NativeTag:
+			return true
+		}
+		return kindByTag(n.Tag) != nil
+	}
+	return true
+}
+
+// anchor makes the given value available to aliases of the given node if the node has an anchor
+func (d *yamlDecoder) anchor(n *yaml.Node, v dgo.Value) {
+	if n.Anchor != `` {
+		if d.anchors == nil {
+			d.anchors = make(map[*yaml.Node]dgo.Value)
+		}
+		d.anchors[n] = v
+	}
+}
+
+// begin makes the given collection available to aliases of the given node if the node has an anchor and
+// marks it as incomplete until end is called
+func (d *yamlDecoder) begin(n *yaml.Node, v dgo.Value) {
+	if n.Anchor != `` {
+		d.anchor(n, v)
+		if d.incomplete == nil {
+			d.incomplete = make(map[*yaml.Node]bool)
+		}
+		d.incomplete[n] = true
+	}
+}
+
+func (d *yamlDecoder) end(n *yaml.Node) {
+	delete(d.incomplete, n)
+}
+
+func (d *yamlDecoder) decodeArray(n *yaml.Node) (*array, error) {
 	ms := n.Content
+	a := &array{frozen: true}
+	d.begin(n, a)
+	defer d.end(n)
 	es := make([]dgo.Value, len(ms))
 	for i, me := range ms {
-		es[i], err = yamlDecodeValue(me)
+		e, err := d.decodeValue(me)
 		if err != nil {
-			return
+			return nil, err
 		}
+		es[i] = e
 	}
-	a = &array{slice: es, frozen: true}
-	return
+	a.slice = es
+	return a, nil
 }
 
-func yamlDecodeMap(n *yaml.Node) (*hashMap, error) {
+// decodeMap decodes a mapping node. The entries of maps that are merged using the "<<" key are added
+// unless the key is present in the mapping node. Maps merged earlier take precedence over maps merged
+// later.
+func (d *yamlDecoder) decodeMap(n *yaml.Node) (*hashMap, error) {
 	ms := n.Content
 	top := len(ms)
-	tbl := make([]*hashNode, tableSizeFor(top/2))
-	hl := len(tbl) - 1
-	m := &hashMap{table: tbl, len: top / 2, frozen: true}
+	m := &hashMap{table: make([]*hashNode, tableSizeFor(top/2))}
+	d.begin(n, m)
+	defer d.end(n)
 
-	for i := 0; i < top; {
-		k, err := yamlDecodeValue(ms[i])
-		if err != nil {
-			return nil, err
+	// Explicit keys must be known before merging
+	explicit := make([]dgo.Value, 0, top/2)
+	for i := 0; i < top; i += 2 {
+		if ms[i].Tag == `!!merge` {
+			explicit = append(explicit, nil)
+			continue
 		}
-		i++
-		v, err := yamlDecodeValue(ms[i])
+		d.keys++
+		k, err := d.decodeValue(ms[i])
+		d.keys--
 		if err != nil {
 			return nil, err
 		}
-		i++
-		hk := hl & hash(k.HashCode())
-		nd := &hashNode{key: k, value: v, hashNext: tbl[hk], prev: m.last}
-		if m.first == nil {
-			m.first = nd
-		} else {
-			m.last.next = nd
+		explicit = append(explicit, k)
+	}
+
+	for i, k := range explicit {
+		vn := ms[i*2+1]
+		if k != nil {
+			v, err := d.decodeValue(vn)
+			if err != nil {
+				return nil, err
+			}
+			m.Put(k, v)
+			continue
+		}
+		if err := d.merge(m, vn, explicit); err != nil {
+			return nil, err
 		}
-		m.last = nd
-		tbl[hk] = nd
 	}
+	m.frozen = true
 	return m, nil
 }
 
+// merge adds the entries of the map, or sequence of maps, that the given node represents to the given
+// map unless the key is already present in the map or found among the given explicit keys.
+func (d *yamlDecoder) merge(m *hashMap, n *yaml.Node, explicit []dgo.Value) error {
+	v, err := d.decodeValue(n)
+	if err != nil {
+		return err
+	}
+	sources := []dgo.Value{v}
+	if a, ok := v.(*array); ok {
+		sources = a.slice
+	}
+	for _, s := range sources {
+		sm, ok := s.(dgo.Map)
+		if !ok {
+			return fmt.Errorf(`line %d: the value of a merge key must be a map or a sequence of maps`, n.Line)
+		}
+		sm.Each(func(e dgo.MapEntry) {
+			k := e.Key()
+			if _, ok := m.Get(k); ok || containsValue(explicit, k) {
+				return
+			}
+			m.Put(k, e.Value())
+		})
+	}
+	return nil
+}
+
+func containsValue(vs []dgo.Value, v dgo.Value) bool {
+	for _, e := range vs {
+		if e != nil && v.Equals(e) {
+			return true
+		}
+	}
+	return false
+}
+
+// yamlParseType parses the value of the given node into a type
+func yamlParseType(n *yaml.Node) (dgo.Type, error) {
+	t, err := parseType(n.Value)
+	if err != nil {
+		err = fmt.Errorf(`line %d: %s`, n.Line, err.Error())
+	}
+	return t, err
+}
+
 func yamlMarshalType(t dgo.Type) (*yaml.Node, error) {
-	return &yaml.Node{Tag: `!puppet.com,2019:dgo/type`, Kind: yaml.ScalarNode, Value: TypeString(t)}, nil
+	tag := DefaultTypeTag
+	if k := kindByID(t.TypeIdentifier()); k != nil {
+		tag = k.Tag()
+	}
+	return &yaml.Node{Tag: tag, Kind: yaml.ScalarNode, Value: TypeString(t)}, nil
 }
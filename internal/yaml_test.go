@@ -2,8 +2,11 @@ package internal_test
 
 import (
 	"errors"
+	"regexp"
 	"testing"
 
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/newtype"
 	"github.com/lyraproj/dgo/typ"
 	"github.com/lyraproj/dgo/vf"
 
@@ -73,3 +76,212 @@ func TestMap_UnmarshalYAML_Array_frozen(t *testing.T) {
 	a := vf.Values()
 	require.Panic(t, func() { _ = yaml.Unmarshal([]byte(`["hello",true,1,3.14,null]`), a) }, `UnmarshalYAML .* frozen`)
 }
+
+func TestUnmarshalYAML_tags(t *testing.T) {
+	v, err := vf.UnmarshalYAML([]byte(`
+type: !puppet.com,2019:dgo/type 'map[string]1..3'
+regexp: !puppet.com,2019:dgo/regexp '^a+$'
+native: !puppet.com,2019:dgo/native '{1}'
+binary: !!binary AQID
+other: !example.com,2019:unknown 12
+`))
+	require.Nil(t, err)
+	require.Equal(t, vf.Map(map[string]interface{}{
+		`type`:   newtype.Parse(`map[string]1..3`),
+		`regexp`: regexp.MustCompile(`^a+$`),
+		`native`: `{1}`,
+		`binary`: []byte{1, 2, 3},
+		`other`:  `12`,
+	}), v)
+
+	_, err = vf.UnmarshalYAML([]byte(`!puppet.com,2019:dgo/type 'map[string'`))
+	require.NotNil(t, err)
+
+	_, err = vf.UnmarshalYAML([]byte(`!puppet.com,2019:dgo/regexp '('`))
+	require.NotNil(t, err)
+}
+
+func TestUnmarshalYAMLStrict(t *testing.T) {
+	v, err := vf.UnmarshalYAMLStrict([]byte(`[!puppet.com,2019:dgo/type int, !!str 12, 3]`))
+	require.Nil(t, err)
+	require.Equal(t, vf.Values(typ.Integer, `12`, 3), v)
+
+	_, err = vf.UnmarshalYAMLStrict([]byte("a: 1\nb: !example.com,2019:unknown 12\n"))
+	require.Equal(t, `line 2: unknown tag '!example.com,2019:unknown'`, err.Error())
+
+	unknown := []string{`!example.com,2019:x [1]`, `!example.com,2019:x {a: 1}`, `!!python/object 12`, `!!foo bar`}
+	for _, s := range unknown {
+		_, err = vf.UnmarshalYAMLStrict([]byte(s))
+		require.NotNil(t, err)
+		_, err = vf.UnmarshalYAML([]byte(s))
+		require.Nil(t, err)
+	}
+
+	v, err = vf.UnmarshalYAMLStrict([]byte("a: &x {b: !!seq [1, null, true, 2.5]}\n<<: {c: *x}\nd: !!map {}\n"))
+	require.Nil(t, err)
+	require.Equal(t, `{"a":{"b":[1,null,true,2.5]},"c":{"b":[1,null,true,2.5]},"d":{}}`, v.String())
+}
+
+func TestYAML_roundTrip(t *testing.T) {
+	v := vf.Values(
+		typ.String,
+		newtype.Parse(`{"a":int,"b"?:[]string}`),
+		vf.Set(1, 2).Type(),
+		regexp.MustCompile(`^a+/$`),
+		[]byte{1, 2, 3})
+	b, err := yaml.Marshal(v)
+	require.Nil(t, err)
+	require.Equal(t, `- !puppet.com,2019:dgo/type string
+- !puppet.com,2019:dgo/type '{"a":int,"b"?:[]string}'
+- !puppet.com,2019:dgo/type set{1,2}
+- !puppet.com,2019:dgo/regexp ^a+/$
+- !!binary AQID
+`, string(b))
+	r, err := vf.UnmarshalYAMLStrict(b)
+	require.Nil(t, err)
+	require.Equal(t, v, r)
+}
+
+func TestYAML_nativePlaceholder(t *testing.T) {
+	b, err := yaml.Marshal(vf.MutableValues(nil, struct{ A int }{1}))
+	require.Nil(t, err)
+	require.Equal(t, "- !puppet.com,2019:dgo/native <struct { A int } Value>\n", string(b))
+}
+
+func TestUnmarshalYAML_alias(t *testing.T) {
+	v, err := vf.UnmarshalYAML([]byte(`
+base: &base
+  a: 1
+  b: [1, 2]
+list: &list [x, y]
+copies: [*base, *list]
+`))
+	require.Nil(t, err)
+	m := v.(dgo.Map)
+	base, _ := m.Get(`base`)
+	list, _ := m.Get(`list`)
+	copies, _ := m.Get(`copies`)
+	require.True(t, vf.SameInstance(base, copies.(dgo.Array).Get(0)))
+	require.True(t, vf.SameInstance(list, copies.(dgo.Array).Get(1)))
+	require.True(t, copies.(dgo.Array).Frozen())
+}
+
+func TestUnmarshalYAML_cyclic(t *testing.T) {
+	v, err := vf.UnmarshalYAML([]byte(`&a [1, *a]`))
+	require.Nil(t, err)
+	a := v.(dgo.Array)
+	require.True(t, vf.SameInstance(a, a.Get(1)))
+
+	v, err = vf.UnmarshalYAML([]byte(`&m {self: *m}`))
+	require.Nil(t, err)
+	m := v.(dgo.Map)
+	self, _ := m.Get(`self`)
+	require.True(t, vf.SameInstance(m, self))
+	require.True(t, m.Frozen())
+
+	_, err = vf.UnmarshalYAML([]byte(`&a [ {*a: 1} ]`))
+	require.Equal(t, `line 1: an alias of a collection cannot be used in a key within that collection`, err.Error())
+
+	_, err = vf.UnmarshalYAML([]byte(`&m {a: {? [*m] : 1}}`))
+	require.NotNil(t, err)
+
+	v, err = vf.UnmarshalYAML([]byte(`{a: &a [1], b: {*a: 2}}`))
+	require.Nil(t, err)
+	b, _ := v.(dgo.Map).Get(`b`)
+	x, _ := b.(dgo.Map).Get(vf.Values(1))
+	require.Equal(t, 2, x)
+}
+
+func TestUnmarshalYAML_merge(t *testing.T) {
+	v, err := vf.UnmarshalYAML([]byte(`
+defaults: &defaults
+  adapter: postgres
+  host: localhost
+extra: &extra
+  host: remote
+  pool: 5
+dev:
+  database: dev
+  <<: *defaults
+test:
+  <<: [*extra, *defaults]
+  database: test
+override:
+  host: other
+  <<: *defaults
+`))
+	require.Nil(t, err)
+	m := v.(dgo.Map)
+	dev, _ := m.Get(`dev`)
+	require.Equal(t, vf.Map(map[string]string{`database`: `dev`, `adapter`: `postgres`, `host`: `localhost`}), dev)
+	require.Equal(t, vf.Strings(`database`, `adapter`, `host`), dev.(dgo.Map).Keys())
+	test, _ := m.Get(`test`)
+	require.Equal(t, vf.Map(map[string]interface{}{`database`: `test`, `adapter`: `postgres`, `host`: `remote`, `pool`: 5}), test)
+	override, _ := m.Get(`override`)
+	require.Equal(t, vf.Map(map[string]string{`adapter`: `postgres`, `host`: `other`}), override)
+
+	_, err = vf.UnmarshalYAML([]byte(`{<<: 1}`))
+	require.Equal(t, `line 1: the value of a merge key must be a map or a sequence of maps`, err.Error())
+
+	_, err = vf.UnmarshalYAML([]byte(`{<<: [{a: 1}, 2]}`))
+	require.NotNil(t, err)
+
+	_, err = vf.UnmarshalYAML([]byte(`{<<: !puppet.com,2019:dgo/type 'x[', a: 1}`))
+	require.NotNil(t, err)
+}
+
+func TestMarshalYAML_anchors(t *testing.T) {
+	s := vf.Values(1, 2)
+	m := vf.Map(map[string]interface{}{`a`: s, `b`: s, `c`: vf.Values(s)})
+	b, err := yaml.Marshal(m)
+	require.Nil(t, err)
+	require.Equal(t, `a: &a1
+  - 1
+  - 2
+b: *a1
+c:
+  - *a1
+`, string(b))
+
+	v, err := vf.UnmarshalYAML(b)
+	require.Nil(t, err)
+	require.Equal(t, m, v)
+	va, _ := v.(dgo.Map).Get(`a`)
+	vb, _ := v.(dgo.Map).Get(`b`)
+	require.True(t, vf.SameInstance(va, vb))
+}
+
+func TestMarshalYAML_cyclic(t *testing.T) {
+	a := vf.MutableValues(nil, 1)
+	a.Add(a)
+	m := vf.MutableMap(0, nil)
+	m.Put(`list`, a)
+	m.Put(`self`, m)
+	b, err := yaml.Marshal(m)
+	require.Nil(t, err)
+	require.Equal(t, `&a1
+list: &a2
+  - 1
+  - *a2
+self: *a1
+`, string(b))
+
+	v, err := vf.UnmarshalYAML(b)
+	require.Nil(t, err)
+	self, _ := v.(dgo.Map).Get(`self`)
+	require.True(t, vf.SameInstance(v, self))
+	l, _ := v.(dgo.Map).Get(`list`)
+	require.True(t, vf.SameInstance(l, l.(dgo.Array).Get(1)))
+}
+
+func TestMarshalYAML_sharedSet(t *testing.T) {
+	s := vf.Set(`a`)
+	b, err := yaml.Marshal(vf.Values(s, s, vf.Values(1), vf.Values(1)))
+	require.Nil(t, err)
+	require.Equal(t, `- &a1
+  - a
+- *a1
+- - 1
+- - 1
+`, string(b))
+}
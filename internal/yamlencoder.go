@@ -0,0 +1,231 @@
+package internal
+
+import (
+	"errors"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/lyraproj/dgo/dgo"
+	"gopkg.in/yaml.v3"
+)
+
+// The positions in which a YAML node can be written
+const (
+	yamlTop       = iota // at the start of the document
+	yamlAfterKey         // after an implicit key and its colon
+	yamlAfterMark        // after a "- ", "? ", or ": " indicator
+)
+
+// yamlPlain matches the strings that can be written as plain scalars without being mistaken for something
+// else than a string
+var yamlPlain = regexp.MustCompile(`\A[A-Za-z_][A-Za-z0-9_./-]*( [A-Za-z0-9_./-]+)*\z`)
+
+type yamlStreamEncoder struct {
+	streamEncoder
+	indent int
+}
+
+// EncodeYAML writes the YAML representation of the given value to the given writer. Unlike MarshalYAML,
+// the YAML is written while the value is traversed so no node tree for the complete document is built.
+//
+// The output uses block style and the same tags as MarshalYAML. A collection that is referenced more than
+// once is written once for each reference, and an error is returned for collections that contain themselves.
+func EncodeYAML(w io.Writer, v dgo.Value, opts dgo.EncoderOptions) (err error) {
+	defer encoderRecover(&err)
+	e := &yamlStreamEncoder{streamEncoder: newStreamEncoder(w, opts), indent: 2}
+	if opts.Indent != `` {
+		if strings.Trim(opts.Indent, ` `) != `` {
+			return errors.New(`a YAML indent must consist of spaces only`)
+		}
+		e.indent = len(opts.Indent)
+	}
+	e.node(v, 0, yamlTop)
+	e.flush()
+	return nil
+}
+
+func (e *yamlStreamEncoder) spaces(n int) {
+	for i := 0; i < n; i++ {
+		e.writeByte(' ')
+	}
+}
+
+// node writes the given value. The col is the column of the entries of the map that contains the value when
+// the position is yamlAfterKey and the column where the value starts when the position is yamlAfterMark.
+func (e *yamlStreamEncoder) node(v dgo.Value, col, pos int) {
+	var es []dgo.Value
+	switch v := v.(type) {
+	case dgo.Type:
+	case *hashSet:
+		es = sliceOf(v.m.Keys())
+	case dgo.Array:
+		es = sliceOf(v)
+	case dgo.Map:
+		if v.Len() > 0 {
+			e.enter(v)
+			e.mapping(e.entries(v), e.blockColumn(col, pos), pos)
+			e.leave(v)
+			return
+		}
+	case dgo.Native:
+		if yv, ok := e.native(v); ok {
+			e.node(yv, col, pos)
+			return
+		}
+	}
+	if len(es) > 0 {
+		e.enter(v)
+		e.sequence(es, e.blockColumn(col, pos), pos)
+		e.leave(v)
+		return
+	}
+	if pos == yamlAfterKey {
+		e.writeByte(' ')
+	}
+	e.scalar(v)
+	e.writeByte('\n')
+}
+
+// blockColumn returns the column of the entries of a block collection written in the given position
+func (e *yamlStreamEncoder) blockColumn(col, pos int) int {
+	switch pos {
+	case yamlTop:
+		return 0
+	case yamlAfterKey:
+		e.writeByte('\n')
+		return col + e.indent
+	default:
+		return col
+	}
+}
+
+func (e *yamlStreamEncoder) sequence(es []dgo.Value, col, pos int) {
+	for i, ev := range es {
+		if i > 0 || pos != yamlAfterMark {
+			e.spaces(col)
+		}
+		e.writeString(`- `)
+		e.node(ev, col+2, yamlAfterMark)
+	}
+}
+
+func (e *yamlStreamEncoder) mapping(es []dgo.MapEntry, col, pos int) {
+	for i, me := range es {
+		if i > 0 || pos != yamlAfterMark {
+			e.spaces(col)
+		}
+		k := me.Key()
+		if e.isScalar(k) {
+			e.scalar(k)
+			e.writeByte(':')
+			e.node(me.Value(), col, yamlAfterKey)
+			continue
+		}
+		e.writeString(`? `)
+		e.node(k, col+2, yamlAfterMark)
+		e.spaces(col)
+		e.writeString(`: `)
+		e.node(me.Value(), col+2, yamlAfterMark)
+	}
+}
+
+// isScalar returns true if the given value is written as a scalar or as an empty flow collection
+func (e *yamlStreamEncoder) isScalar(v dgo.Value) bool {
+	switch v := v.(type) {
+	case dgo.Type:
+		return true
+	case *hashSet:
+		return v.m.Len() == 0
+	case dgo.Array:
+		return v.Len() == 0
+	case dgo.Map:
+		return v.Len() == 0
+	case dgo.Native:
+		if yv, ok := e.native(v); ok {
+			return e.isScalar(yv)
+		}
+	}
+	return true
+}
+
+// native returns the value that represents the given native value in YAML and true, or nil and false if the
+// native value has no YAML representation
+func (e *yamlStreamEncoder) native(v dgo.Native) (dgo.Value, bool) {
+	ym, ok := v.GoValue().(yaml.Marshaler)
+	if !ok {
+		return nil, false
+	}
+	yv, err := ym.MarshalYAML()
+	if err != nil {
+		panic(encoderFailure{err})
+	}
+	if n, ok := yv.(*yaml.Node); ok {
+		if yv, err = (&yamlDecoder{}).decodeValue(n); err != nil {
+			panic(encoderFailure{err})
+		}
+	}
+	return value(yv), true
+}
+
+func (e *yamlStreamEncoder) scalar(v dgo.Value) {
+	switch v := v.(type) {
+	case nilValue:
+		e.writeString(`null`)
+	case Boolean:
+		e.writeString(strconv.FormatBool(bool(v)))
+	case Integer:
+		e.writeString(strconv.FormatInt(int64(v), 10))
+	case Float:
+		f := float64(v)
+		switch {
+		case math.IsNaN(f):
+			e.writeString(`.nan`)
+		case math.IsInf(f, 1):
+			e.writeString(`.inf`)
+		case math.IsInf(f, -1):
+			e.writeString(`-.inf`)
+		default:
+			e.writeString(e.float(f))
+		}
+	case *hstring:
+		e.string(v.s)
+	case dgo.Type:
+		n, _ := yamlMarshalType(v)
+		e.tagged(n.Tag, n.Value)
+	case *binary:
+		e.tagged(`!!binary`, v.String())
+	case *Regexp:
+		e.tagged(RegexpTag, v.String())
+	case *hashSet, dgo.Array:
+		e.writeString(`[]`)
+	case dgo.Map:
+		e.writeString(`{}`)
+	case dgo.Native:
+		e.tagged(NativeTag, v.String())
+	case error:
+		e.string(v.Error())
+	default:
+		e.string(v.String())
+	}
+}
+
+func (e *yamlStreamEncoder) tagged(tag, s string) {
+	e.writeString(tag)
+	e.writeByte(' ')
+	e.quote(s, false)
+}
+
+func (e *yamlStreamEncoder) string(s string) {
+	if yamlPlain.MatchString(s) {
+		switch strings.ToLower(s) {
+		case `y`, `n`, `yes`, `no`, `on`, `off`, `true`, `false`, `null`:
+		default:
+			e.writeString(s)
+			return
+		}
+	}
+	e.quote(s, false)
+}
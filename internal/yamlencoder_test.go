@@ -0,0 +1,157 @@
+package internal_test
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/lyraproj/dgo/dgo"
+	require "github.com/lyraproj/dgo/dgo_test"
+	"github.com/lyraproj/dgo/typ"
+	"github.com/lyraproj/dgo/vf"
+)
+
+func encodeYAML(t *testing.T, v interface{}, opts dgo.EncoderOptions) string {
+	t.Helper()
+	b := bytes.Buffer{}
+	require.Nil(t, vf.EncodeYAML(&b, v, opts))
+	return b.String()
+}
+
+func TestEncodeYAML(t *testing.T) {
+	m := vf.MutableMap(7, nil)
+	m.Put(`s`, vf.Values(`hello`, `yes`, `a b`, `x:y`, ``, `1`, "multi\nline", true, 1, 2.5, nil))
+	m.Put(`nested`, vf.Values(vf.Values(1, 2), vf.Map(map[string]interface{}{`a`: 1}), vf.Values(), vf.Map(map[string]int{})))
+	m.Put(`m`, vf.Map(map[string]interface{}{`a`: vf.Map(map[string]int{`b`: 1}), `c`: vf.Values(1)}))
+	s := encodeYAML(t, m, dgo.EncoderOptions{SortKeys: true})
+	require.Equal(t, `m:
+  a:
+    b: 1
+  c:
+    - 1
+nested:
+  - - 1
+    - 2
+  - a: 1
+  - []
+  - {}
+s:
+  - hello
+  - "yes"
+  - a b
+  - "x:y"
+  - ""
+  - "1"
+  - "multi\nline"
+  - true
+  - 1
+  - 2.5
+  - null
+`, s)
+
+	v, err := vf.UnmarshalYAML([]byte(s))
+	require.Nil(t, err)
+	require.Equal(t, m, v)
+}
+
+func TestEncodeYAML_sortCollectionKeys(t *testing.T) {
+	m := vf.MutableMap(4, nil)
+	m.Put(vf.Map(map[string]int{`b`: 1}), 1)
+	m.Put(vf.Values(2), 2)
+	m.Put(vf.Map(map[string]int{`a`: 1}), 3)
+	m.Put(vf.Values(1), 4)
+	require.Equal(t, `? - 1
+: 4
+? - 2
+: 2
+? a: 1
+: 3
+? b: 1
+: 1
+`, encodeYAML(t, m, dgo.EncoderOptions{SortKeys: true}))
+}
+
+func TestEncodeYAML_scalar(t *testing.T) {
+	require.Equal(t, "hello\n", encodeYAML(t, `hello`, dgo.EncoderOptions{}))
+	require.Equal(t, "[]\n", encodeYAML(t, vf.Values(), dgo.EncoderOptions{}))
+}
+
+func TestEncodeYAML_indent(t *testing.T) {
+	v := vf.Map(map[string]interface{}{`a`: vf.Map(map[string]interface{}{`b`: vf.Values(1, vf.Map(map[string]int{`c`: 2}))})})
+	require.Equal(t, `a:
+    b:
+        - 1
+        - c: 2
+`, encodeYAML(t, v, dgo.EncoderOptions{Indent: `    `}))
+
+	err := vf.EncodeYAML(&bytes.Buffer{}, v, dgo.EncoderOptions{Indent: "\t"})
+	require.Equal(t, `a YAML indent must consist of spaces only`, err.Error())
+}
+
+func TestEncodeYAML_complexKeys(t *testing.T) {
+	m := vf.MutableMap(2, nil)
+	m.Put(vf.Values(1, 2), `a`)
+	m.Put(vf.Map(map[string]int{`x`: 1}), vf.Values(`b`, `c`))
+	s := encodeYAML(t, m, dgo.EncoderOptions{})
+	require.Equal(t, `? - 1
+  - 2
+: a
+? x: 1
+: - b
+  - c
+`, s)
+
+	v, err := vf.UnmarshalYAML([]byte(s))
+	require.Nil(t, err)
+	require.Equal(t, m, v)
+}
+
+func TestEncodeYAML_tagged(t *testing.T) {
+	m := vf.MutableMap(7, nil)
+	m.Put(`binary`, []byte{1, 2, 3})
+	m.Put(`regexp`, regexp.MustCompile(`a+`))
+	m.Put(`type`, typ.Integer)
+	m.Put(`set`, vf.Set(`x`))
+	m.Put(`floats`, vf.Values(math.Inf(1), math.Inf(-1), 1e21))
+	s := encodeYAML(t, m, dgo.EncoderOptions{})
+	require.Equal(t, `binary: !!binary "AQID"
+regexp: !puppet.com,2019:dgo/regexp "a+"
+type: !puppet.com,2019:dgo/type "int"
+set:
+  - x
+floats:
+  - .inf
+  - -.inf
+  - 1E+21
+`, s)
+
+	v, err := vf.UnmarshalYAML([]byte(s))
+	require.Nil(t, err)
+	vm := v.(dgo.Map)
+	for _, k := range []string{`binary`, `regexp`, `type`, `floats`} {
+		ev, _ := vm.Get(k)
+		mv, _ := m.Get(k)
+		require.Equal(t, mv, ev)
+	}
+}
+
+func TestEncodeYAML_native(t *testing.T) {
+	tm := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	require.Equal(t, "a: !puppet.com,2019:dgo/native \"2020-01-02 03:04:05 +0000 UTC\"\n",
+		encodeYAML(t, vf.Map(map[string]interface{}{`a`: tm}), dgo.EncoderOptions{}))
+}
+
+func TestEncodeYAML_errors(t *testing.T) {
+	a := vf.MutableValues(nil, 1)
+	a.Add(a)
+	err := vf.EncodeYAML(&bytes.Buffer{}, a, dgo.EncoderOptions{})
+	require.Equal(t, `unable to encode a collection that contains itself`, err.Error())
+
+	err = vf.EncodeYAML(failingWriter{}, vf.Values(1), dgo.EncoderOptions{})
+	require.Equal(t, `write failed`, err.Error())
+
+	require.Equal(t, "- oops\n", encodeYAML(t, vf.Values(errors.New(`oops`)), dgo.EncoderOptions{}))
+}
@@ -0,0 +1,24 @@
+package newtype
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// DefaultInferWidth is the maximum number of operands that Infer allows in an inferred union before it
+// collapses it into the any type
+const DefaultInferWidth = internal.DefaultInferWidth
+
+// Infer returns a type that generalizes the given values. Exact types are widened to their defaults,
+// arrays yield an array type with an element type inferred from all elements, and maps with string keys
+// yield a Struct type where entries that are absent in some of the maps are optional. Unions with more
+// than DefaultInferWidth operands are collapsed into the any type.
+func Infer(values ...dgo.Value) dgo.Type {
+	return internal.Infer(internal.DefaultInferWidth, values)
+}
+
+// InferWithWidth is like Infer but collapses unions with more than the given number of operands into
+// the any type. A width less than one disables the collapse.
+func InferWithWidth(width int, values ...dgo.Value) dgo.Type {
+	return internal.Infer(width, values)
+}
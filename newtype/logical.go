@@ -24,3 +24,32 @@ func OneOf(types ...dgo.Type) dgo.Type {
 func Not(t dgo.Type) dgo.Type {
 	return internal.NotType(t)
 }
+
+// Intersect returns a type that represents the values that are instances of all of the given types. Unlike
+// AllOf, the result is computed where possible so that e.g. the intersection of 0..10 and 5..20 becomes
+// 5..10, and the intersection of two Struct types merges their entries. The Never type is returned when
+// the given types have no instances in common.
+func Intersect(types ...dgo.Type) dgo.Type {
+	return internal.Intersect(types)
+}
+
+// Union returns a type that represents the values that are instances of at least one of the given types.
+// Unlike AnyOf, operands that are assignable to other operands are dropped and overlapping ranges are
+// merged.
+func Union(types ...dgo.Type) dgo.Type {
+	return internal.Union(types)
+}
+
+// Subtract returns a type that represents the values that are instances of the type a but not of the type
+// b. The Never type is returned when all instances of a are also instances of b.
+func Subtract(a, b dgo.Type) dgo.Type {
+	return internal.Subtract(a, b)
+}
+
+// Normalize returns a simplified but equivalent version of the given type. Nested AllOf and AnyOf types
+// are flattened, operands that are assignable to sibling operands are dropped, double negations are
+// eliminated, adjacent ranges are folded, and operands are sorted in a canonical order so that two
+// normalized types that represent the same values are equal.
+func Normalize(t dgo.Type) dgo.Type {
+	return internal.Normalize(t)
+}
@@ -0,0 +1,34 @@
+package newtype
+
+import (
+	"math/rand"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// RegisterPredicate registers a type with the given name whose instances are the values for which the
+// given function returns true. The name can then be used in type expressions such as "string&even". A
+// predicate type is only assignable from itself.
+//
+// The predicates uuid, email, uri, ipv4, ipv6, hostname, date (YYYY-MM-DD), and semantic_version are built
+// in. They match strings only.
+//
+// RegisterPredicate is intended to be called from an init function. It panics if the name is not a valid
+// identifier or if it is already in use.
+func RegisterPredicate(name string, predicate func(dgo.Value) bool) dgo.Type {
+	return internal.RegisterPredicate(name, predicate)
+}
+
+// RegisterGeneratedPredicate is like RegisterPredicate but also registers a function that vf.Generate uses
+// to produce random instances of the type. Every value that the function returns must satisfy the
+// predicate. Instances of a predicate without a generator are found using rejection sampling.
+func RegisterGeneratedPredicate(
+	name string, predicate func(dgo.Value) bool, generate func(*rand.Rand) dgo.Value) dgo.Type {
+	return internal.RegisterGeneratedPredicate(name, predicate, generate)
+}
+
+// Predicate returns the predicate type registered under the given name or nil if no such type exists
+func Predicate(name string) dgo.Type {
+	return internal.PredicateType(name)
+}
@@ -0,0 +1,12 @@
+package newtype
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// Set returns a type that represents a Set value. The arguments follow the same pattern as the
+// arguments for Array, i.e. an optional element type followed by an optional min and max size.
+func Set(args ...interface{}) dgo.SetType {
+	return internal.SetType(args...)
+}
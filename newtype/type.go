@@ -12,6 +12,12 @@ func FromReflected(vt reflect.Type) dgo.Type {
 	return internal.TypeFromReflected(vt)
 }
 
+// Meta returns the type of the given type, i.e. a type whose only instance is the given type. It is
+// intended to be used by the Type method of types that are implemented outside of this module.
+func Meta(t dgo.Type) dgo.Type {
+	return internal.MetaType(t)
+}
+
 // Parse parses the given content into a dgo.Type.
 func Parse(content string) dgo.Type {
 	return internal.Parse(content)
@@ -0,0 +1,31 @@
+package newtype
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// RegisterKind registers a new kind of type with the given name and allocates a TypeIdentifier for it. The
+// name can then be used in type expressions, optionally followed by arguments in square brackets. The
+// newType function creates a type from such arguments and typeString produces the string representation
+// of a type of the new kind. When typeString is nil, the name is used. The tag is the YAML tag used when
+// serializing types of the new kind. A default tag is used when it is empty.
+//
+// RegisterKind is intended to be called from an init function. It panics if the name is not a valid
+// identifier or if the name or the tag is already in use.
+func RegisterKind(
+	name, tag string,
+	newType func(args dgo.Array) (dgo.Type, error),
+	typeString func(t dgo.Type) string) dgo.TypeKind {
+	return internal.RegisterTypeKind(name, tag, newType, typeString)
+}
+
+// KindByName returns the registered TypeKind with the given name or nil if no such kind exists
+func KindByName(name string) dgo.TypeKind {
+	return internal.TypeKindByName(name)
+}
+
+// KindByIdentifier returns the registered TypeKind with the given identifier or nil if no such kind exists
+func KindByIdentifier(id dgo.TypeIdentifier) dgo.TypeKind {
+	return internal.TypeKindByIdentifier(id)
+}
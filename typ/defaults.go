@@ -24,12 +24,18 @@ var Tuple = internal.DefaultTupleType
 // is assignable to it.
 var Not = internal.DefaultNotType
 
+// Set represents all set values
+const Set = internal.DefaultSetType
+
 // Map is the unconstrained type. It represents all Map values
 const Map = internal.DefaultMapType
 
 // Any is a type that represents all values
 const Any = internal.DefaultAnyType
 
+// Never is a type that has no instances. It is assignable to all other types
+const Never = internal.DefaultNeverType
+
 // Nil is a type that represents the nil Value
 const Nil = internal.DefaultNilType
 
@@ -34,3 +34,17 @@ func Strings(values ...string) dgo.Array {
 func Integers(values ...int) dgo.Array {
 	return internal.Integers(values)
 }
+
+// PersistentArray returns a frozen dgo.Array that represents the values of the given slice. Arrays
+// derived from it using With, WithAll, or WithValues share structure with it so that each such update
+// is O(log n) instead of O(n).
+func PersistentArray(slice []dgo.Value) dgo.Array {
+	return internal.PersistentArray(slice)
+}
+
+// PersistentValues returns a frozen dgo.Array that represents the given values. Arrays derived from
+// it using With, WithAll, or WithValues share structure with it so that each such update is O(log n)
+// instead of O(n).
+func PersistentValues(values ...interface{}) dgo.Array {
+	return internal.PersistentValues(values)
+}
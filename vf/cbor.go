@@ -0,0 +1,35 @@
+package vf
+
+import (
+	"io"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// MarshalCBOR returns the CBOR encoding for the given value
+func MarshalCBOR(v interface{}) ([]byte, error) {
+	return internal.MarshalCBOR(internal.Value(v), false)
+}
+
+// MarshalDeterministicCBOR returns the deterministic CBOR encoding for the given value. Map keys are
+// sorted and all numbers use their shortest form so that the same value always yields the same bytes.
+func MarshalDeterministicCBOR(v interface{}) ([]byte, error) {
+	return internal.MarshalCBOR(internal.Value(v), true)
+}
+
+// UnmarshalCBOR decodes the CBOR representation of the given bytes into a dgo.Value
+func UnmarshalCBOR(b []byte) (dgo.Value, error) {
+	return internal.UnmarshalCBOR(b)
+}
+
+// EncodeCBOR writes the CBOR encoding for the given value to the given writer. The encoding is
+// deterministic when deterministic is true.
+func EncodeCBOR(w io.Writer, v interface{}, deterministic bool) error {
+	return internal.EncodeCBOR(w, internal.Value(v), deterministic)
+}
+
+// DecodeCBOR reads one CBOR encoded data item from the given reader and returns it as a dgo.Value
+func DecodeCBOR(r io.Reader) (dgo.Value, error) {
+	return internal.DecodeCBOR(r)
+}
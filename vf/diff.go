@@ -0,0 +1,13 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// Diff returns the structural difference between the values a and b as a list of additions, removals,
+// modifications, and moved Map keys, each keyed by a JSON Pointer path. The String method of the returned
+// dgo.Diff produces a human-readable report in unified format.
+func Diff(a, b dgo.Value) dgo.Diff {
+	return internal.Diff(a, b)
+}
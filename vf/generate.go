@@ -0,0 +1,15 @@
+package vf
+
+import (
+	"math/rand"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// Generate returns a random instance of the given type using the given source of randomness. Size
+// constraints, ranges, and patterns of the type are respected and an equally seeded source will always
+// produce the same value. An error is returned if no instance of the type could be produced.
+func Generate(t dgo.Type, src rand.Source) (dgo.Value, error) {
+	return internal.Generate(t, src)
+}
@@ -2,6 +2,7 @@ package vf
 
 import (
 	"encoding/json"
+	"io"
 
 	"github.com/lyraproj/dgo/dgo"
 	"github.com/lyraproj/dgo/internal"
@@ -17,7 +18,44 @@ func MarshalJSON(v interface{}) ([]byte, error) {
 	return json.Marshal(v)
 }
 
+// EncodeJSON writes the JSON representation of the given value to the given writer without first building
+// the complete document in memory.
+func EncodeJSON(w io.Writer, v interface{}, opts dgo.EncoderOptions) error {
+	return internal.EncodeJSON(w, internal.Value(v), opts)
+}
+
 // UnmarshalJSON decodes the JSON representation of the given bytes into a dgo.Value
 func UnmarshalJSON(b []byte) (dgo.Value, error) {
 	return internal.UnmarshalJSON(b)
 }
+
+// UnmarshalJSONWithOptions decodes the JSON representation of the given bytes into a dgo.Value using the given
+// options to control duplicate keys, numbers, limits, and whether or not the result is frozen.
+func UnmarshalJSONWithOptions(b []byte, opts dgo.JSONDecoderOptions) (dgo.Value, error) {
+	return internal.UnmarshalJSONWithOptions(b, opts)
+}
+
+// MarshalRichJSON returns a JSON encoding of the given value where binaries, regexps, errors, types, sets,
+// and maps with keys that are not strings are tagged so that UnmarshalRichJSON can restore them.
+func MarshalRichJSON(v interface{}) ([]byte, error) {
+	return internal.MarshalRichJSON(internal.Value(v))
+}
+
+// UnmarshalRichJSON decodes JSON produced by MarshalRichJSON into a dgo.Value
+func UnmarshalRichJSON(b []byte) (dgo.Value, error) {
+	return internal.UnmarshalRichJSON(b)
+}
+
+// DecodeJSON reads one JSON value from the given reader and validates it against the given type while it is
+// being read. A nil type means any value. An error that is caused by invalid JSON or by a value that isn't an
+// instance of its expected type contains the JSON Pointer to the value and its byte offset in the input.
+func DecodeJSON(r io.Reader, typ dgo.Type) (dgo.Value, error) {
+	return internal.DecodeJSON(r, typ)
+}
+
+// StreamJSON reads JSON values from the given reader, such as NDJSON or a top level array of records, and
+// calls the given consumer with each value, or with each element of a top level array, after validating it
+// against the given type. A top level array is never held in memory in its entirety.
+func StreamJSON(r io.Reader, typ dgo.Type, consumer func(dgo.Value) error) error {
+	return internal.StreamJSON(r, typ, consumer)
+}
@@ -24,3 +24,22 @@ func MutableMap(capacity int, typ interface{}) dgo.Map {
 func MapFromReflected(rm reflect.Value, frozen bool) dgo.Map {
 	return internal.MapFromReflected(rm, frozen)
 }
+
+// PersistentMap creates an immutable dgo.Map from the given argument which must be a go map or a
+// dgo.Map. Maps derived from it using With, Without, WithoutAll, or Merge share structure with it so
+// that each such update is O(log n) instead of O(n).
+func PersistentMap(m interface{}) dgo.Map {
+	return internal.PersistentMap(m)
+}
+
+// SortedMap creates an immutable dgo.SortedMap from the given argument which must be a go map or a
+// dgo.Map. The entries of a SortedMap are always ordered by the natural order of their keys.
+func SortedMap(m interface{}) dgo.SortedMap {
+	return internal.SortedMap(m)
+}
+
+// MutableSortedMap creates an empty dgo.SortedMap. The map can be optionally constrained by the given
+// type which can be nil, the zero value of a go map, or a dgo.MapType
+func MutableSortedMap(typ interface{}) dgo.SortedMap {
+	return internal.MutableSortedMap(typ)
+}
@@ -0,0 +1,29 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// FirstMerge returns the strategy that doesn't merge at all. The value with the highest precedence, i.e.
+// the second operand, is used as is.
+func FirstMerge() dgo.MergeStrategy {
+	return internal.FirstMerge()
+}
+
+// HashMerge returns the strategy that merges Maps recursively. Values that are not Maps are not merged.
+func HashMerge() dgo.MergeStrategy {
+	return internal.HashMerge()
+}
+
+// DeepMerge returns the strategy that merges Maps recursively and also merges Arrays. Arrays are
+// concatenated, or when unique is true, combined into a union of their distinct elements.
+func DeepMerge(unique bool) dgo.MergeStrategy {
+	return internal.DeepMerge(unique)
+}
+
+// MergeStrategyByName returns the strategy with the given name. Valid names are "first", "hash", "deep",
+// and "deep-unique". An error is returned for all other names.
+func MergeStrategyByName(name string) (dgo.MergeStrategy, error) {
+	return internal.MergeStrategyByName(name)
+}
@@ -0,0 +1,28 @@
+package vf
+
+import (
+	"io"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// MarshalMsgPack returns the MessagePack encoding for the given value
+func MarshalMsgPack(v interface{}) ([]byte, error) {
+	return internal.MarshalMsgPack(internal.Value(v))
+}
+
+// UnmarshalMsgPack decodes the MessagePack representation of the given bytes into a dgo.Value
+func UnmarshalMsgPack(b []byte) (dgo.Value, error) {
+	return internal.UnmarshalMsgPack(b)
+}
+
+// EncodeMsgPack writes the MessagePack encoding for the given value to the given writer
+func EncodeMsgPack(w io.Writer, v interface{}) error {
+	return internal.EncodeMsgPack(w, internal.Value(v))
+}
+
+// DecodeMsgPack reads one MessagePack encoded value from the given reader and returns it as a dgo.Value
+func DecodeMsgPack(r io.Reader) (dgo.Value, error) {
+	return internal.DecodeMsgPack(r)
+}
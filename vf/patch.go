@@ -0,0 +1,32 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// ApplyPatch applies the given JSON Patch (RFC 6902) to the given value and returns the resulting frozen
+// value. The patch is an Array of operation Maps such as the one produced by UnmarshalJSON when given a
+// JSON Patch document. Frozen parts of the original value that are unaffected by the patch are shared with
+// the returned value. An error is returned if an operation is malformed or fails.
+func ApplyPatch(v dgo.Value, patch dgo.Value) (dgo.Value, error) {
+	return internal.ApplyPatch(v, patch)
+}
+
+// ApplyMergePatch applies the given JSON Merge Patch (RFC 7396) to the given value and returns the
+// resulting frozen value.
+func ApplyMergePatch(v dgo.Value, patch dgo.Value) dgo.Value {
+	return internal.ApplyMergePatch(v, patch)
+}
+
+// CreatePatch returns a JSON Patch (RFC 6902) that transforms the value a into the value b when applied
+// using ApplyPatch.
+func CreatePatch(a, b dgo.Value) dgo.Array {
+	return internal.CreatePatch(a, b)
+}
+
+// CreateMergePatch returns a JSON Merge Patch (RFC 7396) that transforms the value a into the value b when
+// applied using ApplyMergePatch.
+func CreateMergePatch(a, b dgo.Value) dgo.Value {
+	return internal.CreateMergePatch(a, b)
+}
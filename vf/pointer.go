@@ -0,0 +1,27 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// GetPath returns the value that the given JSON Pointer (RFC 6901) refers to in the given value. The error
+// is a dgo.MissingKeyError, dgo.IndexOutOfRangeError, or dgo.PathTypeError when the pointer cannot be
+// resolved.
+func GetPath(v dgo.Value, pointer string) (dgo.Value, error) {
+	return internal.GetPath(v, pointer)
+}
+
+// SetPath returns a frozen copy of the given value where the value that the given JSON Pointer (RFC 6901)
+// refers to has been set to the given value. The last token of the pointer may denote a new key in a Map
+// or be "-" to append the value to an Array. Frozen parts of the original value that are unaffected by
+// the change are shared with the returned value.
+func SetPath(v dgo.Value, pointer string, value interface{}) (dgo.Value, error) {
+	return internal.SetPath(v, pointer, value)
+}
+
+// DeletePath returns a frozen copy of the given value where the value that the given JSON Pointer
+// (RFC 6901) refers to has been removed.
+func DeletePath(v dgo.Value, pointer string) (dgo.Value, error) {
+	return internal.DeletePath(v, pointer)
+}
@@ -0,0 +1,14 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// CompileQuery compiles the given JSONPath expression, e.g. `$.services[?(@.port > 1024)].name`, into
+// a dgo.Query that can be used to select values from any dgo.Value. Filter expressions compare values
+// using Equals and CompareTo and may test the type of a value using the "is" operator, as in
+// `$.ports[?(@ is 1..100)]`. An error is returned when the expression has invalid syntax.
+func CompileQuery(expr string) (dgo.Query, error) {
+	return internal.CompileQuery(expr)
+}
@@ -0,0 +1,17 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// Set returns a frozen dgo.Set that contains the given values
+func Set(values ...interface{}) dgo.Set {
+	return internal.Set(values)
+}
+
+// MutableSet returns a mutable dgo.Set that contains the given values. The set is optionally
+// constrained by the given type which can be nil.
+func MutableSet(typ dgo.SetType, values ...interface{}) dgo.Set {
+	return internal.MutableSet(typ, values)
+}
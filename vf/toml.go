@@ -0,0 +1,18 @@
+package vf
+
+import (
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// MarshalTOML returns the TOML representation of the given map. An error is returned if the map contains
+// a value that has no TOML representation.
+func MarshalTOML(m dgo.Map) ([]byte, error) {
+	return internal.MarshalTOML(m)
+}
+
+// UnmarshalTOML decodes the given TOML document into a frozen dgo.Map that retains the order of the
+// document.
+func UnmarshalTOML(b []byte) (dgo.Map, error) {
+	return internal.UnmarshalTOML(b)
+}
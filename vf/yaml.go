@@ -0,0 +1,26 @@
+package vf
+
+import (
+	"io"
+
+	"github.com/lyraproj/dgo/dgo"
+	"github.com/lyraproj/dgo/internal"
+)
+
+// UnmarshalYAML decodes the YAML representation of the given bytes into a dgo.Value. Nodes with
+// unrecognized tags are decoded as if they were untagged.
+func UnmarshalYAML(b []byte) (dgo.Value, error) {
+	return internal.UnmarshalYAML(b)
+}
+
+// UnmarshalYAMLStrict decodes the YAML representation of the given bytes into a dgo.Value and returns an
+// error if a node has a tag that isn't recognized.
+func UnmarshalYAMLStrict(b []byte) (dgo.Value, error) {
+	return internal.UnmarshalYAMLStrict(b)
+}
+
+// EncodeYAML writes the YAML representation of the given value to the given writer without first building
+// the complete document in memory.
+func EncodeYAML(w io.Writer, v interface{}, opts dgo.EncoderOptions) error {
+	return internal.EncodeYAML(w, internal.Value(v), opts)
+}